@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -13,10 +14,20 @@ type MetricMaker interface {
 	Log() telegraf.Logger
 }
 
+// errorRecorder is implemented by metric makers keeping track of the errors
+// reported via the accumulator
+type errorRecorder interface {
+	RecordError(err error)
+}
+
 type accumulator struct {
 	maker     MetricMaker
 	metrics   chan<- telegraf.Metric
 	precision time.Duration
+
+	// now returns the time of metrics added without timestamp, defaults
+	// to the wall-clock time
+	now func() time.Time
 }
 
 func NewAccumulator(
@@ -103,6 +114,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	ac.maker.Log().Errorf("Error in plugin: %v", err)
+	if r, ok := ac.maker.(errorRecorder); ok {
+		r.RecordError(err)
+	}
 }
 
 func (ac *accumulator) SetPrecision(precision time.Duration) {
@@ -113,6 +127,8 @@ func (ac *accumulator) getTime(t []time.Time) time.Time {
 	var timestamp time.Time
 	if len(t) > 0 {
 		timestamp = t[0]
+	} else if ac.now != nil {
+		timestamp = ac.now()
 	} else {
 		timestamp = time.Now()
 	}
@@ -158,3 +174,80 @@ func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
 		panic("channel is full")
 	}
 }
+
+// chainAccumulator is passed to processors on start. The destination of the
+// metrics is redirected when the processor is handed over to a new chain.
+type chainAccumulator struct {
+	sync.RWMutex
+	acc *accumulator
+}
+
+func newChainAccumulator(maker MetricMaker, metrics chan<- telegraf.Metric) *chainAccumulator {
+	return &chainAccumulator{
+		acc: &accumulator{
+			maker:     maker,
+			metrics:   metrics,
+			precision: time.Nanosecond,
+		},
+	}
+}
+
+// redirect sends all further metrics to the given channel
+func (a *chainAccumulator) redirect(metrics chan<- telegraf.Metric) {
+	a.Lock()
+	defer a.Unlock()
+	a.acc.metrics = metrics
+}
+
+func (a *chainAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.RLock()
+	defer a.RUnlock()
+	a.acc.AddFields(measurement, fields, tags, t...)
+}
+
+func (a *chainAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.RLock()
+	defer a.RUnlock()
+	a.acc.AddGauge(measurement, fields, tags, t...)
+}
+
+func (a *chainAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.RLock()
+	defer a.RUnlock()
+	a.acc.AddCounter(measurement, fields, tags, t...)
+}
+
+func (a *chainAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.RLock()
+	defer a.RUnlock()
+	a.acc.AddSummary(measurement, fields, tags, t...)
+}
+
+func (a *chainAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.RLock()
+	defer a.RUnlock()
+	a.acc.AddHistogram(measurement, fields, tags, t...)
+}
+
+func (a *chainAccumulator) AddMetric(m telegraf.Metric) {
+	a.RLock()
+	defer a.RUnlock()
+	a.acc.AddMetric(m)
+}
+
+func (a *chainAccumulator) AddError(err error) {
+	a.acc.AddError(err)
+}
+
+func (a *chainAccumulator) SetPrecision(precision time.Duration) {
+	a.Lock()
+	defer a.Unlock()
+	a.acc.SetPrecision(precision)
+}
+
+func (a *chainAccumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		Accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
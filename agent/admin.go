@@ -0,0 +1,287 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+// adminHeader must be set on state-changing requests if no token is
+// configured. Browsers cannot set custom headers on cross-origin requests
+// without a preflight, so this protects against cross-site request forgery.
+const adminHeader = "X-Telegraf-Admin"
+
+// pluginInfo identifies a running plugin in the admin API
+type pluginInfo struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Alias string `json:"alias,omitempty"`
+}
+
+type pluginList struct {
+	Inputs      []pluginInfo `json:"inputs"`
+	Processors  []pluginInfo `json:"processors"`
+	Aggregators []pluginInfo `json:"aggregators"`
+	Outputs     []pluginInfo `json:"outputs"`
+}
+
+type inputStatus struct {
+	pluginInfo
+	LastGather    *time.Time `json:"last_gather,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+type outputStatus struct {
+	pluginInfo
+	BufferLength  int        `json:"buffer_length"`
+	LastWrite     *time.Time `json:"last_write,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+}
+
+// adminServer serves the local HTTP API to inspect and control the agent
+type adminServer struct {
+	agent    *Agent
+	token    *config.Secret
+	server   *http.Server
+	listener net.Listener
+}
+
+func newAdminServer(a *Agent, address string, token *config.Secret) (*adminServer, error) {
+	if token != nil && token.Empty() {
+		token = nil
+	}
+	if token == nil && !isLoopbackAddress(address) {
+		return nil, fmt.Errorf("admin API address %q is not a loopback address, an admin token is required", address)
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listening for admin API failed: %w", err)
+	}
+
+	s := &adminServer{agent: a, token: token, listener: listener}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /plugins", s.handlePlugins)
+	mux.HandleFunc("GET /inputs", s.handleInputs)
+	mux.HandleFunc("GET /outputs", s.handleOutputs)
+	mux.HandleFunc("POST /gather", s.handleGather)
+	mux.HandleFunc("POST /flush", s.handleFlush)
+	mux.HandleFunc("POST /reload", s.handleReload)
+
+	s.server = &http.Server{
+		Handler:      s.authorize(mux),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	return s, nil
+}
+
+// isLoopbackAddress checks if the given listen address only accepts local
+// connections. Unspecified hosts bind to all interfaces and are not local.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorize refuses requests issued by browsers and checks the bearer token
+// if configured. Without a token, state-changing requests must carry the
+// admin header to protect against cross-site request forgery.
+func (s *adminServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+
+		if s.token != nil {
+			auth, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing bearer token", http.StatusUnauthorized)
+				return
+			}
+			valid, err := s.token.EqualTo([]byte(auth))
+			if err != nil {
+				log.Printf("E! [agent] Getting admin token failed: %v", err)
+				http.Error(w, "checking token failed", http.StatusInternalServerError)
+				return
+			}
+			if !valid {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+		} else if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Header.Get(adminHeader) == "" {
+			http.Error(w, "missing "+adminHeader+" header", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *adminServer) start() {
+	log.Printf("I! [agent] Starting admin API at: http://%s", s.listener.Addr())
+	go func() {
+		if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! [agent] Admin API failed: %v", err)
+		}
+	}()
+}
+
+func (s *adminServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Printf("E! [agent] Stopping admin API failed: %v", err)
+	}
+}
+
+func (s *adminServer) handlePlugins(w http.ResponseWriter, _ *http.Request) {
+	inputs, outputs := s.agent.inputs(), s.agent.outputs()
+	processors, aggProcessors, aggregators := s.agent.processing()
+
+	list := pluginList{
+		Inputs:      make([]pluginInfo, 0, len(inputs)),
+		Processors:  make([]pluginInfo, 0, len(processors)+len(aggProcessors)),
+		Aggregators: make([]pluginInfo, 0, len(aggregators)),
+		Outputs:     make([]pluginInfo, 0, len(outputs)),
+	}
+	for _, p := range inputs {
+		list.Inputs = append(list.Inputs, pluginInfo{ID: p.ID(), Name: p.Config.Name, Alias: p.Config.Alias})
+	}
+	for _, p := range processors {
+		list.Processors = append(list.Processors, pluginInfo{ID: p.ID(), Name: p.Config.Name, Alias: p.Config.Alias})
+	}
+	for _, p := range aggProcessors {
+		list.Processors = append(list.Processors, pluginInfo{ID: p.ID(), Name: p.Config.Name, Alias: p.Config.Alias})
+	}
+	for _, p := range aggregators {
+		list.Aggregators = append(list.Aggregators, pluginInfo{ID: p.ID(), Name: p.Config.Name, Alias: p.Config.Alias})
+	}
+	for _, p := range outputs {
+		list.Outputs = append(list.Outputs, pluginInfo{ID: p.ID(), Name: p.Config.Name, Alias: p.Config.Alias})
+	}
+
+	writeJSON(w, list)
+}
+
+func (s *adminServer) handleInputs(w http.ResponseWriter, _ *http.Request) {
+	inputs := s.agent.inputs()
+	result := make([]inputStatus, 0, len(inputs))
+	for _, input := range inputs {
+		status := input.Status()
+		result = append(result, inputStatus{
+			pluginInfo:    pluginInfo{ID: input.ID(), Name: input.Config.Name, Alias: input.Config.Alias},
+			LastGather:    optionalTime(status.LastRun),
+			LastError:     status.LastError,
+			LastErrorTime: optionalTime(status.LastErrorTime),
+		})
+	}
+
+	writeJSON(w, result)
+}
+
+func (s *adminServer) handleOutputs(w http.ResponseWriter, _ *http.Request) {
+	outputs := s.agent.outputs()
+	result := make([]outputStatus, 0, len(outputs))
+	for _, output := range outputs {
+		status := output.Status()
+		result = append(result, outputStatus{
+			pluginInfo:    pluginInfo{ID: output.ID(), Name: output.Config.Name, Alias: output.Config.Alias},
+			BufferLength:  output.BufferLength(),
+			LastWrite:     optionalTime(status.LastRun),
+			LastError:     status.LastError,
+			LastErrorTime: optionalTime(status.LastErrorTime),
+		})
+	}
+
+	writeJSON(w, result)
+}
+
+// handleGather triggers a gather of the input given by the "id" query
+// parameter or of all inputs if no ID is given
+func (s *adminServer) handleGather(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	var found bool
+	for _, input := range s.agent.inputs() {
+		if id == "" || input.ID() == id {
+			input.RequestGather()
+			found = true
+		}
+	}
+	if id != "" && !found {
+		http.Error(w, fmt.Sprintf("input %q not found", id), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleFlush triggers a flush of the output given by the "id" query
+// parameter or of all outputs if no ID is given
+func (s *adminServer) handleFlush(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	var found bool
+	for _, output := range s.agent.outputs() {
+		if id == "" || output.ID() == id {
+			output.RequestFlush()
+			found = true
+		}
+	}
+	if id != "" && !found {
+		http.Error(w, fmt.Sprintf("output %q not found", id), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *adminServer) handleReload(w http.ResponseWriter, _ *http.Request) {
+	if s.agent.reload == nil {
+		http.Error(w, "reloading is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	log.Println("I! [agent] Reload requested via admin API")
+	w.WriteHeader(http.StatusAccepted)
+
+	// The reload stops the agent including this server, so do not block
+	// the response
+	go s.agent.reload()
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! [agent] Encoding admin API response failed: %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newAdminTestAgent(t *testing.T) (*Agent, string) {
+	t.Helper()
+
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&adminTestInput{}, &models.InputConfig{Name: "test", ID: "input-1"}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput(&adminTestOutput{}, &models.OutputConfig{Name: "test", Alias: "out", ID: "output-1"}, 10, 100))
+	a := NewAgent(c)
+
+	admin, err := newAdminServer(a, "localhost:0", nil)
+	require.NoError(t, err)
+	admin.start()
+	t.Cleanup(admin.stop)
+
+	return a, "http://" + admin.listener.Addr().String()
+}
+
+func adminPost(t *testing.T, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	require.NoError(t, err)
+	req.Header.Set(adminHeader, "true")
+	return http.DefaultClient.Do(req)
+}
+
+func TestAdminPlugins(t *testing.T) {
+	_, addr := newAdminTestAgent(t)
+
+	resp, err := http.Get(addr + "/plugins")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var actual pluginList
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	expected := pluginList{
+		Inputs:      []pluginInfo{{ID: "input-1", Name: "test"}},
+		Processors:  []pluginInfo{},
+		Aggregators: []pluginInfo{},
+		Outputs:     []pluginInfo{{ID: "output-1", Name: "test", Alias: "out"}},
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestAdminInputStatus(t *testing.T) {
+	a, addr := newAdminTestAgent(t)
+
+	// Do not leak the error into the global statistics checked by other tests
+	gatherErrors := models.GlobalGatherErrors.Get()
+	t.Cleanup(func() { models.GlobalGatherErrors.Set(gatherErrors) })
+
+	input := a.Config.Inputs[0]
+	acc := NewAccumulator(input, make(chan telegraf.Metric, 10))
+	acc.AddError(input.Gather(acc))
+
+	resp, err := http.Get(addr + "/inputs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var actual []inputStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	require.Len(t, actual, 1)
+	require.Equal(t, "input-1", actual[0].ID)
+	require.NotNil(t, actual[0].LastGather)
+	require.Equal(t, "gather failed", actual[0].LastError)
+	require.NotNil(t, actual[0].LastErrorTime)
+}
+
+func TestAdminOutputStatus(t *testing.T) {
+	a, addr := newAdminTestAgent(t)
+
+	output := a.Config.Outputs[0]
+	output.AddMetric(testutil.TestMetric(1))
+	output.AddMetric(testutil.TestMetric(2))
+	require.Error(t, output.Write())
+
+	resp, err := http.Get(addr + "/outputs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var actual []outputStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	require.Len(t, actual, 1)
+	require.Equal(t, "output-1", actual[0].ID)
+	require.Equal(t, 2, actual[0].BufferLength)
+	require.NotNil(t, actual[0].LastWrite)
+	require.Equal(t, "write failed", actual[0].LastError)
+}
+
+func TestAdminTriggers(t *testing.T) {
+	a, addr := newAdminTestAgent(t)
+
+	resp, err := adminPost(t, addr+"/gather")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, a.Config.Inputs[0].GatherRequest, 1)
+
+	resp, err = adminPost(t, addr+"/flush?id=output-1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, a.Config.Outputs[0].FlushRequest, 1)
+
+	resp, err = adminPost(t, addr+"/flush?id=unknown")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestAdminReload(t *testing.T) {
+	a, addr := newAdminTestAgent(t)
+
+	// Reloading is not possible without a handler
+	resp, err := adminPost(t, addr+"/reload")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+
+	reloaded := make(chan bool, 1)
+	a.SetReloadHandler(func() { reloaded <- true })
+
+	resp, err = adminPost(t, addr+"/reload")
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "reload handler not called")
+	}
+}
+
+func TestAdminRequireHeader(t *testing.T) {
+	a, addr := newAdminTestAgent(t)
+
+	resp, err := http.Post(addr+"/gather", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Empty(t, a.Config.Inputs[0].GatherRequest)
+}
+
+func TestAdminRefuseOrigin(t *testing.T) {
+	a, addr := newAdminTestAgent(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		req, err := http.NewRequest(method, addr+"/gather", nil)
+		require.NoError(t, err)
+		req.Header.Set(adminHeader, "true")
+		req.Header.Set("Origin", "https://example.com")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode, method)
+	}
+	require.Empty(t, a.Config.Inputs[0].GatherRequest)
+}
+
+func TestAdminToken(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&adminTestInput{}, &models.InputConfig{Name: "test", ID: "input-1"}))
+	a := NewAgent(c)
+
+	token := config.NewSecret([]byte("s3cr3t"))
+	defer token.Destroy()
+
+	admin, err := newAdminServer(a, "localhost:0", &token)
+	require.NoError(t, err)
+	admin.start()
+	t.Cleanup(admin.stop)
+	addr := "http://" + admin.listener.Addr().String()
+
+	tests := []struct {
+		name     string
+		auth     string
+		expected int
+	}{
+		{name: "missing", expected: http.StatusUnauthorized},
+		{name: "invalid", auth: "Bearer wrong", expected: http.StatusUnauthorized},
+		{name: "basic", auth: "Basic czNjcjN0", expected: http.StatusUnauthorized},
+		{name: "valid", auth: "Bearer s3cr3t", expected: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, addr+"/plugins", nil)
+			require.NoError(t, err)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tt.expected, resp.StatusCode)
+		})
+	}
+
+	// The token replaces the admin header for state-changing requests
+	req, err := http.NewRequest(http.MethodPost, addr+"/gather", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Len(t, a.Config.Inputs[0].GatherRequest, 1)
+}
+
+func TestAdminNonLoopbackAddress(t *testing.T) {
+	a := NewAgent(config.NewConfig())
+
+	for _, address := range []string{":0", "0.0.0.0:0", "[::]:0"} {
+		_, err := newAdminServer(a, address, nil)
+		require.ErrorContains(t, err, "admin token is required", address)
+	}
+
+	token := config.NewSecret([]byte("s3cr3t"))
+	defer token.Destroy()
+
+	admin, err := newAdminServer(a, "0.0.0.0:0", &token)
+	require.NoError(t, err)
+	require.NoError(t, admin.listener.Close())
+}
+
+func TestAdminLoopbackAddress(t *testing.T) {
+	for _, address := range []string{"localhost:8089", "LocalHost:8089", "127.0.0.1:8089", "127.1.2.3:8089", "[::1]:8089"} {
+		require.True(t, isLoopbackAddress(address), address)
+	}
+	for _, address := range []string{":8089", "0.0.0.0:8089", "[::]:8089", "192.168.1.1:8089", "example.com:8089", "localhost"} {
+		require.False(t, isLoopbackAddress(address), address)
+	}
+}
+
+type adminTestInput struct{}
+
+func (*adminTestInput) SampleConfig() string {
+	return ""
+}
+
+func (*adminTestInput) Gather(telegraf.Accumulator) error {
+	return errors.New("gather failed")
+}
+
+type adminTestOutput struct{}
+
+func (*adminTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (*adminTestOutput) Connect() error {
+	return nil
+}
+
+func (*adminTestOutput) Close() error {
+	return nil
+}
+
+func (*adminTestOutput) Write([]telegraf.Metric) error {
+	return errors.New("write failed")
+}
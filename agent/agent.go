@@ -24,6 +24,19 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	reload func()
+
+	// Units running while the agent is running allowing to add and remove
+	// plugins at runtime
+	unitsLock      sync.Mutex
+	inputUnit      *inputUnit
+	processingUnit *processingUnit
+	outputUnit     *outputUnit
+
+	// Configurations applied at runtime whose secret-stores might be used by
+	// the running plugins
+	reloaded []*config.Config
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -34,6 +47,57 @@ func NewAgent(cfg *config.Config) *Agent {
 	return a
 }
 
+// SetReloadHandler sets the function called when a configuration reload is
+// requested via the admin API
+func (a *Agent) SetReloadHandler(f func()) {
+	a.reload = f
+}
+
+// inputs returns the currently running inputs or the configured ones if the
+// agent is not running
+func (a *Agent) inputs() []*models.RunningInput {
+	a.unitsLock.Lock()
+	unit := a.inputUnit
+	a.unitsLock.Unlock()
+	if unit == nil {
+		return a.Config.Inputs
+	}
+
+	unit.Lock()
+	defer unit.Unlock()
+	return unit.inputs
+}
+
+// outputs returns the currently running outputs or the configured ones if
+// the agent is not running
+func (a *Agent) outputs() []*models.RunningOutput {
+	a.unitsLock.Lock()
+	unit := a.outputUnit
+	a.unitsLock.Unlock()
+	if unit == nil {
+		return a.Config.Outputs
+	}
+
+	unit.RLock()
+	defer unit.RUnlock()
+	return unit.outputs
+}
+
+// processing returns the currently running processors and aggregators or
+// the configured ones if the agent is not running
+func (a *Agent) processing() (processors, aggProcessors models.RunningProcessors, aggregators []*models.RunningAggregator) {
+	a.unitsLock.Lock()
+	unit := a.processingUnit
+	a.unitsLock.Unlock()
+	if unit == nil {
+		return a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators
+	}
+
+	unit.Lock()
+	defer unit.Unlock()
+	return unit.chain.processors, unit.chain.aggProcessors, unit.chain.aggregators
+}
+
 // inputUnit is a group of input plugins and the shared channel they write to.
 //
 // ┌───────┐
@@ -48,6 +112,25 @@ func NewAgent(cfg *config.Config) *Agent {
 type inputUnit struct {
 	dst    chan<- telegraf.Metric
 	inputs []*models.RunningInput
+
+	// Gather loops of the running inputs, protected by the lock
+	sync.Mutex
+	ctx       context.Context
+	startTime time.Time
+	loops     map[*models.RunningInput]*pluginLoop
+	wg        sync.WaitGroup
+	closed    bool
+}
+
+// pluginLoop allows to stop the loop of an individual plugin
+type pluginLoop struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (l *pluginLoop) stop() {
+	l.cancel()
+	<-l.done
 }
 
 //  ______     ┌───────────┐     ______
@@ -58,6 +141,32 @@ type processorUnit struct {
 	src       <-chan telegraf.Metric
 	dst       chan<- telegraf.Metric
 	processor *models.RunningProcessor
+
+	// Accumulator passed to the processor on start and the chain the unit
+	// belongs to, if any
+	acc   *chainAccumulator
+	chain *processingChain
+}
+
+// stop ends the processor after the unit is drained. Processors handed over
+// to the next chain keep running and processors never released by the
+// previous chain are left to that chain.
+func (u *processorUnit) stop() {
+	if h := u.chain.takenOver(u.processor); h != nil {
+		select {
+		case <-h.released:
+			u.acc = h.acc
+		default:
+			return
+		}
+	}
+	if h := u.chain.handOver(u.processor); h != nil {
+		u.acc.redirect(h.dst)
+		h.acc = u.acc
+		close(h.released)
+		return
+	}
+	u.processor.Stop()
 }
 
 // aggregatorUnit is a group of Aggregators and their source and sink channels.
@@ -81,6 +190,143 @@ type aggregatorUnit struct {
 	aggC        chan<- telegraf.Metric
 	outputC     chan<- telegraf.Metric
 	aggregators []*models.RunningAggregator
+	chain       *processingChain
+}
+
+// processingUnit relays the metrics of the inputs to the chain of processors
+// and aggregators and passes the metrics leaving the chain on to the outputs.
+// This way the chain can be replaced at runtime without touching the inputs
+// and outputs.
+
+//  ______     ┌───────┐     ┌───────┐     ______
+// ()_____)──▶ │ Relay │──▶ │ Chain │──▶ ()_____)
+//             └───────┘     └───────┘
+
+type processingUnit struct {
+	src <-chan telegraf.Metric
+	dst chan<- telegraf.Metric
+
+	// Requests to replace the chain, acknowledged once the previous chain
+	// is drained and the new chain receives metrics
+	swap    chan *processingChain
+	swapped chan struct{}
+	done    chan struct{}
+
+	// The running chain, protected by the lock
+	sync.Mutex
+	chain *processingChain
+}
+
+// processingChain is a started set of processors and aggregators. Metrics
+// leaving the chain are only passed on once the chain is forwarded.
+type processingChain struct {
+	src  chan<- telegraf.Metric
+	tail chan telegraf.Metric
+	wg   sync.WaitGroup
+
+	processors    models.RunningProcessors
+	aggProcessors models.RunningProcessors
+	aggregators   []*models.RunningAggregator
+
+	// Running processors and aggregators taken over from the previous
+	// chain, only used once released by that chain
+	takeover map[interface{}]*handover
+
+	// The chain replacing this chain, set before stopping the chain
+	next *processingChain
+}
+
+// handover passes a running processor or aggregator on to the chain taking
+// over the plugin
+type handover struct {
+	// Destination of the processor in the new chain and the accumulator the
+	// processor was started with
+	dst chan<- telegraf.Metric
+	acc *chainAccumulator
+
+	// Closed once the previous chain does not use the plugin anymore
+	released chan struct{}
+}
+
+// takenOver returns the handover of a plugin taken over from the previous
+// chain or nil otherwise
+func (c *processingChain) takenOver(plugin interface{}) *handover {
+	if c == nil {
+		return nil
+	}
+	return c.takeover[plugin]
+}
+
+// handOver returns the handover of a plugin if the next chain takes over the
+// plugin or nil otherwise
+func (c *processingChain) handOver(plugin interface{}) *handover {
+	if c == nil {
+		return nil
+	}
+	return c.next.takenOver(plugin)
+}
+
+// acquire waits until the previous chain released the plugin if taken over
+// and returns false if the context is done before
+func (c *processingChain) acquire(ctx context.Context, plugin interface{}) bool {
+	h := c.takenOver(plugin)
+	if h == nil {
+		return true
+	}
+	select {
+	case <-h.released:
+		return true
+	case <-ctx.Done():
+		// Prefer the release if both happened
+		select {
+		case <-h.released:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// forward passes the metrics leaving the chain on to the given channel
+func (c *processingChain) forward(dst chan<- telegraf.Metric) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for m := range c.tail {
+			dst <- m
+		}
+	}()
+}
+
+// discard drops the metrics leaving the chain, e.g. for chains never
+// receiving metrics
+func (c *processingChain) discard() {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for m := range c.tail {
+			m.Drop()
+		}
+	}()
+}
+
+// stop closes the chain and waits until all metrics left the chain and all
+// plugins are stopped
+func (c *processingChain) stop() {
+	close(c.src)
+	c.wg.Wait()
+}
+
+// replace swaps the running chain for the given one and returns false if
+// the unit is not running anymore
+func (u *processingUnit) replace(chain *processingChain) bool {
+	select {
+	case u.swap <- chain:
+		<-u.swapped
+		return true
+	case <-u.done:
+		return false
+	}
 }
 
 // outputUnit is a group of Outputs and their source channel.  Metrics on the
@@ -99,23 +345,38 @@ type aggregatorUnit struct {
 type outputUnit struct {
 	src     <-chan telegraf.Metric
 	outputs []*models.RunningOutput
+
+	// Flush loops of the running outputs and the outputs receiving metrics,
+	// protected by the lock
+	sync.RWMutex
+	ctx       context.Context
+	receivers []*models.RunningOutput
+	loops     map[*models.RunningOutput]*pluginLoop
+	wg        sync.WaitGroup
+	closed    bool
+}
+
+// updateReceivers determines the outputs receiving metrics. Failover and
+// dead-letter outputs in standby only receive metrics from their primaries.
+func (u *outputUnit) updateReceivers() {
+	u.receivers = make([]*models.RunningOutput, 0, len(u.outputs))
+	for _, output := range u.outputs {
+		if !output.Standby() {
+			u.receivers = append(u.receivers, output)
+		}
+	}
 }
 
 // Run starts and runs the Agent until the context is done.
 func (a *Agent) Run(ctx context.Context) error {
+	defer a.Config.StopSecretStores()
+
 	log.Printf("I! [agent] Config: Interval:%s, Quiet:%#v, Hostname:%#v, "+
 		"Flush Interval:%s",
 		time.Duration(a.Config.Agent.Interval), a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, time.Duration(a.Config.Agent.FlushInterval))
 
-	// Set the default for processor skipping
-	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
-		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
-		msg += `If you need the current default behavior, please explicitly set the option to 'false'!`
-		log.Print("W! [agent] ", color.YellowString(msg))
-		skipProcessorsAfterAggregators := false
-		a.Config.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
-	}
+	a.setSkipProcessorsDefault()
 
 	log.Printf("D! [agent] Initializing plugins")
 	if err := a.InitPlugins(); err != nil {
@@ -143,33 +404,49 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(aggC, next, a.Config.Aggregators)
+	chain, err := a.startChain(a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators, nil, a.wallClockAggregators(startTime))
+	if err != nil {
+		return err
 	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
+	src := make(chan telegraf.Metric, 100)
+	pu := &processingUnit{
+		src:     src,
+		dst:     next,
+		swap:    make(chan *processingChain),
+		swapped: make(chan struct{}),
+		done:    make(chan struct{}),
+		chain:   chain,
 	}
 
-	iu, err := a.startInputs(next, a.Config.Inputs)
+	iu, err := a.startInputs(src, a.Config.Inputs)
 	if err != nil {
 		return err
 	}
 
+	if a.Config.Agent.AdminAddress != "" {
+		admin, err := newAdminServer(a, a.Config.Agent.AdminAddress, &a.Config.Agent.AdminToken)
+		if err != nil {
+			return err
+		}
+		admin.start()
+		defer admin.stop()
+	}
+
+	a.unitsLock.Lock()
+	a.inputUnit, a.processingUnit, a.outputUnit = iu, pu, ou
+	a.unitsLock.Unlock()
+	defer func() {
+		a.unitsLock.Lock()
+		a.inputUnit, a.processingUnit, a.outputUnit = nil, nil, nil
+		reloaded := a.reloaded
+		a.reloaded = nil
+		a.unitsLock.Unlock()
+
+		for _, c := range reloaded {
+			c.StopSecretStores()
+		}
+	}()
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -177,34 +454,26 @@ func (a *Agent) Run(ctx context.Context) error {
 		a.runOutputs(ou)
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runProcessing(pu)
+	}()
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runInputs(ctx, startTime, iu)
+	}()
 
-	if pu != nil {
+	if a.Config.Persister != nil && a.Config.Agent.StatefileFlushInterval > 0 {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			a.runProcessors(pu)
+			a.runPersister(ctx, time.Duration(a.Config.Agent.StatefileFlushInterval))
 		}()
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.runInputs(ctx, startTime, iu)
-	}()
-
 	wg.Wait()
 
 	if a.Config.Persister != nil {
@@ -218,6 +487,19 @@ func (a *Agent) Run(ctx context.Context) error {
 	return err
 }
 
+// setSkipProcessorsDefault sets the default for skipping the processors after
+// the aggregators and warns about the upcoming change of the default.
+func (a *Agent) setSkipProcessorsDefault() {
+	if a.Config.Agent.SkipProcessorsAfterAggregators != nil {
+		return
+	}
+	msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
+	msg += `If you need the current default behavior, please explicitly set the option to 'false'!`
+	log.Print("W! [agent] ", color.YellowString(msg))
+	skipProcessorsAfterAggregators := false
+	a.Config.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
+}
+
 // InitPlugins runs the Init function on plugins.
 func (a *Agent) InitPlugins() error {
 	for _, input := range a.Config.Inputs {
@@ -230,32 +512,48 @@ func (a *Agent) InitPlugins() error {
 			return fmt.Errorf("could not initialize input %s: %w", input.LogName(), err)
 		}
 	}
-	for _, processor := range a.Config.Processors {
+	if err := a.initProcessing(); err != nil {
+		return err
+	}
+	for _, output := range a.Config.Outputs {
+		err := output.Init()
+		if err != nil {
+			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
+		}
+	}
+	return nil
+}
+
+// initProcessing runs the Init function on processors and aggregators.
+func (a *Agent) initProcessing() error {
+	return a.initChain(a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators)
+}
+
+// initChain runs the Init function on the given processors and aggregators.
+func (a *Agent) initChain(
+	processors, aggProcessors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+) error {
+	for _, processor := range processors {
 		err := processor.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
 		}
 	}
-	for _, aggregator := range a.Config.Aggregators {
+	for _, aggregator := range aggregators {
 		err := aggregator.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize aggregator %s: %w", aggregator.LogName(), err)
 		}
 	}
 	if !*a.Config.Agent.SkipProcessorsAfterAggregators {
-		for _, processor := range a.Config.AggProcessors {
+		for _, processor := range aggProcessors {
 			err := processor.Init()
 			if err != nil {
 				return fmt.Errorf("could not initialize processor %s: %w", processor.LogName(), err)
 			}
 		}
 	}
-	for _, output := range a.Config.Outputs {
-		err := output.Init()
-		if err != nil {
-			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
-		}
-	}
 	return nil
 }
 
@@ -341,6 +639,25 @@ func (a *Agent) initPersister() error {
 	return nil
 }
 
+// runPersister periodically saves the plugin states until the context is
+// done. The final state is saved on shutdown of the agent.
+func (a *Agent) runPersister(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Printf("D! [agent] Saving checkpoint of plugin states")
+			if err := a.Config.Persister.Store(); err != nil {
+				log.Printf("E! [agent] Saving plugin states failed: %v", err)
+			}
+		}
+	}
+}
+
 func (*Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningInput) (*inputUnit, error) {
 	log.Printf("D! [agent] Starting service inputs")
 
@@ -349,45 +666,56 @@ func (*Agent) startInputs(dst chan<- telegraf.Metric, inputs []*models.RunningIn
 	}
 
 	for _, input := range inputs {
-		// Service input plugins are not normally subject to timestamp
-		// rounding except for when precision is set on the input plugin.
-		//
-		// This only applies to the accumulator passed to Start(), the
-		// Gather() accumulator does apply rounding according to the
-		// precision and interval agent/plugin settings.
-		var interval time.Duration
-		var precision time.Duration
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
-
-		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(getPrecision(precision, interval))
-
-		if err := input.Start(acc); err != nil {
-			// If the model tells us to remove the plugin we do so without error
-			var fatalErr *internal.FatalError
-			if errors.As(err, &fatalErr) {
-				log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
-				continue
-			}
-
+		started, err := startInput(dst, input)
+		if err != nil {
 			stopRunningInputs(unit.inputs)
-
-			return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+			return nil, err
 		}
-		if err := input.Probe(); err != nil {
-			// Probe failures are non-fatal to the agent but should only remove the plugin
-			log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
-			input.Stop()
-			continue
+		if started {
+			unit.inputs = append(unit.inputs, input)
 		}
-		unit.inputs = append(unit.inputs, input)
 	}
 
 	return unit, nil
 }
 
+// startInput starts a service input and returns false if the input failed
+// to start or probe in a way only requiring to remove the plugin.
+func startInput(dst chan<- telegraf.Metric, input *models.RunningInput) (bool, error) {
+	// Service input plugins are not normally subject to timestamp
+	// rounding except for when precision is set on the input plugin.
+	//
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision and interval agent/plugin settings.
+	var interval time.Duration
+	var precision time.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	if err := input.Start(acc); err != nil {
+		// If the model tells us to remove the plugin we do so without error
+		var fatalErr *internal.FatalError
+		if errors.As(err, &fatalErr) {
+			log.Printf("I! [agent] Failed to start %s, shutting down plugin: %s", input.LogName(), err)
+			return false, nil
+		}
+		return false, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+	}
+	if err := input.Probe(); err != nil {
+		// Probe failures are non-fatal to the agent but should only remove the plugin
+		log.Printf("I! [agent] Failed to probe %s, shutting down plugin: %s", input.LogName(), err)
+		input.Stop()
+		return false, nil
+	}
+
+	return true, nil
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
@@ -397,58 +725,78 @@ func (a *Agent) runInputs(
 	startTime time.Time,
 	unit *inputUnit,
 ) {
-	var wg sync.WaitGroup
-	tickers := make([]Ticker, 0, len(unit.inputs))
+	unit.Lock()
+	unit.ctx = ctx
+	unit.startTime = startTime
+	unit.loops = make(map[*models.RunningInput]*pluginLoop, len(unit.inputs))
 	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := time.Duration(a.Config.Agent.Interval)
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
+		a.startGatherLoop(unit, input)
+	}
+	unit.Unlock()
 
-		// Overwrite agent precision if this plugin has its own.
-		precision := time.Duration(a.Config.Agent.Precision)
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
+	<-ctx.Done()
 
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := time.Duration(a.Config.Agent.CollectionJitter)
-		if input.Config.CollectionJitter != 0 {
-			jitter = input.Config.CollectionJitter
-		}
+	// Prevent adding inputs while shutting down
+	unit.Lock()
+	unit.closed = true
+	unit.Unlock()
+	unit.wg.Wait()
 
-		// Overwrite agent collection_offset if this plugin has its own.
-		offset := time.Duration(a.Config.Agent.CollectionOffset)
-		if input.Config.CollectionOffset != 0 {
-			offset = input.Config.CollectionOffset
-		}
+	log.Printf("D! [agent] Stopping service inputs")
+	stopRunningInputs(unit.inputs)
 
-		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter, offset)
-		} else {
-			ticker = NewUnalignedTicker(interval, jitter, offset)
-		}
-		tickers = append(tickers, ticker)
+	close(unit.dst)
+	log.Printf("D! [agent] Input channel closed")
+}
 
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+// startGatherLoop starts gathering the input periodically. The unit must be
+// locked by the caller.
+func (a *Agent) startGatherLoop(unit *inputUnit, input *models.RunningInput) {
+	// Overwrite agent interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.Interval)
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+	// Overwrite agent precision if this plugin has its own.
+	precision := time.Duration(a.Config.Agent.Precision)
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
 	}
-	defer stopTickers(tickers)
-	wg.Wait()
 
-	log.Printf("D! [agent] Stopping service inputs")
-	stopRunningInputs(unit.inputs)
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.CollectionJitter)
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
 
-	close(unit.dst)
-	log.Printf("D! [agent] Input channel closed")
+	// Overwrite agent collection_offset if this plugin has its own.
+	offset := time.Duration(a.Config.Agent.CollectionOffset)
+	if input.Config.CollectionOffset != 0 {
+		offset = input.Config.CollectionOffset
+	}
+
+	var ticker Ticker
+	if a.Config.Agent.RoundInterval {
+		ticker = NewAlignedTicker(unit.startTime, interval, jitter, offset)
+	} else {
+		ticker = NewUnalignedTicker(interval, jitter, offset)
+	}
+
+	acc := NewAccumulator(input, unit.dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	loop := &pluginLoop{cancel: cancel, done: make(chan struct{})}
+	unit.loops[input] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+		defer ticker.Stop()
+		a.gatherLoop(ctx, acc, input, ticker, interval)
+	}()
 }
 
 // testStartInputs is a variation of startInputs for use in --test and --once mode.
@@ -576,6 +924,11 @@ func (a *Agent) gatherLoop(
 			if err != nil {
 				acc.AddError(err)
 			}
+		case <-input.GatherRequest:
+			err := a.gatherOnce(acc, input, ticker, interval)
+			if err != nil {
+				acc.AddError(err)
+			}
 		case <-ctx.Done():
 			return
 		}
@@ -612,7 +965,12 @@ func (*Agent) gatherOnce(acc telegraf.Accumulator, input *models.RunningInput, t
 }
 
 // startProcessors sets up the processor chain and calls Start on all processors.  If an error occurs any started processors are Stopped.
-func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors models.RunningProcessors) (chan<- telegraf.Metric, []*processorUnit, error) {
+// Processors taken over by the given chain, if any, are already running and not started.
+func (*Agent) startProcessors(
+	dst chan<- telegraf.Metric,
+	runningProcessors models.RunningProcessors,
+	chain *processingChain,
+) (chan<- telegraf.Metric, []*processorUnit, error) {
 	var src chan telegraf.Metric
 	units := make([]*processorUnit, 0, len(runningProcessors))
 	// The processor chain is constructed from the output side starting from
@@ -624,22 +982,29 @@ func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors mode
 		processor := runningProcessors[i]
 
 		src = make(chan telegraf.Metric, 100)
-		acc := NewAccumulator(processor, dst)
+		unit := &processorUnit{
+			src:       src,
+			dst:       dst,
+			processor: processor,
+			chain:     chain,
+		}
 
-		err := processor.Start(acc)
-		if err != nil {
-			for _, u := range units {
-				u.processor.Stop()
-				close(u.dst)
+		if h := chain.takenOver(processor); h != nil {
+			h.dst = dst
+		} else {
+			unit.acc = newChainAccumulator(processor, dst)
+			if err := processor.Start(unit.acc); err != nil {
+				for _, u := range units {
+					if chain.takenOver(u.processor) == nil {
+						u.processor.Stop()
+					}
+					close(u.dst)
+				}
+				return nil, nil, fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
 			}
-			return nil, nil, fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
 		}
 
-		units = append(units, &processorUnit{
-			src:       src,
-			dst:       dst,
-			processor: processor,
-		})
+		units = append(units, unit)
 
 		dst = src
 	}
@@ -647,6 +1012,127 @@ func (*Agent) startProcessors(dst chan<- telegraf.Metric, runningProcessors mode
 	return src, units, nil
 }
 
+// aggregatorRunner runs the aggregators of the unit until the source channel
+// of the unit is closed and all metrics have been written
+type aggregatorRunner func(unit *aggregatorUnit)
+
+// wallClockAggregators returns a runner pushing the aggregation windows by
+// the wall-clock time starting at the given time
+func (a *Agent) wallClockAggregators(startTime time.Time) aggregatorRunner {
+	return func(unit *aggregatorUnit) {
+		a.runAggregators(startTime, unit)
+	}
+}
+
+// startChain starts the given processors and aggregators in a new chain
+// running the aggregators with the given runner. The given running plugins
+// are taken over from the running chain once that chain is replaced, all
+// other plugins are started. If an error occurs any started processors are
+// stopped.
+func (a *Agent) startChain(
+	processors, aggProcessors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+	running []interface{},
+	runAggregators aggregatorRunner,
+) (*processingChain, error) {
+	tail := make(chan telegraf.Metric, 100)
+	chain := &processingChain{
+		tail:          tail,
+		processors:    processors,
+		aggProcessors: aggProcessors,
+		aggregators:   aggregators,
+		takeover:      make(map[interface{}]*handover, len(running)),
+	}
+	for _, plugin := range running {
+		chain.takeover[plugin] = &handover{released: make(chan struct{})}
+	}
+
+	var next chan<- telegraf.Metric = tail
+	var err error
+	var apu []*processorUnit
+	var au *aggregatorUnit
+	if len(aggregators) != 0 {
+		aggC := next
+		if len(aggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
+			aggC, apu, err = a.startProcessors(next, aggProcessors, chain)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		next, au = a.startAggregators(aggC, next, aggregators)
+		au.chain = chain
+	}
+
+	var pu []*processorUnit
+	if len(processors) != 0 {
+		next, pu, err = a.startProcessors(next, processors, chain)
+		if err != nil {
+			for _, u := range apu {
+				if chain.takenOver(u.processor) == nil {
+					u.processor.Stop()
+				}
+			}
+			return nil, err
+		}
+	}
+	chain.src = next
+
+	if au != nil {
+		chain.wg.Add(1)
+		go func() {
+			defer chain.wg.Done()
+			a.runProcessors(apu)
+		}()
+
+		chain.wg.Add(1)
+		go func() {
+			defer chain.wg.Done()
+			runAggregators(au)
+		}()
+	}
+
+	if pu != nil {
+		chain.wg.Add(1)
+		go func() {
+			defer chain.wg.Done()
+			a.runProcessors(pu)
+		}()
+	}
+
+	return chain, nil
+}
+
+// runProcessing relays the metrics of the inputs to the chain until the
+// source channel is closed and all metrics left the chain. The chain is
+// replaced on request after draining the previous chain.
+func (*Agent) runProcessing(unit *processingUnit) {
+	defer close(unit.done)
+
+	unit.chain.forward(unit.dst)
+	for {
+		select {
+		case m, ok := <-unit.src:
+			if !ok {
+				unit.chain.stop()
+				close(unit.dst)
+				log.Printf("D! [agent] Processing channel closed")
+				return
+			}
+			unit.chain.src <- m
+		case chain := <-unit.swap:
+			// Pass the plugins kept in the new chain on to that chain
+			unit.chain.next = chain
+			unit.chain.stop()
+			chain.forward(unit.dst)
+			unit.Lock()
+			unit.chain = chain
+			unit.Unlock()
+			unit.swapped <- struct{}{}
+		}
+	}
+}
+
 // runProcessors begins processing metrics and runs until the source channel is closed and all metrics have been written.
 func (*Agent) runProcessors(units []*processorUnit) {
 	var wg sync.WaitGroup
@@ -662,7 +1148,7 @@ func (*Agent) runProcessors(units []*processorUnit) {
 					m.Drop()
 				}
 			}
-			unit.processor.Stop()
+			unit.stop()
 			close(unit.dst)
 			log.Printf("D! [agent] Processor channel closed")
 		}(unit)
@@ -692,7 +1178,11 @@ func (a *Agent) runAggregators(
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
+	// Aggregators taken over from the previous chain keep their window.
+	for _, agg := range unit.aggregators {
+		if unit.chain.takenOver(agg) != nil {
+			continue
+		}
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
 	}
@@ -703,7 +1193,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -718,17 +1208,29 @@ func (a *Agent) runAggregators(
 		cancel()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		wg.Add(1)
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
 
+			if !unit.chain.acquire(ctx, agg) {
+				return
+			}
+
 			interval := time.Duration(a.Config.Agent.Interval)
 			precision := time.Duration(a.Config.Agent.Precision)
 
 			acc := NewAccumulator(agg, unit.aggC)
 			acc.SetPrecision(getPrecision(precision, interval))
 			a.push(ctx, agg, acc)
+
+			// Keep the current window if the next chain continues the
+			// aggregation and push it otherwise
+			if h := unit.chain.handOver(agg); h != nil {
+				close(h.released)
+				return
+			}
+			agg.Push(acc)
 		}(agg)
 	}
 
@@ -756,7 +1258,8 @@ func updateWindow(start time.Time, roundInterval bool, period time.Duration) (si
 	return since, until
 }
 
-// push runs the push for a single aggregator every period.
+// push runs the push for a single aggregator every period until the context
+// is done.
 func (*Agent) push(ctx context.Context, aggregator *models.RunningAggregator, acc telegraf.Accumulator) {
 	for {
 		// Ensures that Push will be called for each period, even if it has
@@ -769,7 +1272,6 @@ func (*Agent) push(ctx context.Context, aggregator *models.RunningAggregator, ac
 		case <-time.After(until):
 			aggregator.Push(acc)
 		case <-ctx.Done():
-			aggregator.Push(acc)
 			return
 		}
 	}
@@ -829,56 +1331,73 @@ func (*Agent) connectOutput(ctx context.Context, output *models.RunningOutput) e
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) {
-	var wg sync.WaitGroup
-
-	// Start flush loop
-	interval := time.Duration(a.Config.Agent.FlushInterval)
-	jitter := time.Duration(a.Config.Agent.FlushJitter)
-
 	ctx, cancel := context.WithCancel(context.Background())
 
+	unit.Lock()
+	unit.ctx = ctx
+	unit.loops = make(map[*models.RunningOutput]*pluginLoop, len(unit.outputs))
 	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
-
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-
-			ticker := NewRollingTicker(interval, jitter)
-			defer ticker.Stop()
-
-			a.flushLoop(ctx, output, ticker)
-		}(output)
+		a.startFlushLoop(unit, output)
 	}
+	unit.updateReceivers()
+	unit.Unlock()
 
 	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(unit.outputs)-1 {
+		unit.RLock()
+		for i, output := range unit.receivers {
+			if i == len(unit.receivers)-1 {
 				output.AddMetricNoCopy(metric)
 			} else {
 				output.AddMetric(metric)
 			}
 		}
+		unit.RUnlock()
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+
+	// Prevent adding outputs while shutting down
+	unit.Lock()
+	unit.closed = true
+	unit.Unlock()
 	cancel()
-	wg.Wait()
+	unit.wg.Wait()
 
 	log.Println("I! [agent] Stopping running outputs")
 	stopRunningOutputs(unit.outputs)
 }
 
+// startFlushLoop starts flushing the output periodically. The unit must be
+// locked by the caller.
+func (a *Agent) startFlushLoop(unit *outputUnit, output *models.RunningOutput) {
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.FlushInterval)
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.FlushJitter)
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	loop := &pluginLoop{cancel: cancel, done: make(chan struct{})}
+	unit.loops[output] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+
+		ticker := NewRollingTicker(interval, jitter)
+		defer ticker.Stop()
+
+		a.flushLoop(ctx, output, ticker)
+	}()
+}
+
 // flushLoop runs an output's flush function periodically until the context is
 // done.
 func (a *Agent) flushLoop(
@@ -914,6 +1433,8 @@ func (a *Agent) flushLoop(
 			logError(a.flushOnce(output, ticker, output.Write))
 		case <-flushRequested:
 			logError(a.flushOnce(output, ticker, output.Write))
+		case <-output.FlushRequest:
+			logError(a.flushOnce(output, ticker, output.Write))
 		case <-output.BatchReady:
 			logError(a.flushBatch(output, output.WriteBatch))
 		}
@@ -950,6 +1471,8 @@ func (*Agent) flushBatch(output *models.RunningOutput, writeFunc func() error) e
 // Test runs the inputs, processors and aggregators for a single gather and
 // writes the metrics to stdout.
 func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
+	defer a.Config.StopSecretStores()
+
 	src := make(chan telegraf.Metric, 100)
 
 	var wg sync.WaitGroup
@@ -983,14 +1506,7 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 // outputC. After gathering pauses for the wait duration to allow service
 // inputs to run.
 func (a *Agent) runTest(ctx context.Context, wait time.Duration, outputC chan<- telegraf.Metric) error {
-	// Set the default for processor skipping
-	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
-		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
-		msg += `If you need the current default behavior, please explicitly set the option to 'false'!`
-		log.Print("W! [agent] ", color.YellowString(msg))
-		skipProcessorsAfterAggregators := false
-		a.Config.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
-	}
+	a.setSkipProcessorsDefault()
 
 	log.Printf("D! [agent] Initializing plugins")
 	if err := a.InitPlugins(); err != nil {
@@ -1007,7 +1523,7 @@ func (a *Agent) runTest(ctx context.Context, wait time.Duration, outputC chan<-
 		procC := next
 		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
 			var err error
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
+			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors, nil)
 			if err != nil {
 				return err
 			}
@@ -1019,7 +1535,7 @@ func (a *Agent) runTest(ctx context.Context, wait time.Duration, outputC chan<-
 	var pu []*processorUnit
 	if len(a.Config.Processors) != 0 {
 		var err error
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
+		next, pu, err = a.startProcessors(next, a.Config.Processors, nil)
 		if err != nil {
 			return err
 		}
@@ -1065,6 +1581,8 @@ func (a *Agent) runTest(ctx context.Context, wait time.Duration, outputC chan<-
 
 // Once runs the full agent for a single gather.
 func (a *Agent) Once(ctx context.Context, wait time.Duration) error {
+	defer a.Config.StopSecretStores()
+
 	err := a.runOnce(ctx, wait)
 	if err != nil {
 		return err
@@ -1088,14 +1606,7 @@ func (a *Agent) Once(ctx context.Context, wait time.Duration) error {
 // outputC. After gathering pauses for the wait duration to allow service
 // inputs to run.
 func (a *Agent) runOnce(ctx context.Context, wait time.Duration) error {
-	// Set the default for processor skipping
-	if a.Config.Agent.SkipProcessorsAfterAggregators == nil {
-		msg := `The default value of 'skip_processors_after_aggregators' will change to 'true' with Telegraf v1.40.0! `
-		msg += `If you need the current default behavior, please explicitly set the option to 'false'!`
-		log.Print("W! [agent] ", color.YellowString(msg))
-		skipProcessorsAfterAggregators := false
-		a.Config.Agent.SkipProcessorsAfterAggregators = &skipProcessorsAfterAggregators
-	}
+	a.setSkipProcessorsDefault()
 
 	log.Printf("D! [agent] Initializing plugins")
 	if err := a.InitPlugins(); err != nil {
@@ -1115,7 +1626,7 @@ func (a *Agent) runOnce(ctx context.Context, wait time.Duration) error {
 	if len(a.Config.Aggregators) != 0 {
 		procC := next
 		if len(a.Config.AggProcessors) != 0 && !*a.Config.Agent.SkipProcessorsAfterAggregators {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
+			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors, nil)
 			if err != nil {
 				return err
 			}
@@ -1126,7 +1637,7 @@ func (a *Agent) runOnce(ctx context.Context, wait time.Duration) error {
 
 	var pu []*processorUnit
 	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
+		next, pu, err = a.startProcessors(next, a.Config.Processors, nil)
 		if err != nil {
 			return err
 		}
@@ -1207,9 +1718,3 @@ func panicRecover(input *models.RunningInput) {
 			"https://github.com/influxdata/telegraf/issues/new/choose")
 	}
 }
-
-func stopTickers(tickers []Ticker) {
-	for _, ticker := range tickers {
-		ticker.Stop()
-	}
-}
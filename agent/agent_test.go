@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/models"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -118,6 +120,45 @@ func TestAgent_LoadOutput(t *testing.T) {
 	require.Len(t, a.Config.Outputs, 3)
 }
 
+func TestAgent_DeadLetterOutput(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Interval = config.Duration(10 * time.Millisecond)
+	c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "good"}, &models.InputConfig{Name: "test", ID: "input-good"}),
+		models.NewRunningInput(&reloadTestInput{name: "bad"}, &models.InputConfig{Name: "test", ID: "input-bad"}),
+	}
+
+	primary := &rejectingTestOutput{reject: "bad"}
+	target := &reloadTestOutput{}
+	source := models.NewRunningOutput(primary, &models.OutputConfig{
+		Name:       "test",
+		Alias:      "primary",
+		ID:         "output-primary",
+		DeadLetter: models.DeadLetterConfig{Output: "target"},
+	}, 10, 100)
+	sink := models.NewRunningOutput(target, &models.OutputConfig{Name: "test", Alias: "target", ID: "output-target"}, 10, 100)
+	source.SetDeadLetterOutput(sink)
+	c.Outputs = []*models.RunningOutput{source, sink}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, NewAgent(c).Run(ctx))
+	}()
+
+	// Only the rejected metrics must reach the dead-letter output
+	require.Eventually(t, func() bool {
+		return primary.received("good") && target.received("bad")
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	require.False(t, target.received("good"))
+}
+
 func TestWindow(t *testing.T) {
 	parse := func(s string) time.Time {
 		tm, err := time.Parse(time.RFC3339, s)
@@ -228,6 +269,33 @@ func TestCases(t *testing.T) {
 	}
 }
 
+// rejectingTestOutput rejects all metrics with the given name
+type rejectingTestOutput struct {
+	reloadTestOutput
+	reject string
+}
+
+func (o *rejectingTestOutput) Write(metrics []telegraf.Metric) error {
+	accepted := make([]telegraf.Metric, 0, len(metrics))
+	werr := &internal.PartialWriteError{Err: internal.ErrSizeLimitReached}
+	for i, m := range metrics {
+		if m.Name() == o.reject {
+			werr.MetricsReject = append(werr.MetricsReject, i)
+			werr.MetricsRejectErrors = append(werr.MetricsRejectErrors, errors.New("rejected"))
+			continue
+		}
+		werr.MetricsAccept = append(werr.MetricsAccept, i)
+		accepted = append(accepted, m)
+	}
+	if err := o.reloadTestOutput.Write(accepted); err != nil {
+		return err
+	}
+	if len(werr.MetricsReject) == 0 {
+		return nil
+	}
+	return werr
+}
+
 // Implement a "test-mode" like call but collect the metrics
 func collect(ctx context.Context, a *Agent, wait time.Duration) ([]telegraf.Metric, error) {
 	var received []telegraf.Metric
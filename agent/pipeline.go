@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// RunPipeline sends the given metrics through the processors and aggregators
+// and returns the resulting metrics. Inputs and outputs are neither
+// initialized nor started. As for Replay, the aggregation windows are driven
+// by the metric time, so the result does not depend on the wall-clock time.
+func (a *Agent) RunPipeline(metrics []telegraf.Metric) ([]telegraf.Metric, error) {
+	a.setSkipProcessorsDefault()
+
+	log.Printf("D! [agent] Initializing plugins")
+	if err := a.initProcessing(); err != nil {
+		return nil, err
+	}
+
+	chain, err := a.startChain(a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators, nil, a.runReplayAggregators)
+	if err != nil {
+		return nil, err
+	}
+	outputC := make(chan telegraf.Metric, 100)
+	chain.forward(outputC)
+
+	go func() {
+		for _, m := range metrics {
+			chain.src <- m
+		}
+		chain.stop()
+		close(outputC)
+	}()
+
+	var result []telegraf.Metric
+	for m := range outputC {
+		result = append(result, m)
+		m.Accept()
+	}
+
+	return result, nil
+}
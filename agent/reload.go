@@ -0,0 +1,384 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// ErrRestartRequired indicates configuration changes that cannot be applied
+// to the running agent but require restarting the agent
+var ErrRestartRequired = errors.New("restart required")
+
+// ReloadPlugins applies the given configuration to the running agent by only
+// stopping removed and starting added inputs and outputs. Plugins are matched
+// by their ID, so changing the settings of a plugin replaces the plugin.
+// Unchanged plugins keep running including their buffers and state. If
+// processors or aggregators changed, the processing chain is rebuilt and
+// replaces the running chain once the latter is drained. The new chain keeps
+// the running processors and aggregators with unchanged ID including their
+// state and aggregation windows. Added plugins restore their state from the
+// state file if any.
+// ErrRestartRequired is returned if the configuration contains changes that
+// cannot be applied at runtime, e.g. changes of the agent settings. In case
+// of other errors, the agent keeps running with the plugins started so far.
+// The secret-stores of the given configuration are stopped with the agent.
+func (a *Agent) ReloadPlugins(ctx context.Context, c *config.Config) error {
+	a.unitsLock.Lock()
+	defer a.unitsLock.Unlock()
+
+	iu, pu, ou := a.inputUnit, a.processingUnit, a.outputUnit
+	if iu == nil || pu == nil || ou == nil {
+		c.StopSecretStores()
+		return fmt.Errorf("%w: agent is not running", ErrRestartRequired)
+	}
+	// Keep the secret-stores of the configuration running until the agent
+	// stops as the added plugins might use them
+	a.reloaded = append(a.reloaded, c)
+
+	if a.Config.SettingsID() != c.SettingsID() {
+		return fmt.Errorf("%w: agent settings, tags or secret-stores changed", ErrRestartRequired)
+	}
+
+	iu.Lock()
+	inputsAdded, inputsRemoved := diffPlugins(iu.inputs, c.Inputs, (*models.RunningInput).ID)
+	iu.Unlock()
+	ou.RLock()
+	outputsAdded, outputsRemoved := diffPlugins(ou.outputs, c.Outputs, (*models.RunningOutput).ID)
+	ou.RUnlock()
+	pu.Lock()
+	running := pu.chain
+	pu.Unlock()
+
+	// Outputs referencing each other are linked when loading the config
+	// so those links cannot be established between running and new outputs.
+	// Kept outputs keep their links as their targets are kept as well.
+	for _, outputs := range [][]*models.RunningOutput{outputsAdded, outputsRemoved} {
+		for _, output := range outputs {
+			if output.Config.DeadLetter.Output != "" || output.Config.FailoverTo != "" || output.Standby() {
+				return fmt.Errorf("%w: outputs referencing other outputs changed", ErrRestartRequired)
+			}
+		}
+	}
+
+	chainChanged := !slices.Equal(pluginIDs(running.processors, (*models.RunningProcessor).ID), pluginIDs(c.Processors, (*models.RunningProcessor).ID)) ||
+		!slices.Equal(pluginIDs(running.aggProcessors, (*models.RunningProcessor).ID), pluginIDs(c.AggProcessors, (*models.RunningProcessor).ID)) ||
+		!slices.Equal(pluginIDs(running.aggregators, (*models.RunningAggregator).ID), pluginIDs(c.Aggregators, (*models.RunningAggregator).ID))
+
+	// Keep the running processors and aggregators in the new chain
+	processors, processorsKept, processorsAdded := mergePlugins(running.processors, c.Processors, (*models.RunningProcessor).ID)
+	aggProcessors, aggProcessorsKept, aggProcessorsAdded := mergePlugins(running.aggProcessors, c.AggProcessors, (*models.RunningProcessor).ID)
+	aggregators, aggregatorsKept, aggregatorsAdded := mergePlugins(running.aggregators, c.Aggregators, (*models.RunningAggregator).ID)
+
+	if len(inputsAdded)+len(inputsRemoved)+len(outputsAdded)+len(outputsRemoved) == 0 && !chainChanged {
+		log.Printf("I! [agent] No plugin changes found")
+		return nil
+	}
+
+	// Initialize the new plugins first to keep the running plugins untouched
+	// in case of configuration errors
+	for _, input := range inputsAdded {
+		if tp, ok := input.Input.(snmp.TranslatorPlugin); ok {
+			tp.SetTranslator(a.Config.Agent.SnmpTranslator)
+		}
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %w", input.LogName(), err)
+		}
+		a.restoreState(input.ID(), input.Input)
+	}
+	for i, output := range outputsAdded {
+		if err := output.Init(); err != nil {
+			stopRunningOutputs(outputsAdded[:i])
+			return fmt.Errorf("could not initialize output %s: %w", output.LogName(), err)
+		}
+		a.restoreState(output.ID(), output.Output)
+	}
+
+	var chain *processingChain
+	if chainChanged {
+		if err := a.initChain(processorsAdded, aggProcessorsAdded, aggregatorsAdded); err != nil {
+			stopRunningOutputs(outputsAdded)
+			return err
+		}
+		for _, processor := range slices.Concat(processorsAdded, aggProcessorsAdded) {
+			a.restoreState(processor.ID(), processorPlugin(processor))
+		}
+		for _, aggregator := range aggregatorsAdded {
+			a.restoreState(aggregator.ID(), aggregator.Aggregator)
+		}
+
+		kept := make([]interface{}, 0, len(processorsKept)+len(aggProcessorsKept)+len(aggregatorsKept))
+		for _, processor := range slices.Concat(processorsKept, aggProcessorsKept) {
+			kept = append(kept, processor)
+		}
+		for _, aggregator := range aggregatorsKept {
+			kept = append(kept, aggregator)
+		}
+
+		var err error
+		chain, err = a.startChain(processors, aggProcessors, aggregators, kept, a.wallClockAggregators(time.Now()))
+		if err != nil {
+			stopRunningOutputs(outputsAdded)
+			return err
+		}
+	}
+
+	// Start the new outputs before stopping the old ones and stop the old
+	// inputs before starting the new ones to neither lose nor duplicate
+	// metrics
+	for i, output := range outputsAdded {
+		if err := a.addOutput(ctx, ou, output); err != nil {
+			if chain != nil {
+				chain.discard()
+				chain.stop()
+			}
+			stopRunningOutputs(outputsAdded[i:])
+			return err
+		}
+		a.registerState(output.ID(), output.Output)
+		log.Printf("I! [agent] Added %s", output.LogName())
+	}
+	if chain != nil {
+		if !pu.replace(chain) {
+			chain.discard()
+			chain.stop()
+			return errors.New("agent is shutting down")
+		}
+		_, processorsRemoved := diffPlugins(running.processors, c.Processors, (*models.RunningProcessor).ID)
+		_, aggProcessorsRemoved := diffPlugins(running.aggProcessors, c.AggProcessors, (*models.RunningProcessor).ID)
+		_, aggregatorsRemoved := diffPlugins(running.aggregators, c.Aggregators, (*models.RunningAggregator).ID)
+		for _, processor := range slices.Concat(processorsRemoved, aggProcessorsRemoved) {
+			a.unregisterState(processor.ID(), processorPlugin(processor))
+		}
+		for _, aggregator := range aggregatorsRemoved {
+			a.unregisterState(aggregator.ID(), aggregator.Aggregator)
+		}
+		for _, processor := range slices.Concat(processorsAdded, aggProcessorsAdded) {
+			a.registerState(processor.ID(), processorPlugin(processor))
+		}
+		for _, aggregator := range aggregatorsAdded {
+			a.registerState(aggregator.ID(), aggregator.Aggregator)
+		}
+		log.Printf("I! [agent] Replaced processors and aggregators")
+	}
+	for _, output := range outputsRemoved {
+		ou.remove(output)
+		a.unregisterState(output.ID(), output.Output)
+		log.Printf("I! [agent] Removed %s", output.LogName())
+	}
+	for _, input := range inputsRemoved {
+		iu.remove(input)
+		a.unregisterState(input.ID(), input.Input)
+		log.Printf("I! [agent] Removed %s", input.LogName())
+	}
+	for _, input := range inputsAdded {
+		if err := a.addInput(iu, input); err != nil {
+			return err
+		}
+		a.registerState(input.ID(), input.Input)
+		log.Printf("I! [agent] Added %s", input.LogName())
+	}
+
+	return nil
+}
+
+// addInput starts the input and its gather loop
+func (a *Agent) addInput(unit *inputUnit, input *models.RunningInput) error {
+	// Keep the unit locked while starting the input to prevent service
+	// inputs from writing to the closed channel during shutdown
+	unit.Lock()
+	defer unit.Unlock()
+
+	if unit.closed {
+		return errors.New("agent is shutting down")
+	}
+
+	started, err := startInput(unit.dst, input)
+	if err != nil || !started {
+		return err
+	}
+
+	unit.inputs = append(unit.inputs, input)
+	a.startGatherLoop(unit, input)
+
+	return nil
+}
+
+// remove stops the input and its gather loop
+func (u *inputUnit) remove(input *models.RunningInput) {
+	u.Lock()
+	if u.closed {
+		// The input is stopped on shutdown
+		u.Unlock()
+		return
+	}
+	loop := u.loops[input]
+	delete(u.loops, input)
+	u.inputs = slices.DeleteFunc(slices.Clone(u.inputs), func(i *models.RunningInput) bool { return i == input })
+	u.Unlock()
+
+	if loop != nil {
+		loop.stop()
+	}
+	input.Stop()
+}
+
+// addOutput connects the output and starts its flush loop
+func (a *Agent) addOutput(ctx context.Context, unit *outputUnit, output *models.RunningOutput) error {
+	if err := a.connectOutput(ctx, output); err != nil {
+		var fatalErr *internal.FatalError
+		if errors.As(err, &fatalErr) {
+			log.Printf("I! [agent] Failed to connect to [%s], error was %q;  shutting down plugin...", output.LogName(), err)
+			output.Close()
+			return nil
+		}
+		return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+	}
+
+	unit.Lock()
+	defer unit.Unlock()
+
+	if unit.closed {
+		output.Close()
+		return errors.New("agent is shutting down")
+	}
+
+	unit.outputs = append(unit.outputs, output)
+	a.startFlushLoop(unit, output)
+	unit.updateReceivers()
+
+	return nil
+}
+
+// remove stops receiving metrics for the output, flushes the output one last
+// time and closes it
+func (u *outputUnit) remove(output *models.RunningOutput) {
+	u.Lock()
+	if u.closed {
+		// The output is closed on shutdown
+		u.Unlock()
+		return
+	}
+	loop := u.loops[output]
+	delete(u.loops, output)
+	u.outputs = slices.DeleteFunc(slices.Clone(u.outputs), func(o *models.RunningOutput) bool { return o == output })
+	u.updateReceivers()
+	u.Unlock()
+
+	if loop != nil {
+		loop.stop()
+	}
+	output.Close()
+}
+
+// restoreState sets the state of a plugin added at runtime from the state
+// file before starting the plugin
+func (a *Agent) restoreState(id string, plugin interface{}) {
+	p, ok := plugin.(telegraf.StatefulPlugin)
+	if !ok || a.Config.Persister == nil {
+		return
+	}
+
+	if err := a.Config.Persister.Restore(id, p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("W! [agent] Restoring state of plugin %q failed: %v", id, err)
+	}
+}
+
+func (a *Agent) registerState(id string, plugin interface{}) {
+	p, ok := plugin.(telegraf.StatefulPlugin)
+	if !ok || a.Config.Persister == nil {
+		return
+	}
+	if err := a.Config.Persister.Register(id, p); err != nil {
+		log.Printf("E! [agent] Registering state of plugin %q failed: %v", id, err)
+	}
+}
+
+func (a *Agent) unregisterState(id string, plugin interface{}) {
+	if _, ok := plugin.(telegraf.StatefulPlugin); !ok || a.Config.Persister == nil {
+		return
+	}
+	a.Config.Persister.Unregister(id)
+}
+
+// diffPlugins returns the plugins only existing in the new list and the ones
+// only existing in the running list. Identically configured plugins share
+// the same ID, so the number of plugins per ID is compared.
+func diffPlugins[T comparable](running, updated []T, id func(T) string) (added, removed []T) {
+	available := make(map[string][]T, len(running))
+	for _, p := range running {
+		available[id(p)] = append(available[id(p)], p)
+	}
+
+	kept := make(map[T]bool, len(running))
+	for _, p := range updated {
+		pid := id(p)
+		if candidates := available[pid]; len(candidates) > 0 {
+			kept[candidates[0]] = true
+			available[pid] = candidates[1:]
+			continue
+		}
+		added = append(added, p)
+	}
+
+	for _, p := range running {
+		if !kept[p] {
+			removed = append(removed, p)
+		}
+	}
+
+	return added, removed
+}
+
+// mergePlugins returns the updated list of plugins with each plugin replaced
+// by a running plugin of the same ID, if any, as well as the kept running
+// plugins and the plugins not running yet
+func mergePlugins[T comparable](running, updated []T, id func(T) string) (merged, kept, added []T) {
+	available := make(map[string][]T, len(running))
+	for _, p := range running {
+		available[id(p)] = append(available[id(p)], p)
+	}
+
+	merged = make([]T, 0, len(updated))
+	for _, p := range updated {
+		pid := id(p)
+		if candidates := available[pid]; len(candidates) > 0 {
+			merged = append(merged, candidates[0])
+			kept = append(kept, candidates[0])
+			available[pid] = candidates[1:]
+			continue
+		}
+		merged = append(merged, p)
+		added = append(added, p)
+	}
+
+	return merged, kept, added
+}
+
+// processorPlugin returns the plugin of the processor unwrapping streaming
+// processors created from regular processors
+func processorPlugin(processor *models.RunningProcessor) interface{} {
+	if p, ok := processor.Processor.(processors.HasUnwrap); ok {
+		return p.Unwrap()
+	}
+	return processor.Processor
+}
+
+func pluginIDs[T any](plugins []T, id func(T) string) []string {
+	ids := make([]string, 0, len(plugins))
+	for _, p := range plugins {
+		ids = append(ids, id(p))
+	}
+	return ids
+}
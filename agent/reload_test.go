@@ -0,0 +1,676 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/persister"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+func TestReloadPlugins(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := config.NewConfig()
+		c.Agent.Interval = config.Duration(10 * time.Millisecond)
+		c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+		return c
+	}
+
+	keptOutput := &reloadTestOutput{}
+	removedOutput := &reloadTestOutput{}
+	addedOutput := &reloadTestOutput{}
+
+	c := newConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+		models.NewRunningInput(&reloadTestInput{name: "removed"}, &models.InputConfig{Name: "test", ID: "input-removed"}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(keptOutput, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+		models.NewRunningOutput(removedOutput, &models.OutputConfig{Name: "test", ID: "output-removed"}, 10, 100),
+	}
+	a := NewAgent(c)
+
+	// Reloading requires a running agent
+	require.ErrorIs(t, a.ReloadPlugins(t.Context(), newConfig()), ErrRestartRequired)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	require.Eventually(t, func() bool {
+		a.unitsLock.Lock()
+		defer a.unitsLock.Unlock()
+		return a.inputUnit != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	keptInput := a.inputs()[0]
+
+	// Replace one input and one output
+	updated := newConfig()
+	updated.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+		models.NewRunningInput(&reloadTestInput{name: "added"}, &models.InputConfig{Name: "test", ID: "input-added"}),
+	}
+	updated.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+		models.NewRunningOutput(addedOutput, &models.OutputConfig{Name: "test", ID: "output-added"}, 10, 100),
+	}
+	require.NoError(t, a.ReloadPlugins(ctx, updated))
+
+	inputs := a.inputs()
+	require.Len(t, inputs, 2)
+	require.Same(t, keptInput, inputs[0])
+	require.Equal(t, "input-added", inputs[1].ID())
+
+	outputs := a.outputs()
+	require.Len(t, outputs, 2)
+	require.Same(t, keptOutput, outputs[0].Output)
+	require.Same(t, addedOutput, outputs[1].Output)
+	require.True(t, removedOutput.isClosed())
+
+	// The added input must write to the kept and added outputs
+	require.Eventually(t, func() bool {
+		return keptOutput.received("added") && addedOutput.received("added")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Changes to the outputs linking require a restart. Changing the
+	// failover setting changes the ID of the output.
+	linked := newConfig()
+	linked.Inputs = updated.Inputs
+	linked.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-linked", FailoverTo: "other"}, 10, 100),
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-added"}, 10, 100),
+	}
+	require.ErrorIs(t, a.ReloadPlugins(ctx, linked), ErrRestartRequired)
+	require.Len(t, a.outputs(), 2)
+}
+
+func TestReloadProcessors(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := config.NewConfig()
+		c.Agent.Interval = config.Duration(10 * time.Millisecond)
+		c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+		c.Persister = &persister.Persister{Filename: filepath.Join(t.TempDir(), "state.json")}
+		return c
+	}
+
+	output := &reloadTestOutput{}
+	counter := &reloadTestProcessor{suffix: "first"}
+
+	c := newConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+	}
+	c.Processors = models.RunningProcessors{
+		models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(counter), &models.ProcessorConfig{Name: "test", ID: "processor-first"}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(output, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	a := NewAgent(c)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	require.Eventually(t, func() bool {
+		return output.received("kept_first")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Keep the first processor and add a second one, the running instance
+	// of the kept processor must be used by the new chain
+	updated := newConfig()
+	updated.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+	}
+	updated.Processors = models.RunningProcessors{
+		models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(&reloadTestProcessor{suffix: "first"}), &models.ProcessorConfig{Name: "test", ID: "processor-first"}),
+		models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(&reloadTestProcessor{suffix: "second"}), &models.ProcessorConfig{Name: "test", ID: "processor-second"}),
+	}
+	updated.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	require.NoError(t, a.ReloadPlugins(ctx, updated))
+
+	procs, _, _ := a.processing()
+	require.Len(t, procs, 2)
+	require.Same(t, counter, processorPlugin(procs[0]))
+	require.Equal(t, "processor-second", procs[1].ID())
+	require.Same(t, output, a.outputs()[0].Output)
+
+	// The metrics must pass the new processing chain
+	require.Eventually(t, func() bool {
+		return output.received("kept_first_second")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestReloadKeepRunningChainPlugins(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := config.NewConfig()
+		c.Agent.Interval = config.Duration(10 * time.Millisecond)
+		c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+		return c
+	}
+
+	output := &reloadTestOutput{}
+	streaming := &reloadTestStreamingProcessor{}
+	aggregator := &reloadTestAggregator{}
+
+	c := newConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+	}
+	c.Processors = models.RunningProcessors{
+		models.NewRunningProcessor(streaming, &models.ProcessorConfig{Name: "test", ID: "processor-streaming"}),
+	}
+	c.Aggregators = []*models.RunningAggregator{
+		models.NewRunningAggregator(aggregator, &models.AggregatorConfig{Name: "test", ID: "aggregator-kept", Period: time.Hour}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(output, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	a := NewAgent(c)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	require.Eventually(t, func() bool {
+		return output.received("kept")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Add a processor, the running processor and aggregator must be kept
+	// without being stopped or pushed
+	updated := newConfig()
+	updated.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+	}
+	updated.Processors = models.RunningProcessors{
+		models.NewRunningProcessor(&reloadTestStreamingProcessor{}, &models.ProcessorConfig{Name: "test", ID: "processor-streaming"}),
+		models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(&reloadTestProcessor{suffix: "added"}), &models.ProcessorConfig{Name: "test", ID: "processor-added"}),
+	}
+	updated.Aggregators = []*models.RunningAggregator{
+		models.NewRunningAggregator(&reloadTestAggregator{}, &models.AggregatorConfig{Name: "test", ID: "aggregator-kept", Period: time.Hour}),
+	}
+	updated.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	require.NoError(t, a.ReloadPlugins(ctx, updated))
+
+	procs, _, aggs := a.processing()
+	require.Len(t, procs, 2)
+	require.Same(t, streaming, procs[0].Processor)
+	require.Len(t, aggs, 1)
+	require.Same(t, aggregator, aggs[0].Aggregator)
+	require.Equal(t, 1, streaming.starts())
+	require.Zero(t, streaming.stops())
+	require.Zero(t, aggregator.pushes())
+
+	// Metrics emitted by the kept processor must pass the new chain
+	streaming.emit("emitted")
+	require.Eventually(t, func() bool {
+		return output.received("emitted_added") && output.received("kept_added")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The window of the aggregator is pushed on shutdown only
+	cancel()
+	wg.Wait()
+	require.Equal(t, 1, streaming.stops())
+	require.Equal(t, 1, aggregator.pushes())
+	require.True(t, output.received("aggregate"))
+}
+
+func TestReloadCloseOutputsOnError(t *testing.T) {
+	newConfig := func() *config.Config {
+		c := config.NewConfig()
+		c.Agent.Interval = config.Duration(10 * time.Millisecond)
+		c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+		return c
+	}
+
+	c := newConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	a := NewAgent(c)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	require.Eventually(t, func() bool {
+		a.unitsLock.Lock()
+		defer a.unitsLock.Unlock()
+		return a.inputUnit != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The added output must be closed if the processors cannot be started
+	added := &reloadTestOutput{}
+	updated := newConfig()
+	updated.Inputs = c.Inputs
+	updated.Processors = models.RunningProcessors{
+		models.NewRunningProcessor(processors.NewStreamingProcessorFromProcessor(&reloadFailingProcessor{}), &models.ProcessorConfig{Name: "test", ID: "processor-failing"}),
+	}
+	updated.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+		models.NewRunningOutput(added, &models.OutputConfig{Name: "test", ID: "output-added"}, 10, 100),
+	}
+	require.ErrorContains(t, a.ReloadPlugins(ctx, updated), "init failed")
+	require.True(t, added.isClosed())
+	require.Len(t, a.outputs(), 1)
+}
+
+func TestReloadRestoreState(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	newConfig := func() *config.Config {
+		c := config.NewConfig()
+		c.Agent.Interval = config.Duration(10 * time.Millisecond)
+		c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+		c.Persister = &persister.Persister{Filename: filename}
+		return c
+	}
+
+	// Create a state file containing the state of a plugin not running yet
+	store := &persister.Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("input-added", &reloadStatefulInput{State: "saved"}))
+	require.NoError(t, store.Store())
+
+	c := newConfig()
+	c.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+	}
+	c.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	a := NewAgent(c)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	require.Eventually(t, func() bool {
+		a.unitsLock.Lock()
+		defer a.unitsLock.Unlock()
+		return a.inputUnit != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The added input must restore its state from the state file
+	added := &reloadStatefulInput{}
+	updated := newConfig()
+	updated.Inputs = []*models.RunningInput{
+		models.NewRunningInput(&reloadTestInput{name: "kept"}, &models.InputConfig{Name: "test", ID: "input-kept"}),
+		models.NewRunningInput(added, &models.InputConfig{Name: "test", ID: "input-added"}),
+	}
+	updated.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output-kept"}, 10, 100),
+	}
+	require.NoError(t, a.ReloadPlugins(ctx, updated))
+	require.Equal(t, "saved", added.GetState())
+}
+
+func TestReloadStopSecretStores(t *testing.T) {
+	newConfig := func(store *reloadTestSecretStore) *config.Config {
+		c := config.NewConfig()
+		c.Agent.Interval = config.Duration(10 * time.Millisecond)
+		c.Agent.FlushInterval = config.Duration(10 * time.Millisecond)
+		c.Inputs = []*models.RunningInput{
+			models.NewRunningInput(&reloadTestInput{name: "test"}, &models.InputConfig{Name: "test", ID: "input"}),
+		}
+		c.Outputs = []*models.RunningOutput{
+			models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{Name: "test", ID: "output"}, 10, 100),
+		}
+		c.SecretStores["store"] = store
+		return c
+	}
+
+	running := &reloadTestSecretStore{}
+	a := NewAgent(newConfig(running))
+
+	// The stores of configurations not applied are stopped immediately
+	discarded := &reloadTestSecretStore{}
+	require.ErrorIs(t, a.ReloadPlugins(t.Context(), newConfig(discarded)), ErrRestartRequired)
+	require.True(t, discarded.isStopped())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	require.Eventually(t, func() bool {
+		a.unitsLock.Lock()
+		defer a.unitsLock.Unlock()
+		return a.inputUnit != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The stores of applied configurations are kept until the agent stops
+	reloaded := &reloadTestSecretStore{}
+	require.NoError(t, a.ReloadPlugins(ctx, newConfig(reloaded)))
+	require.False(t, running.isStopped())
+	require.False(t, reloaded.isStopped())
+
+	cancel()
+	wg.Wait()
+	require.True(t, running.isStopped())
+	require.True(t, reloaded.isStopped())
+}
+
+func TestDiffPlugins(t *testing.T) {
+	id := func(s string) string { return s[:1] }
+
+	// Plugins with identical IDs are matched by their count
+	added, removed := diffPlugins([]string{"a1", "a2", "b1", "c1"}, []string{"a3", "c2", "d1"}, id)
+	require.Equal(t, []string{"d1"}, added)
+	require.Equal(t, []string{"a2", "b1"}, removed)
+}
+
+type reloadTestInput struct {
+	name string
+}
+
+func (*reloadTestInput) SampleConfig() string {
+	return ""
+}
+
+func (i *reloadTestInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields(i.name, map[string]interface{}{"value": 1}, nil)
+	return nil
+}
+
+type reloadStatefulInput struct {
+	sync.Mutex
+	State string
+}
+
+func (*reloadStatefulInput) SampleConfig() string {
+	return ""
+}
+
+func (*reloadStatefulInput) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (i *reloadStatefulInput) GetState() interface{} {
+	i.Lock()
+	defer i.Unlock()
+	return i.State
+}
+
+func (i *reloadStatefulInput) SetState(state interface{}) error {
+	s, ok := state.(string)
+	if !ok {
+		return fmt.Errorf("invalid state type %T", state)
+	}
+	i.Lock()
+	defer i.Unlock()
+	i.State = s
+	return nil
+}
+
+// reloadTestProcessor appends the suffix to the metric name and counts the
+// processed metrics as state
+type reloadTestProcessor struct {
+	sync.Mutex
+	suffix string
+	count  int
+}
+
+func (*reloadTestProcessor) SampleConfig() string {
+	return ""
+}
+
+func (p *reloadTestProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	p.Lock()
+	defer p.Unlock()
+	for _, m := range in {
+		m.SetName(m.Name() + "_" + p.suffix)
+		p.count++
+	}
+	return in
+}
+
+func (p *reloadTestProcessor) GetState() interface{} {
+	p.Lock()
+	defer p.Unlock()
+	return p.count
+}
+
+func (p *reloadTestProcessor) SetState(state interface{}) error {
+	count, ok := state.(int)
+	if !ok {
+		return fmt.Errorf("invalid state type %T", state)
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.count = count
+	return nil
+}
+
+// reloadTestStreamingProcessor passes on the metrics and emits metrics via
+// the accumulator it was started with
+type reloadTestStreamingProcessor struct {
+	sync.Mutex
+	acc     telegraf.Accumulator
+	started int
+	stopped int
+}
+
+func (*reloadTestStreamingProcessor) SampleConfig() string {
+	return ""
+}
+
+func (p *reloadTestStreamingProcessor) Start(acc telegraf.Accumulator) error {
+	p.Lock()
+	defer p.Unlock()
+	p.acc = acc
+	p.started++
+	return nil
+}
+
+func (*reloadTestStreamingProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	acc.AddMetric(m)
+	return nil
+}
+
+func (p *reloadTestStreamingProcessor) Stop() {
+	p.Lock()
+	defer p.Unlock()
+	p.stopped++
+}
+
+func (p *reloadTestStreamingProcessor) emit(name string) {
+	p.Lock()
+	acc := p.acc
+	p.Unlock()
+	acc.AddFields(name, map[string]interface{}{"value": 1}, nil)
+}
+
+func (p *reloadTestStreamingProcessor) starts() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.started
+}
+
+func (p *reloadTestStreamingProcessor) stops() int {
+	p.Lock()
+	defer p.Unlock()
+	return p.stopped
+}
+
+type reloadFailingProcessor struct{}
+
+func (*reloadFailingProcessor) SampleConfig() string {
+	return ""
+}
+
+func (*reloadFailingProcessor) Init() error {
+	return errors.New("init failed")
+}
+
+func (*reloadFailingProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	return in
+}
+
+// reloadTestAggregator counts the added metrics and the pushes
+type reloadTestAggregator struct {
+	sync.Mutex
+	count  int
+	pushed int
+}
+
+func (*reloadTestAggregator) SampleConfig() string {
+	return ""
+}
+
+func (a *reloadTestAggregator) Add(telegraf.Metric) {
+	a.Lock()
+	defer a.Unlock()
+	a.count++
+}
+
+func (a *reloadTestAggregator) Push(acc telegraf.Accumulator) {
+	a.Lock()
+	defer a.Unlock()
+	a.pushed++
+	acc.AddFields("aggregate", map[string]interface{}{"count": a.count}, nil)
+}
+
+func (*reloadTestAggregator) Reset() {}
+
+func (a *reloadTestAggregator) pushes() int {
+	a.Lock()
+	defer a.Unlock()
+	return a.pushed
+}
+
+type reloadTestOutput struct {
+	sync.Mutex
+	names  map[string]bool
+	closed bool
+}
+
+func (*reloadTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (*reloadTestOutput) Connect() error {
+	return nil
+}
+
+func (o *reloadTestOutput) Close() error {
+	o.Lock()
+	defer o.Unlock()
+	o.closed = true
+	return nil
+}
+
+func (o *reloadTestOutput) Write(metrics []telegraf.Metric) error {
+	o.Lock()
+	defer o.Unlock()
+	if o.names == nil {
+		o.names = make(map[string]bool)
+	}
+	for _, m := range metrics {
+		o.names[m.Name()] = true
+	}
+	return nil
+}
+
+func (o *reloadTestOutput) received(name string) bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.names[name]
+}
+
+func (o *reloadTestOutput) isClosed() bool {
+	o.Lock()
+	defer o.Unlock()
+	return o.closed
+}
+
+type reloadTestSecretStore struct {
+	stopped bool
+	sync.Mutex
+}
+
+func (*reloadTestSecretStore) SampleConfig() string {
+	return ""
+}
+
+func (*reloadTestSecretStore) Init() error {
+	return nil
+}
+
+func (*reloadTestSecretStore) Get(string) ([]byte, error) {
+	return nil, errors.New("not found")
+}
+
+func (*reloadTestSecretStore) Set(string, string) error {
+	return errors.New("not supported")
+}
+
+func (*reloadTestSecretStore) List() ([]string, error) {
+	return nil, nil
+}
+
+func (*reloadTestSecretStore) GetResolver(string) (telegraf.ResolveFunc, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *reloadTestSecretStore) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	s.stopped = true
+}
+
+func (s *reloadTestSecretStore) isStopped() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.stopped
+}
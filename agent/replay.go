@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+)
+
+// Replay runs the full agent for a single gather of all inputs, e.g. reading
+// recorded data from files, and sends the metrics through the processors,
+// aggregators and outputs. In contrast to Once, the aggregation windows are
+// driven by the metric time instead of the wall-clock time, so recorded data
+// is aggregated in the same way as live data. The metrics are expected to be
+// ordered by time. A speed factor larger than zero paces the metrics
+// according to the difference of their timestamps, e.g. a speed of 60
+// replays one hour of data in one minute. Otherwise, metrics are replayed
+// as fast as possible.
+func (a *Agent) Replay(ctx context.Context, wait time.Duration, speed float64) error {
+	if err := a.runReplay(ctx, wait, speed); err != nil {
+		return err
+	}
+
+	if models.GlobalGatherErrors.Get() != 0 {
+		return fmt.Errorf("input plugins recorded %d errors", models.GlobalGatherErrors.Get())
+	}
+
+	unsent := 0
+	for _, output := range a.Config.Outputs {
+		unsent += output.BufferLength()
+	}
+	if unsent != 0 {
+		return fmt.Errorf("output plugins unable to send %d metrics", unsent)
+	}
+	return nil
+}
+
+// runReplay runs the agent like runOnce but paces the gathered metrics and
+// runs the aggregators on metric time.
+func (a *Agent) runReplay(ctx context.Context, wait time.Duration, speed float64) error {
+	a.setSkipProcessorsDefault()
+
+	log.Printf("D! [agent] Initializing plugins")
+	if err := a.InitPlugins(); err != nil {
+		return err
+	}
+
+	log.Printf("D! [agent] Connecting outputs")
+	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+
+	chain, err := a.startChain(a.Config.Processors, a.Config.AggProcessors, a.Config.Aggregators, nil, a.runReplayAggregators)
+	if err != nil {
+		return err
+	}
+	chain.forward(next)
+
+	paceC := make(chan telegraf.Metric, 100)
+	iu := a.testStartInputs(paceC, a.Config.Inputs)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.runOutputs(ou)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pace(ctx, paceC, chain.src, speed)
+		chain.stop()
+		close(next)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.testRunInputs(ctx, wait, iu)
+	}()
+
+	wg.Wait()
+
+	log.Printf("D! [agent] Stopped Successfully")
+
+	return nil
+}
+
+// pace forwards the metrics delayed by the difference to the latest metric
+// time divided by the speed factor until the source channel is closed.
+// Metrics are forwarded immediately if the speed is not positive or the
+// context is done.
+func pace(ctx context.Context, src <-chan telegraf.Metric, dst chan<- telegraf.Metric, speed float64) {
+	var latest time.Time
+	for m := range src {
+		if speed > 0 && !latest.IsZero() {
+			if delay := time.Duration(float64(m.Time().Sub(latest)) / speed); delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+				}
+			}
+		}
+		if m.Time().After(latest) {
+			latest = m.Time()
+		}
+		dst <- m
+	}
+	log.Printf("D! [agent] Replay channel closed")
+}
+
+// runReplayAggregators is a variation of runAggregators driving the
+// aggregation windows by the time of the received metrics. The window of
+// an aggregator is pushed as soon as a metric later than the window's end,
+// including the delay, is received. Aggregates are timestamped with the end
+// of their window.
+func (a *Agent) runReplayAggregators(unit *aggregatorUnit) {
+	interval := time.Duration(a.Config.Agent.Interval)
+	precision := time.Duration(a.Config.Agent.Precision)
+
+	windowEnds := make([]time.Time, len(unit.aggregators))
+	accs := make([]*accumulator, len(unit.aggregators))
+	for i, agg := range unit.aggregators {
+		accs[i] = &accumulator{
+			maker:     agg,
+			metrics:   unit.aggC,
+			precision: getPrecision(precision, interval),
+			now:       func() time.Time { return windowEnds[i] },
+		}
+	}
+
+	var started bool
+	for metric := range unit.src {
+		// Initialize the aggregation windows with the first metric
+		if !started {
+			for _, agg := range unit.aggregators {
+				since, until := updateWindow(metric.Time(), a.Config.Agent.RoundInterval, agg.Period())
+				agg.UpdateWindow(since, until)
+			}
+			started = true
+		}
+
+		var dropOriginal bool
+		for i, agg := range unit.aggregators {
+			if metric.Time().After(agg.EndPeriod().Add(agg.Config.Delay)) {
+				windowEnds[i] = agg.EndPeriod()
+				agg.PushAt(accs[i], metric.Time())
+			}
+			if ok := agg.Add(metric); ok {
+				dropOriginal = true
+			}
+		}
+
+		if !dropOriginal {
+			unit.outputC <- metric // keep original.
+		} else {
+			metric.Drop()
+		}
+	}
+
+	// Push the last window of every aggregator
+	if started {
+		for i, agg := range unit.aggregators {
+			windowEnds[i] = agg.EndPeriod()
+			agg.PushAt(accs[i], agg.EndPeriod())
+		}
+	}
+
+	// In the case that there are no processors, both aggC and outputC are the
+	// same channel.  If there are processors, we close the aggC and the
+	// processor chain will close the outputC when it finishes processing.
+	close(unit.aggC)
+	log.Printf("D! [agent] Aggregator channel closed")
+}
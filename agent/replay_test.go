@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestReplayAggregatesOnMetricTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	offsets := []time.Duration{0, time.Second, 5 * time.Second, 12 * time.Second, 45 * time.Second, 47 * time.Second}
+
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(
+		&replayTestInput{start: start, offsets: offsets},
+		&models.InputConfig{Name: "test"},
+	))
+	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(
+		&replayTestAggregator{},
+		&models.AggregatorConfig{Name: "count", Period: 10 * time.Second, DropOriginal: true},
+	))
+	collector := &testutil.Accumulator{}
+	c.Outputs = append(c.Outputs, models.NewRunningOutput(
+		&replayTestOutput{acc: collector},
+		&models.OutputConfig{Name: "test"},
+		10, 100,
+	))
+
+	a := NewAgent(c)
+	require.NoError(t, a.Replay(t.Context(), 0, 0))
+
+	// The window without metrics is skipped and the aggregates are
+	// timestamped with the end of their window
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"count": int64(3)}, start.Add(10*time.Second)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"count": int64(1)}, start.Add(20*time.Second)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"count": int64(2)}, start.Add(50*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, collector.GetTelegrafMetrics())
+}
+
+func TestReplayPace(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	src := make(chan telegraf.Metric, 3)
+	dst := make(chan telegraf.Metric, 3)
+	src <- metric.New("test", nil, map[string]interface{}{"value": 1}, start)
+	src <- metric.New("test", nil, map[string]interface{}{"value": 2}, start.Add(time.Second))
+	src <- metric.New("test", nil, map[string]interface{}{"value": 3}, start.Add(2*time.Second))
+	close(src)
+
+	// Replay two seconds of data at 20 times the speed
+	begin := time.Now()
+	pace(t.Context(), src, dst, 20)
+	require.GreaterOrEqual(t, time.Since(begin), 100*time.Millisecond)
+	require.Len(t, dst, 3)
+}
+
+type replayTestInput struct {
+	start   time.Time
+	offsets []time.Duration
+}
+
+func (*replayTestInput) SampleConfig() string {
+	return ""
+}
+
+func (i *replayTestInput) Gather(acc telegraf.Accumulator) error {
+	for _, offset := range i.offsets {
+		acc.AddFields("test", map[string]interface{}{"value": 1}, nil, i.start.Add(offset))
+	}
+	return nil
+}
+
+type replayTestAggregator struct {
+	count int64
+}
+
+func (*replayTestAggregator) SampleConfig() string {
+	return ""
+}
+
+func (a *replayTestAggregator) Add(telegraf.Metric) {
+	a.count++
+}
+
+func (a *replayTestAggregator) Push(acc telegraf.Accumulator) {
+	acc.AddFields("test", map[string]interface{}{"count": a.count}, nil)
+}
+
+func (a *replayTestAggregator) Reset() {
+	a.count = 0
+}
+
+type replayTestOutput struct {
+	acc *testutil.Accumulator
+}
+
+func (*replayTestOutput) SampleConfig() string {
+	return ""
+}
+
+func (*replayTestOutput) Connect() error {
+	return nil
+}
+
+func (*replayTestOutput) Close() error {
+	return nil
+}
+
+func (o *replayTestOutput) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		o.acc.AddMetric(m)
+	}
+	return nil
+}
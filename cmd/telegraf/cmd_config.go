@@ -99,6 +99,55 @@ func getConfigCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) [
 						return ag.InitPlugins()
 					},
 				},
+				{
+					Name:  "test",
+					Usage: "test the processors and aggregators of the configuration against test cases",
+					Description: `
+The 'test' command sends the metrics of the given test case files through the
+processors and aggregators of the configuration files specified via '--config'
+or '--config-directory' and compares the resulting metrics to the expected
+ones. Inputs and outputs are ignored. Each test case is run with freshly
+loaded plugins. Aggregation windows are driven by the metric time, so the
+results are reproducible. A test case is a TOML file containing the input
+and expected metrics in line protocol, e.g.
+
+  description = "rename the cpu measurement"
+  # time used for metrics without timestamp, defaults to the Unix epoch
+  time = 2024-01-01T00:00:00Z
+  input = '''
+  cpu,host=a usage=42
+  '''
+  expected = '''
+  processor,host=a usage=42
+  '''
+
+The order of the metrics is not compared. Differences are reported with lines
+missing in the result prefixed by '-' and unexpected lines prefixed by '+'.
+
+To run all test cases in the 'tests' directory against 'pipeline.conf' use
+
+> telegraf config test --config pipeline.conf tests/*.toml
+`,
+					Flags: configHandlingFlags,
+					Action: func(cCtx *cli.Context) error {
+						// Setup logging
+						logConfig := &logger.Config{Debug: cCtx.Bool("debug"), Quiet: !cCtx.Bool("debug")}
+						if err := logger.SetupLogging(logConfig); err != nil {
+							return err
+						}
+
+						configFiles, err := collectConfigFiles(cCtx)
+						if err != nil {
+							return err
+						}
+						caseFiles, err := collectTestCases(cCtx.Args().Slice())
+						if err != nil {
+							return err
+						}
+
+						return runPipelineTests(outputBuffer, configFiles, caseFiles)
+					},
+				},
 				{
 					Name:  "create",
 					Usage: "create a full sample configuration and show it",
@@ -0,0 +1,167 @@
+// Command handling for the dead-letter queue "dlq" command
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+func getDeadLetterCommands(configHandlingFlags []cli.Flag, outputBuffer io.Writer) []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:  "dlq",
+			Usage: "commands for handling dead-letter queues of outputs",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "replay",
+					Usage: "write the metrics of a dead-letter file to an output",
+					Description: `
+The 'replay' command reads the metrics stored in a dead-letter file and writes
+them to the output specified by '--output' using either the output's alias or,
+for outputs without alias, the plugin name. The output is taken from the
+configuration files specified via '--config' or '--config-directory' or the
+default locations if no configuration is specified. The tag annotating the
+reason of the failure is removed before writing the metrics.
+
+To replay the file 'failed.lp' to the output with alias 'primary' use
+
+> telegraf dlq replay --file failed.lp --output primary
+`,
+					Flags: append(configHandlingFlags,
+						&cli.StringFlag{
+							Name:     "file",
+							Usage:    "dead-letter file to read the metrics from",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:     "output",
+							Usage:    "alias or name of the output to write the metrics to",
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:  "reason-tag",
+							Usage: "tag containing the failure reason, defaults to the output's setting",
+						},
+					),
+					Action: func(cCtx *cli.Context) error {
+						logConfig := &logger.Config{Debug: cCtx.Bool("debug")}
+						if err := logger.SetupLogging(logConfig); err != nil {
+							return err
+						}
+
+						configFiles, err := collectConfigFiles(cCtx)
+						if err != nil {
+							return err
+						}
+						c := config.NewConfig()
+						c.Agent.Quiet = cCtx.Bool("quiet")
+						if err := c.LoadAll(configFiles...); err != nil {
+							return err
+						}
+						ro, err := c.FindOutput(cCtx.String("output"))
+						if err != nil {
+							return err
+						}
+
+						reasonTag := cCtx.String("reason-tag")
+						if reasonTag == "" {
+							reasonTag = ro.Config.DeadLetter.ReasonTag
+						}
+						if reasonTag == "" {
+							reasonTag = models.DefaultDeadLetterReasonTag
+						}
+
+						metrics, err := readDeadLetterFile(cCtx.String("file"), reasonTag)
+						if err != nil {
+							return err
+						}
+
+						written, err := replayMetrics(ro, metrics)
+						fmt.Fprintf(outputBuffer, "Replayed %d of %d metrics to %s\n", written, len(metrics), ro.LogName())
+						return err
+					},
+				},
+			},
+		},
+	}
+}
+
+// collectConfigFiles returns the configuration files given on the command
+// line or the default configuration files if none are specified
+func collectConfigFiles(cCtx *cli.Context) ([]string, error) {
+	configFiles := cCtx.StringSlice("config")
+	for _, fConfigDirectory := range cCtx.StringSlice("config-directory") {
+		files, err := config.WalkDirectory(fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+		configFiles = append(configFiles, files...)
+	}
+
+	if len(configFiles) == 0 {
+		return config.GetDefaultConfigPath()
+	}
+	return configFiles, nil
+}
+
+func readDeadLetterFile(filename, reasonTag string) ([]telegraf.Metric, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &influx.Parser{}
+	if err := parser.Init(); err != nil {
+		return nil, err
+	}
+	metrics, err := parser.Parse(buf)
+	if err != nil {
+		return nil, fmt.Errorf("parsing dead-letter file failed: %w", err)
+	}
+	for _, m := range metrics {
+		m.RemoveTag(reasonTag)
+	}
+
+	return metrics, nil
+}
+
+// replayMetrics writes the metrics directly to the output plugin in batches
+// and returns the number of metrics written successfully. The metrics are
+// not passed through the running output as they were already modified
+// by the output's settings before being stored.
+func replayMetrics(ro *models.RunningOutput, metrics []telegraf.Metric) (int, error) {
+	if p, ok := ro.Output.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return 0, fmt.Errorf("initializing %s failed: %w", ro.LogName(), err)
+		}
+	}
+	if err := ro.Output.Connect(); err != nil {
+		return 0, fmt.Errorf("connecting %s failed: %w", ro.LogName(), err)
+	}
+	defer ro.Output.Close()
+
+	batchSize := ro.MetricBatchSize
+	if batchSize <= 0 {
+		batchSize = len(metrics)
+	}
+
+	var written int
+	for start := 0; start < len(metrics); start += batchSize {
+		end := min(start+batchSize, len(metrics))
+		if err := ro.Output.Write(metrics[start:end]); err != nil {
+			return written, fmt.Errorf("writing to %s failed: %w", ro.LogName(), err)
+		}
+		written += end - start
+	}
+
+	return written, nil
+}
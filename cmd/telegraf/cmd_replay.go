@@ -0,0 +1,91 @@
+// Command handling for the "replay" command
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/logger"
+)
+
+func getReplayCommands(configHandlingFlags []cli.Flag) []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:  "replay",
+			Usage: "feed recorded data through the processors, aggregators and outputs",
+			Description: `
+The 'replay' command gathers all inputs once and sends the metrics through the
+configured processors, aggregators and outputs keeping the original metric
+timestamps. Use this command to backfill gaps after outages or to test
+aggregator settings on recorded data. The recorded data is usually read by
+an input such as 'inputs.file' using any of the available data formats.
+Use '--input-filter' to select the inputs reading the recorded data.
+
+In contrast to normal operation, the aggregation windows are driven by the
+metric time instead of the wall-clock time and the aggregates are timestamped
+with the end of their window. The metrics are expected to be ordered by time.
+Metrics are replayed as fast as possible unless a '--speed' factor is given.
+
+To replay the data read by the 'file' input at 60 times the recorded speed use
+
+> telegraf replay --config replay.conf --input-filter file --speed 60
+`,
+			Flags: append(configHandlingFlags,
+				&cli.Float64Flag{
+					Name:  "speed",
+					Usage: "replay speed relative to the metric timestamps, zero replays as fast as possible",
+				},
+				&cli.DurationFlag{
+					Name:  "wait",
+					Usage: "time to wait for service inputs to complete",
+				},
+			),
+			Action: func(cCtx *cli.Context) error {
+				if cCtx.Float64("speed") < 0 {
+					return errors.New("speed must not be negative")
+				}
+
+				logConfig := &logger.Config{
+					Debug: cCtx.Bool("debug"),
+					Quiet: cCtx.Bool("quiet"),
+				}
+				if err := logger.SetupLogging(logConfig); err != nil {
+					return err
+				}
+
+				configFiles, err := collectConfigFiles(cCtx)
+				if err != nil {
+					return err
+				}
+				filters := processFilterFlags(cCtx)
+				c := config.NewConfig()
+				c.Agent.Quiet = cCtx.Bool("quiet")
+				c.InputFilters = filters.input
+				c.OutputFilters = filters.output
+				c.SecretStoreFilters = filters.secretstore
+				if err := c.LoadAll(configFiles...); err != nil {
+					return err
+				}
+				if len(c.Inputs) == 0 {
+					return errors.New("no inputs found for reading the recorded data")
+				}
+				if len(c.Outputs) == 0 {
+					return errors.New("no outputs found")
+				}
+
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				defer cancel()
+
+				ag := agent.NewAgent(c)
+				return ag.Replay(ctx, cCtx.Duration("wait"), cCtx.Float64("speed"))
+			},
+		},
+	}
+}
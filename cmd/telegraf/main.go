@@ -262,6 +262,8 @@ func runApp(args []string, outputBuffer io.Writer, pprof Server, c TelegrafConfi
 		getSecretStoreCommands(m)...,
 	)
 	commands = append(commands, getPluginCommands(outputBuffer)...)
+	commands = append(commands, getDeadLetterCommands(configHandlingFlags, outputBuffer)...)
+	commands = append(commands, getReplayCommands(configHandlingFlags)...)
 	commands = append(commands, getServiceCommands(outputBuffer)...)
 
 	app := &cli.App{
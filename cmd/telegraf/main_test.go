@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -427,6 +428,55 @@ func TestCommandConfig(t *testing.T) {
 	}
 }
 
+func TestCommandConfigTest(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "pipeline.conf")
+	require.NoError(t, os.WriteFile(cfg, []byte(`
+[agent]
+  skip_processors_after_aggregators = true
+
+[[processors.rename]]
+  [[processors.rename.replace]]
+    measurement = "cpu"
+    dest = "processor"
+
+[[aggregators.minmax]]
+  period = "10s"
+`), 0600))
+
+	pass := filepath.Join(dir, "pass.toml")
+	require.NoError(t, os.WriteFile(pass, []byte(`
+description = "rename and aggregate"
+input = '''
+cpu value=1 0
+cpu value=3 5000000000
+'''
+expected = '''
+processor value=1 0
+processor value=3 5000000000
+processor value_min=1,value_max=3 10000000000
+'''
+`), 0600))
+
+	fail := filepath.Join(dir, "fail.toml")
+	require.NoError(t, os.WriteFile(fail, []byte(`
+input = "cpu value=1 0"
+expected = "cpu value=1 0"
+`), 0600))
+
+	buf := new(bytes.Buffer)
+	args := []string{"telegraf", "config", "test", "--config", cfg, pass, fail}
+	err := runApp(args, buf, NewMockServer(), NewMockConfig(buf), NewMockTelegraf())
+	require.ErrorContains(t, err, "1 of 2 test cases failed")
+
+	expected := "PASS  " + pass + " (rename and aggregate)\n" +
+		"FAIL  " + fail + "\n" +
+		"      - cpu value=1 0\n" +
+		"      + processor value=1 0\n" +
+		"      + processor value_max=1,value_min=1 10000000000\n"
+	require.Equal(t, expected, buf.String())
+}
+
 func TestCommandVersion(t *testing.T) {
 	tests := []struct {
 		Version        string
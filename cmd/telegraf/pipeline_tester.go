@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// pipelineTestCase describes the metrics sent through the processors and
+// aggregators and the expected resulting metrics, both in line protocol
+type pipelineTestCase struct {
+	Description string    `toml:"description"`
+	Time        time.Time `toml:"time"`
+	Input       string    `toml:"input"`
+	Expected    string    `toml:"expected"`
+}
+
+// runPipelineTests runs the given test case files against the processors and
+// aggregators of the configuration and reports the differences.
+func runPipelineTests(w io.Writer, configFiles, caseFiles []string) error {
+	if len(caseFiles) == 0 {
+		return errors.New("no test case files specified")
+	}
+
+	var failed int
+	for _, fn := range caseFiles {
+		description, diff, err := runPipelineTest(configFiles, fn)
+		if description != "" {
+			description = " (" + description + ")"
+		}
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(w, "ERROR %s%s: %v\n", fn, description, err)
+		case len(diff) > 0:
+			failed++
+			fmt.Fprintf(w, "FAIL  %s%s\n", fn, description)
+			for _, line := range diff {
+				fmt.Fprintf(w, "      %s\n", line)
+			}
+		default:
+			fmt.Fprintf(w, "PASS  %s%s\n", fn, description)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test cases failed", failed, len(caseFiles))
+	}
+	return nil
+}
+
+// runPipelineTest runs a single test case with freshly loaded plugins and
+// returns the description of the test case and the differences, i.e. the
+// lines missing in the result prefixed by '-' and the unexpected lines
+// prefixed by '+'
+func runPipelineTest(configFiles []string, fn string) (string, []string, error) {
+	var tc pipelineTestCase
+	if _, err := toml.DecodeFile(fn, &tc); err != nil {
+		return "", nil, fmt.Errorf("reading test case failed: %w", err)
+	}
+
+	// Metrics without timestamp use the time of the test case to get
+	// reproducible results
+	if tc.Time.IsZero() {
+		tc.Time = time.Unix(0, 0)
+	}
+	parser := &influx.Parser{}
+	if err := parser.Init(); err != nil {
+		return tc.Description, nil, err
+	}
+	parser.SetTimeFunc(func() time.Time { return tc.Time })
+
+	input, err := parser.Parse([]byte(tc.Input))
+	if err != nil {
+		return tc.Description, nil, fmt.Errorf("parsing input failed: %w", err)
+	}
+	expected, err := parser.Parse([]byte(tc.Expected))
+	if err != nil {
+		return tc.Description, nil, fmt.Errorf("parsing expected metrics failed: %w", err)
+	}
+
+	c := config.NewConfig()
+	c.Agent.Quiet = true
+	if err := c.LoadAll(configFiles...); err != nil {
+		return tc.Description, nil, err
+	}
+	actual, err := agent.NewAgent(c).RunPipeline(input)
+	if err != nil {
+		return tc.Description, nil, err
+	}
+
+	diff, err := diffMetrics(expected, actual)
+	return tc.Description, diff, err
+}
+
+// diffMetrics compares the metrics independent of their order as processors
+// and aggregators might reorder metrics
+func diffMetrics(expected, actual []telegraf.Metric) ([]string, error) {
+	expectedLines, err := serializeSorted(expected)
+	if err != nil {
+		return nil, err
+	}
+	actualLines, err := serializeSorted(actual)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	var i, j int
+	for i < len(expectedLines) || j < len(actualLines) {
+		switch {
+		case j >= len(actualLines) || (i < len(expectedLines) && expectedLines[i] < actualLines[j]):
+			diff = append(diff, "- "+expectedLines[i])
+			i++
+		case i >= len(expectedLines) || actualLines[j] < expectedLines[i]:
+			diff = append(diff, "+ "+actualLines[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return diff, nil
+}
+
+func serializeSorted(metrics []telegraf.Metric) ([]string, error) {
+	s := &serializer.Serializer{SortFields: true, UintSupport: true}
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		buf, err := s.Serialize(m)
+		if err != nil {
+			return nil, fmt.Errorf("serializing %q failed: %w", m.Name(), err)
+		}
+		lines = append(lines, strings.TrimSpace(string(buf)))
+	}
+	slices.Sort(lines)
+
+	return lines, nil
+}
+
+// collectTestCases expands the given patterns to the test case files
+func collectTestCases(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid test case pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no test case files found for %q", pattern)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -82,6 +83,9 @@ type Telegraf struct {
 
 	cfg *config.Config
 
+	// running is the agent currently running if any
+	running atomic.Pointer[agent.Agent]
+
 	GlobalFlags
 	WindowFlags
 }
@@ -182,29 +186,44 @@ func (t *Telegraf) reloadLoop() error {
 			}
 		}
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Println("I! Reloading Telegraf config")
-					// May need to update the list of known config files
-					// if a delete or create occured. That way on the reload
-					// we ensure we watch the correct files.
-					if err := t.getConfigFiles(); err != nil {
-						log.Println("E! Error loading config files: ", err)
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Println("I! Reloading Telegraf config")
+						// May need to update the list of known config files
+						// if a delete or create occured. That way on the reload
+						// we ensure we watch the correct files.
+						if err := t.getConfigFiles(); err != nil {
+							log.Println("E! Error loading config files: ", err)
+						}
+						// Only restart the changed plugins if possible
+						if t.reloadPlugins(ctx) {
+							continue
+						}
+						<-reload
+						reload <- true
 					}
-					<-reload
-					reload <- true
+					cancel()
+				case err := <-t.pprofErr:
+					log.Printf("E! pprof server failed: %v", err)
+					cancel()
+				case <-stop:
+					cancel()
 				}
-				cancel()
-			case err := <-t.pprofErr:
-				log.Printf("E! pprof server failed: %v", err)
-				cancel()
-			case <-stop:
-				cancel()
+				return
 			}
 		}()
 
-		err := t.runAgent(ctx, reloadConfig)
+		// Allow to request a reload via the agent's admin API
+		requestReload := func() {
+			select {
+			case signals <- syscall.SIGHUP:
+			default:
+			}
+		}
+
+		err := t.runAgent(ctx, reloadConfig, requestReload)
 		if err != nil && !errors.Is(err, context.Canceled) {
 			return fmt.Errorf("[telegraf] Error running agent: %w", err)
 		}
@@ -344,8 +363,6 @@ func (*Telegraf) watchRemoteConfigs(ctx context.Context, signals chan os.Signal,
 		select {
 		case <-ctx.Done():
 			return
-		case <-signals:
-			return
 		case <-ticker.C:
 			for _, configURL := range remoteConfigs {
 				req, err := http.NewRequest("HEAD", configURL, nil)
@@ -376,14 +393,43 @@ func (*Telegraf) watchRemoteConfigs(ctx context.Context, signals chan os.Signal,
 					lastModified[configURL] = modified
 				} else if lastModified[configURL] != modified {
 					log.Printf("I! Remote config modified: %s\n", configURL)
+					lastModified[configURL] = modified
 					signals <- syscall.SIGHUP
-					return
 				}
 			}
 		}
 	}
 }
 
+// reloadPlugins applies the changed configuration to the running agent by
+// only restarting the added, removed or modified inputs and outputs. It
+// returns false if the whole agent needs to be restarted instead.
+func (t *Telegraf) reloadPlugins(ctx context.Context) bool {
+	ag := t.running.Load()
+	if ag == nil {
+		return false
+	}
+
+	c, err := t.loadConfiguration()
+	if err != nil {
+		log.Printf("E! Loading config failed: %v", err)
+		c.StopSecretStores()
+		return false
+	}
+
+	if err := ag.ReloadPlugins(ctx, c); err != nil {
+		if errors.Is(err, agent.ErrRestartRequired) {
+			log.Printf("I! Restarting agent: %v", err)
+		} else {
+			log.Printf("E! Reloading plugins failed, restarting agent: %v", err)
+		}
+		return false
+	}
+	log.Println("I! Reloaded plugins")
+
+	return true
+}
+
 func (t *Telegraf) loadConfiguration() (*config.Config, error) {
 	// If no other options are specified, load the config file and run.
 	c := config.NewConfig()
@@ -427,7 +473,7 @@ func (t *Telegraf) getConfigFiles() error {
 	return nil
 }
 
-func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
+func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool, requestReload func()) error {
 	c := t.cfg
 	var err error
 	if reloadConfig {
@@ -519,6 +565,7 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 	ag := agent.NewAgent(c)
+	ag.SetReloadHandler(requestReload)
 
 	// Notify systemd that telegraf is ready
 	// SdNotify() only tries to notify if the NOTIFY_SOCKET environment is set, so it's safe to call when systemd isn't present.
@@ -558,6 +605,9 @@ func (t *Telegraf) runAgent(ctx context.Context, reloadConfig bool) error {
 		}
 	}
 
+	t.running.Store(ag)
+	defer t.running.Store(nil)
+
 	return ag.Run(ctx)
 }
 
@@ -103,6 +103,10 @@ type Config struct {
 
 	seenAgentTable     bool
 	seenAgentTableOnce sync.Once
+
+	// IDs of the non-plugin settings, i.e. agent, tags and secret-store
+	// tables, in the order of loading
+	settingIDs []string
 }
 
 // Ordered plugins used to keep the order in which they appear in a file
@@ -275,6 +279,11 @@ type AgentConfig struct {
 	// the state in the file will be restored for the plugins.
 	Statefile string `toml:"statefile"`
 
+	// Interval for periodically saving the state of plugins to the statefile
+	// in addition to saving the state on termination. If zero, the state is
+	// only saved on termination of Telegraf.
+	StatefileFlushInterval Duration `toml:"statefile_flush_interval"`
+
 	// Flag to always keep tags explicitly defined in the plugin itself and
 	// ensure those tags always pass filtering.
 	AlwaysIncludeLocalTags bool `toml:"always_include_local_tags"`
@@ -293,12 +302,38 @@ type AgentConfig struct {
 	ConfigURLRetryAttempts int `toml:"config_url_retry_attempts"`
 
 	// BufferStrategy is the metric buffer type to use for a given output plugin.
-	// Supported types currently are "memory" and "disk_write_through" (alias: "disk").
+	// Supported types currently are "memory", "disk_write_through" (alias: "disk")
+	// and "memory_spill".
 	BufferStrategy string `toml:"buffer_strategy"`
 
 	// BufferDirectory is the directory to store buffer files for serialized
-	// to disk metrics when using the "disk_write_through" buffer strategy.
+	// to disk metrics when using the "disk_write_through" or "memory_spill"
+	// buffer strategy.
 	BufferDirectory string `toml:"buffer_directory"`
+
+	// BufferDiskMaxSize limits the size of the metrics stored on disk per
+	// output plugin. The oldest metrics are dropped when exceeding the limit.
+	BufferDiskMaxSize Size `toml:"buffer_disk_max_size"`
+
+	// BufferDiskMaxAge limits the time metrics are stored on disk. Metrics
+	// stored longer are dropped.
+	BufferDiskMaxAge Duration `toml:"buffer_disk_max_age"`
+
+	// BufferDiskCompression is the algorithm used to compress the metrics
+	// stored on disk. Supported values are "none" and "zstd".
+	BufferDiskCompression string `toml:"buffer_disk_compression"`
+
+	// BufferDiskEncryptionKey is the hex-encoded AES key used to encrypt the
+	// metrics stored on disk. Encryption is disabled if empty.
+	BufferDiskEncryptionKey Secret `toml:"buffer_disk_encryption_key"`
+
+	// AdminAddress is the address to serve the local HTTP admin API on, e.g.
+	// "localhost:8089". The API is disabled if empty.
+	AdminAddress string `toml:"admin_address"`
+
+	// AdminToken is the bearer token required for all admin API requests.
+	// It is mandatory if the admin API listens on a non-loopback address.
+	AdminToken Secret `toml:"admin_token"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -408,6 +443,16 @@ func (c *Config) SecretstoreNamesWithSources() string {
 	return getPluginSourcesTable(plugins)
 }
 
+// StopSecretStores stops background activities of the secret-stores such as
+// renewing credentials.
+func (c *Config) StopSecretStores() {
+	for _, store := range c.SecretStores {
+		if s, ok := store.(interface{ Stop() }); ok {
+			s.Stop()
+		}
+	}
+}
+
 // PluginNameCounts returns a string of plugin names and their counts.
 // PluginNameCounts returns a list of sorted plugin names and their count
 func PluginNameCounts(plugins []string) []string {
@@ -577,10 +622,91 @@ func (c *Config) LoadAll(configFiles ...string) error {
 	}
 	c.NumberSecrets = uint64(count)
 
+	// Connect outputs to the outputs receiving their dead letters
+	if err := c.linkDeadLetterOutputs(); err != nil {
+		return err
+	}
+
+	// Connect outputs to their failover outputs
+	if err := c.linkFailoverOutputs(); err != nil {
+		return err
+	}
+
 	// Let's link all secrets to their secret-stores
 	return c.LinkSecrets()
 }
 
+// FindOutput returns the output with the given alias or, for outputs
+// without alias, the given plugin name.
+func (c *Config) FindOutput(ref string) (*models.RunningOutput, error) {
+	var found *models.RunningOutput
+	for _, ro := range c.Outputs {
+		if ro.Config.Alias != ref && (ro.Config.Alias != "" || ro.Config.Name != ref) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("output %q is ambiguous, please use an alias", ref)
+		}
+		found = ro
+	}
+	if found == nil {
+		return nil, fmt.Errorf("output %q not found", ref)
+	}
+	return found, nil
+}
+
+// linkDeadLetterOutputs resolves the outputs referenced in the dead-letter
+// settings of other outputs by alias or name.
+func (c *Config) linkDeadLetterOutputs() error {
+	for _, ro := range c.Outputs {
+		ref := ro.Config.DeadLetter.Output
+		if ref == "" {
+			continue
+		}
+
+		target, err := c.FindOutput(ref)
+		if err != nil {
+			return fmt.Errorf("dead-letter output of %s: %w", ro.LogName(), err)
+		}
+
+		switch {
+		case target == ro:
+			return fmt.Errorf("%s cannot be its own dead-letter output", ro.LogName())
+		case target.Config.DeadLetter.Output != "":
+			return fmt.Errorf("dead-letter output %q of %s cannot use a dead-letter output itself", ref, ro.LogName())
+		}
+		ro.SetDeadLetterOutput(target)
+	}
+
+	return nil
+}
+
+// linkFailoverOutputs resolves the failover outputs referenced by other
+// outputs by alias or name.
+func (c *Config) linkFailoverOutputs() error {
+	for _, ro := range c.Outputs {
+		ref := ro.Config.FailoverTo
+		if ref == "" {
+			continue
+		}
+
+		target, err := c.FindOutput(ref)
+		if err != nil {
+			return fmt.Errorf("failover output of %s: %w", ro.LogName(), err)
+		}
+
+		switch {
+		case target == ro:
+			return fmt.Errorf("%s cannot be its own failover output", ro.LogName())
+		case target.Config.FailoverTo != "":
+			return fmt.Errorf("failover output %q of %s cannot use a failover output itself", ref, ro.LogName())
+		}
+		ro.SetFailoverOutput(target, ro.Config.FailoverThreshold)
+	}
+
+	return nil
+}
+
 type cfgDataOptions struct {
 	sourcePath string
 }
@@ -607,6 +733,9 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 			if !ok {
 				return fmt.Errorf("invalid configuration, bad table name %q", tableName)
 			}
+			if err := c.addSettingID(tableName, subTable); err != nil {
+				return err
+			}
 			if err = c.toml.UnmarshalTable(subTable, c.Tags); err != nil {
 				return fmt.Errorf("error parsing table name %q: %w", tableName, err)
 			}
@@ -626,6 +755,9 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 		if !ok {
 			return errors.New("invalid configuration, error parsing agent table")
 		}
+		if err := c.addSettingID("agent", subTable); err != nil {
+			return err
+		}
 		if err = c.toml.UnmarshalTable(subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing [agent]: %w", err)
 		}
@@ -782,6 +914,9 @@ func (c *Config) LoadConfigData(data []byte, path string) error {
 						if err = c.addSecretStore(pluginName, path, t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
+						if err := c.addSettingID("secretstores."+pluginName, t); err != nil {
+							return err
+						}
 					}
 				default:
 					return fmt.Errorf("unsupported config format: %s", pluginName)
@@ -1618,6 +1753,7 @@ func (c *Config) buildInput(name, source string, tbl *ast.Table) (*models.InputC
 	cp.NameOverride = c.getFieldString(tbl, "name_override")
 	cp.Alias = c.getFieldString(tbl, "alias")
 	cp.LogLevel = c.getFieldString(tbl, "log_level")
+	cp.SeriesLimit = c.buildSeriesLimit(tbl)
 
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1657,12 +1793,34 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	if bufferStrategy == "disk" {
 		bufferStrategy = "disk_write_through"
 	}
+	switch c.Agent.BufferDiskCompression {
+	case "", "none", "zstd":
+	default:
+		return nil, fmt.Errorf("invalid buffer_disk_compression %q", c.Agent.BufferDiskCompression)
+	}
 	oc := &models.OutputConfig{
-		Name:            name,
-		Source:          source,
-		Filter:          filter,
-		BufferStrategy:  bufferStrategy,
-		BufferDirectory: c.Agent.BufferDirectory,
+		Name:                  name,
+		Source:                source,
+		Filter:                filter,
+		BufferStrategy:        bufferStrategy,
+		BufferDirectory:       c.Agent.BufferDirectory,
+		BufferDiskMaxSize:     int64(c.Agent.BufferDiskMaxSize),
+		BufferDiskMaxAge:      time.Duration(c.Agent.BufferDiskMaxAge),
+		BufferDiskCompression: c.Agent.BufferDiskCompression,
+	}
+
+	// The secret is not linked to its secret-store yet so defer getting the
+	// key until the buffer first uses it.
+	if !c.Agent.BufferDiskEncryptionKey.Empty() {
+		key := &c.Agent.BufferDiskEncryptionKey
+		oc.BufferDiskEncryptionKey = func() ([]byte, error) {
+			secret, err := key.Get()
+			if err != nil {
+				return nil, err
+			}
+			defer secret.Destroy()
+			return bytes.Clone(secret.Bytes()), nil
+		}
 	}
 
 	// TODO: support FieldPass/FieldDrop on outputs
@@ -1677,13 +1835,32 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	oc.NamePrefix = c.getFieldString(tbl, "name_prefix")
 	oc.StartupErrorBehavior = c.getFieldString(tbl, "startup_error_behavior")
 	oc.LogLevel = c.getFieldString(tbl, "log_level")
+	oc.FailoverTo = c.getFieldString(tbl, "failover_to")
+	oc.FailoverThreshold = c.getFieldInt(tbl, "failover_threshold")
+	oc.SeriesLimit = c.buildSeriesLimit(tbl)
+
+	if node, ok := tbl.Fields["dead_letter"]; ok {
+		subTbl, ok := node.(*ast.Table)
+		if !ok {
+			return nil, errors.New("invalid format for 'dead_letter', expecting a table")
+		}
+		oc.DeadLetter.Output = c.getFieldString(subTbl, "output")
+		oc.DeadLetter.File = c.getFieldString(subTbl, "file")
+		oc.DeadLetter.ReasonTag = c.getFieldString(subTbl, "reason_tag")
+		if oc.DeadLetter.Output != "" && oc.DeadLetter.File != "" {
+			return nil, errors.New("'dead_letter' cannot specify both 'output' and 'file'")
+		}
+	}
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
 
-	if oc.BufferStrategy == "disk_write_through" {
+	switch oc.BufferStrategy {
+	case "disk_write_through":
 		log.Printf("W! Using disk-write-through buffer strategy for plugin outputs.%s, this is an experimental feature", name)
+	case "memory_spill":
+		log.Printf("W! Using memory-spill buffer strategy for plugin outputs.%s, this is an experimental feature", name)
 	}
 
 	// Generate an ID for the plugin
@@ -1691,17 +1868,32 @@ func (c *Config) buildOutput(name, source string, tbl *ast.Table) (*models.Outpu
 	return oc, err
 }
 
+// buildSeriesLimit parses the series cardinality settings of inputs and
+// outputs
+func (c *Config) buildSeriesLimit(tbl *ast.Table) models.SeriesLimitConfig {
+	expiry, _ := c.getFieldDuration(tbl, "max_series_expiry")
+	return models.SeriesLimitConfig{
+		MaxSeries: c.getFieldInt(tbl, "max_series"),
+		Action:    c.getFieldString(tbl, "max_series_action"),
+		Tags:      c.getFieldStringSlice(tbl, "max_series_tags"),
+		Buckets:   c.getFieldInt(tbl, "max_series_buckets"),
+		Expiry:    expiry,
+	}
+}
+
 func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 	switch key {
 	// General options to ignore
 	case "alias", "always_include_local_tags",
 		"buffer_strategy", "buffer_directory",
 		"collection_jitter", "collection_offset",
-		"data_format", "delay", "drop", "drop_original",
+		"data_format", "dead_letter", "delay", "drop", "drop_original",
+		"failover_threshold", "failover_to",
 		"fielddrop", "fieldexclude", "fieldinclude", "fieldpass", "flush_interval", "flush_jitter",
 		"grace",
 		"interval",
 		"log_level", "lvm", // What is this used for?
+		"max_series", "max_series_action", "max_series_buckets", "max_series_expiry", "max_series_tags",
 		"metric_batch_size", "metric_buffer_limit", "metricpass",
 		"name_override", "name_prefix", "name_suffix", "namedrop", "namedrop_separator", "namepass", "namepass_separator",
 		"order",
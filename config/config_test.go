@@ -518,6 +518,61 @@ func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	}
 }
 
+func TestConfig_DeadLetterOutput(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/dead_letter_output.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	expected := models.DeadLetterConfig{Output: "fallback", ReasonTag: "failure"}
+	require.Equal(t, expected, c.Outputs[0].Config.DeadLetter)
+	require.False(t, c.Outputs[1].Config.DeadLetter.Enabled())
+	require.Empty(t, c.UnusedFields)
+	require.False(t, c.Outputs[0].Standby())
+	require.True(t, c.Outputs[1].Standby())
+
+	target, err := c.FindOutput("fallback")
+	require.NoError(t, err)
+	require.Same(t, c.Outputs[1], target)
+
+	_, err = c.FindOutput("http")
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestConfig_DeadLetterOutputSelfReference(t *testing.T) {
+	c := config.NewConfig()
+	require.ErrorContains(t, c.LoadAll("./testdata/dead_letter_self.toml"), "cannot be its own dead-letter output")
+}
+
+func TestConfig_FailoverOutput(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/failover_output.toml"))
+	require.Len(t, c.Outputs, 2)
+	require.Empty(t, c.UnusedFields)
+
+	require.Equal(t, "cloud", c.Outputs[0].Config.FailoverTo)
+	require.Equal(t, 5, c.Outputs[0].Config.FailoverThreshold)
+	require.False(t, c.Outputs[0].Standby())
+	require.True(t, c.Outputs[1].Standby())
+}
+
+func TestConfig_SeriesLimit(t *testing.T) {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadAll("./testdata/series_limit.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Len(t, c.Outputs, 1)
+	require.Empty(t, c.UnusedFields)
+
+	expected := models.SeriesLimitConfig{
+		MaxSeries: 1000,
+		Action:    models.SeriesLimitHashTags,
+		Tags:      []string{"id"},
+		Buckets:   10,
+		Expiry:    time.Hour,
+	}
+	require.Equal(t, expected, c.Inputs[0].Config.SeriesLimit)
+	require.Equal(t, models.SeriesLimitConfig{MaxSeries: 500}, c.Outputs[0].Config.SeriesLimit)
+}
+
 func TestGetDefaultConfigPathFromEnvURL(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -1091,6 +1146,41 @@ func TestConfigPluginIDsSame(t *testing.T) {
 	}
 }
 
+func TestConfigSettingsID(t *testing.T) {
+	load := func(cfg string) string {
+		c := config.NewConfig()
+		require.NoError(t, c.LoadConfigData([]byte(cfg), config.EmptySourcePath))
+		return c.SettingsID()
+	}
+
+	base := `
+[global_tags]
+  dc = "us-east-1"
+[agent]
+  interval = "10s"
+[[inputs.memcached]]
+  servers = ["localhost:11211"]
+`
+
+	// Plugin changes keep the settings ID
+	plugins := `
+[global_tags]
+  dc = "us-east-1"
+[agent]
+  interval = "10s"
+[[inputs.memcached]]
+  servers = ["localhost:11212"]
+[[inputs.file]]
+`
+	require.Equal(t, load(base), load(plugins))
+
+	// Agent settings and tags change the settings ID
+	agent := strings.Replace(base, `"10s"`, `"20s"`, 1)
+	require.NotEqual(t, load(base), load(agent))
+	tags := strings.Replace(base, `"us-east-1"`, `"eu-west-1"`, 1)
+	require.NotEqual(t, load(base), load(tags))
+}
+
 func TestPersisterInputStoreLoad(t *testing.T) {
 	// Reserve a temporary state file
 	file, err := os.CreateTemp(t.TempDir(), "telegraf_state-*.json")
@@ -77,3 +77,26 @@ func generatePluginID(prefix string, table *ast.Table) (string, error) {
 
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
+
+// addSettingID records the ID of a non-plugin settings table
+func (c *Config) addSettingID(name string, tbl *ast.Table) error {
+	id, err := generatePluginID(name, tbl)
+	if err != nil {
+		return fmt.Errorf("generating ID for %q failed: %w", name, err)
+	}
+	c.settingIDs = append(c.settingIDs, id)
+	return nil
+}
+
+// SettingsID returns an ID of all settings not belonging to inputs,
+// processors, aggregators or outputs, i.e. the agent, global tags and
+// secret-store settings. Configurations with the same settings ID only differ
+// in their plugins.
+func (c *Config) SettingsID() string {
+	hash := sha256.New()
+	for _, id := range c.settingIDs {
+		hash.Write([]byte(id))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -92,10 +92,12 @@ type BufferStats struct {
 	MetricsDropped  selfstat.Stat
 	BufferSize      selfstat.Stat
 	BufferLimit     selfstat.Stat
+
+	deadLetter *deadLetterHook
 }
 
 // NewBuffer returns a new empty Buffer with the given capacity.
-func NewBuffer(name, id, alias string, capacity int, strategy, path string) (Buffer, error) {
+func NewBuffer(name, id, alias string, capacity int, strategy, path string, options ...DiskBufferOption) (Buffer, error) {
 	registerGob()
 
 	bs := NewBufferStats(name, alias, capacity)
@@ -104,7 +106,9 @@ func NewBuffer(name, id, alias string, capacity int, strategy, path string) (Buf
 	case "", "memory":
 		return NewMemoryBuffer(capacity, bs)
 	case "disk_write_through":
-		return NewDiskBuffer(id, path, bs)
+		return NewDiskBuffer(id, path, bs, options...)
+	case "memory_spill":
+		return NewSpillBuffer(id, path, capacity, bs, options...)
 	}
 	return nil, fmt.Errorf("invalid buffer strategy %q", strategy)
 }
@@ -149,9 +153,18 @@ func NewBufferStats(name, alias string, capacity int) BufferStats {
 	}
 	bs.BufferSize.Set(int64(0))
 	bs.BufferLimit.Set(int64(capacity))
+	bs.deadLetter = &deadLetterHook{}
 	return bs
 }
 
+// SetDeadLetterQueue sets the queue receiving the metrics dropped by the
+// buffer. The queue is shared by all copies of the statistics.
+func (b *BufferStats) SetDeadLetterQueue(q DeadLetterQueue) {
+	if b.deadLetter != nil {
+		b.deadLetter.queue = q
+	}
+}
+
 func (b *BufferStats) metricAdded() {
 	b.MetricsAdded.Incr(1)
 }
@@ -168,8 +181,11 @@ func (b *BufferStats) metricRejected(m telegraf.Metric) {
 	m.Reject()
 }
 
-func (b *BufferStats) metricDropped(m telegraf.Metric) {
+func (b *BufferStats) metricDropped(m telegraf.Metric, reason string) {
 	AgentMetricsDropped.Incr(1)
 	b.MetricsDropped.Incr(1)
+	if b.deadLetter != nil && b.deadLetter.queue != nil {
+		b.deadLetter.queue.Add(m, reason)
+	}
 	m.Reject()
 }
@@ -9,6 +9,7 @@ import (
 	"slices"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/tidwall/wal"
 
@@ -39,26 +40,86 @@ type DiskBuffer struct {
 	// transaction. Metrics at those offsets should not be contained in new
 	// batches.
 	mask []int
+
+	// Offsets of the metrics in the currently running transaction
+	batch []int
+
+	// Limits for the data kept on disk
+	maxSize int64
+	maxAge  time.Duration
+
+	// Size and time of addition for each entry in the WAL file starting at
+	// the read index. Only tracked if any limit is set.
+	info     []entryInfo
+	liveSize int64
+
+	compression   string
+	encryptionKey func() ([]byte, error)
+	codec         *entryCodec
+
+	now func() time.Time
 }
 
-func NewDiskBuffer(id, path string, stats BufferStats) (*DiskBuffer, error) {
-	filePath := filepath.Join(path, id)
-	walFile, err := wal.Open(filePath, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open wal file: %w", err)
-	}
+type entryInfo struct {
+	size  int64
+	added time.Time
+}
 
+func NewDiskBuffer(id, path string, stats BufferStats, options ...DiskBufferOption) (*DiskBuffer, error) {
 	buf := &DiskBuffer{
 		BufferStats: stats,
-		file:        walFile,
-		path:        filePath,
+		path:        filepath.Join(path, id),
+		now:         time.Now,
+	}
+	for _, opt := range options {
+		opt(buf)
+	}
+
+	codec, err := newEntryCodec(buf.compression, buf.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	buf.codec = codec
+
+	walFile, err := wal.Open(buf.path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wal file: %w", err)
 	}
+	buf.file = walFile
+
 	if buf.Len() > 0 {
 		buf.originalEnd = buf.writeIndex()
 	}
+
+	// Collect the information on the existing entries required to apply the
+	// limits.
+	if buf.limited() && buf.entries() > 0 {
+		opened := buf.now()
+		for index := buf.readIndex(); index < buf.writeIndex(); index++ {
+			data, err := buf.file.Read(index)
+			if err != nil {
+				return nil, fmt.Errorf("reading entry %d failed: %w", index, err)
+			}
+			buf.info = append(buf.info, entryInfo{
+				size:  int64(len(data)),
+				added: buf.codec.header(data, opened),
+			})
+			buf.liveSize += int64(len(data))
+		}
+	}
+
 	return buf, nil
 }
 
+// Init resolves and validates the encryption key if any. This must be done
+// after creating the buffer as the key might reference a secret-store not
+// available at that time.
+func (b *DiskBuffer) Init() error {
+	b.Lock()
+	defer b.Unlock()
+	return b.codec.init()
+}
+
 func (b *DiskBuffer) Len() int {
 	b.Lock()
 	defer b.Unlock()
@@ -102,34 +163,60 @@ func (b *DiskBuffer) Add(metrics ...telegraf.Metric) int {
 	b.Lock()
 	defer b.Unlock()
 
-	dropped := 0
+	var dropped int
+	var errs []error
 	for _, m := range metrics {
-		if !b.addSingleMetric(m) {
+		if err := b.addSingleMetric(m); err != nil {
+			b.metricDropped(m, DeadLetterOverflow)
+			errs = append(errs, err)
 			dropped++
 		}
 		// as soon as a new metric is added, if this was empty, try to flush the "empty" metric out
 		b.handleEmptyFile()
 	}
+	if len(errs) > 0 {
+		log.Printf("E! Adding %d metric(s) to disk buffer failed: %v", len(errs), errs[0])
+	}
+	dropped += b.enforceLimits()
 	b.BufferSize.Set(int64(b.length()))
 	return dropped
 }
 
-func (b *DiskBuffer) addSingleMetric(m telegraf.Metric) bool {
-	data, err := metric.ToBytes(m)
+func (b *DiskBuffer) addSingleMetric(m telegraf.Metric) error {
+	if err := b.writeMetric(m); err != nil {
+		return err
+	}
+	b.metricAdded()
+	return nil
+}
+
+// writeMetric appends the metric to the WAL file without updating the
+// statistics of the buffer.
+func (b *DiskBuffer) writeMetric(m telegraf.Metric) error {
+	added := b.now()
+	data, err := b.codec.encode(m, added)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	if err := b.file.Write(b.writeIndex(), data); err != nil {
-		return false
+		return err
 	}
-	b.metricAdded()
-	return true
+
+	if b.limited() {
+		b.info = append(b.info, entryInfo{size: int64(len(data)), added: added})
+		b.liveSize += int64(len(data))
+	}
+	return nil
 }
 
 func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
 	b.Lock()
 	defer b.Unlock()
 
+	// Drop expired metrics before sending them
+	b.enforceLimits()
+	b.BufferSize.Set(int64(b.length()))
+
 	if b.length() == 0 {
 		return &Transaction{}
 	}
@@ -160,12 +247,27 @@ func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
 		// - ErrSkipTracking:  means that the tracking information was unable to be found for a tracking ID.
 		// - Outside of range: means that the metric was guaranteed to be left over from the previous instance
 		//                     as it was here when we opened the wal file in this instance.
-		m, err := metric.FromBytes(data)
+		m, err := b.codec.decode(data)
 		if err != nil {
 			if errors.Is(err, metric.ErrSkipTracking) {
 				// Could not look up tracking information for metric so skip
 				// the metric and mask it so it is truncated later on.
-				b.mask = append(b.mask, offset)
+				b.maskEntry(offset)
+				continue
+			}
+			if errors.Is(err, errKeyUnavailable) {
+				// The entry cannot be decrypted at the moment, e.g. due to
+				// an unreachable secret-store, so keep the entry and all
+				// following ones for the next write.
+				log.Printf("E! Reading metric from disk buffer failed: %v", err)
+				break
+			}
+			if errors.Is(err, errEntryUndecodable) {
+				// The entry cannot be decrypted or decompressed, e.g. due to
+				// a changed key, so drop the metric.
+				log.Printf("E! Dropping metric from disk buffer: %v", err)
+				b.entryDropped()
+				b.maskEntry(offset)
 				continue
 			}
 			// non-recoverable error in deserialization, abort
@@ -176,7 +278,7 @@ func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
 			// This tracking metric is a left-over from a previous instance e.g.
 			// after restarting Telegraf. Skip the metric and mask it so it is
 			// trucated later on
-			b.mask = append(b.mask, offset)
+			b.maskEntry(offset)
 			continue
 		}
 
@@ -185,6 +287,9 @@ func (b *DiskBuffer) BeginTransaction(batchSize int) *Transaction {
 		b.batchSize++
 		batchSize--
 	}
+	if len(metrics) > 0 {
+		b.batch = offsets
+	}
 	return &Transaction{Batch: metrics, valid: true, state: offsets}
 }
 
@@ -206,16 +311,28 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 	defer b.Unlock()
 
 	// Mark metrics which should be removed in the internal mask
-	remove := make([]int, 0, len(tx.Accept)+len(tx.Reject))
 	for _, idx := range tx.Accept {
 		b.metricWritten(tx.Batch[idx])
-		remove = append(remove, offsets[idx])
+		b.maskEntry(offsets[idx])
 	}
 	for _, idx := range tx.Reject {
 		b.metricRejected(tx.Batch[idx])
-		remove = append(remove, offsets[idx])
+		b.maskEntry(offsets[idx])
 	}
-	b.mask = append(b.mask, remove...)
+	b.batch = nil
+
+	// Kept metrics might exceed the limits now
+	b.enforceLimits()
+	b.truncate()
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+}
+
+// truncate removes the metrics that are marked for removal from the front of
+// the WAL file. Must not be called while a transaction is running as the
+// offsets of the transaction would be invalidated.
+func (b *DiskBuffer) truncate() {
 	sort.Ints(b.mask)
 
 	// Remove the metrics that are marked for removal from the front of the
@@ -247,8 +364,8 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 		// item to not throw an error
 		removeIdx--
 	}
-	if err := b.file.TruncateFront(b.batchFirst + uint64(removeIdx)); err != nil {
-		log.Printf("E! batch length: %d, first: %d, size: %d", len(tx.Batch), b.batchFirst, b.batchSize)
+	if err := b.file.TruncateFront(b.readIndex() + uint64(removeIdx)); err != nil {
+		log.Printf("E! read index: %d, remove index: %d, batch first: %d, size: %d", b.readIndex(), removeIdx, b.batchFirst, b.batchSize)
 		panic(err)
 	}
 
@@ -257,14 +374,14 @@ func (b *DiskBuffer) EndTransaction(tx *Transaction) {
 	for i := range b.mask {
 		b.mask[i] -= correction
 	}
+	if b.limited() {
+		b.info = b.info[removeIdx:]
+	}
 
 	// check if the original end index is still valid, clear if not
 	if b.originalEnd < b.readIndex() {
 		b.originalEnd = 0
 	}
-
-	b.resetBatch()
-	b.BufferSize.Set(int64(b.length()))
 }
 
 func (b *DiskBuffer) Stats() BufferStats {
@@ -306,5 +423,86 @@ func (b *DiskBuffer) handleEmptyFile() {
 		panic(err)
 	}
 	b.mask = b.mask[1:]
+	if b.limited() {
+		b.info = b.info[1:]
+	}
 	b.isEmpty = false
 }
+
+func (b *DiskBuffer) limited() bool {
+	return b.maxSize > 0 || b.maxAge > 0
+}
+
+// maskEntry marks the entry at the given offset for removal
+func (b *DiskBuffer) maskEntry(offset int) {
+	b.mask = append(b.mask, offset)
+	if b.limited() {
+		b.liveSize -= b.info[offset].size
+	}
+}
+
+// entryDropped accounts for a dropped entry that cannot be decoded to a metric
+func (b *DiskBuffer) entryDropped() {
+	AgentMetricsDropped.Incr(1)
+	b.MetricsDropped.Incr(1)
+}
+
+// enforceLimits drops the oldest metrics exceeding the configured size or age
+// limits and returns the number of dropped metrics. Metrics being part of a
+// running transaction are skipped.
+func (b *DiskBuffer) enforceLimits() int {
+	if !b.limited() || b.isEmpty {
+		return 0
+	}
+
+	var cutoff time.Time
+	if b.maxAge > 0 {
+		cutoff = b.now().Add(-b.maxAge)
+	}
+
+	// Collect the entries already scheduled for removal or being part of a
+	// running transaction
+	skip := make(map[int]bool, len(b.mask)+len(b.batch))
+	for _, offset := range b.mask {
+		skip[offset] = true
+	}
+	for _, offset := range b.batch {
+		skip[offset] = true
+	}
+
+	var dropped int
+	for offset, info := range b.info {
+		oversized := b.maxSize > 0 && b.liveSize > b.maxSize
+		expired := b.maxAge > 0 && info.added.Before(cutoff)
+		if !oversized && !expired {
+			// All remaining entries are newer
+			break
+		}
+		if skip[offset] {
+			continue
+		}
+
+		data, err := b.file.Read(b.readIndex() + uint64(offset))
+		if err != nil {
+			panic(err)
+		}
+		if m, err := b.codec.decode(data); err == nil {
+			reason := DeadLetterOverflow
+			if !oversized {
+				reason = DeadLetterExpired
+			}
+			b.metricDropped(m, reason)
+		} else {
+			b.entryDropped()
+		}
+		b.maskEntry(offset)
+		dropped++
+	}
+
+	// Remove the dropped metrics from disk if possible
+	if dropped > 0 && b.batch == nil {
+		b.truncate()
+	}
+
+	return dropped
+}
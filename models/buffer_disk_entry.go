@@ -0,0 +1,250 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Entries written to the WAL file are prefixed by a header consisting of a
+// marker byte, a flags byte and the time the entry was added to the buffer
+// as unix timestamp in nanoseconds. The marker allows to distinguish entries
+// from plain gob-encoded metrics written by older versions of Telegraf, as
+// gob-encoded data never starts with a zero byte.
+const (
+	entryMarker     byte = 0x00
+	entryHeaderSize      = 10
+
+	entryFlagZstd   byte = 1 << 0
+	entryFlagAESGCM byte = 1 << 1
+)
+
+// errEntryUndecodable indicates entries that cannot be decrypted or
+// decompressed, e.g. due to a changed encryption key
+var errEntryUndecodable = errors.New("undecodable entry")
+
+// errKeyUnavailable indicates that the encryption key could not be retrieved
+// or is invalid. In contrast to undecodable entries this error might be
+// temporary, e.g. due to an unreachable secret-store, so entries must be kept.
+var errKeyUnavailable = errors.New("encryption key unavailable")
+
+// DiskBufferOption configures optional features of the disk buffer
+type DiskBufferOption func(*DiskBuffer)
+
+// WithDiskMaxSize limits the size of the metrics stored on disk to the given
+// number of bytes. The oldest metrics are dropped when exceeding the limit.
+func WithDiskMaxSize(size int64) DiskBufferOption {
+	return func(b *DiskBuffer) {
+		b.maxSize = size
+	}
+}
+
+// WithDiskMaxAge limits the time metrics are kept on disk. Metrics are
+// dropped if they were added to the buffer longer than the given age ago.
+func WithDiskMaxAge(age time.Duration) DiskBufferOption {
+	return func(b *DiskBuffer) {
+		b.maxAge = age
+	}
+}
+
+// WithDiskCompression compresses the metrics stored on disk with the given
+// algorithm. Currently only "zstd" is supported.
+func WithDiskCompression(algorithm string) DiskBufferOption {
+	return func(b *DiskBuffer) {
+		b.compression = algorithm
+	}
+}
+
+// WithDiskEncryptionKey encrypts the metrics stored on disk using AES-GCM.
+// The key function must return the hex-encoded key and is called when
+// initializing the buffer allowing to reference secrets not yet resolved when
+// creating the buffer.
+func WithDiskEncryptionKey(key func() ([]byte, error)) DiskBufferOption {
+	return func(b *DiskBuffer) {
+		b.encryptionKey = key
+	}
+}
+
+// entryCodec converts metrics to WAL entries and back
+type entryCodec struct {
+	encoder internal.ContentEncoder
+	decoder internal.ContentDecoder
+
+	key  func() ([]byte, error)
+	aead cipher.AEAD
+}
+
+func newEntryCodec(compression string, key func() ([]byte, error)) (*entryCodec, error) {
+	c := &entryCodec{key: key}
+
+	switch compression {
+	case "", "none":
+	case "zstd":
+		encoder, err := internal.NewContentEncoder("zstd")
+		if err != nil {
+			return nil, fmt.Errorf("creating encoder failed: %w", err)
+		}
+		decoder, err := internal.NewContentDecoder("zstd")
+		if err != nil {
+			return nil, fmt.Errorf("creating decoder failed: %w", err)
+		}
+		c.encoder = encoder
+		c.decoder = decoder
+	default:
+		return nil, fmt.Errorf("invalid buffer compression %q", compression)
+	}
+
+	return c, nil
+}
+
+// init resolves and validates the encryption key if any
+func (c *entryCodec) init() error {
+	if c.key == nil {
+		return nil
+	}
+	_, err := c.cipher()
+	return err
+}
+
+func (c *entryCodec) cipher() (cipher.AEAD, error) {
+	if c.aead != nil {
+		return c.aead, nil
+	}
+
+	encoded, err := c.key()
+	if err != nil {
+		return nil, fmt.Errorf("%w: getting key failed: %w", errKeyUnavailable, err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding key failed: %w", errKeyUnavailable, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid key: %w", errKeyUnavailable, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errKeyUnavailable, err)
+	}
+	c.aead = aead
+
+	return aead, nil
+}
+
+func (c *entryCodec) encode(m telegraf.Metric, added time.Time) ([]byte, error) {
+	payload, err := metric.ToBytes(m)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, entryHeaderSize)
+	header[0] = entryMarker
+	binary.BigEndian.PutUint64(header[2:], uint64(added.UnixNano()))
+
+	if c.encoder != nil {
+		header[1] |= entryFlagZstd
+		if payload, err = c.encoder.Encode(payload); err != nil {
+			return nil, fmt.Errorf("compressing entry failed: %w", err)
+		}
+	}
+
+	if c.key == nil {
+		return append(header, payload...), nil
+	}
+
+	header[1] |= entryFlagAESGCM
+	aead, err := c.cipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce failed: %w", err)
+	}
+
+	// Authenticate the header to prevent tampering with the timestamp
+	return append(header, aead.Seal(nonce, nonce, payload, header)...), nil
+}
+
+// header returns the time the entry was added to the buffer. For entries
+// written by older versions of Telegraf the time is unknown and the given
+// default is used.
+func (*entryCodec) header(data []byte, unknown time.Time) time.Time {
+	if len(data) < entryHeaderSize || data[0] != entryMarker {
+		return unknown
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data[2:])))
+}
+
+func (c *entryCodec) decode(data []byte) (telegraf.Metric, error) {
+	// Handle plain entries written by older versions of Telegraf
+	if len(data) == 0 || data[0] != entryMarker {
+		return metric.FromBytes(data)
+	}
+	payload, err := c.unwrap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return metric.FromBytes(payload)
+}
+
+// unwrap returns the serialized metric contained in the entry. Errors for
+// entries that can never be decoded, e.g. due to a failing authentication,
+// wrap errEntryUndecodable while errors that might be temporary, e.g. failing
+// to retrieve the key, are returned as is.
+func (c *entryCodec) unwrap(data []byte) ([]byte, error) {
+	if len(data) < entryHeaderSize {
+		return nil, fmt.Errorf("%w: entry too short", errEntryUndecodable)
+	}
+
+	header, payload := data[:entryHeaderSize], data[entryHeaderSize:]
+	flags := header[1]
+
+	if flags&entryFlagAESGCM != 0 {
+		if c.key == nil {
+			return nil, fmt.Errorf("%w: entry is encrypted but no key is configured", errEntryUndecodable)
+		}
+		aead, err := c.cipher()
+		if err != nil {
+			return nil, err
+		}
+		if len(payload) < aead.NonceSize() {
+			return nil, fmt.Errorf("%w: encrypted entry too short", errEntryUndecodable)
+		}
+		nonce, ciphertext := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+		if payload, err = aead.Open(nil, nonce, ciphertext, header); err != nil {
+			return nil, fmt.Errorf("%w: decrypting entry failed: %w", errEntryUndecodable, err)
+		}
+	}
+
+	if flags&entryFlagZstd != 0 {
+		decoder := c.decoder
+		if decoder == nil {
+			// The entry was written with compression enabled in a previous
+			// run, so create the decoder on demand.
+			var err error
+			if decoder, err = internal.NewContentDecoder("zstd"); err != nil {
+				return nil, err
+			}
+			c.decoder = decoder
+		}
+		var err error
+		if payload, err = decoder.Decode(payload); err != nil {
+			return nil, fmt.Errorf("%w: decompressing entry failed: %w", errEntryUndecodable, err)
+		}
+	}
+
+	return payload, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
 	"time"
@@ -261,3 +262,241 @@ func TestDiskBufferEmptyClose(t *testing.T) {
 	tx.AcceptAll()
 	reopened.EndTransaction(tx)
 }
+
+func TestDiskBufferMaxSize(t *testing.T) {
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 0}, time.Unix(0, 0))
+	data, err := newDiskBufferTestCodec(t).encode(m, time.Unix(0, 0))
+	require.NoError(t, err)
+
+	// Allow for three entries on disk
+	stats := NewBufferStats("test", "", 0)
+	stats.MetricsDropped.Set(0)
+	buf, err := NewDiskBuffer("id123", t.TempDir(), stats, WithDiskMaxSize(int64(3*len(data))))
+	require.NoError(t, err)
+	defer buf.Close()
+
+	expected := make([]telegraf.Metric, 0, 5)
+	for i := range 5 {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(0, 0))
+		expected = append(expected, m)
+	}
+	require.Equal(t, 2, buf.Add(expected...))
+	require.Equal(t, 3, buf.Len())
+	require.Equal(t, int64(2), buf.Stats().MetricsDropped.Get())
+
+	// The oldest metrics must have been dropped
+	tx := buf.BeginTransaction(5)
+	testutil.RequireMetricsEqual(t, expected[2:], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.Len())
+}
+
+func TestDiskBufferMaxAge(t *testing.T) {
+	stats := NewBufferStats("test", "", 0)
+	stats.MetricsDropped.Set(0)
+	buf, err := NewDiskBuffer("id123", t.TempDir(), stats, WithDiskMaxAge(time.Minute))
+	require.NoError(t, err)
+	defer buf.Close()
+
+	now := time.Unix(1700000000, 0)
+	buf.now = func() time.Time { return now }
+
+	expected := make([]telegraf.Metric, 0, 4)
+	for i := range 4 {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(0, 0))
+		expected = append(expected, m)
+	}
+	buf.Add(expected[:2]...)
+	now = now.Add(45 * time.Second)
+	buf.Add(expected[2:]...)
+	require.Equal(t, 4, buf.Len())
+
+	// The first two metrics are older than a minute when starting the batch
+	now = now.Add(30 * time.Second)
+	tx := buf.BeginTransaction(4)
+	testutil.RequireMetricsEqual(t, expected[2:], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, int64(2), buf.Stats().MetricsDropped.Get())
+	require.Zero(t, buf.Len())
+}
+
+func TestDiskBufferCompressionEncryption(t *testing.T) {
+	key := func() ([]byte, error) {
+		return []byte("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"), nil
+	}
+
+	tests := []struct {
+		name    string
+		options []DiskBufferOption
+	}{
+		{
+			name: "compressed",
+			options: []DiskBufferOption{
+				WithDiskCompression("zstd"),
+			},
+		},
+		{
+			name: "encrypted",
+			options: []DiskBufferOption{
+				WithDiskEncryptionKey(key),
+			},
+		},
+		{
+			name: "compressed and encrypted",
+			options: []DiskBufferOption{
+				WithDiskCompression("zstd"),
+				WithDiskEncryptionKey(key),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := t.TempDir()
+			buf, err := NewBuffer("test", "id123", "", 0, "disk_write_through", path, tt.options...)
+			require.NoError(t, err)
+
+			expected := make([]telegraf.Metric, 0, 5)
+			for i := range 5 {
+				m := metric.New("test", map[string]string{"secret": "tag"}, map[string]interface{}{"value": "sensitive"}, time.Unix(int64(i), 0))
+				expected = append(expected, m)
+			}
+			buf.Add(expected...)
+			require.NoError(t, buf.Close())
+
+			// Make sure the data is not stored in plain text
+			walfile, err := wal.Open(filepath.Join(path, "id123"), nil)
+			require.NoError(t, err)
+			data, err := walfile.Read(1)
+			require.NoError(t, err)
+			require.NotContains(t, string(data), "sensitive")
+			require.NoError(t, walfile.Close())
+
+			// Reopen the buffer and check we get the metrics back
+			buf, err = NewBuffer("test", "id123", "", 0, "disk_write_through", path, tt.options...)
+			require.NoError(t, err)
+			defer buf.Close()
+
+			tx := buf.BeginTransaction(5)
+			testutil.RequireMetricsEqual(t, expected, tx.Batch)
+			tx.AcceptAll()
+			buf.EndTransaction(tx)
+		})
+	}
+}
+
+func TestDiskBufferWrongEncryptionKey(t *testing.T) {
+	path := t.TempDir()
+	key := func() ([]byte, error) {
+		return []byte("000102030405060708090a0b0c0d0e0f"), nil
+	}
+	buf, err := NewBuffer("test", "id123", "", 0, "disk_write_through", path, WithDiskEncryptionKey(key))
+	require.NoError(t, err)
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	buf.Add(m)
+	require.NoError(t, buf.Close())
+
+	// Reopen the buffer using a different key, the metric must be dropped
+	wrong := func() ([]byte, error) {
+		return []byte("0f0e0d0c0b0a09080706050403020100"), nil
+	}
+	buf, err = NewBuffer("test", "id123", "", 0, "disk_write_through", path, WithDiskEncryptionKey(wrong))
+	require.NoError(t, err)
+	defer buf.Close()
+	buf.Stats().MetricsDropped.Set(0)
+
+	tx := buf.BeginTransaction(5)
+	require.Empty(t, tx.Batch)
+	buf.EndTransaction(tx)
+	require.Equal(t, int64(1), buf.Stats().MetricsDropped.Get())
+}
+
+func TestDiskBufferInvalidEncryptionKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      func() ([]byte, error)
+		expected string
+	}{
+		{
+			name:     "unavailable",
+			key:      func() ([]byte, error) { return nil, errors.New("secret-store unreachable") },
+			expected: "secret-store unreachable",
+		},
+		{
+			name:     "not hex-encoded",
+			key:      func() ([]byte, error) { return []byte("not a key"), nil },
+			expected: "decoding key failed",
+		},
+		{
+			name:     "invalid length",
+			key:      func() ([]byte, error) { return []byte("0001020304"), nil },
+			expected: "invalid key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := NewDiskBuffer("id123", t.TempDir(), NewBufferStats("test", "", 0), WithDiskEncryptionKey(tt.key))
+			require.NoError(t, err)
+			defer buf.Close()
+			require.ErrorContains(t, buf.Init(), tt.expected)
+		})
+	}
+}
+
+func TestDiskBufferEncryptionKeyTemporarilyUnavailable(t *testing.T) {
+	path := t.TempDir()
+	key := func() ([]byte, error) {
+		return []byte("000102030405060708090a0b0c0d0e0f"), nil
+	}
+	buf, err := NewDiskBuffer("id123", path, NewBufferStats("test", "", 0), WithDiskEncryptionKey(key))
+	require.NoError(t, err)
+	require.NoError(t, buf.Init())
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	buf.Add(m)
+	require.NoError(t, buf.Close())
+
+	// Reopen the buffer with the key being unavailable, the metric must be
+	// kept for a later write
+	var available bool
+	flaky := func() ([]byte, error) {
+		if !available {
+			return nil, errors.New("secret-store unreachable")
+		}
+		return key()
+	}
+	buf, err = NewDiskBuffer("id123", path, NewBufferStats("test", "", 0), WithDiskEncryptionKey(flaky))
+	require.NoError(t, err)
+	defer buf.Close()
+	buf.Stats().MetricsDropped.Set(0)
+
+	tx := buf.BeginTransaction(5)
+	require.Empty(t, tx.Batch)
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.Stats().MetricsDropped.Get())
+	require.Equal(t, 1, buf.Len())
+
+	// Once the key is available the metric must be written
+	available = true
+	tx = buf.BeginTransaction(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.Stats().MetricsDropped.Get())
+	require.Zero(t, buf.Len())
+}
+
+func TestDiskBufferInvalidCompression(t *testing.T) {
+	_, err := NewBuffer("test", "id123", "", 0, "disk_write_through", t.TempDir(), WithDiskCompression("foo"))
+	require.ErrorContains(t, err, "invalid buffer compression")
+}
+
+func newDiskBufferTestCodec(t *testing.T) *entryCodec {
+	t.Helper()
+	registerGob()
+	codec, err := newEntryCodec("", nil)
+	require.NoError(t, err)
+	return codec
+}
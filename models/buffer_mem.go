@@ -111,7 +111,7 @@ func (b *MemoryBuffer) EndTransaction(tx *Transaction) {
 
 		// Drop all remaining metrics
 		for i := restore; i < len(keep); i++ {
-			b.metricDropped(tx.Batch[keep[i]])
+			b.metricDropped(tx.Batch[keep[i]], DeadLetterOverflow)
 		}
 	}
 
@@ -135,7 +135,7 @@ func (b *MemoryBuffer) addMetric(m telegraf.Metric) int {
 	dropped := 0
 	// Check if Buffer is full
 	if b.size == b.cap {
-		b.metricDropped(b.buf[b.last])
+		b.metricDropped(b.buf[b.last], DeadLetterOverflow)
 		dropped++
 
 		if b.batchSize > 0 {
@@ -157,6 +157,21 @@ func (b *MemoryBuffer) addMetric(m telegraf.Metric) int {
 	return dropped
 }
 
+// removeOldest removes the oldest metric, not being part of the current batch,
+// from the buffer without updating the statistics. Returns nil if there is no
+// such metric.
+func (b *MemoryBuffer) removeOldest() telegraf.Metric {
+	if b.size == 0 {
+		return nil
+	}
+
+	m := b.buf[b.first]
+	b.buf[b.first] = nil
+	b.first = b.next(b.first)
+	b.size--
+	return m
+}
+
 // next returns the next index with wrapping.
 func (b *MemoryBuffer) next(index int) int {
 	index++
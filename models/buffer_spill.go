@@ -0,0 +1,163 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// SpillBuffer stores metrics in memory and only moves metrics to disk once
+// the memory buffer is full. In this case the oldest metrics are moved from
+// memory to disk to make room for new metrics, so metrics on disk are always
+// older than the ones in memory. Transactions drain the disk before using the
+// metrics in memory to keep the oldest-first ordering.
+//
+// Metrics not written during a transaction taken from memory are put back in
+// front of the memory buffer. If the buffer spilled to disk while the
+// transaction was running, those metrics are written after the spilled ones.
+type SpillBuffer struct {
+	sync.Mutex
+	BufferStats
+
+	memory *MemoryBuffer
+	disk   *DiskBuffer
+
+	// Transaction currently running against the disk buffer if any
+	diskTx *Transaction
+}
+
+func NewSpillBuffer(id, path string, capacity int, stats BufferStats, options ...DiskBufferOption) (*SpillBuffer, error) {
+	memory, err := NewMemoryBuffer(capacity, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	disk, err := NewDiskBuffer(id, path, stats, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &SpillBuffer{
+		BufferStats: stats,
+		memory:      memory,
+		disk:        disk,
+	}
+	buf.BufferSize.Set(int64(buf.length()))
+
+	return buf, nil
+}
+
+// Init initializes the underlying disk buffer
+func (b *SpillBuffer) Init() error {
+	return b.disk.Init()
+}
+
+func (b *SpillBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.length()
+}
+
+func (b *SpillBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	for _, m := range metrics {
+		// Fast path, there is room in memory
+		if b.memory.size+b.memory.batchSize < b.memory.cap {
+			b.memory.addMetric(m)
+			continue
+		}
+
+		// Move the oldest metric to disk to make room for the new one. If all
+		// metrics in memory are part of the running transaction, write the
+		// new metric to disk directly.
+		if oldest := b.memory.removeOldest(); oldest != nil {
+			dropped += b.spill(oldest)
+			b.memory.addMetric(m)
+			continue
+		}
+
+		b.metricAdded()
+		dropped += b.spill(m)
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	return dropped
+}
+
+func (b *SpillBuffer) BeginTransaction(batchSize int) *Transaction {
+	b.Lock()
+	defer b.Unlock()
+
+	// Metrics on disk are older than the ones in memory so drain the disk
+	// first. The batch might be empty even though the disk buffer is not, e.g.
+	// if it only contains left-over tracking metrics. Fall back to memory in
+	// this case.
+	if b.disk.Len() > 0 {
+		tx := b.disk.BeginTransaction(batchSize)
+		if len(tx.Batch) > 0 {
+			b.diskTx = tx
+			return tx
+		}
+	}
+
+	return b.memory.BeginTransaction(batchSize)
+}
+
+func (b *SpillBuffer) EndTransaction(tx *Transaction) {
+	b.Lock()
+	defer b.Unlock()
+
+	if tx == b.diskTx {
+		b.diskTx = nil
+		b.disk.EndTransaction(tx)
+	} else {
+		b.memory.EndTransaction(tx)
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+}
+
+func (b *SpillBuffer) Stats() BufferStats {
+	return b.BufferStats
+}
+
+// Close moves all metrics remaining in memory to disk, so they survive a
+// restart of Telegraf, and closes the buffers.
+func (b *SpillBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	for m := b.memory.removeOldest(); m != nil; m = b.memory.removeOldest() {
+		b.spill(m)
+	}
+
+	return errors.Join(b.memory.Close(), b.disk.Close())
+}
+
+func (b *SpillBuffer) length() int {
+	return b.memory.Len() + b.disk.Len()
+}
+
+// spill writes the metric to the disk buffer without accounting it as added
+// and returns the number of metrics dropped in the course of doing so.
+func (b *SpillBuffer) spill(m telegraf.Metric) int {
+	b.disk.Lock()
+	defer b.disk.Unlock()
+
+	var dropped int
+	err := b.disk.writeMetric(m)
+	b.disk.handleEmptyFile()
+	if err != nil {
+		log.Printf("E! Moving metric to disk buffer failed: %v", err)
+		b.metricDropped(m, DeadLetterOverflow)
+		dropped++
+	}
+
+	return dropped + b.disk.enforceLimits()
+}
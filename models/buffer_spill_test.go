@@ -0,0 +1,136 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func newSpillTestMetrics(n int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := range n {
+		m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": int64(i)}, time.Unix(int64(i), 0))
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func TestSpillBufferKeepsMemoryUntilFull(t *testing.T) {
+	buf, err := NewBuffer("test", "123", "", 5, "memory_spill", t.TempDir())
+	require.NoError(t, err)
+	defer buf.Close()
+
+	spill := buf.(*SpillBuffer)
+	buf.Add(newSpillTestMetrics(5)...)
+	require.Equal(t, 5, buf.Len())
+	require.Equal(t, 5, spill.memory.Len())
+	require.Zero(t, spill.disk.Len())
+}
+
+func TestSpillBufferOverflowOrder(t *testing.T) {
+	buf, err := NewBuffer("test", "123", "", 5, "memory_spill", t.TempDir())
+	require.NoError(t, err)
+	buf.Stats().MetricsAdded.Set(0)
+	buf.Stats().MetricsDropped.Set(0)
+	defer buf.Close()
+
+	spill := buf.(*SpillBuffer)
+	metrics := newSpillTestMetrics(12)
+	require.Zero(t, buf.Add(metrics...))
+	require.Equal(t, 12, buf.Len())
+	require.Equal(t, 5, spill.memory.Len())
+	require.Equal(t, 7, spill.disk.Len())
+	require.Equal(t, int64(12), buf.Stats().MetricsAdded.Get())
+	require.Equal(t, int64(12), buf.Stats().BufferSize.Get())
+	require.Zero(t, buf.Stats().MetricsDropped.Get())
+
+	// Transactions must return the metrics oldest-first draining the disk
+	actual := make([]telegraf.Metric, 0, len(metrics))
+	for buf.Len() > 0 {
+		tx := buf.BeginTransaction(4)
+		require.NotEmpty(t, tx.Batch)
+		actual = append(actual, tx.Batch...)
+		tx.AcceptAll()
+		buf.EndTransaction(tx)
+	}
+	require.Len(t, actual, len(metrics))
+	for i, m := range actual {
+		require.Equal(t, metrics[i].Time(), m.Time())
+		require.Equal(t, metrics[i].Fields(), m.Fields())
+	}
+}
+
+func TestSpillBufferKeepDiskBatch(t *testing.T) {
+	buf, err := NewBuffer("test", "123", "", 2, "memory_spill", t.TempDir())
+	require.NoError(t, err)
+	defer buf.Close()
+
+	metrics := newSpillTestMetrics(4)
+	buf.Add(metrics...)
+
+	// Failed writes must keep the metrics on disk
+	tx := buf.BeginTransaction(2)
+	require.Len(t, tx.Batch, 2)
+	require.Equal(t, metrics[0].Time(), tx.Batch[0].Time())
+	tx.KeepAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 4, buf.Len())
+
+	tx = buf.BeginTransaction(2)
+	require.Len(t, tx.Batch, 2)
+	require.Equal(t, metrics[0].Time(), tx.Batch[0].Time())
+	require.Equal(t, metrics[1].Time(), tx.Batch[1].Time())
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 2, buf.Len())
+}
+
+func TestSpillBufferAddDuringMemoryTransaction(t *testing.T) {
+	buf, err := NewBuffer("test", "123", "", 3, "memory_spill", t.TempDir())
+	require.NoError(t, err)
+	buf.Stats().MetricsDropped.Set(0)
+	defer buf.Close()
+
+	metrics := newSpillTestMetrics(7)
+	buf.Add(metrics[:3]...)
+
+	// All metrics in memory are part of the transaction, so new metrics have
+	// to go to disk without dropping anything
+	tx := buf.BeginTransaction(3)
+	require.Len(t, tx.Batch, 3)
+	buf.Add(metrics[3:]...)
+	require.Equal(t, 7, buf.Len())
+	tx.KeepAll()
+	buf.EndTransaction(tx)
+	require.Equal(t, 7, buf.Len())
+	require.Zero(t, buf.Stats().MetricsDropped.Get())
+}
+
+func TestSpillBufferCloseMovesMemoryToDisk(t *testing.T) {
+	path := t.TempDir()
+	buf, err := NewBuffer("test", "123", "", 5, "memory_spill", path)
+	require.NoError(t, err)
+
+	metrics := newSpillTestMetrics(3)
+	buf.Add(metrics...)
+	require.NoError(t, buf.Close())
+
+	// Reopen the buffer and check the metrics are restored
+	buf, err = NewBuffer("test", "123", "", 5, "memory_spill", path)
+	require.NoError(t, err)
+	defer buf.Close()
+	require.Equal(t, 3, buf.Len())
+
+	tx := buf.BeginTransaction(5)
+	require.Len(t, tx.Batch, 3)
+	for i, m := range tx.Batch {
+		require.Equal(t, metrics[i].Time(), m.Time())
+	}
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.Zero(t, buf.Len())
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Reasons for sending a metric to the dead-letter queue
+const (
+	DeadLetterRejected      = "rejected"
+	DeadLetterSerialization = "serialization_failed"
+	DeadLetterOverflow      = "buffer_overflow"
+	DeadLetterExpired       = "expired"
+)
+
+// DefaultDeadLetterReasonTag is the tag used to annotate the reason of a
+// metric being sent to the dead-letter queue if not configured otherwise.
+const DefaultDeadLetterReasonTag = "dlq_reason"
+
+// DeadLetterConfig contains the dead-letter settings of an output
+type DeadLetterConfig struct {
+	// Output references another output by alias or name to send the metrics to
+	Output string `toml:"output"`
+	// File to append the metrics to in InfluxDB line-protocol format
+	File string `toml:"file"`
+	// ReasonTag is the tag to annotate the reason with
+	ReasonTag string `toml:"reason_tag"`
+}
+
+// Enabled returns true if a dead-letter destination is configured
+func (c *DeadLetterConfig) Enabled() bool {
+	return c.Output != "" || c.File != ""
+}
+
+// DeadLetterQueue receives the metrics an output failed to write
+type DeadLetterQueue interface {
+	// Add annotates a copy of the metric with the given reason and stores it.
+	// The original metric is left untouched.
+	Add(m telegraf.Metric, reason string)
+
+	// Close finalizes the queue and closes all open resources
+	Close() error
+}
+
+// deadLetterHook allows to share the queue across all copies of the
+// buffer statistics as those are embedded by value into the buffers.
+type deadLetterHook struct {
+	queue DeadLetterQueue
+}
+
+// deadLetterCopy creates a plain copy of the metric, i.e. without tracking
+// information, annotated with the given reason.
+func deadLetterCopy(m telegraf.Metric, tag, reason string) telegraf.Metric {
+	if um, ok := m.(telegraf.UnwrappableMetric); ok {
+		m = um.Unwrap()
+	}
+	c := m.Copy()
+	c.AddTag(tag, reason)
+	return c
+}
+
+// DeadLetterFile appends metrics to a local file in line-protocol format
+type DeadLetterFile struct {
+	tag        string
+	file       *os.File
+	serializer *influx.Serializer
+	written    selfstat.Stat
+	errs       selfstat.Stat
+	sync.Mutex
+}
+
+func NewDeadLetterFile(filename, tag string, tags map[string]string) (*DeadLetterFile, error) {
+	serializer := &influx.Serializer{SortFields: true, UintSupport: true}
+	if err := serializer.Init(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("opening dead-letter file failed: %w", err)
+	}
+
+	return &DeadLetterFile{
+		tag:        tag,
+		file:       f,
+		serializer: serializer,
+		written:    selfstat.Register("write", "dead_letter_metrics", tags),
+		errs:       selfstat.Register("write", "dead_letter_errors", tags),
+	}, nil
+}
+
+func (q *DeadLetterFile) Add(m telegraf.Metric, reason string) {
+	q.Lock()
+	defer q.Unlock()
+
+	octets, err := q.serializer.Serialize(deadLetterCopy(m, q.tag, reason))
+	if err != nil {
+		q.errs.Incr(1)
+		return
+	}
+	if _, err := q.file.Write(octets); err != nil {
+		q.errs.Incr(1)
+		return
+	}
+	q.written.Incr(1)
+}
+
+func (q *DeadLetterFile) Close() error {
+	q.Lock()
+	defer q.Unlock()
+	return errors.Join(q.file.Sync(), q.file.Close())
+}
+
+// DeadLetterOutput forwards metrics to another output
+type DeadLetterOutput struct {
+	tag     string
+	target  *RunningOutput
+	written selfstat.Stat
+}
+
+func NewDeadLetterOutput(target *RunningOutput, tag string, tags map[string]string) *DeadLetterOutput {
+	return &DeadLetterOutput{
+		tag:     tag,
+		target:  target,
+		written: selfstat.Register("write", "dead_letter_metrics", tags),
+	}
+}
+
+func (q *DeadLetterOutput) Add(m telegraf.Metric, reason string) {
+	q.target.AddMetricNoCopy(deadLetterCopy(m, q.tag, reason))
+	q.written.Incr(1)
+}
+
+func (*DeadLetterOutput) Close() error {
+	return nil
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// requireDeadLetterFile checks the file content against the serialized
+// metrics as the line-protocol parser cannot be used in this package
+func requireDeadLetterFile(t *testing.T, expected []telegraf.Metric, filename string) {
+	t.Helper()
+
+	serializer := &influx.Serializer{SortFields: true, UintSupport: true}
+	require.NoError(t, serializer.Init())
+	octets, err := serializer.SerializeBatch(expected)
+	require.NoError(t, err)
+
+	actual, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, string(octets), string(actual))
+}
+
+func deadLetterTestMetric(name, tag, reason string) telegraf.Metric {
+	m := testutil.TestMetric(101, name)
+	m.AddTag(tag, reason)
+	return m
+}
+
+func TestDeadLetterRejectedToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dlq.lp")
+
+	lost := 0
+	plugin := &mockOutput{
+		batchAcceptSize:  4,
+		metricFatalIndex: &lost,
+	}
+	model := NewRunningOutput(plugin, &OutputConfig{DeadLetter: DeadLetterConfig{File: filename}}, 5, 10)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+	require.ErrorIs(t, model.Write(), internal.ErrSizeLimitReached)
+	model.Close()
+
+	expected := []telegraf.Metric{deadLetterTestMetric("metric1", DefaultDeadLetterReasonTag, DeadLetterRejected)}
+	requireDeadLetterFile(t, expected, filename)
+}
+
+func TestDeadLetterSerializationReason(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dlq.lp")
+
+	plugin := &serializationFailureOutput{}
+	model := NewRunningOutput(plugin, &OutputConfig{DeadLetter: DeadLetterConfig{File: filename, ReasonTag: "reason"}}, 5, 10)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+
+	model.AddMetric(first5[0])
+	require.Error(t, model.Write())
+	require.Zero(t, model.BufferLength())
+	model.Close()
+
+	expected := []telegraf.Metric{deadLetterTestMetric("metric1", "reason", DeadLetterSerialization)}
+	requireDeadLetterFile(t, expected, filename)
+}
+
+func TestDeadLetterOverflowToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dlq.lp")
+
+	model := NewRunningOutput(&mockOutput{}, &OutputConfig{DeadLetter: DeadLetterConfig{File: filename}}, 5, 5)
+	require.NoError(t, model.Init())
+
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+	model.AddMetric(next5[0])
+	model.AddMetric(next5[1])
+	model.Close()
+
+	expected := []telegraf.Metric{
+		deadLetterTestMetric("metric1", DefaultDeadLetterReasonTag, DeadLetterOverflow),
+		deadLetterTestMetric("metric2", DefaultDeadLetterReasonTag, DeadLetterOverflow),
+	}
+	requireDeadLetterFile(t, expected, filename)
+}
+
+func TestDeadLetterExpiredToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "dlq.lp")
+	queue, err := NewDeadLetterFile(filename, DefaultDeadLetterReasonTag, nil)
+	require.NoError(t, err)
+
+	stats := NewBufferStats("test", "", 0)
+	stats.SetDeadLetterQueue(queue)
+	buf, err := NewDiskBuffer("id123", t.TempDir(), stats, WithDiskMaxAge(time.Minute))
+	require.NoError(t, err)
+	defer buf.Close()
+
+	now := time.Unix(1700000000, 0)
+	buf.now = func() time.Time { return now }
+
+	buf.Add(first5[0])
+	now = now.Add(45 * time.Second)
+	buf.Add(first5[1])
+
+	// The first metric is older than a minute when starting the batch
+	now = now.Add(30 * time.Second)
+	tx := buf.BeginTransaction(5)
+	testutil.RequireMetricsEqual(t, first5[1:2], tx.Batch)
+	tx.AcceptAll()
+	buf.EndTransaction(tx)
+	require.NoError(t, queue.Close())
+
+	expected := []telegraf.Metric{deadLetterTestMetric("metric1", DefaultDeadLetterReasonTag, DeadLetterExpired)}
+	requireDeadLetterFile(t, expected, filename)
+}
+
+func TestDeadLetterToOutput(t *testing.T) {
+	target := NewRunningOutput(&mockOutput{}, &OutputConfig{Name: "target"}, 5, 10)
+	require.NoError(t, target.Init())
+
+	lost := 1
+	plugin := &mockOutput{
+		batchAcceptSize:  4,
+		metricFatalIndex: &lost,
+	}
+	model := NewRunningOutput(plugin, &OutputConfig{Name: "source", DeadLetter: DeadLetterConfig{Output: "target"}}, 5, 10)
+	model.SetDeadLetterOutput(target)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+	defer model.Close()
+
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+	require.ErrorIs(t, model.Write(), internal.ErrSizeLimitReached)
+	require.Equal(t, 1, target.BufferLength())
+
+	tx := target.buffer.BeginTransaction(5)
+	expected := []telegraf.Metric{deadLetterTestMetric("metric2", DefaultDeadLetterReasonTag, DeadLetterRejected)}
+	testutil.RequireMetricsEqual(t, expected, tx.Batch)
+
+	// The original metric must not be modified
+	require.False(t, first5[1].HasTag(DefaultDeadLetterReasonTag))
+}
+
+func TestDeadLetterMissingOutput(t *testing.T) {
+	model := NewRunningOutput(&mockOutput{}, &OutputConfig{DeadLetter: DeadLetterConfig{Output: "missing"}}, 5, 10)
+	require.ErrorContains(t, model.Init(), "not found")
+}
+
+type serializationFailureOutput struct{}
+
+func (*serializationFailureOutput) Connect() error {
+	return nil
+}
+
+func (*serializationFailureOutput) Close() error {
+	return nil
+}
+
+func (*serializationFailureOutput) SampleConfig() string {
+	return ""
+}
+
+func (*serializationFailureOutput) Write(metrics []telegraf.Metric) error {
+	werr := &internal.PartialWriteError{Err: internal.ErrSerialization}
+	for i := range metrics {
+		werr.MetricsReject = append(werr.MetricsReject, i)
+	}
+	return werr
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// PluginStatus contains runtime information of a plugin
+type PluginStatus struct {
+	// LastRun is the time the last gather or write finished
+	LastRun time.Time
+	// LastError is the last error reported by the plugin
+	LastError string
+	// LastErrorTime is the time the last error was reported
+	LastErrorTime time.Time
+}
+
+// statusTracker collects the runtime information of a plugin and is safe
+// for concurrent use
+type statusTracker struct {
+	sync.Mutex
+	status PluginStatus
+}
+
+func (s *statusTracker) ran(t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.status.LastRun = t
+}
+
+func (s *statusTracker) failed(t time.Time, err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.status.LastError = err.Error()
+	s.status.LastErrorTime = t
+}
+
+func (s *statusTracker) get() PluginStatus {
+	s.Lock()
+	defer s.Unlock()
+	return s.status
+}
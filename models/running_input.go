@@ -29,6 +29,11 @@ type RunningInput struct {
 	retries     uint64
 	gatherStart time.Time
 	gatherEnd   time.Time
+	status      statusTracker
+	seriesLimit *seriesLimiter
+
+	// GatherRequest signals an immediate gather requested via RequestGather
+	GatherRequest chan struct{}
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
@@ -54,8 +59,9 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 	SetLoggerOnPlugin(input, logger)
 
 	return &RunningInput{
-		Input:  input,
-		Config: config,
+		Input:         input,
+		Config:        config,
+		GatherRequest: make(chan struct{}, 1),
 		MetricsGathered: selfstat.Register(
 			"gather",
 			"metrics_gathered",
@@ -101,6 +107,7 @@ type InputConfig struct {
 	Filter                  Filter
 	AlwaysIncludeLocalTags  bool
 	AlwaysIncludeGlobalTags bool
+	SeriesLimit             SeriesLimitConfig
 }
 
 func (*RunningInput) metricFiltered(metric telegraf.Metric) {
@@ -126,6 +133,18 @@ func (r *RunningInput) Init() error {
 		return fmt.Errorf("invalid 'time_source' setting %q", r.Config.TimeSource)
 	}
 
+	if r.Config.SeriesLimit.MaxSeries != 0 {
+		tags := map[string]string{"input": r.Config.Name}
+		if r.Config.Alias != "" {
+			tags["alias"] = r.Config.Alias
+		}
+		limiter, err := newSeriesLimiter(r.Config.SeriesLimit, "gather", tags, r.log)
+		if err != nil {
+			return err
+		}
+		r.seriesLimit = limiter
+	}
+
 	if p, ok := r.Input.(telegraf.Initializer); ok {
 		return p.Init()
 	}
@@ -234,6 +253,11 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 	default:
 	}
 
+	if r.seriesLimit != nil && !r.seriesLimit.apply(metric) {
+		metric.Drop()
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return metric
@@ -261,9 +285,29 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	r.gatherEnd = time.Now()
 
 	r.GatherTime.Incr(r.gatherEnd.Sub(r.gatherStart).Nanoseconds())
+	r.status.ran(r.gatherEnd)
 	return err
 }
 
+// RequestGather requests an immediate gather of the input in addition to
+// the regular interval. Requests are dropped if a request is still pending.
+func (r *RunningInput) RequestGather() {
+	select {
+	case r.GatherRequest <- struct{}{}:
+	default:
+	}
+}
+
+// RecordError stores the error as the last error of the input
+func (r *RunningInput) RecordError(err error) {
+	r.status.failed(time.Now(), err)
+}
+
+// Status returns the runtime information of the input
+func (r *RunningInput) Status() PluginStatus {
+	return r.status.get()
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
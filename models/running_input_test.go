@@ -548,3 +548,21 @@ func (m *mockInput) Probe() error {
 func (*mockInput) Gather(telegraf.Accumulator) error {
 	return nil
 }
+
+func TestRunningInputSeriesLimit(t *testing.T) {
+	ri := NewRunningInput(&mockInput{}, &InputConfig{
+		Name:        "TestRunningInputSeriesLimit",
+		SeriesLimit: SeriesLimitConfig{MaxSeries: 1},
+	})
+	require.NoError(t, ri.Init())
+
+	now := time.Now()
+	m := metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 42}, now)
+	require.NotNil(t, ri.MakeMetric(m))
+	m = metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 42}, now)
+	require.Nil(t, ri.MakeMetric(m))
+	m = metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 23}, now)
+	require.NotNil(t, ri.MakeMetric(m))
+
+	require.Equal(t, int64(2), ri.MetricsGathered.Get())
+}
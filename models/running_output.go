@@ -19,6 +19,10 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DefaultMetricBufferLimit = 10000
+
+	// Default number of consecutive failed writes before switching to the
+	// failover output.
+	DefaultFailoverThreshold = 3
 )
 
 // OutputConfig containing name and filter
@@ -39,8 +43,19 @@ type OutputConfig struct {
 	NamePrefix   string
 	NameSuffix   string
 
-	BufferStrategy  string
-	BufferDirectory string
+	BufferStrategy          string
+	BufferDirectory         string
+	BufferDiskMaxSize       int64
+	BufferDiskMaxAge        time.Duration
+	BufferDiskCompression   string
+	BufferDiskEncryptionKey func() ([]byte, error)
+
+	DeadLetter DeadLetterConfig
+
+	FailoverTo        string
+	FailoverThreshold int
+
+	SeriesLimit SeriesLimitConfig
 
 	LogLevel string
 }
@@ -62,11 +77,28 @@ type RunningOutput struct {
 
 	BatchReady chan time.Time
 
+	// FlushRequest signals an immediate flush requested via RequestFlush
+	FlushRequest chan struct{}
+
 	buffer Buffer
 	log    telegraf.Logger
 
-	started bool
-	retries uint64
+	deadLetter       DeadLetterQueue
+	deadLetterTarget *RunningOutput
+
+	failoverTarget    *RunningOutput
+	failoverThreshold int
+	failures          int
+	standby           bool
+	failedOverMetrics selfstat.Stat
+	writeMutex        sync.Mutex
+
+	seriesLimit *seriesLimiter
+
+	started      bool
+	retries      uint64
+	connectMutex sync.Mutex
+	status       statusTracker
 
 	aggMutex sync.Mutex
 }
@@ -100,7 +132,15 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 		batchSize = DefaultMetricBatchSize
 	}
 
-	b, err := NewBuffer(config.Name, config.ID, config.Alias, bufferLimit, config.BufferStrategy, config.BufferDirectory)
+	options := []DiskBufferOption{
+		WithDiskMaxSize(config.BufferDiskMaxSize),
+		WithDiskMaxAge(config.BufferDiskMaxAge),
+		WithDiskCompression(config.BufferDiskCompression),
+	}
+	if config.BufferDiskEncryptionKey != nil {
+		options = append(options, WithDiskEncryptionKey(config.BufferDiskEncryptionKey))
+	}
+	b, err := NewBuffer(config.Name, config.ID, config.Alias, bufferLimit, config.BufferStrategy, config.BufferDirectory, options...)
 	if err != nil {
 		panic(err)
 	}
@@ -108,6 +148,7 @@ func NewRunningOutput(output telegraf.Output, config *OutputConfig, batchSize, b
 	ro := &RunningOutput{
 		buffer:            b,
 		BatchReady:        make(chan time.Time, 1),
+		FlushRequest:      make(chan struct{}, 1),
 		Output:            output,
 		Config:            config,
 		MetricBufferLimit: bufferLimit,
@@ -156,12 +197,98 @@ func (r *RunningOutput) Init() error {
 		return fmt.Errorf("invalid 'startup_error_behavior' setting %q", r.Config.StartupErrorBehavior)
 	}
 
+	if r.Config.SeriesLimit.MaxSeries != 0 {
+		tags := map[string]string{"output": r.Config.Name}
+		if r.Config.Alias != "" {
+			tags["alias"] = r.Config.Alias
+		}
+		limiter, err := newSeriesLimiter(r.Config.SeriesLimit, "write", tags, r.log)
+		if err != nil {
+			return err
+		}
+		r.seriesLimit = limiter
+	}
+
+	if b, ok := r.buffer.(telegraf.Initializer); ok {
+		if err := b.Init(); err != nil {
+			return fmt.Errorf("initializing buffer failed: %w", err)
+		}
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
 			return err
 		}
 	}
+
+	return r.initDeadLetter()
+}
+
+// SetDeadLetterOutput sets the output receiving the metrics this output
+// failed to write. The target becomes a standby output only receiving the
+// metrics of the outputs using it as dead-letter output.
+func (r *RunningOutput) SetDeadLetterOutput(target *RunningOutput) {
+	r.deadLetterTarget = target
+	target.standby = true
+}
+
+// SetFailoverOutput sets the output receiving the batches of this output
+// after the given number of consecutive failed writes. The target becomes a
+// standby output only receiving metrics from its primary outputs.
+func (r *RunningOutput) SetFailoverOutput(target *RunningOutput, threshold int) {
+	if threshold <= 0 {
+		threshold = DefaultFailoverThreshold
+	}
+
+	r.failoverTarget = target
+	r.failoverThreshold = threshold
+	target.standby = true
+
+	tags := map[string]string{"output": r.Config.Name}
+	if r.Config.Alias != "" {
+		tags["alias"] = r.Config.Alias
+	}
+	r.failedOverMetrics = selfstat.Register("write", "metrics_failed_over", tags)
+}
+
+// Standby returns true if the output is a failover or dead-letter output of
+// other outputs and must not receive metrics directly.
+func (r *RunningOutput) Standby() bool {
+	return r.standby
+}
+
+func (r *RunningOutput) initDeadLetter() error {
+	cfg := r.Config.DeadLetter
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	tag := cfg.ReasonTag
+	if tag == "" {
+		tag = DefaultDeadLetterReasonTag
+	}
+	tags := map[string]string{"output": r.Config.Name}
+	if r.Config.Alias != "" {
+		tags["alias"] = r.Config.Alias
+	}
+
+	switch {
+	case cfg.File != "":
+		q, err := NewDeadLetterFile(cfg.File, tag, tags)
+		if err != nil {
+			return err
+		}
+		r.deadLetter = q
+	case r.deadLetterTarget != nil:
+		r.deadLetter = NewDeadLetterOutput(r.deadLetterTarget, tag, tags)
+	default:
+		return fmt.Errorf("dead-letter output %q not found", cfg.Output)
+	}
+
+	stats := r.buffer.Stats()
+	stats.SetDeadLetterQueue(r.deadLetter)
+
 	return nil
 }
 
@@ -204,6 +331,12 @@ func (r *RunningOutput) Close() {
 	if err := r.buffer.Close(); err != nil {
 		r.log.Errorf("Error closing output buffer: %v", err)
 	}
+
+	if r.deadLetter != nil {
+		if err := r.deadLetter.Close(); err != nil {
+			r.log.Errorf("Error closing dead-letter queue: %v", err)
+		}
+	}
 }
 
 // AddMetric adds a metric to the output.
@@ -241,6 +374,11 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 		return
 	}
 
+	if r.seriesLimit != nil && !r.seriesLimit.apply(metric) {
+		metric.Drop()
+		return
+	}
+
 	if output, ok := r.Output.(telegraf.AggregatingOutput); ok {
 		r.aggMutex.Lock()
 		output.Add(metric)
@@ -275,21 +413,17 @@ func (r *RunningOutput) add(metric telegraf.Metric) {
 
 // Write writes all metrics to the output, stopping when all have been sent on
 // or error.
-func (r *RunningOutput) Write() error {
+func (r *RunningOutput) Write() (err error) {
+	defer func() { r.recordWrite(err) }()
+
 	// Try to connect if we are not yet started up
-	if !r.started {
-		r.retries++
-		if err := r.Output.Connect(); err != nil {
-			var serr *internal.StartupError
-			if !errors.As(err, &serr) || !serr.Retry || !serr.Partial {
-				r.StartupErrors.Incr(1)
-				return internal.ErrNotConnected
-			}
-			r.log.Debugf("Partially connected after %d attempts", r.retries)
-		} else {
-			r.started = true
-			r.log.Debugf("Successfully connected after %d attempts", r.retries)
+	probe := true
+	if err := r.tryConnect(); err != nil && !isPartialStartup(err) {
+		r.StartupErrors.Incr(1)
+		if !r.trackFailure(internal.ErrNotConnected) {
+			return internal.ErrNotConnected
 		}
+		probe = false
 	}
 
 	if output, ok := r.Output.(telegraf.AggregatingOutput); ok {
@@ -311,40 +445,156 @@ func (r *RunningOutput) Write() error {
 		if len(tx.Batch) == 0 {
 			return nil
 		}
-		err := r.writeMetrics(tx.Batch)
-		r.updateTransaction(tx, err)
+		err := r.writeTransaction(tx, probe)
 		r.buffer.EndTransaction(tx)
 		if err != nil {
 			return err
 		}
+		probe = false
 	}
 	return nil
 }
 
 // WriteBatch writes a single batch of metrics to the output.
-func (r *RunningOutput) WriteBatch() error {
+func (r *RunningOutput) WriteBatch() (err error) {
+	defer func() { r.recordWrite(err) }()
+
 	// Try to connect if we are not yet started up
-	if !r.started {
-		r.retries++
-		if err := r.Output.Connect(); err != nil {
-			r.StartupErrors.Incr(1)
+	if err := r.tryConnect(); err != nil {
+		r.StartupErrors.Incr(1)
+		if !r.trackFailure(internal.ErrNotConnected) {
 			return internal.ErrNotConnected
 		}
-		r.started = true
-		r.log.Debugf("Successfully connected after %d attempts", r.retries)
 	}
 
 	tx := r.buffer.BeginTransaction(r.MetricBatchSize)
 	if len(tx.Batch) == 0 {
 		return nil
 	}
-	err := r.writeMetrics(tx.Batch)
-	r.updateTransaction(tx, err)
+	// Only probe the output for recovery on regular flushes to avoid delaying
+	// full batches while being failed over
+	err = r.writeTransaction(tx, false)
 	r.buffer.EndTransaction(tx)
 
 	return err
 }
 
+// tryConnect connects the output if it did not start up yet. The connection
+// state is guarded as a standby output is also connected by the outputs
+// failing over to it.
+func (r *RunningOutput) tryConnect() error {
+	r.connectMutex.Lock()
+	defer r.connectMutex.Unlock()
+
+	if r.started {
+		return nil
+	}
+
+	r.retries++
+	if err := r.Output.Connect(); err != nil {
+		if isPartialStartup(err) {
+			r.log.Debugf("Partially connected after %d attempts", r.retries)
+		}
+		return err
+	}
+	r.started = true
+	r.log.Debugf("Successfully connected after %d attempts", r.retries)
+
+	return nil
+}
+
+// isPartialStartup returns true if the output reports a partial connection
+// allowing to write metrics while retrying to connect
+func isPartialStartup(err error) bool {
+	var serr *internal.StartupError
+	return errors.As(err, &serr) && serr.Retry && serr.Partial
+}
+
+// RequestFlush requests an immediate flush of the output in addition to the
+// regular interval. Requests are dropped if a request is still pending.
+func (r *RunningOutput) RequestFlush() {
+	select {
+	case r.FlushRequest <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the runtime information of the output
+func (r *RunningOutput) Status() PluginStatus {
+	return r.status.get()
+}
+
+func (r *RunningOutput) recordWrite(err error) {
+	now := time.Now()
+	r.status.ran(now)
+	if err != nil {
+		r.status.failed(now, err)
+	}
+}
+
+// writeTransaction writes the batch of the transaction to the output and
+// updates the transaction accordingly. While being failed over, batches are
+// sent to the failover output and the output itself only receives probe
+// batches to detect its recovery. Failed probes are sent to the failover
+// output as well.
+func (r *RunningOutput) writeTransaction(tx *Transaction, probe bool) error {
+	if !r.failedOver() || probe {
+		err := r.writeMetrics(tx.Batch)
+		if !r.trackFailure(err) {
+			r.updateTransaction(tx, err)
+			return err
+		}
+	}
+
+	// The failover output might not have started up yet, e.g. due to a
+	// retry-able startup error, so try to connect before writing
+	if err := r.failoverTarget.tryConnect(); err != nil && !isPartialStartup(err) {
+		r.failoverTarget.StartupErrors.Incr(1)
+		tx.KeepAll()
+		return fmt.Errorf("connecting to failover output %s failed: %w", r.failoverTarget.LogName(), err)
+	}
+
+	err := r.failoverTarget.writeMetrics(tx.Batch)
+	r.updateTransaction(tx, err)
+	if err != nil {
+		return fmt.Errorf("writing to failover output %s failed: %w", r.failoverTarget.LogName(), err)
+	}
+	r.failedOverMetrics.Incr(int64(len(tx.Batch)))
+
+	return nil
+}
+
+// failedOver returns true if the output failed often enough to send its
+// metrics to the failover output
+func (r *RunningOutput) failedOver() bool {
+	return r.failoverTarget != nil && r.failures >= r.failoverThreshold
+}
+
+// trackFailure counts consecutive failed writes, i.e. writes without any
+// metric being accepted or rejected, and returns true if the output is
+// failed over.
+func (r *RunningOutput) trackFailure(err error) bool {
+	if r.failoverTarget == nil {
+		return false
+	}
+
+	var writeErr *internal.PartialWriteError
+	if err != nil && !errors.As(err, &writeErr) {
+		r.failures++
+		if r.failures == r.failoverThreshold {
+			r.log.Warnf("Switching to failover output %s after %d failed writes", r.failoverTarget.LogName(), r.failures)
+		}
+		return r.failedOver()
+	}
+
+	if r.failedOver() {
+		r.log.Infof("Output recovered, switching back from failover output %s", r.failoverTarget.LogName())
+	}
+	r.failures = 0
+
+	return false
+}
+
 func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
 	dropped := atomic.LoadInt64(&r.droppedMetrics)
 	if dropped > 0 {
@@ -352,6 +602,10 @@ func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
 		atomic.StoreInt64(&r.droppedMetrics, 0)
 	}
 
+	// Failover outputs might be written by multiple outputs concurrently
+	r.writeMutex.Lock()
+	defer r.writeMutex.Unlock()
+
 	start := time.Now()
 	err := r.Output.Write(metrics)
 	elapsed := time.Since(start)
@@ -363,7 +617,7 @@ func (r *RunningOutput) writeMetrics(metrics []telegraf.Metric) error {
 	return err
 }
 
-func (*RunningOutput) updateTransaction(tx *Transaction, err error) {
+func (r *RunningOutput) updateTransaction(tx *Transaction, err error) {
 	// No error indicates all metrics were written successfully
 	if err == nil {
 		tx.AcceptAll()
@@ -381,11 +635,22 @@ func (*RunningOutput) updateTransaction(tx *Transaction, err error) {
 	// Transfer the accepted and rejected indices based on the write error values
 	tx.Accept = writeErr.MetricsAccept
 	tx.Reject = writeErr.MetricsReject
+
+	// Keep the rejected metrics in the dead-letter queue
+	if r.deadLetter != nil && len(tx.Reject) > 0 {
+		reason := DeadLetterRejected
+		if errors.Is(writeErr.Err, internal.ErrSerialization) {
+			reason = DeadLetterSerialization
+		}
+		for _, idx := range tx.Reject {
+			r.deadLetter.Add(tx.Batch[idx], reason)
+		}
+	}
 }
 
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
-	if r.Config.BufferStrategy == "disk_write_through" {
+	if r.Config.BufferStrategy == "disk_write_through" || r.Config.BufferStrategy == "memory_spill" {
 		r.log.Debugf("Buffer fullness: %d metrics", nBuffer)
 	} else {
 		r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
@@ -864,6 +864,114 @@ func TestRunningOutputWriteBatchPartialSuccessAndLoss(t *testing.T) {
 	require.Zero(t, model.buffer.Len())
 }
 
+func TestRunningOutputFailover(t *testing.T) {
+	secondary := &mockOutput{}
+	failover := NewRunningOutput(secondary, &OutputConfig{Name: "secondary"}, 5, 10)
+	require.NoError(t, failover.Init())
+	require.NoError(t, failover.Connect())
+	defer failover.Close()
+
+	primary := &mockOutput{batchAcceptSize: -1}
+	model := NewRunningOutput(primary, &OutputConfig{Name: "primary"}, 5, 10)
+	model.SetFailoverOutput(failover, 2)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+	defer model.Close()
+	require.True(t, failover.Standby())
+	require.False(t, model.Standby())
+
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+
+	// The first failure must keep the metrics in the buffer
+	require.Error(t, model.Write())
+	require.Equal(t, 5, model.buffer.Len())
+	require.Empty(t, secondary.Metrics())
+
+	// Reaching the threshold switches to the failover output
+	require.NoError(t, model.Write())
+	require.Zero(t, model.buffer.Len())
+	testutil.RequireMetricsEqual(t, first5, secondary.Metrics())
+	require.Equal(t, 2, primary.writes)
+
+	// Full batches must be sent to the failover output without probing
+	for _, metric := range next5 {
+		model.AddMetric(metric)
+	}
+	require.NoError(t, model.WriteBatch())
+	require.Equal(t, 2, primary.writes)
+	require.Len(t, secondary.Metrics(), 10)
+
+	// The primary recovers and receives the metrics again
+	primary.batchAcceptSize = 0
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+	require.NoError(t, model.Write())
+	testutil.RequireMetricsEqual(t, first5, primary.Metrics())
+	require.Len(t, secondary.Metrics(), 10)
+	require.False(t, model.failedOver())
+}
+
+func TestRunningOutputFailoverError(t *testing.T) {
+	failover := NewRunningOutput(&mockOutput{batchAcceptSize: -1}, &OutputConfig{Name: "secondary"}, 5, 10)
+	require.NoError(t, failover.Init())
+	require.NoError(t, failover.Connect())
+	defer failover.Close()
+
+	model := NewRunningOutput(&mockOutput{batchAcceptSize: -1}, &OutputConfig{Name: "primary"}, 5, 10)
+	model.SetFailoverOutput(failover, 1)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+	defer model.Close()
+
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+
+	// Metrics must be kept if both outputs fail
+	require.ErrorContains(t, model.Write(), "failover output")
+	require.Equal(t, 5, model.buffer.Len())
+}
+
+func TestRunningOutputFailoverConnect(t *testing.T) {
+	secondary := &mockOutput{
+		startupError: &internal.StartupError{
+			Err:   errors.New("connection refused"),
+			Retry: true,
+		},
+		startupErrorCount: 2,
+	}
+	failover := NewRunningOutput(secondary, &OutputConfig{Name: "secondary", StartupErrorBehavior: "retry"}, 5, 10)
+	require.NoError(t, failover.Init())
+	require.NoError(t, failover.Connect())
+	require.False(t, failover.started)
+	defer failover.Close()
+
+	model := NewRunningOutput(&mockOutput{batchAcceptSize: -1}, &OutputConfig{Name: "primary"}, 5, 10)
+	model.SetFailoverOutput(failover, 1)
+	require.NoError(t, model.Init())
+	require.NoError(t, model.Connect())
+	defer model.Close()
+
+	for _, metric := range first5 {
+		model.AddMetric(metric)
+	}
+
+	// The metrics must be kept as long as the failover output cannot connect
+	require.ErrorContains(t, model.Write(), "connecting to failover output")
+	require.Equal(t, 5, model.buffer.Len())
+	require.False(t, failover.started)
+	require.Empty(t, secondary.Metrics())
+
+	// The failover output must receive the metrics after connecting
+	require.NoError(t, model.Write())
+	require.True(t, failover.started)
+	require.Zero(t, model.buffer.Len())
+	testutil.RequireMetricsEqual(t, first5, secondary.Metrics())
+}
+
 // Benchmark adding metrics.
 func BenchmarkRunningOutputAddWrite(b *testing.B) {
 	conf := &OutputConfig{
@@ -998,3 +1106,32 @@ func (m *perfOutput) Write([]telegraf.Metric) error {
 	}
 	return nil
 }
+
+func TestRunningOutputSeriesLimit(t *testing.T) {
+	conf := &OutputConfig{
+		Name: "TestRunningOutputSeriesLimit",
+		SeriesLimit: SeriesLimitConfig{
+			MaxSeries: 2,
+			Action:    SeriesLimitStripTags,
+			Tags:      []string{"tag1"},
+		},
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput(m, conf, 1000, 10000)
+	require.NoError(t, ro.Init())
+
+	// Metrics of new series exceeding the limit are written with the tag
+	// stripped
+	for _, name := range []string{"metric1", "metric2", "metric3", "metric1"} {
+		ro.AddMetric(testutil.TestMetric(101, name))
+	}
+	require.NoError(t, ro.Write())
+
+	actual := m.Metrics()
+	require.Len(t, actual, 4)
+	require.True(t, actual[0].HasTag("tag1"))
+	require.True(t, actual[1].HasTag("tag1"))
+	require.False(t, actual[2].HasTag("tag1"))
+	require.True(t, actual[3].HasTag("tag1"))
+}
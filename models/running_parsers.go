@@ -1,6 +1,7 @@
 package models
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -83,6 +84,23 @@ func (r *RunningParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	return m, err
 }
 
+// ParseWithHeader passes the headers to the parser if supported and falls back
+// to Parse otherwise
+func (r *RunningParser) ParseWithHeader(buf []byte, header http.Header) ([]telegraf.Metric, error) {
+	hp, ok := r.Parser.(telegraf.HeaderParser)
+	if !ok {
+		return r.Parse(buf)
+	}
+
+	start := time.Now()
+	m, err := hp.ParseWithHeader(buf, header)
+	elapsed := time.Since(start)
+	r.ParseTime.Incr(elapsed.Nanoseconds())
+	r.MetricsParsed.Incr(int64(len(m)))
+
+	return m, err
+}
+
 func (r *RunningParser) ParseLine(line string) (telegraf.Metric, error) {
 	start := time.Now()
 	m, err := r.Parser.ParseLine(line)
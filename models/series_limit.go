@@ -0,0 +1,210 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Actions taken for metrics of new series exceeding the series limit
+const (
+	SeriesLimitDrop      = "drop"
+	SeriesLimitStripTags = "strip_tags"
+	SeriesLimitHashTags  = "hash_tags"
+)
+
+// DefaultSeriesLimitBuckets is the default number of buckets for hashing tag
+// values
+const DefaultSeriesLimitBuckets = 100
+
+// SeriesLimitConfig limits the number of unique series produced by a plugin
+type SeriesLimitConfig struct {
+	// MaxSeries is the maximum number of unique series, zero disables the limit
+	MaxSeries int
+	// Action taken for metrics of new series once the limit is reached
+	Action string
+	// Tags stripped or hashed by the corresponding actions
+	Tags []string
+	// Buckets is the number of distinct values of hashed tags
+	Buckets int
+	// Expiry after which series not seen anymore are forgotten, zero keeps
+	// series forever
+	Expiry time.Duration
+}
+
+// seriesLimiter tracks the series of a plugin by the hash of the series key
+// so the number of series is approximate in case of hash collisions. The
+// series of metrics reduced by the configured action are tracked separately
+// and are capped at MaxSeries as well, as the remaining tags might still be
+// unbounded. The memory is therefore bounded by twice MaxSeries hashes.
+type seriesLimiter struct {
+	sync.Mutex
+	cfg       SeriesLimitConfig
+	log       telegraf.Logger
+	series    map[uint64]time.Time
+	reduced   map[uint64]time.Time
+	lastSweep time.Time
+	warned    bool
+
+	seriesCount     selfstat.Stat
+	metricsDropped  selfstat.Stat
+	metricsModified selfstat.Stat
+}
+
+func newSeriesLimiter(cfg SeriesLimitConfig, measurement string, tags map[string]string, log telegraf.Logger) (*seriesLimiter, error) {
+	if cfg.MaxSeries < 0 {
+		return nil, errors.New("'max_series' must not be negative")
+	}
+	switch cfg.Action {
+	case "":
+		cfg.Action = SeriesLimitDrop
+	case SeriesLimitDrop:
+	case SeriesLimitStripTags, SeriesLimitHashTags:
+		if len(cfg.Tags) == 0 {
+			return nil, fmt.Errorf("'max_series_tags' required for action %q", cfg.Action)
+		}
+	default:
+		return nil, fmt.Errorf("invalid 'max_series_action' setting %q", cfg.Action)
+	}
+	if cfg.Buckets < 0 {
+		return nil, errors.New("'max_series_buckets' must not be negative")
+	}
+	if cfg.Buckets == 0 {
+		cfg.Buckets = DefaultSeriesLimitBuckets
+	}
+
+	return &seriesLimiter{
+		cfg:             cfg,
+		log:             log,
+		series:          make(map[uint64]time.Time),
+		reduced:         make(map[uint64]time.Time),
+		lastSweep:       time.Now(),
+		seriesCount:     selfstat.Register(measurement, "series_count", tags),
+		metricsDropped:  selfstat.Register(measurement, "series_limit_dropped", tags),
+		metricsModified: selfstat.Register(measurement, "series_limit_modified", tags),
+	}, nil
+}
+
+// apply checks the series of the metric against the limit and modifies the
+// metric according to the configured action if the limit is exceeded.
+// Returns false if the metric should be dropped.
+func (l *seriesLimiter) apply(m telegraf.Metric) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	l.expire(now)
+
+	if l.admit(m.HashID(), now) {
+		return true
+	}
+
+	if !l.warned {
+		l.log.Warnf("Limit of %d series reached, applying action %q to new series", l.cfg.MaxSeries, l.cfg.Action)
+		l.warned = true
+	}
+
+	var modified bool
+	switch l.cfg.Action {
+	case SeriesLimitStripTags:
+		for _, key := range l.cfg.Tags {
+			if m.HasTag(key) {
+				m.RemoveTag(key)
+				modified = true
+			}
+		}
+	case SeriesLimitHashTags:
+		for _, key := range l.cfg.Tags {
+			if value, found := m.GetTag(key); found {
+				m.AddTag(key, l.bucket(value))
+				modified = true
+			}
+		}
+	}
+	if !modified {
+		// Metrics without any of the tags cannot be reduced
+		l.metricsDropped.Incr(1)
+		return false
+	}
+
+	// Reduced series are tracked separately and are only dropped once their
+	// own limit is reached
+	if !l.admitReduced(m.HashID(), now) {
+		l.metricsDropped.Incr(1)
+		return false
+	}
+	l.metricsModified.Incr(1)
+	return true
+}
+
+// admit returns true if the series is known or can be added without
+// exceeding the limit
+func (l *seriesLimiter) admit(id uint64, now time.Time) bool {
+	if _, found := l.series[id]; found {
+		l.series[id] = now
+		return true
+	}
+	if _, found := l.reduced[id]; found {
+		l.reduced[id] = now
+		return true
+	}
+	if len(l.series) >= l.cfg.MaxSeries {
+		return false
+	}
+	l.series[id] = now
+	l.updateCount()
+	return true
+}
+
+// admitReduced returns true if the reduced series is known or can be added
+// without exceeding the limit of reduced series
+func (l *seriesLimiter) admitReduced(id uint64, now time.Time) bool {
+	if _, found := l.series[id]; found {
+		l.series[id] = now
+		return true
+	}
+	if _, found := l.reduced[id]; found {
+		l.reduced[id] = now
+		return true
+	}
+	if len(l.reduced) >= l.cfg.MaxSeries {
+		return false
+	}
+	l.reduced[id] = now
+	l.updateCount()
+	return true
+}
+
+func (l *seriesLimiter) updateCount() {
+	l.seriesCount.Set(int64(len(l.series) + len(l.reduced)))
+}
+
+// expire removes series not seen within the expiry. The check is done at most
+// every half expiry period to limit the overhead.
+func (l *seriesLimiter) expire(now time.Time) {
+	if l.cfg.Expiry <= 0 || now.Sub(l.lastSweep) < l.cfg.Expiry/2 {
+		return
+	}
+	l.lastSweep = now
+
+	for _, series := range []map[uint64]time.Time{l.series, l.reduced} {
+		for id, seen := range series {
+			if now.Sub(seen) > l.cfg.Expiry {
+				delete(series, id)
+			}
+		}
+	}
+	l.updateCount()
+}
+
+func (l *seriesLimiter) bucket(value string) string {
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return strconv.FormatUint(uint64(h.Sum32()%uint32(l.cfg.Buckets)), 10)
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSeriesLimitInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      SeriesLimitConfig
+		expected string
+	}{
+		{
+			name:     "negative limit",
+			cfg:      SeriesLimitConfig{MaxSeries: -1},
+			expected: "'max_series' must not be negative",
+		},
+		{
+			name:     "unknown action",
+			cfg:      SeriesLimitConfig{MaxSeries: 1, Action: "foo"},
+			expected: `invalid 'max_series_action' setting "foo"`,
+		},
+		{
+			name:     "strip without tags",
+			cfg:      SeriesLimitConfig{MaxSeries: 1, Action: SeriesLimitStripTags},
+			expected: `'max_series_tags' required for action "strip_tags"`,
+		},
+		{
+			name:     "negative buckets",
+			cfg:      SeriesLimitConfig{MaxSeries: 1, Action: SeriesLimitHashTags, Tags: []string{"id"}, Buckets: -1},
+			expected: "'max_series_buckets' must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSeriesLimiter(tt.cfg, "test", map[string]string{"test": t.Name()}, testutil.Logger{})
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestSeriesLimitDrop(t *testing.T) {
+	limiter, err := newSeriesLimiter(
+		SeriesLimitConfig{MaxSeries: 2},
+		"test",
+		map[string]string{"test": t.Name()},
+		testutil.Logger{},
+	)
+	require.NoError(t, err)
+
+	require.True(t, limiter.apply(seriesLimitMetric("a")))
+	require.True(t, limiter.apply(seriesLimitMetric("b")))
+	require.False(t, limiter.apply(seriesLimitMetric("c")))
+
+	// Known series are still accepted
+	require.True(t, limiter.apply(seriesLimitMetric("a")))
+
+	require.Equal(t, int64(2), limiter.seriesCount.Get())
+	require.Equal(t, int64(1), limiter.metricsDropped.Get())
+}
+
+func TestSeriesLimitStripTags(t *testing.T) {
+	limiter, err := newSeriesLimiter(
+		SeriesLimitConfig{MaxSeries: 2, Action: SeriesLimitStripTags, Tags: []string{"id"}},
+		"test",
+		map[string]string{"test": t.Name()},
+		testutil.Logger{},
+	)
+	require.NoError(t, err)
+
+	// Fill the limit
+	require.True(t, limiter.apply(seriesLimitMetric("a")))
+	require.True(t, limiter.apply(seriesLimitMetric("b")))
+
+	// Metrics of new series are accepted with the tag stripped
+	for _, id := range []string{"c", "d", "e"} {
+		m := seriesLimitMetric(id)
+		require.True(t, limiter.apply(m))
+		require.False(t, m.HasTag("id"))
+		require.Equal(t, "x", m.Tags()["host"])
+	}
+
+	// The stripped series is counted once
+	other := seriesLimitMetric("f")
+	other.AddTag("host", "y")
+	require.True(t, limiter.apply(other))
+	require.False(t, other.HasTag("id"))
+	require.Equal(t, int64(4), limiter.seriesCount.Get())
+
+	// Known series are kept as is
+	known := seriesLimitMetric("a")
+	require.True(t, limiter.apply(known))
+	require.Equal(t, "a", known.Tags()["id"])
+
+	// Metrics of new series without the tag cannot be reduced and are dropped
+	untagged := seriesLimitMetric("")
+	untagged.AddTag("host", "z")
+	require.False(t, limiter.apply(untagged))
+
+	// Reduced series are limited as the remaining tags might be unbounded
+	unbounded := seriesLimitMetric("g")
+	unbounded.AddTag("host", "w")
+	require.False(t, limiter.apply(unbounded))
+	reduced := seriesLimitMetric("h")
+	require.True(t, limiter.apply(reduced))
+	require.False(t, reduced.HasTag("id"))
+	require.Equal(t, int64(4), limiter.seriesCount.Get())
+
+	require.Equal(t, int64(5), limiter.metricsModified.Get())
+	require.Equal(t, int64(2), limiter.metricsDropped.Get())
+}
+
+func TestSeriesLimitHashTags(t *testing.T) {
+	limiter, err := newSeriesLimiter(
+		SeriesLimitConfig{MaxSeries: 10, Action: SeriesLimitHashTags, Tags: []string{"id"}, Buckets: 4},
+		"test",
+		map[string]string{"test": t.Name()},
+		testutil.Logger{},
+	)
+	require.NoError(t, err)
+
+	// Fill the limit with series not matching any bucket
+	for i := range 10 {
+		require.True(t, limiter.apply(seriesLimitMetric(string(rune('A'+i)))))
+	}
+	require.Equal(t, int64(10), limiter.seriesCount.Get())
+
+	buckets := make(map[string]bool)
+	for i := range 100 {
+		id := "value" + strconv.Itoa(i)
+		m := seriesLimitMetric(id)
+		require.True(t, limiter.apply(m))
+		bucket, found := m.GetTag("id")
+		require.True(t, found)
+		require.Contains(t, []string{"0", "1", "2", "3"}, bucket)
+		buckets[bucket] = true
+
+		// Values are hashed deterministically
+		again := seriesLimitMetric(id)
+		require.True(t, limiter.apply(again))
+		require.Equal(t, bucket, again.Tags()["id"])
+	}
+
+	// The number of series is bounded by the number of buckets
+	require.Len(t, buckets, 4)
+	require.Equal(t, int64(14), limiter.seriesCount.Get())
+	require.Equal(t, int64(200), limiter.metricsModified.Get())
+	require.Zero(t, limiter.metricsDropped.Get())
+}
+
+func TestSeriesLimitExpiry(t *testing.T) {
+	limiter, err := newSeriesLimiter(
+		SeriesLimitConfig{MaxSeries: 1, Expiry: time.Minute},
+		"test",
+		map[string]string{"test": t.Name()},
+		testutil.Logger{},
+	)
+	require.NoError(t, err)
+
+	require.True(t, limiter.apply(seriesLimitMetric("a")))
+	require.False(t, limiter.apply(seriesLimitMetric("b")))
+
+	// Pretend the series was last seen long ago
+	for id := range limiter.series {
+		limiter.series[id] = time.Now().Add(-2 * time.Minute)
+	}
+	limiter.lastSweep = time.Now().Add(-time.Minute)
+
+	require.True(t, limiter.apply(seriesLimitMetric("b")))
+	require.Equal(t, int64(1), limiter.seriesCount.Get())
+}
+
+func seriesLimitMetric(id string) telegraf.Metric {
+	tags := map[string]string{"host": "x"}
+	if id != "" {
+		tags["id"] = id
+	}
+	return metric.New("cpu", tags, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+}
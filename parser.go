@@ -1,5 +1,7 @@
 package telegraf
 
+import "net/http"
+
 // Parser is an interface defining functions that a parser plugin must satisfy.
 type Parser interface {
 	// Parse takes a byte buffer separated by newlines
@@ -22,6 +24,17 @@ type Parser interface {
 	SetDefaultTags(tags map[string]string)
 }
 
+// HeaderParser is an optional interface for parsers requiring the transport
+// headers, e.g. the HTTP headers of a request, to interpret the payload.
+// Input plugins providing such headers should check for this interface and
+// use ParseWithHeader instead of Parse.
+type HeaderParser interface {
+	// ParseWithHeader parses the payload using the given headers.
+	//
+	// Must be thread-safe.
+	ParseWithHeader(buf []byte, header http.Header) ([]Metric, error)
+}
+
 // ParserFunc is a function to create a new instance of a parser
 type ParserFunc func() (Parser, error)
 
@@ -1,18 +1,43 @@
 package persister
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
+	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sync"
 
 	"github.com/influxdata/telegraf"
 )
 
+// formatVersion is the version of the state-file layout written by Store().
+// Files without a format version are treated as legacy files containing a
+// plain id to serialized-state map.
+const formatVersion = 2
+
+// stateFile is the on-disk layout of the state file
+type stateFile struct {
+	Format int                 `json:"format"`
+	States map[string]envelope `json:"states"`
+}
+
+// envelope wraps the state of a single plugin to allow detecting corrupted
+// or incompatible entries without affecting the states of other plugins.
+type envelope struct {
+	Version  int             `json:"version"`
+	Checksum uint32          `json:"checksum"`
+	State    json.RawMessage `json:"state"`
+}
+
 type Persister struct {
 	Filename string
 
 	register map[string]telegraf.StatefulPlugin
+	mu       sync.Mutex
 }
 
 func (p *Persister) Init() error {
@@ -22,6 +47,9 @@ func (p *Persister) Init() error {
 }
 
 func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if _, found := p.register[id]; found {
 		return fmt.Errorf("plugin with ID %q already registered", id)
 	}
@@ -30,6 +58,15 @@ func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
 	return nil
 }
 
+// Unregister removes the plugin with the given ID, e.g. when removing the
+// plugin at runtime, so its state is not stored anymore.
+func (p *Persister) Unregister(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.register, id)
+}
+
 func (p *Persister) Load() error {
 	// Read the states from disk
 	in, err := os.ReadFile(p.Filename)
@@ -37,66 +74,191 @@ func (p *Persister) Load() error {
 		return fmt.Errorf("reading states file failed: %w", err)
 	}
 
-	// Unmarshal the id to serialized states map
-	var states map[string][]byte
-	if err := json.Unmarshal(in, &states); err != nil {
+	// Unmarshal the id to state-envelope map
+	states, err := decode(in)
+	if err != nil {
 		return fmt.Errorf("unmarshalling states failed: %w", err)
 	}
 
-	// Get the initialized state as blueprint for unmarshalling
-	for id, serialized := range states {
+	// Restore the states of the plugins individually and reset the plugins
+	// with corrupted or incompatible states to their initial state.
+	for id, env := range states {
 		// Check if we have a plugin with that ID
 		plugin, found := p.register[id]
 		if !found {
 			continue
 		}
 
-		// Create a new empty state of the "state"-type. As we need a pointer
-		// of the state, we cannot dereference it here due to the unknown
-		// nature of the state-type.
-		nstate := reflect.New(reflect.TypeOf(plugin.GetState())).Interface()
-		if err := json.Unmarshal(serialized, &nstate); err != nil {
-			return fmt.Errorf("unmarshalling state for %q failed: %w", id, err)
-		}
-		state := reflect.ValueOf(nstate).Elem().Interface()
-
-		// Set the state in the plugin
-		if err := plugin.SetState(state); err != nil {
-			return fmt.Errorf("setting state of %q failed: %w", id, err)
+		if err := restore(plugin, env); err != nil {
+			log.Printf("W! [persister] Discarding state of plugin %q: %v", id, err)
 		}
 	}
 
 	return nil
 }
 
+// Restore sets the state of a single plugin from the states file, e.g. for
+// plugins added at runtime. Plugins without a state in the file are left
+// untouched.
+func (p *Persister) Restore(id string, plugin telegraf.StatefulPlugin) error {
+	in, err := os.ReadFile(p.Filename)
+	if err != nil {
+		return fmt.Errorf("reading states file failed: %w", err)
+	}
+
+	states, err := decode(in)
+	if err != nil {
+		return fmt.Errorf("unmarshalling states failed: %w", err)
+	}
+
+	env, found := states[id]
+	if !found {
+		return nil
+	}
+	return restore(plugin, env)
+}
+
 func (p *Persister) Store() error {
-	states := make(map[string][]byte)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := make(map[string]envelope, len(p.register))
 
 	// Collect the states and serialize the individual data chunks
-	// to later serialize all items in the id / serialized-states map
+	// to later serialize all items in the id / state-envelope map
 	for id, plugin := range p.register {
 		state, err := json.Marshal(plugin.GetState())
 		if err != nil {
 			return fmt.Errorf("marshalling state for id %q failed: %w", id, err)
 		}
-		states[id] = state
+		states[id] = envelope{
+			Version:  stateVersion(plugin),
+			Checksum: crc32.ChecksumIEEE(state),
+			State:    state,
+		}
 	}
 
 	// Serialize the states
-	serialized, err := json.Marshal(states)
+	serialized, err := json.Marshal(stateFile{Format: formatVersion, States: states})
 	if err != nil {
 		return fmt.Errorf("marshalling states failed: %w", err)
 	}
 
 	// Write the states to disk
-	f, err := os.Create(p.Filename)
+	if err := writeAtomic(p.Filename, serialized); err != nil {
+		return fmt.Errorf("writing states failed: %w", err)
+	}
+
+	return nil
+}
+
+func decode(in []byte) (map[string]envelope, error) {
+	var file stateFile
+	if err := json.Unmarshal(in, &file); err == nil && file.Format > 0 {
+		if file.Format > formatVersion {
+			return nil, fmt.Errorf("unsupported state file format %d", file.Format)
+		}
+		return file.States, nil
+	}
+
+	// Fallback to the legacy format without envelopes
+	var legacy map[string][]byte
+	if err := json.Unmarshal(in, &legacy); err != nil {
+		return nil, err
+	}
+	states := make(map[string]envelope, len(legacy))
+	for id, state := range legacy {
+		states[id] = envelope{
+			Checksum: crc32.ChecksumIEEE(state),
+			State:    state,
+		}
+	}
+	return states, nil
+}
+
+func restore(plugin telegraf.StatefulPlugin, env envelope) error {
+	// Check the integrity of the entry. Use the compacted form as the state
+	// might have been reformatted when editing the file manually.
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, env.State); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	if checksum := crc32.ChecksumIEEE(buf.Bytes()); checksum != env.Checksum {
+		return fmt.Errorf("checksum mismatch, expected %08x but got %08x", env.Checksum, checksum)
+	}
+
+	// Make sure the state is compatible with the plugin
+	if version := stateVersion(plugin); version != env.Version {
+		return fmt.Errorf("incompatible state version %d, expected %d", env.Version, version)
+	}
+
+	// Create a new empty state of the "state"-type. As we need a pointer
+	// of the state, we cannot dereference it here due to the unknown
+	// nature of the state-type.
+	nstate := reflect.New(reflect.TypeOf(plugin.GetState())).Interface()
+	if err := json.Unmarshal(env.State, &nstate); err != nil {
+		return fmt.Errorf("unmarshalling state failed: %w", err)
+	}
+	state := reflect.ValueOf(nstate).Elem().Interface()
+
+	// Set the state in the plugin
+	if err := plugin.SetState(state); err != nil {
+		return fmt.Errorf("setting state failed: %w", err)
+	}
+
+	return nil
+}
+
+func stateVersion(plugin telegraf.StatefulPlugin) int {
+	if p, ok := plugin.(telegraf.VersionedStatefulPlugin); ok {
+		return p.StateVersion()
+	}
+	return 0
+}
+
+// writeAtomic writes the data to a temporary file in the same directory,
+// syncs it to disk and replaces the target file by renaming. This way the
+// target file either contains the old or the new content even if Telegraf
+// is killed during writing.
+func writeAtomic(filename string, data []byte) error {
+	// Special files such as devices cannot be replaced, so write directly
+	if info, err := os.Stat(filename); err == nil && !info.Mode().IsRegular() {
+		return os.WriteFile(filename, data, info.Mode().Perm())
+	}
+
+	dir := filepath.Dir(filename)
+	f, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp-*")
 	if err != nil {
-		return fmt.Errorf("creating states file %q failed: %w", p.Filename, err)
+		return fmt.Errorf("creating temporary file failed: %w", err)
 	}
-	defer f.Close()
+	tmpfn := f.Name()
 
-	if _, err := f.Write(serialized); err != nil {
-		return fmt.Errorf("writing states failed: %w", err)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpfn)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpfn)
+		return fmt.Errorf("syncing temporary file failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpfn)
+		return err
+	}
+
+	if err := os.Rename(tmpfn, filename); err != nil {
+		os.Remove(tmpfn)
+		return fmt.Errorf("replacing %q failed: %w", filename, err)
+	}
+
+	// Persist the rename by syncing the directory. Not all platforms support
+	// syncing directories so ignore errors here.
+	if d, err := os.Open(dir); err == nil {
+		//nolint:errcheck // best effort, not supported on all platforms
+		d.Sync()
+		d.Close()
 	}
 
 	return nil
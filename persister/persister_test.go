@@ -0,0 +1,199 @@
+package persister
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockState struct {
+	Name   string
+	Offset uint64
+}
+
+type mockPlugin struct {
+	state   mockState
+	version int
+	fail    bool
+}
+
+func (m *mockPlugin) GetState() interface{} {
+	return m.state
+}
+
+func (m *mockPlugin) SetState(state interface{}) error {
+	if m.fail {
+		return errors.New("refusing state")
+	}
+	s, ok := state.(mockState)
+	if !ok {
+		return errors.New("invalid state type")
+	}
+	m.state = s
+	return nil
+}
+
+type mockVersionedPlugin struct {
+	mockPlugin
+}
+
+func (m *mockVersionedPlugin) StateVersion() int {
+	return m.version
+}
+
+func TestStoreLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	store := &Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("a", &mockPlugin{state: mockState{Name: "a", Offset: 42}}))
+	require.NoError(t, store.Register("b", &mockVersionedPlugin{mockPlugin{state: mockState{Name: "b", Offset: 23}, version: 3}}))
+	require.NoError(t, store.Store())
+
+	// Make sure no temporary files are left over
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	pa := &mockPlugin{}
+	pb := &mockVersionedPlugin{mockPlugin{version: 3}}
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	require.NoError(t, load.Register("a", pa))
+	require.NoError(t, load.Register("b", pb))
+	require.NoError(t, load.Load())
+
+	require.Equal(t, mockState{Name: "a", Offset: 42}, pa.state)
+	require.Equal(t, mockState{Name: "b", Offset: 23}, pb.state)
+}
+
+func TestStoreOverwrite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(filename, []byte("garbage that is much longer than the new content"), 0600))
+
+	plugin := &mockPlugin{state: mockState{Name: "a"}}
+	store := &Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("a", plugin))
+
+	// Store multiple times to simulate periodic checkpoints
+	for i := range 3 {
+		plugin.state.Offset = uint64(i)
+		require.NoError(t, store.Store())
+	}
+
+	restored := &mockPlugin{}
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	require.NoError(t, load.Register("a", restored))
+	require.NoError(t, load.Load())
+	require.Equal(t, mockState{Name: "a", Offset: 2}, restored.state)
+}
+
+func TestLoadLegacyFormat(t *testing.T) {
+	state, err := json.Marshal(mockState{Name: "legacy", Offset: 1})
+	require.NoError(t, err)
+	content, err := json.Marshal(map[string][]byte{"a": state})
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(filename, content, 0600))
+
+	plugin := &mockPlugin{}
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	require.NoError(t, load.Register("a", plugin))
+	require.NoError(t, load.Load())
+	require.Equal(t, mockState{Name: "legacy", Offset: 1}, plugin.state)
+}
+
+func TestLoadDiscardInvalidEntries(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	store := &Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	for _, id := range []string{"ok", "corrupted", "version", "refused"} {
+		p := &mockVersionedPlugin{mockPlugin{state: mockState{Name: id, Offset: 1}, version: 1}}
+		require.NoError(t, store.Register(id, p))
+	}
+	require.NoError(t, store.Store())
+
+	// Corrupt one of the entries
+	buf, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	var file stateFile
+	require.NoError(t, json.Unmarshal(buf, &file))
+	env := file.States["corrupted"]
+	env.State = json.RawMessage(`{"Name":"corrupted","Offset":2}`)
+	file.States["corrupted"] = env
+	buf, err = json.Marshal(file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filename, buf, 0600))
+
+	// Restore the states with one plugin having a new state version and
+	// another one refusing the state
+	initial := mockState{Name: "initial"}
+	plugins := map[string]*mockVersionedPlugin{
+		"ok":        {mockPlugin{state: initial, version: 1}},
+		"corrupted": {mockPlugin{state: initial, version: 1}},
+		"version":   {mockPlugin{state: initial, version: 2}},
+		"refused":   {mockPlugin{state: initial, version: 1, fail: true}},
+	}
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	for id, p := range plugins {
+		require.NoError(t, load.Register(id, p))
+	}
+	require.NoError(t, load.Load())
+
+	require.Equal(t, mockState{Name: "ok", Offset: 1}, plugins["ok"].state)
+	require.Equal(t, initial, plugins["corrupted"].state)
+	require.Equal(t, initial, plugins["version"].state)
+	require.Equal(t, initial, plugins["refused"].state)
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(filename, []byte("{not json"), 0600))
+
+	load := &Persister{Filename: filename}
+	require.NoError(t, load.Init())
+	require.ErrorContains(t, load.Load(), "unmarshalling states failed")
+}
+
+func TestUnregister(t *testing.T) {
+	store := &Persister{Filename: filepath.Join(t.TempDir(), "state.json")}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("a", &mockPlugin{}))
+	require.Error(t, store.Register("a", &mockPlugin{}))
+
+	// The ID can be registered again after removing the plugin
+	store.Unregister("a")
+	require.NoError(t, store.Register("a", &mockPlugin{}))
+}
+
+func TestRestore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "state.json")
+
+	store := &Persister{Filename: filename}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Register("a", &mockPlugin{state: mockState{Name: "a", Offset: 42}}))
+	require.NoError(t, store.Store())
+
+	// Restore plugins not registered when storing the states
+	plugin := &mockPlugin{}
+	require.NoError(t, store.Restore("a", plugin))
+	require.Equal(t, mockState{Name: "a", Offset: 42}, plugin.state)
+
+	unknown := &mockPlugin{state: mockState{Name: "unknown"}}
+	require.NoError(t, store.Restore("b", unknown))
+	require.Equal(t, mockState{Name: "unknown"}, unknown.state)
+
+	// Incompatible states must be rejected
+	versioned := &mockVersionedPlugin{mockPlugin{version: 1}}
+	require.ErrorContains(t, store.Restore("a", versioned), "incompatible state version")
+}
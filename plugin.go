@@ -57,6 +57,18 @@ type StatefulPlugin interface {
 	SetState(state interface{}) error
 }
 
+// VersionedStatefulPlugin can be implemented by stateful plugins to version
+// the format of their state. A persisted state with a version different from
+// the one returned by StateVersion() is discarded on loading and the plugin
+// starts with its initial state instead.
+type VersionedStatefulPlugin interface {
+	StatefulPlugin
+
+	// StateVersion returns the version of the state format. Increase the
+	// version whenever the state becomes incompatible with older states.
+	StateVersion() int
+}
+
 // ProbePlugin is an interface that all input/output plugins need to
 // implement in order to support the `probe` value of `startup_error_behavior`
 type ProbePlugin interface {
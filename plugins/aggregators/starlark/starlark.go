@@ -16,6 +16,9 @@ var sampleConfig string
 
 type Starlark struct {
 	common.Common
+
+	// Metrics emitted by the script to be added with the next push
+	emitted []telegraf.Metric
 }
 
 func (*Starlark) SampleConfig() string {
@@ -23,6 +26,10 @@ func (*Starlark) SampleConfig() string {
 }
 
 func (s *Starlark) Init() error {
+	s.Builtins = starlark.StringDict{
+		"emit": starlark.NewBuiltin("emit", s.emit),
+	}
+
 	// Execute source
 	err := s.Common.Init()
 	if err != nil {
@@ -65,6 +72,12 @@ func (s *Starlark) Add(metric telegraf.Metric) {
 }
 
 func (s *Starlark) Push(acc telegraf.Accumulator) {
+	// Add the metrics emitted since the last push
+	for _, m := range s.emitted {
+		acc.AddMetric(m)
+	}
+	s.emitted = s.emitted[:0]
+
 	rv, err := s.Call("push")
 	if err != nil {
 		s.LogError(err)
@@ -95,6 +108,18 @@ func (s *Starlark) Push(acc telegraf.Accumulator) {
 	}
 }
 
+// emit(metric) queues a copy of the metric to be added with the next push
+// independent of the return value of the push function. This allows to emit
+// metrics from the add function e.g. once a window is complete.
+func (s *Starlark) emit(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var m *common.Metric
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &m); err != nil {
+		return starlark.None, err
+	}
+	s.emitted = append(s.emitted, m.Unwrap().Copy())
+	return starlark.None, nil
+}
+
 func (s *Starlark) Reset() {
 	_, err := s.Call("reset")
 	if err != nil {
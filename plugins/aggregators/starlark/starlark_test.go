@@ -403,6 +403,67 @@ def reset():
 	plugin.Reset()
 }
 
+func TestEmitWindow(t *testing.T) {
+	plugin, err := newStarlarkFromSource(`
+def add(metric):
+  if "window" not in state:
+    state["window"] = Window("1m")
+  state["window"].add(metric)
+
+  # Emit the completed windows as soon as a newer metric arrives
+  for start, metrics in state["window"].expired(metric.time):
+    emit(summarize(start, metrics))
+
+def push():
+  # Keep the current window open across pushes
+  return None
+
+def reset():
+  pass
+
+def summarize(start, metrics):
+  m = Metric("cpu_window")
+  m.fields["max"] = max([x.fields["usage"] for x in metrics])
+  m.fields["count"] = len(metrics)
+  m.time = start
+  return m
+`)
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	for i, usage := range []float64{10, 30, 20, 50} {
+		plugin.Add(metric.New(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": usage},
+			time.Unix(int64(i)*40, 0),
+		))
+	}
+	plugin.Push(&acc)
+	plugin.Reset()
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu_window",
+			map[string]string{},
+			map[string]interface{}{"max": 30.0, "count": 2},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"cpu_window",
+			map[string]string{},
+			map[string]interface{}{"max": 20.0, "count": 1},
+			time.Unix(60, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// Emitted metrics are only added once
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
 func newStarlarkFromSource(source string) (*Starlark, error) {
 	plugin := &Starlark{
 		Common: common.Common{
@@ -0,0 +1,118 @@
+package avro
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// SchemaRegistry is a client for the REST API of a Confluent compatible
+// schema registry
+type SchemaRegistry struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+type errorResponse struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// NewSchemaRegistry creates a client for the registry at the given address.
+// Credentials contained in the address are sent via basic authentication,
+// the optional CA certificate is used to verify the registry's certificate.
+func NewSchemaRegistry(addr, caCertPath string) (*SchemaRegistry, error) {
+	var tlsCfg *tls.Config
+	if caCertPath != "" {
+		caCert, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+		caCertPool := x509.NewCertPool()
+		caCertPool.AppendCertsFromPEM(caCert)
+		tlsCfg = &tls.Config{
+			RootCAs: caCertPool,
+		}
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			MaxIdleConns:    10,
+			IdleConnTimeout: 90 * time.Second,
+		},
+		Timeout: 30 * time.Second,
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing registry URL failed: %w", err)
+	}
+
+	var username, password string
+	if u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	return &SchemaRegistry{
+		url:      strings.TrimSuffix(u.String(), "/"),
+		username: username,
+		password: password,
+		client:   client,
+	}, nil
+}
+
+// Do sends a request to the given endpoint of the registry, e.g.
+// "/schemas/ids/1". The body is sent as JSON if not nil and the JSON response
+// is decoded into the result. Responses with a status other than "200 OK"
+// are returned as error including the message sent by the registry.
+func (sr *SchemaRegistry) Do(method, endpoint string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, sr.url+endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if sr.username != "" {
+		req.SetBasicAuth(sr.username, sr.password)
+	}
+
+	resp, err := sr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Message == "" {
+			return fmt.Errorf("received status %s", resp.Status)
+		}
+		return fmt.Errorf("received status %s: %s (error code %d)", resp.Status, errResp.Message, errResp.Code)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,59 @@
+package avro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistryDo(t *testing.T) {
+	var username, password, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		path = r.URL.Path
+		if r.Method == http.MethodPost && r.Header.Get("Content-Type") != contentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	// Credentials are taken from the address and sent via basic auth
+	registry, err := NewSchemaRegistry(strings.Replace(server.URL, "http://", "http://user:secret@", 1)+"/", "")
+	require.NoError(t, err)
+	require.Equal(t, server.URL, registry.url)
+
+	var result struct {
+		ID int `json:"id"`
+	}
+	require.NoError(t, registry.Do(http.MethodPost, "/subjects/test", map[string]string{"schema": "{}"}, &result))
+	require.Equal(t, 1, result.ID)
+	require.Equal(t, "/subjects/test", path)
+	require.Equal(t, "user", username)
+	require.Equal(t, "secret", password)
+}
+
+func TestSchemaRegistryError(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	registry, err := NewSchemaRegistry(server.URL, "")
+	require.NoError(t, err)
+
+	var result map[string]interface{}
+	body = `{"error_code":40403,"message":"Schema not found"}`
+	err = registry.Do(http.MethodGet, "/schemas/ids/1", nil, &result)
+	require.EqualError(t, err, "received status 404 Not Found: Schema not found (error code 40403)")
+
+	body = "not found"
+	err = registry.Do(http.MethodGet, "/schemas/ids/1", nil, &result)
+	require.EqualError(t, err, "received status 404 Not Found")
+}
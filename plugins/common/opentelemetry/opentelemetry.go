@@ -0,0 +1,64 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb-observability/common"
+
+	"github.com/influxdata/telegraf"
+)
+
+// MetricsSchemata maps the names of the supported metrics schemata to the
+// schemata used when converting OpenTelemetry metrics
+var MetricsSchemata = map[string]common.MetricsSchema{
+	"prometheus-v1": common.MetricsSchemaTelegrafPrometheusV1,
+	"prometheus-v2": common.MetricsSchemaTelegrafPrometheusV2,
+}
+
+// Logger adapts a telegraf.Logger for the conversion library
+type Logger struct {
+	telegraf.Logger
+}
+
+// Debug logs a debug message, patterned after log.Print.
+func (l Logger) Debug(msg string, kv ...interface{}) {
+	format := msg + strings.Repeat(" %s=%q", len(kv)/2)
+	l.Logger.Debugf(format, kv...)
+}
+
+// ValueType returns the value type used by the conversion library for the
+// given metric type
+func ValueType(t telegraf.ValueType) (common.InfluxMetricValueType, error) {
+	switch t {
+	case telegraf.Gauge:
+		return common.InfluxMetricValueTypeGauge, nil
+	case telegraf.Untyped:
+		return common.InfluxMetricValueTypeUntyped, nil
+	case telegraf.Counter:
+		return common.InfluxMetricValueTypeSum, nil
+	case telegraf.Histogram:
+		return common.InfluxMetricValueTypeHistogram, nil
+	case telegraf.Summary:
+		return common.InfluxMetricValueTypeSummary, nil
+	}
+	return common.InfluxMetricValueTypeUntyped, fmt.Errorf("unrecognized metric type %v", t)
+}
+
+// MetricType returns the metric type for the given value type of the
+// conversion library
+func MetricType(t common.InfluxMetricValueType) (telegraf.ValueType, error) {
+	switch t {
+	case common.InfluxMetricValueTypeUntyped:
+		return telegraf.Untyped, nil
+	case common.InfluxMetricValueTypeGauge:
+		return telegraf.Gauge, nil
+	case common.InfluxMetricValueTypeSum:
+		return telegraf.Counter, nil
+	case common.InfluxMetricValueTypeHistogram:
+		return telegraf.Histogram, nil
+	case common.InfluxMetricValueTypeSummary:
+		return telegraf.Summary, nil
+	}
+	return telegraf.Untyped, fmt.Errorf("unrecognized InfluxMetricValueType %q", t)
+}
@@ -0,0 +1,66 @@
+package pulsar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// ClientConfig for Pulsar clients
+type ClientConfig struct {
+	URL               string          `toml:"url"`
+	AuthToken         config.Secret   `toml:"auth_token"`
+	ConnectionTimeout config.Duration `toml:"connection_timeout"`
+	OperationTimeout  config.Duration `toml:"operation_timeout"`
+	tls.ClientConfig
+}
+
+// NewClient creates a Pulsar client using the configuration
+func (c *ClientConfig) NewClient(log telegraf.Logger) (pulsar.Client, error) {
+	if c.URL == "" {
+		return nil, errors.New("'url' required")
+	}
+
+	tlsConfig, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := pulsar.ClientOptions{
+		URL:               c.URL,
+		ConnectionTimeout: time.Duration(c.ConnectionTimeout),
+		OperationTimeout:  time.Duration(c.OperationTimeout),
+		TLSConfig:         tlsConfig,
+		Logger:            &logger{log: log},
+		// Use a private registry to not expose the client metrics
+		MetricsRegisterer: prometheus.NewRegistry(),
+	}
+	if tlsConfig != nil {
+		opts.TLSAllowInsecureConnection = tlsConfig.InsecureSkipVerify
+	}
+
+	switch {
+	case !c.AuthToken.Empty() && c.TLSCert != "":
+		return nil, errors.New("either 'auth_token' or 'tls_cert' can be used for authentication")
+	case !c.AuthToken.Empty():
+		opts.Authentication = pulsar.NewAuthenticationTokenFromSupplier(func() (string, error) {
+			token, err := c.AuthToken.Get()
+			if err != nil {
+				return "", fmt.Errorf("getting token failed: %w", err)
+			}
+			defer token.Destroy()
+			return token.String(), nil
+		})
+	case c.TLSCert != "":
+		opts.Authentication = pulsar.NewAuthenticationTLS(c.TLSCert, c.TLSKey)
+	}
+
+	return pulsar.NewClient(opts)
+}
@@ -0,0 +1,96 @@
+package pulsar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// logger forwards the messages of the Pulsar client to the plugin logger,
+// prefixed by the fields of the logger. Informational messages of the client
+// are logged at debug level as they are only relevant for troubleshooting.
+type logger struct {
+	log    telegraf.Logger
+	fields log.Fields
+}
+
+func (l *logger) SubLogger(fields log.Fields) log.Logger {
+	return l.with(fields)
+}
+
+func (l *logger) WithFields(fields log.Fields) log.Entry {
+	return l.with(fields)
+}
+
+func (l *logger) WithField(name string, value interface{}) log.Entry {
+	return l.with(log.Fields{name: value})
+}
+
+func (l *logger) WithError(err error) log.Entry {
+	return l.with(log.Fields{"error": err})
+}
+
+func (l *logger) Debug(args ...interface{}) {
+	l.log.Trace(l.prefix() + fmt.Sprint(args...))
+}
+
+func (l *logger) Info(args ...interface{}) {
+	l.log.Debug(l.prefix() + fmt.Sprint(args...))
+}
+
+func (l *logger) Warn(args ...interface{}) {
+	l.log.Warn(l.prefix() + fmt.Sprint(args...))
+}
+
+func (l *logger) Error(args ...interface{}) {
+	l.log.Error(l.prefix() + fmt.Sprint(args...))
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log.Trace(l.prefix() + fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log.Debug(l.prefix() + fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log.Warn(l.prefix() + fmt.Sprintf(format, args...))
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log.Error(l.prefix() + fmt.Sprintf(format, args...))
+}
+
+func (l *logger) with(fields log.Fields) *logger {
+	merged := make(log.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &logger{log: l.log, fields: merged}
+}
+
+func (l *logger) prefix() string {
+	if len(l.fields) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, l.fields[k]))
+	}
+	return "[" + strings.Join(parts, " ") + "] "
+}
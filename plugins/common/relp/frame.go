@@ -0,0 +1,174 @@
+// Package relp implements the Reliable Event Logging Protocol (RELP) used by
+// rsyslog for guaranteed delivery of syslog messages, see
+// https://www.rsyslog.com/doc/relp.html
+package relp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Commands of the protocol
+const (
+	CommandOpen        = "open"
+	CommandClose       = "close"
+	CommandSyslog      = "syslog"
+	CommandResponse    = "rsp"
+	CommandServerClose = "serverclose"
+)
+
+// Status codes of responses
+const (
+	StatusOK    = 200
+	StatusError = 500
+)
+
+const (
+	// Transaction numbers wrap around after reaching this value
+	maxTxnr = 999999999
+	// Maximum number of digits of the transaction number and data length
+	maxDigits = 9
+	// Maximum length of a command
+	maxCommandLen = 32
+	// MaxDataLen is the maximum size of the data of a frame accepted
+	MaxDataLen = 16 * 1024 * 1024
+)
+
+// Offers sent when opening a session
+const offers = "relp_version=0\nrelp_software=telegraf\ncommands=" + CommandSyslog
+
+// Frame of the protocol in the form "TXNR SP COMMAND SP DATALEN [SP DATA] LF"
+type Frame struct {
+	Txnr    uint32
+	Command string
+	Data    []byte
+}
+
+// Bytes returns the serialized frame
+func (f *Frame) Bytes() []byte {
+	buf := make([]byte, 0, len(f.Data)+len(f.Command)+24)
+	buf = strconv.AppendUint(buf, uint64(f.Txnr), 10)
+	buf = append(buf, ' ')
+	buf = append(buf, f.Command...)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(len(f.Data)), 10)
+	if len(f.Data) > 0 {
+		buf = append(buf, ' ')
+		buf = append(buf, f.Data...)
+	}
+	return append(buf, '\n')
+}
+
+// ReadFrame reads the next frame
+func ReadFrame(r *bufio.Reader) (*Frame, error) {
+	token, _, err := readToken(r, maxDigits, false)
+	if err != nil {
+		return nil, err
+	}
+	txnr, err := strconv.ParseUint(token, 10, 32)
+	if err != nil || txnr > maxTxnr {
+		return nil, fmt.Errorf("invalid transaction number %q", token)
+	}
+
+	command, _, err := readToken(r, maxCommandLen, false)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if command == "" {
+		return nil, errors.New("empty command")
+	}
+
+	token, last, err := readToken(r, maxDigits, true)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	length, err := strconv.Atoi(token)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid data length %q", token)
+	}
+	if length > MaxDataLen {
+		return nil, fmt.Errorf("data length %d exceeds maximum of %d", length, MaxDataLen)
+	}
+
+	frame := &Frame{Txnr: uint32(txnr), Command: command}
+	if last {
+		// Frames without data are terminated directly after the length
+		if length != 0 {
+			return nil, fmt.Errorf("missing data of length %d", length)
+		}
+		return frame, nil
+	}
+
+	frame.Data = make([]byte, length)
+	if _, err := io.ReadFull(r, frame.Data); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	trailer, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if trailer != '\n' {
+		return nil, fmt.Errorf("invalid trailer %q", trailer)
+	}
+	return frame, nil
+}
+
+// readToken reads up to the next space or, if allowed, up to the trailer and
+// returns if the token was terminated by the trailer
+func readToken(r *bufio.Reader, maxLen int, allowTrailer bool) (string, bool, error) {
+	var sb strings.Builder
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && sb.Len() > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", false, err
+		}
+		switch {
+		case c == ' ':
+			return sb.String(), false, nil
+		case c == '\n' && allowTrailer:
+			return sb.String(), true, nil
+		case sb.Len() >= maxLen:
+			return "", false, fmt.Errorf("token %q exceeds maximum length", sb.String())
+		}
+		sb.WriteByte(c)
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Response returns the data of a response with the given status
+func Response(code int, message string) []byte {
+	return []byte(strconv.Itoa(code) + " " + message)
+}
+
+// ParseResponse returns the status code and message of the response data in
+// the form "CODE SP MESSAGE [LF DATA]"
+func ParseResponse(data []byte) (int, string, error) {
+	line, _, _ := strings.Cut(string(data), "\n")
+	rawCode, message, _ := strings.Cut(line, " ")
+	code, err := strconv.Atoi(rawCode)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid response %q", line)
+	}
+	return code, message, nil
+}
+
+// nextTxnr returns the transaction number following the given one
+func nextTxnr(txnr uint32) uint32 {
+	if txnr >= maxTxnr {
+		return 1
+	}
+	return txnr + 1
+}
@@ -0,0 +1,140 @@
+package relp
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []*Frame
+	}{
+		{
+			name:  "open",
+			input: "1 open 86 relp_version=0\nrelp_software=librelp,1.2.13,http://librelp.adiscon.com\ncommands=syslog\n",
+			expected: []*Frame{
+				{
+					Txnr:    1,
+					Command: "open",
+					Data:    []byte("relp_version=0\nrelp_software=librelp,1.2.13,http://librelp.adiscon.com\ncommands=syslog"),
+				},
+			},
+		},
+		{
+			name:  "multiple frames",
+			input: "2 syslog 10 <13>1 test\n3 rsp 6 200 OK\n4 close 0\n",
+			expected: []*Frame{
+				{Txnr: 2, Command: "syslog", Data: []byte("<13>1 test")},
+				{Txnr: 3, Command: "rsp", Data: []byte("200 OK")},
+				{Txnr: 4, Command: "close"},
+			},
+		},
+		{
+			name:  "data containing separators",
+			input: "999999999 syslog 7 a b\nc d\n",
+			expected: []*Frame{
+				{Txnr: 999999999, Command: "syslog", Data: []byte("a b\nc d")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			for _, expected := range tt.expected {
+				frame, err := ReadFrame(r)
+				require.NoError(t, err)
+				require.Equal(t, expected, frame)
+
+				// Serializing must produce the original input
+				require.True(t, strings.HasPrefix(tt.input, string(frame.Bytes())))
+				tt.input = strings.TrimPrefix(tt.input, string(frame.Bytes()))
+			}
+			_, err := ReadFrame(r)
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "invalid transaction number",
+			input:    "a syslog 1 x\n",
+			expected: `invalid transaction number "a"`,
+		},
+		{
+			name:     "transaction number too long",
+			input:    "1234567890 syslog 1 x\n",
+			expected: "exceeds maximum length",
+		},
+		{
+			name:     "empty command",
+			input:    "1  1 x\n",
+			expected: "empty command",
+		},
+		{
+			name:     "invalid length",
+			input:    "1 syslog x x\n",
+			expected: `invalid data length "x"`,
+		},
+		{
+			name:     "length too large",
+			input:    "1 syslog 999999999 x\n",
+			expected: "exceeds maximum",
+		},
+		{
+			name:     "missing data",
+			input:    "1 syslog 2\n",
+			expected: "missing data of length 2",
+		},
+		{
+			name:     "invalid trailer",
+			input:    "1 syslog 1 xy",
+			expected: `invalid trailer 'y'`,
+		},
+		{
+			name:     "truncated",
+			input:    "1 syslog 10 x",
+			expected: "unexpected EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFrame(bufio.NewReader(strings.NewReader(tt.input)))
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	code, message, err := ParseResponse([]byte("200 OK\nrelp_version=0"))
+	require.NoError(t, err)
+	require.Equal(t, StatusOK, code)
+	require.Equal(t, "OK", message)
+
+	code, message, err = ParseResponse([]byte("500 message too large"))
+	require.NoError(t, err)
+	require.Equal(t, StatusError, code)
+	require.Equal(t, "message too large", message)
+
+	_, _, err = ParseResponse([]byte("OK"))
+	require.ErrorContains(t, err, `invalid response "OK"`)
+}
+
+func TestNextTxnr(t *testing.T) {
+	require.Equal(t, uint32(1), nextTxnr(0))
+	require.Equal(t, uint32(2), nextTxnr(1))
+	require.Equal(t, uint32(1), nextTxnr(maxTxnr))
+}
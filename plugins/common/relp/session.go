@@ -0,0 +1,233 @@
+package relp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrRefused is returned if the server did not acknowledge a message
+var ErrRefused = errors.New("message refused")
+
+// Client session sending syslog messages to a RELP server
+type Client struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	timeout time.Duration
+	txnr    uint32
+}
+
+// NewClient opens a session on the given connection. Each operation on the
+// connection fails if it takes longer than the timeout if non-zero.
+func NewClient(conn net.Conn, timeout time.Duration) (*Client, error) {
+	c := &Client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		writer:  bufio.NewWriter(conn),
+		timeout: timeout,
+	}
+
+	if err := c.write(&Frame{Txnr: c.next(), Command: CommandOpen, Data: []byte(offers)}); err != nil {
+		return nil, err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, err
+	}
+	rsp, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	code, message, err := ParseResponse(rsp.Data)
+	if err != nil {
+		return nil, err
+	}
+	if code != StatusOK {
+		return nil, fmt.Errorf("opening session refused: %d %s", code, message)
+	}
+	return c, nil
+}
+
+// Send sends the messages keeping at most window messages unacknowledged and
+// returns the indices of the messages acknowledged by the server. Messages
+// refused by the server are reported in an error wrapping ErrRefused but do
+// not stop sending the remaining messages. The client must be closed if
+// sending fails due to other errors as the session state is unknown.
+func (c *Client) Send(messages [][]byte, window int) ([]int, error) {
+	window = max(window, 1)
+	acked := make([]int, 0, len(messages))
+	pending := make(map[uint32]int, window)
+
+	var refused error
+	var next int
+	for next < len(messages) || len(pending) > 0 {
+		// Fill the window before waiting for responses
+		if next < len(messages) && len(pending) < window {
+			for next < len(messages) && len(pending) < window {
+				txnr := c.next()
+				if err := c.write(&Frame{Txnr: txnr, Command: CommandSyslog, Data: messages[next]}); err != nil {
+					return acked, err
+				}
+				pending[txnr] = next
+				next++
+			}
+			if err := c.writer.Flush(); err != nil {
+				return acked, err
+			}
+		}
+
+		rsp, err := c.read()
+		if err != nil {
+			return acked, err
+		}
+		idx, found := pending[rsp.Txnr]
+		if !found {
+			return acked, fmt.Errorf("response for unknown transaction %d", rsp.Txnr)
+		}
+		delete(pending, rsp.Txnr)
+
+		code, message, err := ParseResponse(rsp.Data)
+		if err != nil {
+			return acked, err
+		}
+		if code != StatusOK {
+			if refused == nil {
+				refused = fmt.Errorf("%w: %d %s", ErrRefused, code, message)
+			}
+			continue
+		}
+		acked = append(acked, idx)
+	}
+
+	return acked, refused
+}
+
+// Close closes the session and the connection
+func (c *Client) Close() error {
+	defer c.conn.Close()
+
+	if err := c.write(&Frame{Txnr: c.next(), Command: CommandClose}); err != nil {
+		return err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	_, err := c.read()
+	if errors.Is(err, errServerClose) {
+		return nil
+	}
+	return err
+}
+
+var errServerClose = errors.New("session closed by server")
+
+func (c *Client) next() uint32 {
+	c.txnr = nextTxnr(c.txnr)
+	return c.txnr
+}
+
+func (c *Client) write(frame *Frame) error {
+	if c.timeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+			return err
+		}
+	}
+	_, err := c.writer.Write(frame.Bytes())
+	return err
+}
+
+// read returns the next response of the server
+func (c *Client) read() (*Frame, error) {
+	if c.timeout > 0 {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+	}
+	frame, err := ReadFrame(c.reader)
+	if err != nil {
+		return nil, err
+	}
+	switch frame.Command {
+	case CommandResponse:
+		return frame, nil
+	case CommandServerClose:
+		return nil, errServerClose
+	}
+	return nil, fmt.Errorf("unexpected command %q", frame.Command)
+}
+
+// ServerSession handles a session of a client sending messages
+type ServerSession struct {
+	conn   io.ReadWriter
+	reader *bufio.Reader
+
+	// Responses are sent from the message handler and after processing the
+	// message asynchronously
+	sync.Mutex
+}
+
+// NewServerSession creates a session on the connection of a client
+func NewServerSession(conn io.ReadWriter) *ServerSession {
+	return &ServerSession{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// Serve handles the session until the client closes it or an error occurs.
+// The handler is called for each syslog message and the message must be
+// acknowledged using Respond, either directly or after processing the
+// message.
+func (s *ServerSession) Serve(onMessage func(txnr uint32, data []byte)) error {
+	var opened bool
+	for {
+		frame, err := ReadFrame(s.reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		switch frame.Command {
+		case CommandOpen:
+			opened = true
+			if err := s.respond(frame.Txnr, append(Response(StatusOK, "OK\n"), offers...)); err != nil {
+				return err
+			}
+		case CommandSyslog:
+			if !opened {
+				//nolint:errcheck // the session is terminated anyway
+				s.Respond(frame.Txnr, StatusError, "session not opened")
+				return errors.New("message received before opening session")
+			}
+			onMessage(frame.Txnr, frame.Data)
+		case CommandClose:
+			if err := s.respond(frame.Txnr, nil); err != nil {
+				return err
+			}
+			return nil
+		default:
+			if err := s.Respond(frame.Txnr, StatusError, "unsupported command"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Respond sends the response with the given status for the transaction
+func (s *ServerSession) Respond(txnr uint32, code int, message string) error {
+	return s.respond(txnr, Response(code, message))
+}
+
+func (s *ServerSession) respond(txnr uint32, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	_, err := s.conn.Write((&Frame{Txnr: txnr, Command: CommandResponse, Data: data}).Bytes())
+	return err
+}
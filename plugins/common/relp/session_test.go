@@ -0,0 +1,105 @@
+package relp
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// serve runs a server refusing messages containing "refuse" and returns the
+// address and a function waiting for the received messages and the result of
+// the session
+func serve(t *testing.T) (string, func() ([]string, error)) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var received []string
+	var serveErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		session := NewServerSession(conn)
+		serveErr = session.Serve(func(txnr uint32, data []byte) {
+			if bytes.Contains(data, []byte("refuse")) {
+				//nolint:errcheck // checked by the client
+				session.Respond(txnr, StatusError, "refused")
+				return
+			}
+			mu.Lock()
+			received = append(received, string(data))
+			mu.Unlock()
+
+			// Acknowledge asynchronously
+			go session.Respond(txnr, StatusOK, "OK") //nolint:errcheck // checked by the client
+		})
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		wg.Wait()
+	})
+
+	return listener.Addr().String(), func() ([]string, error) {
+		wg.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		return received, serveErr
+	}
+}
+
+func TestSession(t *testing.T) {
+	addr, received := serve(t)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	client, err := NewClient(conn, 5*time.Second)
+	require.NoError(t, err)
+
+	messages := [][]byte{
+		[]byte("<13>1 first"),
+		[]byte("<13>1 refuse"),
+		[]byte("<13>1 third"),
+		[]byte("<13>1 fourth"),
+		[]byte("<13>1 fifth"),
+	}
+	acked, err := client.Send(messages, 2)
+	require.ErrorIs(t, err, ErrRefused)
+	require.ErrorContains(t, err, "message refused: 500 refused")
+	require.ElementsMatch(t, []int{0, 2, 3, 4}, acked)
+	require.NoError(t, client.Close())
+
+	actual, err := received()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"<13>1 first", "<13>1 third", "<13>1 fourth", "<13>1 fifth"}, actual)
+}
+
+func TestSessionNotOpened(t *testing.T) {
+	addr, received := serve(t)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Skip opening the session
+	client := &Client{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	_, err = client.Send([][]byte{[]byte("<13>1 test")}, 1)
+	require.ErrorContains(t, err, "message refused: 500 session not opened")
+
+	actual, err := received()
+	require.ErrorContains(t, err, "message received before opening session")
+	require.Empty(t, actual)
+}
@@ -0,0 +1,47 @@
+// Package siem contains helpers shared by the parsers of event formats used
+// by security information and event management systems such as CEF and LEEF.
+package siem
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SplitHeader splits the given number of pipe-separated header fields, where
+// pipes and backslashes are escaped with a backslash, from the remainder
+func SplitHeader(line string, n int) (header []string, remainder string, err error) {
+	header = make([]string, 0, n)
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line) && (line[i+1] == '|' || line[i+1] == '\\'):
+			i++
+			field.WriteByte(line[i])
+		case c == '|':
+			header = append(header, strings.TrimSpace(field.String()))
+			field.Reset()
+			if len(header) == n {
+				return header, line[i+1:], nil
+			}
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("expected %d header fields but found %d", n, len(header))
+}
+
+// CurrentYear sets the year of timestamps parsed without year, i.e. with year
+// zero, to the current year in the location of the timestamp
+func CurrentYear(ts time.Time) time.Time {
+	if ts.Year() != 0 {
+		return ts
+	}
+	return withYear(ts, time.Now().In(ts.Location()).Year())
+}
+
+// withYear builds the date in the given year keeping the wall clock time of
+// the timestamp
+func withYear(ts time.Time, year int) time.Time {
+	return time.Date(year, ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), ts.Nanosecond(), ts.Location())
+}
@@ -0,0 +1,37 @@
+package siem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitHeader(t *testing.T) {
+	header, remainder, err := SplitHeader(`a|b\|c|d\\| e |rest|more`, 4)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b|c", `d\`, "e"}, header)
+	require.Equal(t, "rest|more", remainder)
+
+	_, _, err = SplitHeader("a|b|c", 4)
+	require.ErrorContains(t, err, "expected 4 header fields but found 2")
+}
+
+func TestCurrentYear(t *testing.T) {
+	ts := time.Date(2024, time.October, 11, 22, 14, 15, 0, time.UTC)
+	require.Equal(t, ts, CurrentYear(ts))
+
+	ts, err := time.Parse(time.Stamp, "Oct 11 22:14:15")
+	require.NoError(t, err)
+	expected := time.Date(time.Now().UTC().Year(), time.October, 11, 22, 14, 15, 0, time.UTC)
+	require.Equal(t, expected, CurrentYear(ts))
+}
+
+func TestWithYearLeapDay(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	ts, err := time.ParseInLocation(time.Stamp, "Feb 29 12:00:00", loc)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2028, time.February, 29, 12, 0, 0, 0, loc), withYear(ts, 2028))
+}
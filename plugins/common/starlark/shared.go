@@ -0,0 +1,103 @@
+package starlark
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// sharedStore holds values shared across all starlark plugin instances. The
+// values are stored as copies so instances never share mutable objects.
+var sharedStore = struct {
+	values map[string]interface{}
+	sync.Mutex
+}{values: make(map[string]interface{})}
+
+// SharedModule builds a module to exchange values between the instances of
+// the starlark plugins. The values are kept in memory only and are not
+// persisted.
+func SharedModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "shared",
+		Members: starlark.StringDict{
+			"get":    starlark.NewBuiltin("shared.get", sharedGet),
+			"set":    starlark.NewBuiltin("shared.set", sharedSet),
+			"delete": starlark.NewBuiltin("shared.delete", sharedDelete),
+			"keys":   starlark.NewBuiltin("shared.keys", sharedKeys),
+		},
+	}
+}
+
+// get(key, default=None) returns a copy of the value stored for the key
+func sharedGet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var dflt starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "default?", &dflt); err != nil {
+		return starlark.None, err
+	}
+
+	sharedStore.Lock()
+	value, found := sharedStore.values[key]
+	sharedStore.Unlock()
+	if !found {
+		return dflt, nil
+	}
+	return fromStateValue(value)
+}
+
+// set(key, value) stores a copy of the value for the key
+func sharedSet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var value starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "value", &value); err != nil {
+		return starlark.None, err
+	}
+
+	v, err := asStateValue(value)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%s: value of type %s cannot be shared: %w", b.Name(), value.Type(), err)
+	}
+
+	sharedStore.Lock()
+	sharedStore.values[key] = v
+	sharedStore.Unlock()
+	return starlark.None, nil
+}
+
+// delete(key) removes the value for the key and returns if the key existed
+func sharedDelete(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key); err != nil {
+		return starlark.None, err
+	}
+
+	sharedStore.Lock()
+	_, found := sharedStore.values[key]
+	delete(sharedStore.values, key)
+	sharedStore.Unlock()
+	return starlark.Bool(found), nil
+}
+
+// keys() returns the sorted list of keys
+func sharedKeys(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, err
+	}
+
+	sharedStore.Lock()
+	keys := make([]string, 0, len(sharedStore.values))
+	for k := range sharedStore.values {
+		keys = append(keys, k)
+	}
+	sharedStore.Unlock()
+	slices.Sort(keys)
+
+	list := make([]starlark.Value, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, starlark.String(k))
+	}
+	return starlark.NewList(list), nil
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"go.starlark.net/lib/json"
 	"go.starlark.net/lib/math"
@@ -17,22 +20,41 @@ import (
 )
 
 type Common struct {
-	Source    string                 `toml:"source"`
-	Script    string                 `toml:"script"`
-	Constants map[string]interface{} `toml:"constants"`
+	Source       string                 `toml:"source"`
+	Script       string                 `toml:"script"`
+	Constants    map[string]interface{} `toml:"constants"`
+	LibraryPaths []string               `toml:"library_paths"`
 
 	Log              telegraf.Logger `toml:"-"`
 	StarlarkLoadFunc func(module string, logger telegraf.Logger) (starlark.StringDict, error)
 
+	// Builtins contains additional plugin-specific builtins available to the
+	// script and loaded library modules
+	Builtins starlark.StringDict `toml:"-"`
+
 	thread     *starlark.Thread
 	builtins   starlark.StringDict
 	globals    starlark.StringDict
 	functions  map[string]*starlark.Function
 	parameters map[string]starlark.Tuple
 	state      *starlark.Dict
+	modules    map[string]*libraryModule
+
+	// mu serializes running the script and accessing the state as the state
+	// is saved periodically while the script is running
+	mu sync.Mutex
+}
+
+// libraryModule is a module loaded from one of the library paths
+type libraryModule struct {
+	globals starlark.StringDict
+	err     error
 }
 
 func (s *Common) GetState() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Return the actual byte-type instead of nil allowing the persister
 	// to guess instantiate variable of the appropriate type
 	if s.state == nil {
@@ -54,7 +76,7 @@ func (s *Common) GetState() interface{} {
 			s.Log.Errorf("state item %+v has invalid key type %T", item, item.Index(0))
 			continue
 		}
-		v, err := asGoValue(item.Index(1))
+		v, err := asStateValue(item.Index(1))
 		if err != nil {
 			s.Log.Errorf("state item %+v value cannot be converted: %v", item, err)
 			continue
@@ -90,7 +112,7 @@ func (s *Common) SetState(state interface{}) error {
 	// Convert the golang dict back to starlark types
 	s.state = starlark.NewDict(len(dict))
 	for k, v := range dict {
-		sv, err := asStarlarkValue(v)
+		sv, err := fromStateValue(v)
 		if err != nil {
 			return fmt.Errorf("value %v of state item %q cannot be set: %w", v, k, err)
 		}
@@ -115,11 +137,25 @@ func (s *Common) Init() error {
 	s.builtins["Metric"] = starlark.NewBuiltin("Metric", newMetric)
 	s.builtins["deepcopy"] = starlark.NewBuiltin("deepcopy", deepcopy)
 	s.builtins["catch"] = starlark.NewBuiltin("catch", catch)
+	s.builtins["Window"] = starlark.NewBuiltin("Window", newWindow)
+	for name, value := range s.Builtins {
+		s.builtins[name] = value
+	}
 
 	if err := s.addConstants(&s.builtins); err != nil {
 		return err
 	}
 
+	for _, path := range s.LibraryPaths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("invalid library path: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("library path %q is not a directory", path)
+		}
+	}
+
 	// Initialize the program
 	if err := s.InitProgram(); err != nil {
 		// Try again with a declared state. This might be necessary for
@@ -146,11 +182,10 @@ func (s *Common) InitProgram() error {
 	}
 
 	// Execute source
+	s.modules = make(map[string]*libraryModule)
 	s.thread = &starlark.Thread{
 		Print: func(_ *starlark.Thread, msg string) { s.Log.Debug(msg) },
-		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
-			return s.StarlarkLoadFunc(module, s.Log)
-		},
+		Load:  s.load,
 	}
 	globals, err := program.Init(s.thread, s.builtins)
 	if err != nil {
@@ -221,17 +256,70 @@ func (s *Common) sourceProgram(builtins starlark.StringDict) (*starlark.Program,
 		src = s.Source
 	}
 
+	_, program, err := starlark.SourceProgramOptions(fileOptions(), s.Script, src, builtins.Has)
+	return program, err
+}
+
+// load returns the members of the given module. Builtin modules take
+// precedence over modules found in the configured library paths.
+func (s *Common) load(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	members, err := s.StarlarkLoadFunc(module, s.Log)
+	if err == nil {
+		return members, nil
+	}
+
+	filename, found := s.findLibrary(module)
+	if !found {
+		return nil, err
+	}
+
+	// Only load each module once and detect cyclic loads
+	if m, loaded := s.modules[filename]; loaded {
+		if m == nil {
+			return nil, fmt.Errorf("cycle in loading module %q", module)
+		}
+		return m.globals, m.err
+	}
+	s.modules[filename] = nil
+
+	t := &starlark.Thread{
+		Name:  "load " + module,
+		Print: thread.Print,
+		Load:  thread.Load,
+	}
+	globals, err := starlark.ExecFileOptions(fileOptions(), t, filename, nil, s.builtins)
+	if err == nil {
+		globals.Freeze()
+	}
+	s.modules[filename] = &libraryModule{globals: globals, err: err}
+	return globals, err
+}
+
+// findLibrary returns the file of the module in the first library path
+// containing the module. Modules must be relative to the library path and
+// cannot reference files outside of it.
+func (s *Common) findLibrary(module string) (string, bool) {
+	if !filepath.IsLocal(module) {
+		return "", false
+	}
+	for _, path := range s.LibraryPaths {
+		filename := filepath.Join(path, module)
+		if info, err := os.Stat(filename); err == nil && info.Mode().IsRegular() {
+			return filename, true
+		}
+	}
+	return "", false
+}
+
+func fileOptions() *syntax.FileOptions {
 	// AllowFloat - obsolete, no effect
 	// AllowNestedDef - always on https://github.com/google/starlark-go/pull/328
 	// AllowLambda - always on https://github.com/google/starlark-go/pull/328
-	options := syntax.FileOptions{
+	return &syntax.FileOptions{
 		Recursion:      true,
 		GlobalReassign: true,
 		Set:            true,
 	}
-
-	_, program, err := starlark.SourceProgramOptions(&options, s.Script, src, builtins.Has)
-	return program, err
 }
 
 // Call calls the function corresponding to the given name.
@@ -244,6 +332,9 @@ func (s *Common) Call(name string) (starlark.Value, error) {
 	if !ok {
 		return nil, fmt.Errorf("params for function %q do not exist", name)
 	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return starlark.Call(s.thread, fn, args, nil)
 }
 
@@ -272,6 +363,10 @@ func LoadFunc(module string, logger telegraf.Logger) (starlark.StringDict, error
 		return starlark.StringDict{
 			"math": math.Module,
 		}, nil
+	case "shared.star":
+		return starlark.StringDict{
+			"shared": SharedModule(),
+		}, nil
 	case "time.star":
 		return starlark.StringDict{
 			"time": time.Module,
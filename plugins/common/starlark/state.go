@@ -0,0 +1,178 @@
+package starlark
+
+import (
+	"encoding/gob"
+	"time"
+
+	"go.starlark.net/starlark"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Types used to persist container values of the state. The types are
+// registered for GOB encoding as they are stored in interface values.
+type (
+	stateList  []interface{}
+	stateTuple []interface{}
+	stateDict  []stateItem
+)
+
+type stateItem struct {
+	Key   interface{}
+	Value interface{}
+}
+
+type stateMetric struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]interface{}
+	Time   int64
+	Type   telegraf.ValueType
+}
+
+type stateWindow struct {
+	Period  time.Duration
+	Buckets map[int64][]stateMetric
+}
+
+func init() {
+	gob.Register(stateList{})
+	gob.Register(stateTuple{})
+	gob.Register(stateDict{})
+	gob.Register(stateMetric{})
+	gob.Register(stateWindow{})
+}
+
+// asStateValue converts a starlark.Value to a value suitable for persisting
+// the state including containers, metrics and windows.
+func asStateValue(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *starlark.List:
+		list := make(stateList, 0, v.Len())
+		for i := range v.Len() {
+			item, err := asStateValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case starlark.Tuple:
+		tuple := make(stateTuple, 0, len(v))
+		for _, e := range v {
+			item, err := asStateValue(e)
+			if err != nil {
+				return nil, err
+			}
+			tuple = append(tuple, item)
+		}
+		return tuple, nil
+	case *starlark.Dict:
+		dict := make(stateDict, 0, v.Len())
+		for _, kv := range v.Items() {
+			key, err := asStateValue(kv[0])
+			if err != nil {
+				return nil, err
+			}
+			val, err := asStateValue(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			dict = append(dict, stateItem{Key: key, Value: val})
+		}
+		return dict, nil
+	case *Metric:
+		return asStateMetric(v.metric), nil
+	case *Window:
+		window := stateWindow{
+			Period:  v.period,
+			Buckets: make(map[int64][]stateMetric, len(v.buckets)),
+		}
+		for start, metrics := range v.buckets {
+			persisted := make([]stateMetric, 0, len(metrics))
+			for _, m := range metrics {
+				persisted = append(persisted, asStateMetric(m))
+			}
+			window.Buckets[start] = persisted
+		}
+		return window, nil
+	}
+	return asGoValue(value)
+}
+
+// fromStateValue converts a persisted state value back to a starlark.Value
+func fromStateValue(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case stateList:
+		list := make([]starlark.Value, 0, len(v))
+		for _, e := range v {
+			item, err := fromStateValue(e)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return starlark.NewList(list), nil
+	case stateTuple:
+		tuple := make(starlark.Tuple, 0, len(v))
+		for _, e := range v {
+			item, err := fromStateValue(e)
+			if err != nil {
+				return nil, err
+			}
+			tuple = append(tuple, item)
+		}
+		return tuple, nil
+	case stateDict:
+		dict := starlark.NewDict(len(v))
+		for _, kv := range v {
+			key, err := fromStateValue(kv.Key)
+			if err != nil {
+				return nil, err
+			}
+			val, err := fromStateValue(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(key, val); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case stateMetric:
+		return &Metric{metric: v.metric()}, nil
+	case stateWindow:
+		window := &Window{
+			period:  v.Period,
+			buckets: make(map[int64][]telegraf.Metric, len(v.Buckets)),
+		}
+		for start, persisted := range v.Buckets {
+			metrics := make([]telegraf.Metric, 0, len(persisted))
+			for _, m := range persisted {
+				metrics = append(metrics, m.metric())
+			}
+			window.buckets[start] = metrics
+		}
+		return window, nil
+	}
+	return asStarlarkValue(value)
+}
+
+func asStateMetric(m telegraf.Metric) stateMetric {
+	return stateMetric{
+		Name:   m.Name(),
+		Tags:   m.Tags(),
+		Fields: m.Fields(),
+		Time:   m.Time().UnixNano(),
+		Type:   m.Type(),
+	}
+}
+
+func (m stateMetric) metric() telegraf.Metric {
+	return metric.New(m.Name, m.Tags, m.Fields, time.Unix(0, m.Time), m.Type)
+}
@@ -0,0 +1,218 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	starlarktime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Window is a starlark.Value buffering metrics in tumbling time windows. The
+// windows have a fixed period and are aligned to the epoch.
+type Window struct {
+	period  time.Duration
+	buckets map[int64][]telegraf.Metric
+	frozen  bool
+}
+
+// newWindow creates a new window, e.g. Window("1m") or Window(period=60000000000)
+func newWindow(_ *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var period starlark.Value
+	if err := starlark.UnpackArgs("Window", args, kwargs, "period", &period); err != nil {
+		return nil, err
+	}
+
+	d, err := toDuration(period)
+	if err != nil {
+		return nil, fmt.Errorf("Window: %w", err)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("Window: period must be positive but is %s", d)
+	}
+
+	return &Window{period: d, buckets: make(map[int64][]telegraf.Metric)}, nil
+}
+
+func (w *Window) String() string {
+	return fmt.Sprintf("Window(period=%s, windows=%d, metrics=%d)", w.period, len(w.buckets), w.count())
+}
+
+func (*Window) Type() string {
+	return "Window"
+}
+
+func (w *Window) Freeze() {
+	w.frozen = true
+}
+
+func (w *Window) Truth() starlark.Bool {
+	return len(w.buckets) != 0
+}
+
+func (*Window) Hash() (uint32, error) {
+	return 0, errors.New("not hashable")
+}
+
+// count returns the number of buffered metrics
+func (w *Window) count() int {
+	var n int
+	for _, metrics := range w.buckets {
+		n += len(metrics)
+	}
+	return n
+}
+
+// AttrNames implements the starlark.HasAttrs interface.
+func (w *Window) AttrNames() []string {
+	return append(builtinAttrNames(windowMethods), "period")
+}
+
+// Attr implements the starlark.HasAttrs interface.
+func (w *Window) Attr(name string) (starlark.Value, error) {
+	if name == "period" {
+		return starlarktime.Duration(w.period), nil
+	}
+	return builtinAttr(w, name, windowMethods)
+}
+
+var windowMethods = map[string]builtinMethod{
+	"add":     windowAdd,
+	"expired": windowExpired,
+	"flush":   windowFlush,
+}
+
+// add(metric) buffers a copy of the metric in the window its timestamp
+// belongs to. Tracking information is not copied.
+func windowAdd(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var sm *Metric
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &sm); err != nil {
+		return starlark.None, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	w := b.Receiver().(*Window)
+	if w.frozen {
+		return starlark.None, errors.New("cannot modify frozen window")
+	}
+
+	m := sm.metric
+	if tm, ok := m.(telegraf.TrackingMetric); ok {
+		m = tm.Unwrap()
+	}
+	m = m.Copy()
+
+	start := w.start(m.Time())
+	w.buckets[start] = append(w.buckets[start], m)
+	return starlark.None, nil
+}
+
+// expired(now=None) removes and returns all windows ending before or at the
+// given time (default: current time) as list of (start, metrics) tuples
+// ordered by the window start.
+func windowExpired(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var now starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "now?", &now); err != nil {
+		return starlark.None, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	t, err := toTime(now)
+	if err != nil {
+		return starlark.None, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	w := b.Receiver().(*Window)
+	if w.frozen {
+		return starlark.None, errors.New("cannot modify frozen window")
+	}
+	return w.pop(func(start int64) bool {
+		return start+int64(w.period) <= t.UnixNano()
+	}), nil
+}
+
+// flush() removes and returns all windows as list of (start, metrics) tuples
+// ordered by the window start.
+func windowFlush(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	w := b.Receiver().(*Window)
+	if w.frozen {
+		return starlark.None, errors.New("cannot modify frozen window")
+	}
+	return w.pop(func(int64) bool { return true }), nil
+}
+
+// start returns the start of the window the given time belongs to in
+// nanoseconds since the epoch
+func (w *Window) start(t time.Time) int64 {
+	ts := t.UnixNano()
+	offset := ts % int64(w.period)
+	if offset < 0 {
+		offset += int64(w.period)
+	}
+	return ts - offset
+}
+
+func (w *Window) pop(selected func(start int64) bool) *starlark.List {
+	starts := make([]int64, 0, len(w.buckets))
+	for start := range w.buckets {
+		if selected(start) {
+			starts = append(starts, start)
+		}
+	}
+	slices.Sort(starts)
+
+	windows := make([]starlark.Value, 0, len(starts))
+	for _, start := range starts {
+		metrics := make([]starlark.Value, 0, len(w.buckets[start]))
+		for _, m := range w.buckets[start] {
+			metrics = append(metrics, &Metric{metric: m})
+		}
+		windows = append(windows, starlark.Tuple{
+			starlark.MakeInt64(start),
+			starlark.NewList(metrics),
+		})
+		delete(w.buckets, start)
+	}
+	return starlark.NewList(windows)
+}
+
+// toDuration converts a duration string, an integer in nanoseconds or a
+// duration of the time module to a duration
+func toDuration(value starlark.Value) (time.Duration, error) {
+	switch v := value.(type) {
+	case starlark.String:
+		return time.ParseDuration(v.GoString())
+	case starlark.Int:
+		n, ok := v.Int64()
+		if !ok {
+			return 0, fmt.Errorf("duration %v out of range", v)
+		}
+		return time.Duration(n), nil
+	case starlarktime.Duration:
+		return time.Duration(v), nil
+	}
+	return 0, fmt.Errorf("invalid type %s for duration", value.Type())
+}
+
+// toTime converts an integer timestamp in nanoseconds or a time of the time
+// module to a time, None is converted to the current time
+func toTime(value starlark.Value) (time.Time, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return time.Now(), nil
+	case starlark.Int:
+		n, ok := v.Int64()
+		if !ok {
+			return time.Time{}, fmt.Errorf("timestamp %v out of range", v)
+		}
+		return time.Unix(0, n), nil
+	case starlarktime.Time:
+		return time.Time(v), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid type %s for time", value.Type())
+}
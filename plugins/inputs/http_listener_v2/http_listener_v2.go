@@ -271,7 +271,13 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	metrics, err := h.Parse(bytes)
+	var metrics []telegraf.Metric
+	var err error
+	if hp, ok := h.Parser.(telegraf.HeaderParser); ok && strings.ToLower(h.DataSource) != query {
+		metrics, err = hp.ParseWithHeader(bytes, req.Header)
+	} else {
+		metrics, err = h.Parse(bytes)
+	}
 	if err != nil {
 		h.Log.Debugf("Parse error: %s", err.Error())
 		if err := badRequest(res); err != nil {
@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/parsers/cloudevents"
 	"github.com/influxdata/telegraf/plugins/parsers/form_urlencoded"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/testutil"
@@ -712,6 +713,48 @@ func TestWriteHTTPFormData(t *testing.T) {
 	)
 }
 
+func TestWriteHTTPCloudEventsBinaryMode(t *testing.T) {
+	parser := &cloudevents.Parser{
+		DataFormat: "influx",
+		TypeTag:    "type",
+		SourceTag:  "source",
+	}
+	require.NoError(t, parser.Init())
+
+	listener, err := newTestHTTPListenerV2()
+	require.NoError(t, err)
+	listener.Parser = parser
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Init())
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBufferString(testMsgNoNewline))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", "4711")
+	req.Header.Set("Ce-Source", "/servers/01")
+	req.Header.Set("Ce-Type", "com.example.metric")
+	req.Header.Set("Ce-Tenant", "acme")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(12)},
+		map[string]string{
+			"host":   "server01",
+			"source": "/servers/01",
+			"type":   "com.example.metric",
+			"tenant": "acme",
+		},
+	)
+}
+
 func TestServerHeaders(t *testing.T) {
 	listener, err := newTestHTTPListenerV2()
 	require.NoError(t, err)
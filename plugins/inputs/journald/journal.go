@@ -0,0 +1,428 @@
+//go:build linux
+
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// The journal file format is described in
+// https://systemd.io/JOURNAL_FILE_FORMAT/
+
+var signature = []byte("LPKSHHRH")
+
+// Incompatible flags of the file header
+const (
+	incompatibleCompressedXZ   = 1 << 0
+	incompatibleCompressedLZ4  = 1 << 1
+	incompatibleKeyedHash      = 1 << 2
+	incompatibleCompressedZSTD = 1 << 3
+	incompatibleCompact        = 1 << 4
+	incompatibleSupported      = incompatibleCompressedXZ | incompatibleCompressedLZ4 | incompatibleKeyedHash |
+		incompatibleCompressedZSTD | incompatibleCompact
+)
+
+// Object types and flags
+const (
+	objectData       = 1
+	objectEntry      = 3
+	objectEntryArray = 6
+
+	objectCompressedXZ   = 1 << 0
+	objectCompressedLZ4  = 1 << 1
+	objectCompressedZSTD = 1 << 2
+)
+
+// Sizes of the fixed parts of the objects
+const (
+	headerMinSize         = 208
+	objectHeaderSize      = 16
+	dataObjectSize        = 64
+	dataObjectCompactSize = 72
+	entryObjectSize       = 64
+	entryArrayObjectSize  = 24
+
+	// Upper limit for the size of objects to protect against corrupted files
+	maxObjectSize = 64 * 1024 * 1024
+)
+
+// Offset of the first entry array in the file header
+const headerEntryArrayOffset = 176
+
+// Offset and value of the file state in the header marking files not written
+// anymore
+const (
+	headerStateOffset = 16
+	stateArchived     = 2
+)
+
+// Errors of files that cannot be read at all, in contrast to other errors
+// which might be caused by the file being written concurrently
+var (
+	errCorrupted   = errors.New("corrupted journal file")
+	errNotJournal  = errors.New("not a journal file")
+	errUnsupported = errors.New("unsupported journal file")
+)
+
+type id128 [16]byte
+
+func (id id128) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// entry of the journal, the fields contain the first value of each field
+type entry struct {
+	seqnumID  id128
+	seqnum    uint64
+	realtime  uint64
+	monotonic uint64
+	bootID    id128
+	xorHash   uint64
+	fields    map[string]string
+}
+
+// cursor returns the position of the entry in the format used by journalctl
+func (e *entry) cursor() string {
+	return fmt.Sprintf("s=%s;i=%x;b=%s;m=%x;t=%x;x=%x", e.seqnumID, e.seqnum, e.bootID, e.monotonic, e.realtime, e.xorHash)
+}
+
+// cursor to resume reading the journal
+type cursor struct {
+	seqnumID id128
+	seqnum   uint64
+	realtime uint64
+}
+
+func parseCursor(s string) (*cursor, error) {
+	var c cursor
+	var hasSeqnum, hasRealtime bool
+	for _, part := range strings.Split(s, ";") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid cursor element %q", part)
+		}
+		var err error
+		switch key {
+		case "s":
+			var id []byte
+			if id, err = hex.DecodeString(value); err == nil && len(id) != len(c.seqnumID) {
+				err = errors.New("invalid length")
+			}
+			copy(c.seqnumID[:], id)
+		case "i":
+			c.seqnum, err = strconv.ParseUint(value, 16, 64)
+			hasSeqnum = true
+		case "t":
+			c.realtime, err = strconv.ParseUint(value, 16, 64)
+			hasRealtime = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor element %q: %w", part, err)
+		}
+	}
+	if !hasSeqnum || !hasRealtime {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	return &c, nil
+}
+
+// after returns true if the entry was written after the cursor position.
+// Sequence numbers are only comparable within the same sequence number ID,
+// otherwise the realtime is used.
+func (c *cursor) after(e *entry) bool {
+	if e.seqnumID == c.seqnumID {
+		return e.seqnum > c.seqnum
+	}
+	return e.realtime > c.realtime
+}
+
+// journalFile reads the entries of a single journal file in the order they
+// were written. The file might be written concurrently by journald, so the
+// header is read again whenever the known entries are exhausted.
+type journalFile struct {
+	path         string
+	file         *os.File
+	fileID       id128
+	seqnumID     id128
+	incompatible uint32
+
+	// Position of the next entry in the chain of entry arrays
+	arrayOffset uint64
+	arrayIndex  uint64
+
+	// Cache of parsed data objects as entries share most of their data
+	cache map[uint64][2]string
+
+	zstd *zstd.Decoder
+}
+
+func openJournalFile(path string) (*journalFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, headerMinSize)
+	if _, err := file.ReadAt(buf, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading header failed: %w", err)
+	}
+	if !bytes.Equal(buf[0:8], signature) {
+		file.Close()
+		return nil, errNotJournal
+	}
+
+	jf := &journalFile{
+		path:         path,
+		file:         file,
+		incompatible: binary.LittleEndian.Uint32(buf[12:16]),
+		cache:        make(map[uint64][2]string),
+	}
+	if unsupported := jf.incompatible &^ incompatibleSupported; unsupported != 0 {
+		file.Close()
+		return nil, fmt.Errorf("%w: incompatible flags %#x", errUnsupported, unsupported)
+	}
+	copy(jf.fileID[:], buf[24:40])
+	copy(jf.seqnumID[:], buf[72:88])
+
+	return jf, nil
+}
+
+func (jf *journalFile) close() error {
+	if jf.zstd != nil {
+		jf.zstd.Close()
+	}
+	return jf.file.Close()
+}
+
+// archived returns true if journald finished writing the file
+func (jf *journalFile) archived() bool {
+	buf := make([]byte, 1)
+	if _, err := jf.file.ReadAt(buf, headerStateOffset); err != nil {
+		return false
+	}
+	return buf[0] == stateArchived
+}
+
+func (jf *journalFile) compact() bool {
+	return jf.incompatible&incompatibleCompact != 0
+}
+
+// next returns the next entry of the file or nil if there is none
+func (jf *journalFile) next() (*entry, error) {
+	offset, err := jf.nextOffset()
+	if err != nil || offset == 0 {
+		return nil, err
+	}
+	return jf.readEntry(offset)
+}
+
+// nextOffset returns the offset of the next entry object and advances the
+// position or returns zero if no new entry exists
+func (jf *journalFile) nextOffset() (uint64, error) {
+	if jf.arrayOffset == 0 {
+		// The first entry array is created with the first entry
+		buf := make([]byte, 8)
+		if _, err := jf.file.ReadAt(buf, headerEntryArrayOffset); err != nil {
+			return 0, fmt.Errorf("reading header failed: %w", err)
+		}
+		jf.arrayOffset = binary.LittleEndian.Uint64(buf)
+		if jf.arrayOffset == 0 {
+			return 0, nil
+		}
+	}
+
+	itemSize := uint64(8)
+	if jf.compact() {
+		itemSize = 4
+	}
+
+	for {
+		typ, size, err := jf.readObjectHeader(jf.arrayOffset)
+		if err != nil {
+			return 0, err
+		}
+		if typ != objectEntryArray || size < entryArrayObjectSize {
+			return 0, fmt.Errorf("%w: invalid entry array at %d", errCorrupted, jf.arrayOffset)
+		}
+
+		if n := (size - entryArrayObjectSize) / itemSize; jf.arrayIndex < n {
+			buf := make([]byte, itemSize)
+			if _, err := jf.file.ReadAt(buf, int64(jf.arrayOffset+entryArrayObjectSize+jf.arrayIndex*itemSize)); err != nil {
+				return 0, err
+			}
+			var offset uint64
+			if jf.compact() {
+				offset = uint64(binary.LittleEndian.Uint32(buf))
+			} else {
+				offset = binary.LittleEndian.Uint64(buf)
+			}
+			// Unused items at the end of the array are zero
+			if offset == 0 {
+				return 0, nil
+			}
+			jf.arrayIndex++
+			return offset, nil
+		}
+
+		// Continue with the next array of the chain if any
+		buf := make([]byte, 8)
+		if _, err := jf.file.ReadAt(buf, int64(jf.arrayOffset+objectHeaderSize)); err != nil {
+			return 0, err
+		}
+		next := binary.LittleEndian.Uint64(buf)
+		if next == 0 {
+			return 0, nil
+		}
+		jf.arrayOffset = next
+		jf.arrayIndex = 0
+	}
+}
+
+func (jf *journalFile) readObjectHeader(offset uint64) (typ uint8, size uint64, err error) {
+	buf := make([]byte, objectHeaderSize)
+	if _, err := jf.file.ReadAt(buf, int64(offset)); err != nil {
+		return 0, 0, fmt.Errorf("reading object at %d failed: %w", offset, err)
+	}
+	size = binary.LittleEndian.Uint64(buf[8:16])
+	if size > maxObjectSize {
+		return 0, 0, fmt.Errorf("%w: object at %d too large", errCorrupted, offset)
+	}
+	return buf[0], size, nil
+}
+
+func (jf *journalFile) readObject(offset uint64, expected uint8, minSize uint64) ([]byte, error) {
+	typ, size, err := jf.readObjectHeader(offset)
+	if err != nil {
+		return nil, err
+	}
+	if typ != expected || size < minSize {
+		return nil, fmt.Errorf("%w: unexpected object type %d at %d", errCorrupted, typ, offset)
+	}
+	buf := make([]byte, size)
+	if _, err := jf.file.ReadAt(buf, int64(offset)); err != nil {
+		return nil, fmt.Errorf("reading object at %d failed: %w", offset, err)
+	}
+	return buf, nil
+}
+
+func (jf *journalFile) readEntry(offset uint64) (*entry, error) {
+	buf, err := jf.readObject(offset, objectEntry, entryObjectSize)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{
+		seqnumID:  jf.seqnumID,
+		seqnum:    binary.LittleEndian.Uint64(buf[16:24]),
+		realtime:  binary.LittleEndian.Uint64(buf[24:32]),
+		monotonic: binary.LittleEndian.Uint64(buf[32:40]),
+		xorHash:   binary.LittleEndian.Uint64(buf[56:64]),
+		fields:    make(map[string]string),
+	}
+	copy(e.bootID[:], buf[40:56])
+
+	itemSize := 16
+	if jf.compact() {
+		itemSize = 4
+	}
+	for items := buf[entryObjectSize:]; len(items) >= itemSize; items = items[itemSize:] {
+		var dataOffset uint64
+		if jf.compact() {
+			dataOffset = uint64(binary.LittleEndian.Uint32(items))
+		} else {
+			dataOffset = binary.LittleEndian.Uint64(items)
+		}
+		field, err := jf.readData(dataOffset)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := e.fields[field[0]]; !found {
+			e.fields[field[0]] = field[1]
+		}
+	}
+
+	return e, nil
+}
+
+// readData returns the name and value of the field stored in the data object
+func (jf *journalFile) readData(offset uint64) ([2]string, error) {
+	if field, found := jf.cache[offset]; found {
+		return field, nil
+	}
+
+	start := uint64(dataObjectSize)
+	if jf.compact() {
+		start = dataObjectCompactSize
+	}
+	buf, err := jf.readObject(offset, objectData, start)
+	if err != nil {
+		return [2]string{}, err
+	}
+	payload, err := jf.decompress(buf[1], buf[start:])
+	if err != nil {
+		return [2]string{}, fmt.Errorf("decompressing data at %d failed: %w", offset, err)
+	}
+
+	name, value, found := bytes.Cut(payload, []byte("="))
+	if !found {
+		return [2]string{}, fmt.Errorf("%w: invalid field at %d", errCorrupted, offset)
+	}
+	field := [2]string{string(name), string(value)}
+
+	// Limit the memory used by the cache for long-running files
+	if len(jf.cache) >= 10000 {
+		clear(jf.cache)
+	}
+	jf.cache[offset] = field
+
+	return field, nil
+}
+
+func (jf *journalFile) decompress(flags uint8, payload []byte) ([]byte, error) {
+	switch {
+	case flags&objectCompressedZSTD != 0:
+		if jf.zstd == nil {
+			decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			jf.zstd = decoder
+		}
+		return jf.zstd.DecodeAll(payload, nil)
+	case flags&objectCompressedLZ4 != 0:
+		// The block is prefixed by the size of the uncompressed data
+		if len(payload) < 8 {
+			return nil, errCorrupted
+		}
+		size := binary.LittleEndian.Uint64(payload[:8])
+		if size > maxObjectSize {
+			return nil, errCorrupted
+		}
+		buf := make([]byte, size)
+		n, err := lz4.UncompressBlock(payload[8:], buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case flags&objectCompressedXZ != 0:
+		r, err := xz.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(r, maxObjectSize))
+	}
+	return payload, nil
+}
@@ -0,0 +1,570 @@
+//go:generate ../../../tools/readme_config_includer/generator
+//go:build linux
+
+package journald
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+// Journal fields with integer values
+var integerFields = map[string]bool{
+	"PRIORITY":        true,
+	"SYSLOG_FACILITY": true,
+	"SYSLOG_PID":      true,
+	"ERRNO":           true,
+	"CODE_LINE":       true,
+	"_PID":            true,
+	"_UID":            true,
+	"_GID":            true,
+	"_AUDIT_SESSION":  true,
+	"_AUDIT_LOGINUID": true,
+}
+
+// Syslog priorities as used by journalctl
+var priorities = map[string]int64{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+type Journald struct {
+	Paths                 []string        `toml:"paths"`
+	Units                 []string        `toml:"units"`
+	Identifiers           []string        `toml:"identifiers"`
+	Priority              string          `toml:"priority"`
+	FromBeginning         bool            `toml:"from_beginning"`
+	TagFields             []string        `toml:"tag_fields"`
+	Fields                []string        `toml:"fields"`
+	PollInterval          config.Duration `toml:"poll_interval"`
+	MaxUndeliveredEntries int             `toml:"max_undelivered_entries"`
+	Log                   telegraf.Logger `toml:"-"`
+
+	unitFilter       filter.Filter
+	identifierFilter filter.Filter
+	maxPriority      int64
+
+	files map[id128]*journalFile
+	bad   map[string]bool
+	retry map[string]func(*entry) bool
+
+	acc telegraf.TrackingAccumulator
+	sem chan struct{}
+
+	// Position of the last entry delivered in journalctl cursor format and
+	// the entries read but not yet delivered in reading order
+	position string
+	pending  []*pendingEntry
+	tracked  map[telegraf.TrackingID]*pendingEntry
+	sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// pendingEntry is an entry not yet delivered. Entries not matching the
+// filters are done immediately but still delay advancing the position until
+// the preceding entries are delivered.
+type pendingEntry struct {
+	cursor string
+	done   bool
+}
+
+func (*Journald) SampleConfig() string {
+	return sampleConfig
+}
+
+func (j *Journald) Init() error {
+	if len(j.Paths) == 0 {
+		j.Paths = []string{"/var/log/journal", "/run/log/journal"}
+	}
+
+	var err error
+	if j.unitFilter, err = filter.Compile(j.Units); err != nil {
+		return fmt.Errorf("invalid 'units' setting: %w", err)
+	}
+	if j.identifierFilter, err = filter.Compile(j.Identifiers); err != nil {
+		return fmt.Errorf("invalid 'identifiers' setting: %w", err)
+	}
+
+	j.maxPriority = -1
+	if j.Priority != "" {
+		if p, found := priorities[j.Priority]; found {
+			j.maxPriority = p
+		} else if p, err := strconv.ParseInt(j.Priority, 10, 64); err == nil && p >= 0 && p <= 7 {
+			j.maxPriority = p
+		} else {
+			return fmt.Errorf("invalid 'priority' setting %q", j.Priority)
+		}
+	}
+
+	if j.TagFields == nil {
+		j.TagFields = []string{"_HOSTNAME", "_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER"}
+	}
+	if j.Fields == nil {
+		j.Fields = []string{"MESSAGE", "PRIORITY", "_PID"}
+	}
+
+	if j.PollInterval <= 0 {
+		j.PollInterval = config.Duration(time.Second)
+	}
+	if j.MaxUndeliveredEntries <= 0 {
+		j.MaxUndeliveredEntries = 1000
+	}
+	j.sem = make(chan struct{}, j.MaxUndeliveredEntries)
+	j.tracked = make(map[telegraf.TrackingID]*pendingEntry)
+
+	j.files = make(map[id128]*journalFile)
+	j.bad = make(map[string]bool)
+	j.retry = make(map[string]func(*entry) bool)
+
+	return nil
+}
+
+func (j *Journald) GetState() interface{} {
+	j.Lock()
+	defer j.Unlock()
+	return j.position
+}
+
+func (j *Journald) SetState(state interface{}) error {
+	position, ok := state.(string)
+	if !ok {
+		return fmt.Errorf("invalid type %T for state", state)
+	}
+	if position != "" {
+		if _, err := parseCursor(position); err != nil {
+			return err
+		}
+	}
+
+	j.Lock()
+	defer j.Unlock()
+	j.position = position
+	return nil
+}
+
+func (j *Journald) Start(acc telegraf.Accumulator) error {
+	// Skip the entries already processed according to the saved position or
+	// all existing entries unless reading from the beginning
+	var skip func(*entry) bool
+	if j.position != "" {
+		c, err := parseCursor(j.position)
+		if err != nil {
+			return err
+		}
+		skip = func(e *entry) bool { return !c.after(e) }
+	} else if !j.FromBeginning {
+		skip = func(*entry) bool { return true }
+	}
+	if err := j.scan(skip); err != nil {
+		return err
+	}
+
+	j.acc = acc.WithTracking(j.MaxUndeliveredEntries)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case info := <-j.acc.Delivered():
+				j.delivered(info.ID())
+				<-j.sem
+			}
+		}
+	}()
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(time.Duration(j.PollInterval))
+		defer ticker.Stop()
+
+		j.read(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := j.scan(nil); err != nil {
+					j.acc.AddError(err)
+				}
+				j.read(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (*Journald) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (j *Journald) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+
+	for id, jf := range j.files {
+		if err := jf.close(); err != nil {
+			j.Log.Debugf("Closing %q failed: %v", jf.path, err)
+		}
+		delete(j.files, id)
+	}
+}
+
+// scan opens journal files not yet known. Archived files are renamed by
+// journald, so the files are identified by their ID instead of the path to
+// not read the same file twice. New files are positioned after the entries
+// matching the skip function. Files failing to open or to seek are retried
+// on the next scan with the same skip function unless they cannot be read
+// at all.
+func (j *Journald) scan(skip func(*entry) bool) error {
+	var paths []string
+	for _, dir := range j.Paths {
+		// Journal files are located in the given directory or in machine
+		// specific sub-directories
+		for _, pattern := range []string{"*.journal", "*/*.journal"} {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				return fmt.Errorf("invalid path %q: %w", dir, err)
+			}
+			paths = append(paths, matches...)
+		}
+	}
+
+	known := make(map[string]id128, len(j.files))
+	for id, jf := range j.files {
+		known[jf.path] = id
+	}
+
+	found := make(map[id128]bool, len(paths))
+	exists := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exists[path] = true
+		if j.bad[path] {
+			continue
+		}
+
+		s := skip
+		if retrySkip, retry := j.retry[path]; retry {
+			s = retrySkip
+		}
+
+		jf, err := openJournalFile(path)
+		if err != nil {
+			// Keep the file already read from this path
+			if id, ok := known[path]; ok {
+				found[id] = true
+			}
+			j.failed(path, s, err, errors.Is(err, errNotJournal) || errors.Is(err, errUnsupported))
+			continue
+		}
+		found[jf.fileID] = true
+
+		if known, ok := j.files[jf.fileID]; ok {
+			known.path = path
+			jf.close()
+			delete(j.retry, path)
+			continue
+		}
+
+		if s != nil {
+			if err := j.seek(jf, s); err != nil {
+				// Active files might be written concurrently
+				permanent := errors.Is(err, errCorrupted) && jf.archived()
+				jf.close()
+				j.failed(path, s, err, permanent)
+				continue
+			}
+		}
+		delete(j.retry, path)
+		j.Log.Debugf("Reading journal file %q", path)
+		j.files[jf.fileID] = jf
+	}
+
+	// Forget files removed by journald, e.g. due to vacuuming
+	for id, jf := range j.files {
+		if !found[id] {
+			j.Log.Debugf("Journal file %q removed", jf.path)
+			jf.close()
+			delete(j.files, id)
+		}
+	}
+	for path := range j.retry {
+		if !exists[path] {
+			delete(j.retry, path)
+		}
+	}
+
+	return nil
+}
+
+// failed skips files failing permanently and schedules all other files for
+// retrying with the given skip function on the next scan
+func (j *Journald) failed(path string, skip func(*entry) bool, err error, permanent bool) {
+	if permanent {
+		j.Log.Warnf("Skipping journal file %q: %v", path, err)
+		j.bad[path] = true
+		delete(j.retry, path)
+		return
+	}
+
+	// Only warn on the first failure to not flood the log
+	if _, retry := j.retry[path]; !retry {
+		j.Log.Warnf("Accessing journal file %q failed, retrying: %v", path, err)
+	} else {
+		j.Log.Debugf("Accessing journal file %q failed, retrying: %v", path, err)
+	}
+	j.retry[path] = skip
+}
+
+// seek advances the file to the first entry not matching the skip function
+func (*Journald) seek(jf *journalFile, skip func(*entry) bool) error {
+	for {
+		arrayOffset, arrayIndex := jf.arrayOffset, jf.arrayIndex
+		offset, err := jf.nextOffset()
+		if err != nil || offset == 0 {
+			return err
+		}
+		e, err := jf.readEntry(offset)
+		if err != nil {
+			return err
+		}
+		if !skip(e) {
+			// Go back to read the entry again
+			jf.arrayOffset, jf.arrayIndex = arrayOffset, arrayIndex
+			return nil
+		}
+	}
+}
+
+// fileHead is the next entry of a file to be merged with the entries of
+// the other files and the position of the file before the entry
+type fileHead struct {
+	id          id128
+	jf          *journalFile
+	entry       *entry
+	arrayOffset uint64
+	arrayIndex  uint64
+}
+
+// read adds the new entries of all files in chronological order. Only the
+// next entry of each file is kept in memory while merging the files. Files
+// failing to read are read again from the failed entry on the next poll
+// as the active file might be written concurrently. Only corrupted archived
+// files are skipped.
+func (j *Journald) read(ctx context.Context) {
+	heads := make([]*fileHead, 0, len(j.files))
+	for id, jf := range j.files {
+		if h := j.head(id, jf); h != nil {
+			heads = append(heads, h)
+		}
+	}
+
+	for len(heads) > 0 {
+		// Take the earliest entry of all files
+		i := 0
+		for k, h := range heads[1:] {
+			if compareEntries(h.entry, heads[i].entry) < 0 {
+				i = k + 1
+			}
+		}
+		h := heads[i]
+
+		if !j.add(ctx, h.entry) {
+			// Read the entries again after restarting
+			for _, h := range heads {
+				h.jf.arrayOffset, h.jf.arrayIndex = h.arrayOffset, h.arrayIndex
+			}
+			return
+		}
+
+		if next := j.head(h.id, h.jf); next != nil {
+			heads[i] = next
+		} else {
+			heads = slices.Delete(heads, i, i+1)
+		}
+	}
+}
+
+// head reads the next entry of the file or returns nil if there is none
+func (j *Journald) head(id id128, jf *journalFile) *fileHead {
+	arrayOffset, arrayIndex := jf.arrayOffset, jf.arrayIndex
+	e, err := jf.next()
+	if err != nil {
+		if errors.Is(err, errCorrupted) && jf.archived() {
+			j.acc.AddError(fmt.Errorf("skipping corrupted journal file %q: %w", jf.path, err))
+			jf.close()
+			delete(j.files, id)
+			j.bad[jf.path] = true
+			return nil
+		}
+		j.Log.Debugf("Reading journal file %q failed, retrying: %v", jf.path, err)
+		jf.arrayOffset, jf.arrayIndex = arrayOffset, arrayIndex
+		return nil
+	}
+	if e == nil {
+		return nil
+	}
+	return &fileHead{id: id, jf: jf, entry: e, arrayOffset: arrayOffset, arrayIndex: arrayIndex}
+}
+
+// add passes the entry on if it matches the filters and returns false if the
+// context is done before the entry could be added
+func (j *Journald) add(ctx context.Context, e *entry) bool {
+	if !j.match(e) {
+		j.skipped(e.cursor())
+		return true
+	}
+
+	// Limit the number of undelivered entries
+	select {
+	case <-ctx.Done():
+		return false
+	case j.sem <- struct{}{}:
+	}
+
+	j.Lock()
+	id := j.acc.AddTrackingMetricGroup([]telegraf.Metric{j.toMetric(e)})
+	p := &pendingEntry{cursor: e.cursor()}
+	j.pending = append(j.pending, p)
+	j.tracked[id] = p
+	j.Unlock()
+	return true
+}
+
+// compareEntries orders entries of the same sequence by their sequence number
+// and entries of different sequences by their time
+func compareEntries(a, b *entry) int {
+	if a.seqnumID == b.seqnumID {
+		return cmp.Compare(a.seqnum, b.seqnum)
+	}
+	return cmp.Compare(a.realtime, b.realtime)
+}
+
+// skipped marks an entry not matching the filters as done
+func (j *Journald) skipped(cursor string) {
+	j.Lock()
+	defer j.Unlock()
+
+	if len(j.pending) == 0 {
+		j.position = cursor
+		return
+	}
+	// Only keep the last of consecutive skipped entries
+	if last := j.pending[len(j.pending)-1]; last.done {
+		last.cursor = cursor
+		return
+	}
+	j.pending = append(j.pending, &pendingEntry{cursor: cursor, done: true})
+}
+
+// delivered marks the entry as done and advances the position across the
+// leading entries being done
+func (j *Journald) delivered(id telegraf.TrackingID) {
+	j.Lock()
+	defer j.Unlock()
+
+	p, found := j.tracked[id]
+	if !found {
+		return
+	}
+	delete(j.tracked, id)
+	p.done = true
+
+	var n int
+	for n < len(j.pending) && j.pending[n].done {
+		j.position = j.pending[n].cursor
+		n++
+	}
+	j.pending = j.pending[n:]
+}
+
+func (j *Journald) match(e *entry) bool {
+	if j.unitFilter != nil && !j.unitFilter.Match(e.fields["_SYSTEMD_UNIT"]) {
+		return false
+	}
+	if j.identifierFilter != nil && !j.identifierFilter.Match(e.fields["SYSLOG_IDENTIFIER"]) {
+		return false
+	}
+	if j.maxPriority >= 0 {
+		// Entries without priority are excluded like journalctl does
+		p, err := strconv.ParseInt(e.fields["PRIORITY"], 10, 64)
+		if err != nil || p > j.maxPriority {
+			return false
+		}
+	}
+	return true
+}
+
+func (j *Journald) toMetric(e *entry) telegraf.Metric {
+	tags := make(map[string]string, len(j.TagFields))
+	for _, name := range j.TagFields {
+		if value, found := e.fields[name]; found && value != "" {
+			tags[key(name)] = value
+		}
+	}
+
+	fields := make(map[string]interface{}, len(j.Fields))
+	for _, name := range j.Fields {
+		value, found := e.fields[name]
+		if !found {
+			continue
+		}
+		if integerFields[name] {
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[key(name)] = v
+				continue
+			}
+		}
+		fields[key(name)] = value
+	}
+
+	return metric.New("journald", tags, fields, time.UnixMicro(int64(e.realtime)))
+}
+
+// key converts the journal field name to the tag or field key by removing the
+// underscores marking trusted fields and converting to lower-case
+func key(name string) string {
+	return strings.ToLower(strings.TrimLeft(name, "_"))
+}
+
+func init() {
+	inputs.Add("journald", func() telegraf.Input {
+		return &Journald{}
+	})
+}
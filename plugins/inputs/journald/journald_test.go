@@ -0,0 +1,474 @@
+//go:build linux
+
+package journald
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// The journal files in testdata were written by systemd-journald v252 using
+// compact mode and zstd compression. The first file was archived by rotating
+// the journal after the first five entries.
+const (
+	archivedFile = "system@1020d60451114fa581045a07b1058268-0000000000000001-00065e1bf5964114.journal"
+	activeFile   = "system.journal"
+
+	// Cursor of the last entry of the archived file
+	archivedCursor = "s=1020d60451114fa581045a07b1058268;i=5;b=df97712ffa1b44a88df1dfa274eed5a8;m=2912a7182;t=65e1bf5a5d028;x=be2d4e4f8a6bd0a9"
+	// Cursor of the last entry of the journal
+	lastCursor = "s=1020d60451114fa581045a07b1058268;i=a;b=df97712ffa1b44a88df1dfa274eed5a8;m=2913d596f;t=65e1bf5b8b816;x=fc2cdc342e79a08e"
+)
+
+// Message stored as zstd compressed data object
+var payloadMessage = "payload " + strings.Repeat("x", 1016)
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Journald
+		expected string
+	}{
+		{
+			name:     "invalid priority keyword",
+			plugin:   &Journald{Priority: "error"},
+			expected: `invalid 'priority' setting "error"`,
+		},
+		{
+			name:     "priority out of range",
+			plugin:   &Journald{Priority: "8"},
+			expected: `invalid 'priority' setting "8"`,
+		},
+		{
+			name:     "invalid unit pattern",
+			plugin:   &Journald{Units: []string{"[nginx"}},
+			expected: "invalid 'units' setting",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestReadFromBeginning(t *testing.T) {
+	plugin := &Journald{
+		Paths:         []string{"testdata"},
+		Units:         []string{"nginx.service"},
+		FromBeginning: true,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	acc.Wait(2)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"journald",
+			map[string]string{
+				"hostname":          "vm",
+				"systemd_unit":      "nginx.service",
+				"syslog_identifier": "nginx",
+			},
+			map[string]interface{}{
+				"message":  "GET /index.html HTTP/1.1 200",
+				"priority": int64(6),
+				"pid":      int64(8763),
+			},
+			time.UnixMicro(1792324038665726),
+		),
+		metric.New(
+			"journald",
+			map[string]string{
+				"hostname":          "vm",
+				"systemd_unit":      "nginx.service",
+				"syslog_identifier": "nginx",
+			},
+			map[string]interface{}{
+				"message":  "connect() failed (111: Connection refused) while connecting to upstream",
+				"priority": int64(3),
+				"pid":      int64(8766),
+			},
+			time.UnixMicro(1792324038671005),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// The position is the last entry read after delivery even if it did not
+	// match
+	for _, m := range acc.GetTelegrafMetrics() {
+		m.Accept()
+	}
+	require.Eventually(t, func() bool {
+		return plugin.GetState() == lastCursor
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		units       []string
+		identifiers []string
+		priority    string
+		expected    []string
+	}{
+		{
+			name: "all",
+			expected: []string{
+				"Journal started",
+				"Runtime Journal (/run/log/journal/fed6b2924c424cf1b9a322f606b4de6d) is 512.0K, max 4.0G, 3.9G free.",
+				"GET /index.html HTTP/1.1 200",
+				"connect() failed (111: Connection refused) while connecting to upstream",
+				"Accepted publickey for admin from 192.0.2.10 port 52144",
+				"Runtime Journal (/run/log/journal/fed6b2924c424cf1b9a322f606b4de6d) is 512.0K, max 4.0G, 3.9G free.",
+				"(root) CMD (run-parts /etc/cron.hourly)",
+				payloadMessage,
+				"Received disconnect from 192.0.2.10 port 52144:11: disconnected by user",
+				"Journal stopped",
+			},
+		},
+		{
+			name:  "unit pattern",
+			units: []string{"ssh*", "cron.service"},
+			expected: []string{
+				"Accepted publickey for admin from 192.0.2.10 port 52144",
+				"(root) CMD (run-parts /etc/cron.hourly)",
+				"Received disconnect from 192.0.2.10 port 52144:11: disconnected by user",
+			},
+		},
+		{
+			name:        "identifier",
+			identifiers: []string{"CRON", "app"},
+			expected: []string{
+				"(root) CMD (run-parts /etc/cron.hourly)",
+				payloadMessage,
+			},
+		},
+		{
+			name:     "priority keyword",
+			priority: "warning",
+			expected: []string{
+				"connect() failed (111: Connection refused) while connecting to upstream",
+				payloadMessage,
+			},
+		},
+		{
+			name:     "priority number and unit",
+			units:    []string{"sshd.service"},
+			priority: "5",
+			expected: []string{
+				"Received disconnect from 192.0.2.10 port 52144:11: disconnected by user",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Journald{
+				Paths:         []string{"testdata"},
+				Units:         tt.units,
+				Identifiers:   tt.identifiers,
+				Priority:      tt.priority,
+				FromBeginning: true,
+				TagFields:     []string{},
+				Fields:        []string{"MESSAGE"},
+				Log:           testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Start(&acc))
+			defer plugin.Stop()
+			acc.Wait(len(tt.expected))
+
+			actual := make([]string, 0, len(tt.expected))
+			for _, m := range acc.GetTelegrafMetrics() {
+				msg, ok := m.GetField("message")
+				require.True(t, ok)
+				actual = append(actual, msg.(string))
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestReadNewEntriesOnly(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", archivedFile), filepath.Join(dir, archivedFile))
+
+	plugin := &Journald{
+		Paths:        []string{dir},
+		Fields:       []string{"MESSAGE"},
+		TagFields:    []string{},
+		PollInterval: config.Duration(10 * time.Millisecond),
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Files created after starting are read from the beginning while renamed
+	// files are not read again
+	require.NoError(t, os.Rename(filepath.Join(dir, archivedFile), filepath.Join(dir, "renamed.journal")))
+	copyFile(t, filepath.Join("testdata", activeFile), filepath.Join(dir, activeFile))
+	acc.Wait(5)
+
+	require.Never(t, func() bool {
+		return acc.NMetrics() > 5
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	require.Equal(t, "Journal stopped", acc.GetTelegrafMetrics()[4].Fields()["message"])
+
+	for _, m := range acc.GetTelegrafMetrics() {
+		m.Accept()
+	}
+	require.Eventually(t, func() bool {
+		return plugin.GetState() == lastCursor
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestStatePersistence(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		expected []string
+	}{
+		{
+			name:  "same sequence number id",
+			state: archivedCursor,
+			expected: []string{
+				"Runtime Journal (/run/log/journal/fed6b2924c424cf1b9a322f606b4de6d) is 512.0K, max 4.0G, 3.9G free.",
+				"(root) CMD (run-parts /etc/cron.hourly)",
+				payloadMessage,
+				"Received disconnect from 192.0.2.10 port 52144:11: disconnected by user",
+				"Journal stopped",
+			},
+		},
+		{
+			name:  "other sequence number id uses realtime",
+			state: "s=00000000000000000000000000000000;i=1;b=df97712ffa1b44a88df1dfa274eed5a8;m=0;t=65e1bf5b10f3f;x=0",
+			expected: []string{
+				"Journal stopped",
+			},
+		},
+		{
+			name:  "last entry",
+			state: lastCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The saved state takes precedence over the start position
+			plugin := &Journald{
+				Paths:     []string{"testdata"},
+				Fields:    []string{"MESSAGE"},
+				TagFields: []string{},
+				Log:       testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.SetState(tt.state))
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Start(&acc))
+			acc.Wait(len(tt.expected))
+
+			actual := make([]string, 0, len(tt.expected))
+			for _, m := range acc.GetTelegrafMetrics() {
+				actual = append(actual, m.Fields()["message"].(string))
+				m.Accept()
+			}
+			require.ElementsMatch(t, tt.expected, actual)
+			require.Eventually(t, func() bool {
+				return plugin.GetState() == lastCursor
+			}, 5*time.Second, 10*time.Millisecond)
+			plugin.Stop()
+		})
+	}
+}
+
+func TestInvalidState(t *testing.T) {
+	plugin := &Journald{}
+	require.ErrorContains(t, plugin.SetState(42), "invalid type int for state")
+	require.ErrorContains(t, plugin.SetState("s=1020d6;i=1;t=1"), `invalid cursor element "s=1020d6"`)
+	require.ErrorContains(t, plugin.SetState("s=1020d60451114fa581045a07b1058268"), "invalid cursor")
+}
+
+func TestSkipInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", activeFile), filepath.Join(dir, activeFile))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.journal"), []byte("not a journal"), 0o600))
+
+	plugin := &Journald{
+		Paths:         []string{dir},
+		FromBeginning: true,
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	acc.Wait(5)
+	require.Empty(t, acc.Errors)
+}
+
+func TestPositionAfterDelivery(t *testing.T) {
+	plugin := &Journald{
+		Paths:         []string{"testdata"},
+		Units:         []string{"ssh*", "cron.service"},
+		FromBeginning: true,
+		Fields:        []string{"MESSAGE"},
+		TagFields:     []string{},
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	acc.Wait(3)
+
+	// The position must not advance across undelivered entries
+	metrics := acc.GetTelegrafMetrics()
+	initial := plugin.GetState()
+	metrics[2].Accept()
+	require.Never(t, func() bool {
+		return plugin.GetState() != initial
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	// The position includes the entries not matching the filters
+	metrics[0].Accept()
+	metrics[1].Accept()
+	require.Eventually(t, func() bool {
+		return plugin.GetState() == lastCursor
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRetryIncompleteFile(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", activeFile))
+	require.NoError(t, err)
+
+	// Simulate reading a file while journald is writing to it by cutting the
+	// file in the middle of the second entry array linking the last entry
+	first := binary.LittleEndian.Uint64(buf[headerEntryArrayOffset:])
+	size := binary.LittleEndian.Uint64(buf[first+objectHeaderSize:]) + 8
+	fn := filepath.Join(t.TempDir(), activeFile)
+	require.NoError(t, os.WriteFile(fn, buf[:size], 0o600))
+
+	plugin := &Journald{
+		Paths:         []string{filepath.Dir(fn)},
+		FromBeginning: true,
+		PollInterval:  config.Duration(10 * time.Millisecond),
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Poll the incomplete file a few times before completing it
+	time.Sleep(100 * time.Millisecond)
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = f.Write(buf[size:])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() == 5
+	}, 5*time.Second, 10*time.Millisecond)
+	acc.Lock()
+	defer acc.Unlock()
+	require.Empty(t, acc.Errors)
+}
+
+func TestRetryUnreadableFile(t *testing.T) {
+	// Simulate a file just being created by journald with an incomplete
+	// header
+	buf, err := os.ReadFile(filepath.Join("testdata", activeFile))
+	require.NoError(t, err)
+	fn := filepath.Join(t.TempDir(), activeFile)
+	require.NoError(t, os.WriteFile(fn, buf[:16], 0o600))
+
+	plugin := &Journald{
+		Paths:         []string{filepath.Dir(fn)},
+		FromBeginning: true,
+		PollInterval:  config.Duration(10 * time.Millisecond),
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// The file must be opened again once it is complete
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, os.WriteFile(fn, buf, 0o600))
+
+	require.Eventually(t, func() bool {
+		return acc.NMetrics() == 5
+	}, 5*time.Second, 10*time.Millisecond)
+	acc.Lock()
+	defer acc.Unlock()
+	require.Empty(t, acc.Errors)
+}
+
+func TestSkipCorruptedArchivedFile(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", archivedFile))
+	require.NoError(t, err)
+
+	// Break the type of the first entry array
+	offset := binary.LittleEndian.Uint64(buf[headerEntryArrayOffset:])
+	buf[offset] = 0xff
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, archivedFile), buf, 0o600))
+
+	plugin := &Journald{
+		Paths:         []string{dir},
+		FromBeginning: true,
+		PollInterval:  config.Duration(10 * time.Millisecond),
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	acc.WaitError(1)
+	require.ErrorContains(t, acc.FirstError(), "skipping corrupted journal file")
+	require.Never(t, func() bool {
+		return len(acc.Errors) > 1
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+
+	// Write to a temporary file first to not expose partially written files
+	buf, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst+"~", buf, 0o600))
+	require.NoError(t, os.Rename(dst+"~", dst))
+}
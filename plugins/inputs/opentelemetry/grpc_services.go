@@ -9,6 +9,8 @@ import (
 	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+
+	otel "github.com/influxdata/telegraf/plugins/common/opentelemetry"
 )
 
 type traceService struct {
@@ -45,13 +47,8 @@ type metricsService struct {
 
 var _ pmetricotlp.GRPCServer = (*metricsService)(nil)
 
-var metricsSchemata = map[string]common.MetricsSchema{
-	"prometheus-v1": common.MetricsSchemaTelegrafPrometheusV1,
-	"prometheus-v2": common.MetricsSchemaTelegrafPrometheusV2,
-}
-
 func newMetricsService(logger common.Logger, writer *writeToAccumulator, schema string) (*metricsService, error) {
-	ms, found := metricsSchemata[schema]
+	ms, found := otel.MetricsSchemata[schema]
 	if !found {
 		return nil, fmt.Errorf("schema %q not recognized", schema)
 	}
@@ -0,0 +1,154 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+type DNSSDConfig struct {
+	Enabled       bool            `toml:"enabled"`
+	Names         []string        `toml:"names"`
+	Type          string          `toml:"type"`
+	Port          int             `toml:"port"`
+	Scheme        string          `toml:"scheme"`
+	Path          string          `toml:"path"`
+	QueryInterval config.Duration `toml:"query_interval"`
+}
+
+// dnsResolver is the subset of net.Resolver used for DNS service discovery
+type dnsResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+func (p *Prometheus) startDNSSD(ctx context.Context) error {
+	if len(p.DNSSDConfig.Names) == 0 {
+		return errors.New("no names specified for DNS service discovery")
+	}
+
+	// default settings
+	switch p.DNSSDConfig.Type {
+	case "":
+		p.DNSSDConfig.Type = "SRV"
+	case "SRV":
+	case "A", "AAAA":
+		if p.DNSSDConfig.Port <= 0 {
+			return fmt.Errorf("port required for DNS service discovery of %s records", p.DNSSDConfig.Type)
+		}
+	default:
+		return fmt.Errorf("invalid DNS record type %q", p.DNSSDConfig.Type)
+	}
+	if p.DNSSDConfig.Scheme == "" {
+		p.DNSSDConfig.Scheme = "http"
+	}
+	queryInterval := 30 * time.Second
+	if p.DNSSDConfig.QueryInterval > 0 {
+		queryInterval = time.Duration(p.DNSSDConfig.QueryInterval)
+	}
+	if p.resolver == nil {
+		p.resolver = net.DefaultResolver
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := p.refreshDNSServices(ctx); err != nil {
+			p.Log.Errorf("Unable to refresh DNS discovered services: %v", err)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(queryInterval):
+				if err := p.refreshDNSServices(ctx); err != nil {
+					p.Log.Errorf("Unable to refresh DNS discovered services: %v", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// refreshDNSServices looks up the configured names. Services of names that
+// cannot be resolved are kept until the next successful lookup.
+func (p *Prometheus) refreshDNSServices(ctx context.Context) error {
+	p.lock.Lock()
+	previous := p.dnsServices
+	p.lock.Unlock()
+
+	var errs []error
+	services := make(map[string]urlAndAddress)
+	for _, name := range p.DNSSDConfig.Names {
+		targets, err := p.lookupTargets(ctx, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("looking up %q failed: %w", name, err))
+			for k, v := range previous {
+				if v.tags["dns_name"] == name {
+					services[k] = v
+				}
+			}
+			continue
+		}
+
+		for _, target := range targets {
+			targetURL := &url.URL{
+				Scheme: p.DNSSDConfig.Scheme,
+				Host:   target,
+				Path:   p.DNSSDConfig.Path,
+			}
+			services[targetURL.String()] = urlAndAddress{
+				url:         targetURL,
+				originalURL: targetURL,
+				tags:        map[string]string{"dns_name": name},
+			}
+		}
+	}
+
+	p.lock.Lock()
+	p.dnsServices = services
+	p.lock.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// lookupTargets returns the host and port of the targets for the name
+func (p *Prometheus) lookupTargets(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if p.DNSSDConfig.Type == "SRV" {
+		_, records, err := p.resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, err
+		}
+		targets := make([]string, 0, len(records))
+		for _, r := range records {
+			host := strings.TrimSuffix(r.Target, ".")
+			targets = append(targets, net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+		}
+		return targets, nil
+	}
+
+	network := "ip4"
+	if p.DNSSDConfig.Type == "AAAA" {
+		network = "ip6"
+	}
+	ips, err := p.resolver.LookupIP(ctx, network, name)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		targets = append(targets, net.JoinHostPort(ip.String(), strconv.Itoa(p.DNSSDConfig.Port)))
+	}
+	return targets, nil
+}
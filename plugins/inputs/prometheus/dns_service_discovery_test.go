@@ -0,0 +1,138 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type mockResolver struct {
+	srv map[string][]*net.SRV
+	ips map[string][]net.IP
+}
+
+func (r *mockResolver) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	records, found := r.srv[name]
+	if !found {
+		return "", nil, errors.New("no such host")
+	}
+	return name, records, nil
+}
+
+func (r *mockResolver) LookupIP(_ context.Context, _, host string) ([]net.IP, error) {
+	ips, found := r.ips[host]
+	if !found {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+func TestDNSSDSRV(t *testing.T) {
+	resolver := &mockResolver{
+		srv: map[string][]*net.SRV{
+			"_metrics._tcp.example.com": {
+				{Target: "node1.example.com.", Port: 9100},
+				{Target: "node2.example.com.", Port: 9100},
+			},
+		},
+	}
+	plugin := &Prometheus{
+		DNSSDConfig: DNSSDConfig{
+			Enabled: true,
+			Names:   []string{"_metrics._tcp.example.com"},
+		},
+		Log:      testutil.Logger{},
+		resolver: resolver,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(nil))
+	defer plugin.Stop()
+
+	require.Eventually(t, func() bool {
+		plugin.lock.Lock()
+		defer plugin.lock.Unlock()
+		return len(plugin.dnsServices) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	urls, err := plugin.getAllURLs()
+	require.NoError(t, err)
+	require.Contains(t, urls, "http://node1.example.com:9100")
+	require.Contains(t, urls, "http://node2.example.com:9100")
+	require.Equal(t, map[string]string{"dns_name": "_metrics._tcp.example.com"}, urls["http://node1.example.com:9100"].tags)
+}
+
+func TestDNSSDA(t *testing.T) {
+	resolver := &mockResolver{
+		ips: map[string][]net.IP{
+			"nodes.example.com": {net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")},
+		},
+	}
+	plugin := &Prometheus{
+		DNSSDConfig: DNSSDConfig{
+			Enabled: true,
+			Names:   []string{"nodes.example.com"},
+			Type:    "A",
+			Port:    9100,
+			Scheme:  "https",
+			Path:    "/federate",
+		},
+		Log:      testutil.Logger{},
+		resolver: resolver,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(nil))
+	plugin.Stop()
+
+	require.NoError(t, plugin.refreshDNSServices(t.Context()))
+	plugin.lock.Lock()
+	require.Len(t, plugin.dnsServices, 2)
+	require.Contains(t, plugin.dnsServices, "https://10.0.0.1:9100/federate")
+	require.Contains(t, plugin.dnsServices, "https://10.0.0.2:9100/federate")
+	plugin.lock.Unlock()
+
+	// Keep the previous services on lookup errors
+	delete(resolver.ips, "nodes.example.com")
+	require.ErrorContains(t, plugin.refreshDNSServices(t.Context()), "no such host")
+	plugin.lock.Lock()
+	require.Len(t, plugin.dnsServices, 2)
+	plugin.lock.Unlock()
+}
+
+func TestDNSSDInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      DNSSDConfig
+		expected string
+	}{
+		{
+			name:     "no names",
+			cfg:      DNSSDConfig{Enabled: true},
+			expected: "no names specified",
+		},
+		{
+			name:     "missing port",
+			cfg:      DNSSDConfig{Enabled: true, Names: []string{"example.com"}, Type: "A"},
+			expected: "port required",
+		},
+		{
+			name:     "invalid type",
+			cfg:      DNSSDConfig{Enabled: true, Names: []string{"example.com"}, Type: "MX"},
+			expected: `invalid DNS record type "MX"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Prometheus{DNSSDConfig: tt.cfg, Log: testutil.Logger{}}
+			require.NoError(t, plugin.Init())
+			require.ErrorContains(t, plugin.Start(nil), tt.expected)
+			plugin.Stop()
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+type FileSDConfig struct {
+	Enabled         bool            `toml:"enabled"`
+	Files           []string        `toml:"files"`
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+}
+
+// fileSDState caches the services of a discovery file until the file changes
+type fileSDState struct {
+	modTime  time.Time
+	size     int64
+	services map[string]urlAndAddress
+}
+
+func (p *Prometheus) startFileSD(ctx context.Context) error {
+	if len(p.FileSDConfig.Files) == 0 {
+		return errors.New("no files specified for file service discovery")
+	}
+	for _, pattern := range p.FileSDConfig.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+	}
+
+	// default settings
+	refreshInterval := 30 * time.Second
+	if p.FileSDConfig.RefreshInterval > 0 {
+		refreshInterval = time.Duration(p.FileSDConfig.RefreshInterval)
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		states := make(map[string]*fileSDState)
+		if err := p.refreshFileServices(states); err != nil {
+			p.Log.Errorf("Unable to refresh file discovered services: %v", err)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(refreshInterval):
+				if err := p.refreshFileServices(states); err != nil {
+					p.Log.Errorf("Unable to refresh file discovered services: %v", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// refreshFileServices reads the discovery files changed since the last call.
+// Services of files that cannot be read are kept until the file is fixed.
+func (p *Prometheus) refreshFileServices(states map[string]*fileSDState) error {
+	var errs []error
+	found := make(map[string]bool)
+	for _, pattern := range p.FileSDConfig.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid pattern %q: %w", pattern, err))
+			continue
+		}
+		for _, fn := range matches {
+			found[fn] = true
+
+			info, err := os.Stat(fn)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			state, exists := states[fn]
+			if exists && state.modTime.Equal(info.ModTime()) && state.size == info.Size() {
+				continue
+			}
+
+			groups, err := readTargetGroups(fn)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("reading %q failed: %w", fn, err))
+				continue
+			}
+			states[fn] = &fileSDState{
+				modTime:  info.ModTime(),
+				size:     info.Size(),
+				services: p.targetGroupServices(groups),
+			}
+		}
+	}
+
+	// Forget about files that disappeared
+	for fn := range states {
+		if !found[fn] {
+			delete(states, fn)
+		}
+	}
+
+	services := make(map[string]urlAndAddress)
+	for _, state := range states {
+		for k, v := range state.services {
+			services[k] = v
+		}
+	}
+
+	p.lock.Lock()
+	p.fileServices = services
+	p.lock.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// readTargetGroups reads the target groups from a JSON or YAML file in the
+// format used by Prometheus' file-based service discovery
+func readTargetGroups(fn string) ([]targetGroup, error) {
+	buf, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []targetGroup
+	switch ext := strings.ToLower(filepath.Ext(fn)); ext {
+	case ".json":
+		err = json.Unmarshal(buf, &groups)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(buf, &groups)
+	default:
+		return nil, fmt.Errorf("unsupported file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing failed: %w", err)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestFileSD(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "nodes.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[
+  {
+    "targets": ["10.0.10.2:9100", "10.0.10.3:9100"],
+    "labels": {"datacenter": "london", "job": "node"}
+  }
+]`), 0600))
+	yamlFile := filepath.Join(dir, "alertmanager.yml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`
+- targets:
+    - https://10.0.40.2:9093/metrics
+  labels:
+    datacenter: newyork
+    job: alertmanager
+`), 0600))
+
+	plugin := &Prometheus{
+		FileSDConfig: FileSDConfig{
+			Enabled: true,
+			Files:   []string{filepath.Join(dir, "*.json"), filepath.Join(dir, "*.yml")},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	states := make(map[string]*fileSDState)
+	require.NoError(t, plugin.refreshFileServices(states))
+
+	plugin.lock.Lock()
+	require.Len(t, plugin.fileServices, 3)
+	require.Equal(t, map[string]string{"datacenter": "london", "job": "node"}, plugin.fileServices["http://10.0.10.2:9100"].tags)
+	require.Equal(t, map[string]string{"datacenter": "newyork", "job": "alertmanager"}, plugin.fileServices["https://10.0.40.2:9093/metrics"].tags)
+	plugin.lock.Unlock()
+
+	// Change a file and make sure the modification time differs even on
+	// file-systems with coarse timestamps
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[{"targets": ["10.0.10.4:9100"], "labels": {"job": "node"}}]`), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(jsonFile, future, future))
+	require.NoError(t, plugin.refreshFileServices(states))
+
+	plugin.lock.Lock()
+	require.Len(t, plugin.fileServices, 2)
+	require.Contains(t, plugin.fileServices, "http://10.0.10.4:9100")
+	require.Contains(t, plugin.fileServices, "https://10.0.40.2:9093/metrics")
+	plugin.lock.Unlock()
+
+	// Keep the services of broken files
+	require.NoError(t, os.WriteFile(yamlFile, []byte("- targets: [broken"), 0600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(yamlFile, future, future))
+	require.ErrorContains(t, plugin.refreshFileServices(states), "parsing failed")
+
+	plugin.lock.Lock()
+	require.Len(t, plugin.fileServices, 2)
+	plugin.lock.Unlock()
+
+	// Drop the services of removed files
+	require.NoError(t, os.Remove(yamlFile))
+	require.NoError(t, plugin.refreshFileServices(states))
+
+	plugin.lock.Lock()
+	require.Len(t, plugin.fileServices, 1)
+	require.Contains(t, plugin.fileServices, "http://10.0.10.4:9100")
+	plugin.lock.Unlock()
+}
+
+func TestFileSDInvalid(t *testing.T) {
+	plugin := &Prometheus{
+		FileSDConfig: FileSDConfig{Enabled: true},
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.ErrorContains(t, plugin.Start(nil), "no files specified")
+	plugin.Stop()
+
+	fn := filepath.Join(t.TempDir(), "targets.txt")
+	require.NoError(t, os.WriteFile(fn, []byte("10.0.0.1:9100"), 0600))
+	_, err := readTargetGroups(fn)
+	require.ErrorContains(t, err, `unsupported file extension ".txt"`)
+}
@@ -19,10 +19,12 @@ type HTTPSDConfig struct {
 	QueryInterval config.Duration `toml:"query_interval"`
 }
 
-// standard output for http service discovery is here https://prometheus.io/docs/prometheus/latest/http_sd/#http_sd-format
-type httpSDOutput struct {
-	Targets []string          `json:"targets"`
-	Labels  map[string]string `json:"labels"`
+// targetGroup is the standard output for http service discovery described at
+// https://prometheus.io/docs/prometheus/latest/http_sd/#http_sd-format and
+// also used by file-based service discovery
+type targetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
 }
 
 func (p *Prometheus) startHTTPSD(ctx context.Context) error {
@@ -73,7 +75,6 @@ func (p *Prometheus) startHTTPSD(ctx context.Context) error {
 }
 
 func (p *Prometheus) refreshHTTPServices(sdURL string, client *http.Client) error {
-	services := make(map[string]urlAndAddress)
 	req, err := http.NewRequest("GET", sdURL, nil)
 	if err != nil {
 		return fmt.Errorf("creating request failed: %w", err)
@@ -95,7 +96,7 @@ func (p *Prometheus) refreshHTTPServices(sdURL string, client *http.Client) erro
 		return fmt.Errorf("reading response body failed: %w", err)
 	}
 
-	var result []httpSDOutput
+	var result []targetGroup
 	if err := json.Unmarshal(body, &result); err != nil {
 		return fmt.Errorf("unmarshalling JSON failed: %w", err)
 	}
@@ -105,8 +106,20 @@ func (p *Prometheus) refreshHTTPServices(sdURL string, client *http.Client) erro
 		p.Log.Warnf("Service discovery returned no results")
 	}
 
-	for _, sdOutputItem := range result {
-		for _, targetValue := range sdOutputItem.Targets {
+	services := p.targetGroupServices(result)
+
+	p.lock.Lock()
+	p.httpServices = services
+	p.lock.Unlock()
+
+	return nil
+}
+
+// targetGroupServices converts the target groups to the services to scrape
+func (p *Prometheus) targetGroupServices(groups []targetGroup) map[string]urlAndAddress {
+	services := make(map[string]urlAndAddress)
+	for _, group := range groups {
+		for _, targetValue := range group.Targets {
 			if !strings.HasPrefix(targetValue, "http://") && !strings.HasPrefix(targetValue, "https://") {
 				targetValue = "http://" + targetValue
 			}
@@ -120,15 +133,10 @@ func (p *Prometheus) refreshHTTPServices(sdURL string, client *http.Client) erro
 				url:         targetURL,
 				originalURL: targetURL,
 				// in this case target labels should just be added to the tags
-				tags: sdOutputItem.Labels,
+				tags: group.Labels,
 			}
 			services[service.url.String()] = service
 		}
 	}
-
-	p.lock.Lock()
-	p.httpServices = services
-	p.lock.Unlock()
-
-	return nil
+	return services
 }
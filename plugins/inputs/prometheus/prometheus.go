@@ -86,6 +86,12 @@ type Prometheus struct {
 	// HTTP service discovery
 	HTTPSDConfig HTTPSDConfig `toml:"http_service_discovery"`
 
+	// File-based service discovery
+	FileSDConfig FileSDConfig `toml:"file_service_discovery"`
+
+	// DNS service discovery
+	DNSSDConfig DNSSDConfig `toml:"dns_service_discovery"`
+
 	Log telegraf.Logger `toml:"-"`
 	common_http.HTTPClientConfig
 
@@ -117,6 +123,13 @@ type Prometheus struct {
 
 	// list of http services to scrape
 	httpServices map[string]urlAndAddress
+
+	// list of services discovered via files to scrape
+	fileServices map[string]urlAndAddress
+
+	// list of services discovered via DNS to scrape
+	dnsServices map[string]urlAndAddress
+	resolver    dnsResolver
 }
 
 type urlAndAddress struct {
@@ -258,7 +271,7 @@ func (p *Prometheus) Init() error {
 	return nil
 }
 
-// Start will start the Kubernetes and/or Consul scraping if enabled in the configuration
+// Start will start the Kubernetes scraping and service discoveries if enabled in the configuration
 func (p *Prometheus) Start(_ telegraf.Accumulator) error {
 	var ctx context.Context
 	p.wg = sync.WaitGroup{}
@@ -279,6 +292,16 @@ func (p *Prometheus) Start(_ telegraf.Accumulator) error {
 			return err
 		}
 	}
+	if p.FileSDConfig.Enabled {
+		if err := p.startFileSD(ctx); err != nil {
+			return err
+		}
+	}
+	if p.DNSSDConfig.Enabled {
+		if err := p.startDNSSD(ctx); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -369,7 +392,7 @@ func addressToURL(u *url.URL, address string) *url.URL {
 }
 
 func (p *Prometheus) getAllURLs() (map[string]urlAndAddress, error) {
-	allURLs := make(map[string]urlAndAddress, len(p.URLs)+len(p.consulServices)+len(p.kubernetesPods)+len(p.httpServices))
+	allURLs := make(map[string]urlAndAddress, len(p.URLs)+len(p.consulServices)+len(p.kubernetesPods)+len(p.httpServices)+len(p.fileServices)+len(p.dnsServices))
 	for _, u := range p.URLs {
 		address, err := url.Parse(u)
 		if err != nil {
@@ -389,6 +412,14 @@ func (p *Prometheus) getAllURLs() (map[string]urlAndAddress, error) {
 	for k, v := range p.httpServices {
 		allURLs[k] = v
 	}
+	// add all services discovered via files
+	for k, v := range p.fileServices {
+		allURLs[k] = v
+	}
+	// add all services discovered via DNS
+	for k, v := range p.dnsServices {
+		allURLs[k] = v
+	}
 	// loop through all pods scraped via the prometheus annotation on the pods
 	for _, v := range p.kubernetesPods {
 		if namespaceAnnotationMatch(v.namespace, p) {
@@ -648,6 +679,8 @@ func init() {
 			kubernetesPods: make(map[podID]urlAndAddress),
 			consulServices: make(map[string]urlAndAddress),
 			httpServices:   make(map[string]urlAndAddress),
+			fileServices:   make(map[string]urlAndAddress),
+			dnsServices:    make(map[string]urlAndAddress),
 			URLTag:         "url",
 		}
 	})
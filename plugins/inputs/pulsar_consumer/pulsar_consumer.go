@@ -0,0 +1,303 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+var once sync.Once
+
+const defaultMaxUndeliveredMessages = 1000
+
+type empty struct{}
+type semaphore chan empty
+
+type PulsarConsumer struct {
+	Topics                 []string        `toml:"topics"`
+	TopicsPattern          string          `toml:"topics_pattern"`
+	Subscription           string          `toml:"subscription"`
+	SubscriptionType       string          `toml:"subscription_type"`
+	InitialPosition        string          `toml:"initial_position"`
+	TopicTag               string          `toml:"topic_tag"`
+	PropertiesAsTags       []string        `toml:"properties_as_tags"`
+	TimestampSource        string          `toml:"timestamp_source"`
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+	NackRedeliveryDelay    config.Duration `toml:"nack_redelivery_delay"`
+	MaxRedeliveries        uint32          `toml:"max_redeliveries"`
+	DeadLetterTopic        string          `toml:"dead_letter_topic"`
+	Log                    telegraf.Logger `toml:"-"`
+	common_pulsar.ClientConfig
+
+	parser        telegraf.Parser
+	subType       pulsar.SubscriptionType
+	client        pulsar.Client
+	subscribeFunc func(pulsar.ConsumerOptions) (pulsar.Consumer, error)
+	consumer      pulsar.Consumer
+	acc           telegraf.TrackingAccumulator
+	undelivered   map[telegraf.TrackingID]pulsar.Message
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+func (*PulsarConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PulsarConsumer) SetParser(parser telegraf.Parser) {
+	p.parser = parser
+}
+
+func (p *PulsarConsumer) Init() error {
+	if len(p.Topics) == 0 && p.TopicsPattern == "" {
+		return errors.New("either 'topics' or 'topics_pattern' required")
+	}
+	if len(p.Topics) > 0 && p.TopicsPattern != "" {
+		return errors.New("'topics' and 'topics_pattern' cannot be used together")
+	}
+	if p.Subscription == "" {
+		return errors.New("'subscription' required")
+	}
+
+	switch p.SubscriptionType {
+	case "", "shared":
+		p.subType = pulsar.Shared
+	case "failover":
+		p.subType = pulsar.Failover
+	case "exclusive":
+		p.subType = pulsar.Exclusive
+	case "key_shared":
+		p.subType = pulsar.KeyShared
+	default:
+		return fmt.Errorf("invalid subscription type %q", p.SubscriptionType)
+	}
+
+	switch p.InitialPosition {
+	case "":
+		p.InitialPosition = "latest"
+	case "latest", "earliest":
+	default:
+		return fmt.Errorf("invalid initial position %q", p.InitialPosition)
+	}
+
+	switch p.TimestampSource {
+	case "":
+		p.TimestampSource = "metric"
+	case "metric", "publish", "event":
+	default:
+		return fmt.Errorf("invalid timestamp source %q", p.TimestampSource)
+	}
+
+	if p.DeadLetterTopic != "" && p.MaxRedeliveries == 0 {
+		return errors.New("'max_redeliveries' required when using 'dead_letter_topic'")
+	}
+	if p.MaxRedeliveries > 0 && p.subType != pulsar.Shared && p.subType != pulsar.KeyShared {
+		return errors.New("'max_redeliveries' requires a shared or key_shared subscription")
+	}
+
+	if p.MaxUndeliveredMessages == 0 {
+		p.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+
+	return nil
+}
+
+func (p *PulsarConsumer) Start(acc telegraf.Accumulator) error {
+	if p.subscribeFunc == nil {
+		client, err := p.ClientConfig.NewClient(p.Log)
+		if err != nil {
+			return fmt.Errorf("creating client failed: %w", err)
+		}
+		p.client = client
+		p.subscribeFunc = client.Subscribe
+	}
+
+	opts := pulsar.ConsumerOptions{
+		Topics:                      p.Topics,
+		TopicsPattern:               p.TopicsPattern,
+		SubscriptionName:            p.Subscription,
+		Type:                        p.subType,
+		SubscriptionInitialPosition: pulsar.SubscriptionPositionLatest,
+		NackRedeliveryDelay:         time.Duration(p.NackRedeliveryDelay),
+		// Do not prefetch more messages than we are allowed to process
+		ReceiverQueueSize: p.MaxUndeliveredMessages,
+	}
+	if p.InitialPosition == "earliest" {
+		opts.SubscriptionInitialPosition = pulsar.SubscriptionPositionEarliest
+	}
+	if p.MaxRedeliveries > 0 {
+		opts.DLQ = &pulsar.DLQPolicy{
+			MaxDeliveries:   p.MaxRedeliveries,
+			DeadLetterTopic: p.DeadLetterTopic,
+		}
+	}
+
+	consumer, err := p.subscribeFunc(opts)
+	if err != nil {
+		if p.client != nil {
+			p.client.Close()
+			p.client = nil
+			p.subscribeFunc = nil
+		}
+		return &internal.StartupError{
+			Err:   fmt.Errorf("subscribing failed: %w", err),
+			Retry: true,
+		}
+	}
+	p.consumer = consumer
+
+	p.acc = acc.WithTracking(p.MaxUndeliveredMessages)
+	p.undelivered = make(map[telegraf.TrackingID]pulsar.Message, p.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.receive(ctx)
+	}()
+
+	return nil
+}
+
+func (*PulsarConsumer) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *PulsarConsumer) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	if p.consumer != nil {
+		p.consumer.Close()
+		p.consumer = nil
+	}
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+		p.subscribeFunc = nil
+	}
+}
+
+// receive reads messages while limiting the number of messages not yet
+// delivered to the outputs
+func (p *PulsarConsumer) receive(ctx context.Context) {
+	sem := make(semaphore, p.MaxUndeliveredMessages)
+	messages := p.consumer.Chan()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case track := <-p.acc.Delivered():
+			p.onDelivery(track)
+			<-sem
+		case sem <- empty{}:
+			select {
+			case <-ctx.Done():
+				return
+			case track := <-p.acc.Delivered():
+				p.onDelivery(track)
+				<-sem
+				<-sem
+			case cm, ok := <-messages:
+				if !ok {
+					return
+				}
+				if err := p.handle(cm.Message); err != nil {
+					p.acc.AddError(err)
+					<-sem
+				}
+			}
+		}
+	}
+}
+
+// onDelivery acknowledges the message once its metrics were written by all
+// outputs. Messages with metrics not written are negatively acknowledged so
+// the broker delivers them again.
+func (p *PulsarConsumer) onDelivery(track telegraf.DeliveryInfo) {
+	msg, ok := p.undelivered[track.ID()]
+	if !ok {
+		p.Log.Errorf("Could not mark message delivered: %d", track.ID())
+		return
+	}
+	delete(p.undelivered, track.ID())
+
+	if !track.Delivered() {
+		p.consumer.Nack(msg)
+		return
+	}
+	if err := p.consumer.Ack(msg); err != nil {
+		p.Log.Errorf("Acknowledging message %v failed: %v", msg.ID(), err)
+	}
+}
+
+// handle parses the message and adds the metrics for tracking. Messages that
+// cannot be parsed are acknowledged as they will never succeed.
+func (p *PulsarConsumer) handle(msg pulsar.Message) error {
+	metrics, err := p.parser.Parse(msg.Payload())
+	if err != nil {
+		if aerr := p.consumer.Ack(msg); aerr != nil {
+			p.Log.Errorf("Acknowledging message %v failed: %v", msg.ID(), aerr)
+		}
+		return fmt.Errorf("parsing message from topic %q failed: %w", msg.Topic(), err)
+	}
+
+	if len(metrics) == 0 {
+		once.Do(func() {
+			p.Log.Debug(internal.NoMetricsCreatedMsg)
+		})
+	}
+
+	var ts time.Time
+	switch p.TimestampSource {
+	case "publish":
+		ts = msg.PublishTime()
+	case "event":
+		ts = msg.EventTime()
+	}
+
+	properties := msg.Properties()
+	for _, m := range metrics {
+		if p.TopicTag != "" {
+			m.AddTag(p.TopicTag, msg.Topic())
+		}
+		for _, key := range p.PropertiesAsTags {
+			if value, found := properties[key]; found {
+				m.AddTag(key, value)
+			}
+		}
+		// The event time is optional and zero if not set by the producer
+		if !ts.IsZero() {
+			m.SetTime(ts)
+		}
+	}
+
+	id := p.acc.AddTrackingMetricGroup(metrics)
+	p.undelivered[id] = msg
+	return nil
+}
+
+func init() {
+	inputs.Add("pulsar_consumer", func() telegraf.Input {
+		return &PulsarConsumer{}
+	})
+}
@@ -0,0 +1,385 @@
+package pulsar_consumer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type fakeMessage struct {
+	pulsar.Message
+	topic       string
+	payload     []byte
+	properties  map[string]string
+	publishTime time.Time
+	eventTime   time.Time
+}
+
+func (m *fakeMessage) Topic() string                 { return m.topic }
+func (m *fakeMessage) Payload() []byte               { return m.payload }
+func (m *fakeMessage) Properties() map[string]string { return m.properties }
+func (m *fakeMessage) PublishTime() time.Time        { return m.publishTime }
+func (m *fakeMessage) EventTime() time.Time          { return m.eventTime }
+
+type fakeConsumer struct {
+	pulsar.Consumer
+	options  pulsar.ConsumerOptions
+	messages chan pulsar.ConsumerMessage
+
+	sync.Mutex
+	acked  []pulsar.Message
+	nacked []pulsar.Message
+	closed bool
+}
+
+func (c *fakeConsumer) subscribe(options pulsar.ConsumerOptions) (pulsar.Consumer, error) {
+	c.options = options
+	return c, nil
+}
+
+func (c *fakeConsumer) send(msg *fakeMessage) {
+	c.messages <- pulsar.ConsumerMessage{Consumer: c, Message: msg}
+}
+
+func (c *fakeConsumer) Chan() <-chan pulsar.ConsumerMessage {
+	return c.messages
+}
+
+func (c *fakeConsumer) Ack(msg pulsar.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	c.acked = append(c.acked, msg)
+	return nil
+}
+
+func (c *fakeConsumer) Nack(msg pulsar.Message) {
+	c.Lock()
+	defer c.Unlock()
+	c.nacked = append(c.nacked, msg)
+}
+
+func (c *fakeConsumer) Close() {
+	c.Lock()
+	defer c.Unlock()
+	c.closed = true
+}
+
+func (c *fakeConsumer) counts() (acked, nacked int) {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.acked), len(c.nacked)
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{messages: make(chan pulsar.ConsumerMessage, 10)}
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *PulsarConsumer
+		expected string
+	}{
+		{
+			name:     "no topics",
+			plugin:   &PulsarConsumer{Subscription: "telegraf"},
+			expected: "either 'topics' or 'topics_pattern' required",
+		},
+		{
+			name: "topics and pattern",
+			plugin: &PulsarConsumer{
+				Topics:        []string{"telegraf"},
+				TopicsPattern: "telegraf-.*",
+				Subscription:  "telegraf",
+			},
+			expected: "'topics' and 'topics_pattern' cannot be used together",
+		},
+		{
+			name:     "no subscription",
+			plugin:   &PulsarConsumer{Topics: []string{"telegraf"}},
+			expected: "'subscription' required",
+		},
+		{
+			name: "invalid subscription type",
+			plugin: &PulsarConsumer{
+				Topics:           []string{"telegraf"},
+				Subscription:     "telegraf",
+				SubscriptionType: "broadcast",
+			},
+			expected: `invalid subscription type "broadcast"`,
+		},
+		{
+			name: "invalid initial position",
+			plugin: &PulsarConsumer{
+				Topics:          []string{"telegraf"},
+				Subscription:    "telegraf",
+				InitialPosition: "oldest",
+			},
+			expected: `invalid initial position "oldest"`,
+		},
+		{
+			name: "invalid timestamp source",
+			plugin: &PulsarConsumer{
+				Topics:          []string{"telegraf"},
+				Subscription:    "telegraf",
+				TimestampSource: "now",
+			},
+			expected: `invalid timestamp source "now"`,
+		},
+		{
+			name: "dead letter topic without redeliveries",
+			plugin: &PulsarConsumer{
+				Topics:          []string{"telegraf"},
+				Subscription:    "telegraf",
+				DeadLetterTopic: "telegraf-dlq",
+			},
+			expected: "'max_redeliveries' required when using 'dead_letter_topic'",
+		},
+		{
+			name: "redeliveries with failover subscription",
+			plugin: &PulsarConsumer{
+				Topics:           []string{"telegraf"},
+				Subscription:     "telegraf",
+				SubscriptionType: "failover",
+				MaxRedeliveries:  3,
+			},
+			expected: "'max_redeliveries' requires a shared or key_shared subscription",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestInitDefaults(t *testing.T) {
+	plugin := &PulsarConsumer{
+		Topics:       []string{"telegraf"},
+		Subscription: "telegraf",
+	}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, pulsar.Shared, plugin.subType)
+	require.Equal(t, "latest", plugin.InitialPosition)
+	require.Equal(t, "metric", plugin.TimestampSource)
+	require.Equal(t, defaultMaxUndeliveredMessages, plugin.MaxUndeliveredMessages)
+}
+
+func TestSubscribeOptions(t *testing.T) {
+	consumer := newFakeConsumer()
+	plugin := &PulsarConsumer{
+		Topics:                 []string{"telegraf"},
+		Subscription:           "telegraf",
+		SubscriptionType:       "key_shared",
+		InitialPosition:        "earliest",
+		MaxUndeliveredMessages: 10,
+		MaxRedeliveries:        3,
+		DeadLetterTopic:        "telegraf-dlq",
+		Log:                    testutil.Logger{},
+		subscribeFunc:          consumer.subscribe,
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	plugin.Stop()
+
+	require.Equal(t, []string{"telegraf"}, consumer.options.Topics)
+	require.Equal(t, "telegraf", consumer.options.SubscriptionName)
+	require.Equal(t, pulsar.KeyShared, consumer.options.Type)
+	require.Equal(t, pulsar.SubscriptionPositionEarliest, consumer.options.SubscriptionInitialPosition)
+	require.Equal(t, 10, consumer.options.ReceiverQueueSize)
+	require.Equal(t, &pulsar.DLQPolicy{MaxDeliveries: 3, DeadLetterTopic: "telegraf-dlq"}, consumer.options.DLQ)
+	require.True(t, consumer.closed)
+}
+
+func TestAcknowledgeOnDelivery(t *testing.T) {
+	consumer := newFakeConsumer()
+	plugin := &PulsarConsumer{
+		Topics:        []string{"telegraf"},
+		Subscription:  "telegraf",
+		Log:           testutil.Logger{},
+		subscribeFunc: consumer.subscribe,
+	}
+	require.NoError(t, plugin.Init())
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	consumer.send(&fakeMessage{topic: "telegraf", payload: []byte("test value=1i 0\ntest value=2i 0\n")})
+	consumer.send(&fakeMessage{topic: "telegraf", payload: []byte("test value=3i 0\n")})
+	acc.Wait(3)
+
+	// Nothing is acknowledged before the metrics are delivered
+	acked, nacked := consumer.counts()
+	require.Zero(t, acked)
+	require.Zero(t, nacked)
+
+	// Acknowledge the first message only after all its metrics are delivered
+	metrics := acc.GetTelegrafMetrics()
+	metrics[0].Accept()
+	acked, _ = consumer.counts()
+	require.Zero(t, acked)
+	metrics[1].Accept()
+	require.Eventually(t, func() bool {
+		acked, _ := consumer.counts()
+		return acked == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Negatively acknowledge messages with metrics not written
+	metrics[2].Reject()
+	require.Eventually(t, func() bool {
+		_, nacked := consumer.counts()
+		return nacked == 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	consumer.Lock()
+	defer consumer.Unlock()
+	require.Equal(t, []byte("test value=1i 0\ntest value=2i 0\n"), consumer.acked[0].Payload())
+	require.Equal(t, []byte("test value=3i 0\n"), consumer.nacked[0].Payload())
+}
+
+func TestAcknowledgeParseError(t *testing.T) {
+	consumer := newFakeConsumer()
+	plugin := &PulsarConsumer{
+		Topics:        []string{"telegraf"},
+		Subscription:  "telegraf",
+		Log:           testutil.Logger{},
+		subscribeFunc: consumer.subscribe,
+	}
+	require.NoError(t, plugin.Init())
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	consumer.send(&fakeMessage{topic: "telegraf", payload: []byte("invalid")})
+	acc.WaitError(1)
+	require.ErrorContains(t, acc.FirstError(), `parsing message from topic "telegraf" failed`)
+
+	acked, nacked := consumer.counts()
+	require.Equal(t, 1, acked)
+	require.Zero(t, nacked)
+}
+
+func TestTags(t *testing.T) {
+	publishTime := time.Unix(1715851200, 0)
+	eventTime := time.Unix(1715851100, 0)
+
+	tests := []struct {
+		name            string
+		topicTag        string
+		properties      []string
+		timestampSource string
+		eventTime       time.Time
+		expected        telegraf.Metric
+	}{
+		{
+			name: "defaults",
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{"value": int64(42)},
+				time.Unix(0, 0),
+			),
+		},
+		{
+			name:       "topic and properties",
+			topicTag:   "topic",
+			properties: []string{"region", "unknown"},
+			expected: metric.New(
+				"test",
+				map[string]string{
+					"topic":  "persistent://public/default/telegraf",
+					"region": "eu",
+				},
+				map[string]interface{}{"value": int64(42)},
+				time.Unix(0, 0),
+			),
+		},
+		{
+			name:            "publish time",
+			timestampSource: "publish",
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{"value": int64(42)},
+				publishTime,
+			),
+		},
+		{
+			name:            "event time",
+			timestampSource: "event",
+			eventTime:       eventTime,
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{"value": int64(42)},
+				eventTime,
+			),
+		},
+		{
+			name:            "event time not set",
+			timestampSource: "event",
+			expected: metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{"value": int64(42)},
+				time.Unix(0, 0),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := newFakeConsumer()
+			plugin := &PulsarConsumer{
+				Topics:           []string{"persistent://public/default/telegraf"},
+				Subscription:     "telegraf",
+				TopicTag:         tt.topicTag,
+				PropertiesAsTags: tt.properties,
+				TimestampSource:  tt.timestampSource,
+				Log:              testutil.Logger{},
+				subscribeFunc:    consumer.subscribe,
+			}
+			require.NoError(t, plugin.Init())
+
+			parser := &influx.Parser{}
+			require.NoError(t, parser.Init())
+			plugin.SetParser(parser)
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Start(&acc))
+			defer plugin.Stop()
+
+			consumer.send(&fakeMessage{
+				topic:       "persistent://public/default/telegraf",
+				payload:     []byte("test value=42i 0\n"),
+				properties:  map[string]string{"region": "eu", "zone": "a"},
+				publishTime: publishTime,
+				eventTime:   tt.eventTime,
+			})
+			acc.Wait(1)
+
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{tt.expected}, acc.GetTelegrafMetrics())
+		})
+	}
+}
@@ -19,18 +19,46 @@ const (
 	eventWarning = "warning"
 	eventError   = "error"
 	eventSuccess = "success"
+
+	serviceCheckOK      = 0
+	serviceCheckUnknown = 3
 )
 
 var uncommenter = strings.NewReplacer("\\n", "\n")
 
+// Service check messages additionally escape the message prefix
+var serviceCheckUncommenter = strings.NewReplacer("\\n", "\n", "m\\:", "m:")
+
+// ignoredDataDogField returns true for DogStatsD fields accepted but not
+// supported. The external data field ("e:") contains the pod UID and container
+// name injected by the Datadog admission controller in Kubernetes. The Datadog
+// agent resolves this data to the container ID using the Kubelet API which is
+// not available to Telegraf, so clients should send the container ID field or
+// use origin detection instead. The tag cardinality field ("card:") selects
+// the tags the Datadog agent adds on its own and has no equivalent either.
+func ignoredDataDogField(field string) bool {
+	return strings.HasPrefix(field, "e:") || strings.HasPrefix(field, "card:")
+}
+
 func (s *Statsd) parseEventMessage(now time.Time, message, defaultHostname string) error {
+	return s.parseEventMessageWithContainer(now, message, defaultHostname, "")
+}
+
+// parseEventMessageWithContainer parses the event like parseEventMessage
+// using the container determined by origin detection unless the event
+// contains a container ID field.
+func (s *Statsd) parseEventMessageWithContainer(now time.Time, message, defaultHostname, defaultContainer string) error {
 	// _e{title.length,text.length}:title|text
 	//  [
 	//   |d:date_happened
 	//   |p:priority
 	//   |h:hostname
 	//   |t:alert_type
+	//   |k:aggregation_key
 	//   |s:source_type_name
+	//   |c:container_id
+	//   |e:external_data
+	//   |card:cardinality
 	//   |#tag1,tag2
 	//  ]
 	//
@@ -80,6 +108,9 @@ func (s *Statsd) parseEventMessage(now time.Time, message, defaultHostname strin
 		tags["source"] = defaultHostname
 	}
 	fields["priority"] = priorityNormal
+	if s.DataDogKeepContainerTag && defaultContainer != "" {
+		tags["container"] = defaultContainer
+	}
 	ts := now
 	if len(message) < 2 {
 		s.acc.AddFields(name, fields, tags, ts)
@@ -91,6 +122,9 @@ func (s *Statsd) parseEventMessage(now time.Time, message, defaultHostname strin
 		if len(rawMetadataFields[i]) < 2 {
 			return errors.New("too short metadata field")
 		}
+		if ignoredDataDogField(rawMetadataFields[i]) {
+			continue
+		}
 		switch rawMetadataFields[i][:2] {
 		case "d:":
 			ts, err := strconv.ParseInt(rawMetadataFields[i][2:], 10, 64)
@@ -119,6 +153,10 @@ func (s *Statsd) parseEventMessage(now time.Time, message, defaultHostname strin
 			tags["aggregation_key"] = rawMetadataFields[i][2:]
 		case "s:":
 			fields["source_type_name"] = rawMetadataFields[i][2:]
+		case "c:":
+			if s.DataDogKeepContainerTag {
+				tags["container"] = rawMetadataFields[i][2:]
+			}
 		default:
 			if rawMetadataFields[i][0] != '#' {
 				return fmt.Errorf("unknown metadata type: %q", rawMetadataFields[i])
@@ -136,6 +174,74 @@ func (s *Statsd) parseEventMessage(now time.Time, message, defaultHostname strin
 	return nil
 }
 
+func (s *Statsd) parseServiceCheckMessage(now time.Time, message, defaultHostname, defaultContainer string) error {
+	// _sc|name|status
+	//  [
+	//   |d:timestamp
+	//   |h:hostname
+	//   |c:container_id
+	//   |e:external_data
+	//   |card:cardinality
+	//   |#tag1,tag2
+	//   |m:service_check_message
+	//  ]
+	//
+	// status is 0 (ok), 1 (warning), 2 (critical) or 3 (unknown)
+	rawFields := strings.Split(message, "|")
+	if len(rawFields) < 3 || rawFields[0] != "_sc" {
+		return errors.New("invalid service check format")
+	}
+	name := rawFields[1]
+	if name == "" {
+		return errors.New("invalid service check format: empty 'name' field")
+	}
+	status, err := strconv.ParseInt(rawFields[2], 10, 64)
+	if err != nil || status < serviceCheckOK || status > serviceCheckUnknown {
+		return fmt.Errorf("invalid service check format, could not parse status: %q", rawFields[2])
+	}
+
+	tags := make(map[string]string)
+	fields := map[string]interface{}{"status": status}
+	if defaultHostname != "" {
+		tags["source"] = defaultHostname
+	}
+	if s.DataDogKeepContainerTag && defaultContainer != "" {
+		tags["container"] = defaultContainer
+	}
+
+	for _, field := range rawFields[3:] {
+		switch {
+		case strings.HasPrefix(field, "d:"):
+			ts, err := strconv.ParseInt(field[2:], 10, 64)
+			if err != nil {
+				continue
+			}
+			fields["ts"] = ts
+		case strings.HasPrefix(field, "h:"):
+			tags["source"] = field[2:]
+		case strings.HasPrefix(field, "m:"):
+			fields["message"] = serviceCheckUncommenter.Replace(field[2:])
+		case strings.HasPrefix(field, "c:"):
+			if s.DataDogKeepContainerTag {
+				tags["container"] = field[2:]
+			}
+		case ignoredDataDogField(field):
+			// Not supported, see ignoredDataDogField
+		case strings.HasPrefix(field, "#"):
+			parseDataDogTags(tags, field[1:])
+		default:
+			return fmt.Errorf("unknown metadata type: %q", field)
+		}
+	}
+	// Use source tag because host is reserved tag key in Telegraf.
+	if host, ok := tags["host"]; ok {
+		delete(tags, "host")
+		tags["source"] = host
+	}
+	s.acc.AddFields(name, fields, tags, now)
+	return nil
+}
+
 func parseDataDogTags(tags map[string]string, message string) {
 	if len(message) == 0 {
 		return
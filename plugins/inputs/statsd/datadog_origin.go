@@ -0,0 +1,95 @@
+package statsd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Duration to keep the container of a process before reading it again as
+	// process IDs might be reused
+	originCacheTTL = time.Minute
+	// Number of processes to keep in the origin cache
+	originCacheSize = 1000
+)
+
+// Root of the proc filesystem, overridden in tests
+var procRoot = "/proc"
+
+// containerIDPattern matches the container IDs used by docker, containerd,
+// CRI-O and ECS as last element of the cgroup path, e.g.
+//
+//	0::/system.slice/docker-<id>.scope
+//	12:memory:/kubepods/burstable/pod<uid>/<id>
+var containerIDPattern = regexp.MustCompile(`(?:^|[-/])([0-9a-f]{64}|[0-9a-f]{32}-[0-9]+)(?:\.scope)?$`)
+
+type originEntry struct {
+	container string
+	expires   time.Time
+}
+
+// originCache caches the container of the sending processes to not read the
+// cgroup file of the process for each message
+type originCache struct {
+	entries map[int32]originEntry
+}
+
+func newOriginCache() *originCache {
+	return &originCache{entries: make(map[int32]originEntry)}
+}
+
+// container returns the container of the process or an empty string if the
+// process is not running in a container
+func (c *originCache) container(pid int32) (string, error) {
+	now := time.Now()
+	if entry, found := c.entries[pid]; found && now.Before(entry.expires) {
+		return entry.container, nil
+	}
+
+	f, err := os.Open(filepath.Join(procRoot, strconv.FormatInt(int64(pid), 10), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	container, err := parseContainerID(f)
+	if err != nil {
+		return "", err
+	}
+
+	if len(c.entries) >= originCacheSize {
+		for p, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, p)
+			}
+		}
+		// Start over if all entries are still valid
+		if len(c.entries) >= originCacheSize {
+			c.entries = make(map[int32]originEntry)
+		}
+	}
+	c.entries[pid] = originEntry{container: container, expires: now.Add(originCacheTTL)}
+
+	return container, nil
+}
+
+// parseContainerID extracts the container ID from the cgroup file of a
+// process with lines in the format "<id>:<controllers>:<path>"
+func parseContainerID(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if match := containerIDPattern.FindStringSubmatch(parts[2]); match != nil {
+			return match[1], nil
+		}
+	}
+	return "", scanner.Err()
+}
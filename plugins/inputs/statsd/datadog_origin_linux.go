@@ -0,0 +1,45 @@
+//go:build linux
+
+package statsd
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// Size of the buffer receiving the credentials of the sending process
+var oobSize = unix.CmsgSpace(unix.SizeofUcred)
+
+// enableOriginDetection requests the credentials of the sending process to be
+// passed along with each message
+func enableOriginDetection(conn *net.UnixConn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_PASSCRED, 1)
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// originPID returns the process ID of the sender from the credentials passed
+// along with the message
+func originPID(oob []byte) (int32, error) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, err
+	}
+	for i := range msgs {
+		if cred, err := unix.ParseUnixCredentials(&msgs[i]); err == nil {
+			return cred.Pid, nil
+		}
+	}
+	return 0, errors.New("no credentials received")
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package statsd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestUnixgramOriginDetection(t *testing.T) {
+	// Pretend the test process is running in a container
+	procRoot = t.TempDir()
+	defer func() { procRoot = "/proc" }()
+	cgroup := filepath.Join(procRoot, strconv.Itoa(os.Getpid()), "cgroup")
+	require.NoError(t, os.MkdirAll(filepath.Dir(cgroup), 0o750))
+	require.NoError(t, os.WriteFile(cgroup, []byte("0::/system.slice/docker-"+testContainerID+".scope\n"), 0o600))
+
+	sock := filepath.Join(t.TempDir(), "statsd.sock")
+	statsd := Statsd{
+		Log:                     testutil.Logger{},
+		Protocol:                "unixgram",
+		ServiceAddress:          sock,
+		AllowedPendingMessages:  10000,
+		NumberWorkerThreads:     5,
+		DataDogExtensions:       true,
+		DataDogKeepContainerTag: true,
+		DataDogOriginDetection:  true,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, statsd.Start(&acc))
+	defer statsd.Stop()
+
+	conn, err := net.Dial("unixgram", sock)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("cpu:42|g\nmem:42|g|c:abc\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, statsd.Gather(&acc))
+		return acc.NMetrics() >= 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// The container ID field takes precedence over the origin
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"container": testContainerID, "metric_type": "gauge"},
+			map[string]interface{}{"value": 42.0},
+			time.Now(),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"container": "abc", "metric_type": "gauge"},
+			map[string]interface{}{"value": 42.0},
+			time.Now(),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics()[:2],
+		testutil.SortMetrics(), testutil.IgnoreTime())
+}
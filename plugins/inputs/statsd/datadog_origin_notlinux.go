@@ -0,0 +1,19 @@
+//go:build !linux
+
+package statsd
+
+import (
+	"errors"
+	"net"
+)
+
+// Size of the buffer receiving the credentials of the sending process
+var oobSize = 0
+
+func enableOriginDetection(*net.UnixConn) error {
+	return errors.New("origin detection is only supported on Linux")
+}
+
+func originPID([]byte) (int32, error) {
+	return 0, errors.New("origin detection is only supported on Linux")
+}
@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testContainerID = "f76b5a1c03caa192580874b253c158010ade668cf03080a57aa8283919d56e75"
+
+func TestParseContainerID(t *testing.T) {
+	tests := []struct {
+		name     string
+		cgroup   string
+		expected string
+	}{
+		{
+			name:     "docker cgroup v2",
+			cgroup:   "0::/system.slice/docker-" + testContainerID + ".scope\n",
+			expected: testContainerID,
+		},
+		{
+			name: "kubernetes cgroup v1",
+			cgroup: "12:memory:/kubepods/burstable/pod2d3da189_6407_48e3_9ab6_78188d75e609/" + testContainerID + "\n" +
+				"11:cpu,cpuacct:/kubepods/burstable/pod2d3da189_6407_48e3_9ab6_78188d75e609/" + testContainerID + "\n",
+			expected: testContainerID,
+		},
+		{
+			name:     "containerd",
+			cgroup:   "0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + testContainerID + ".scope\n",
+			expected: testContainerID,
+		},
+		{
+			name:     "ecs fargate",
+			cgroup:   "0::/ecs/a7b2c1d3e5f4/a7b2c1d3e5f44f3e8d7b6a5c4d3e2f1a-1234567890\n",
+			expected: "a7b2c1d3e5f44f3e8d7b6a5c4d3e2f1a-1234567890",
+		},
+		{
+			name:   "host process",
+			cgroup: "0::/user.slice/user-1000.slice/session-2.scope\n",
+		},
+		{
+			name:   "cgroup namespace",
+			cgroup: "0::/\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			container, err := parseContainerID(strings.NewReader(tt.cgroup))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, container)
+		})
+	}
+}
+
+func TestOriginCache(t *testing.T) {
+	procRoot = t.TempDir()
+	defer func() { procRoot = "/proc" }()
+
+	cgroup := filepath.Join(procRoot, "42", "cgroup")
+	require.NoError(t, os.MkdirAll(filepath.Dir(cgroup), 0o750))
+	require.NoError(t, os.WriteFile(cgroup, []byte("0::/system.slice/docker-"+testContainerID+".scope\n"), 0o600))
+
+	cache := newOriginCache()
+	container, err := cache.container(42)
+	require.NoError(t, err)
+	require.Equal(t, testContainerID, container)
+
+	// The container is taken from the cache
+	require.NoError(t, os.Remove(cgroup))
+	container, err = cache.container(42)
+	require.NoError(t, err)
+	require.Equal(t, testContainerID, container)
+
+	// Unknown processes
+	_, err = cache.container(43)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
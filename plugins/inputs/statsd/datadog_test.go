@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -483,3 +484,124 @@ func TestEventError(t *testing.T) {
 	err = s.parseEventMessage(now, "_e{5,4}:title|text|x:1234", "default-hostname")
 	require.Error(t, err)
 }
+
+func TestEventContainer(t *testing.T) {
+	now := time.Now()
+	s := newTestStatsd()
+	s.DataDogKeepContainerTag = true
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	// The container ID field takes precedence over the origin
+	require.NoError(t, s.parseEventMessageWithContainer(now, "_e{5,4}:title|text|c:abc|e:it-false,cn-app|card:low", "", "origin"))
+	require.NoError(t, s.parseEventMessageWithContainer(now, "_e{5,4}:title|text|#env:prod", "", "origin"))
+	require.Len(t, acc.Metrics, 2)
+	require.Equal(t, map[string]string{"container": "abc"}, acc.Metrics[0].Tags)
+	require.Equal(t, map[string]string{"container": "origin", "env": "prod"}, acc.Metrics[1].Tags)
+
+	// Drop the container if not requested
+	acc.ClearMetrics()
+	s.DataDogKeepContainerTag = false
+	require.NoError(t, s.parseEventMessageWithContainer(now, "_e{5,4}:title|text|c:abc", "", "origin"))
+	require.Empty(t, acc.Metrics[0].Tags)
+}
+
+func TestServiceCheckGather(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		message  string
+		hostname string
+		expected *testutil.Metric
+	}{
+		{
+			name:     "basic",
+			message:  "_sc|agent.up|0",
+			hostname: "default-hostname",
+			expected: &testutil.Metric{
+				Measurement: "agent.up",
+				Tags:        map[string]string{"source": "default-hostname"},
+				Fields:      map[string]interface{}{"status": int64(0)},
+				Time:        now,
+				Type:        telegraf.Untyped,
+			},
+		},
+		{
+			name:     "all fields",
+			message:  "_sc|agent.up|2|d:21|h:some.host|#tag1,tag2:test|m:unable to connect\\nm\\: retrying",
+			hostname: "default-hostname",
+			expected: &testutil.Metric{
+				Measurement: "agent.up",
+				Tags:        map[string]string{"source": "some.host", "tag1": "true", "tag2": "test"},
+				Fields: map[string]interface{}{
+					"status":  int64(2),
+					"ts":      int64(21),
+					"message": "unable to connect\nm: retrying",
+				},
+				Time: now,
+				Type: telegraf.Untyped,
+			},
+		},
+		{
+			name:    "host tag",
+			message: "_sc|agent.up|1|#host:some.host",
+			expected: &testutil.Metric{
+				Measurement: "agent.up",
+				Tags:        map[string]string{"source": "some.host"},
+				Fields:      map[string]interface{}{"status": int64(1)},
+				Time:        now,
+				Type:        telegraf.Untyped,
+			},
+		},
+		{
+			name:    "container",
+			message: "_sc|agent.up|3|c:abc|e:it-false,cn-app|card:low",
+			expected: &testutil.Metric{
+				Measurement: "agent.up",
+				Tags:        map[string]string{"container": "abc"},
+				Fields:      map[string]interface{}{"status": int64(3)},
+				Time:        now,
+				Type:        telegraf.Untyped,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStatsd()
+			s.DataDogKeepContainerTag = true
+			acc := &testutil.Accumulator{}
+			require.NoError(t, s.Start(acc))
+			defer s.Stop()
+
+			require.NoError(t, s.parseServiceCheckMessage(now, tt.message, tt.hostname, ""))
+			require.Len(t, acc.Metrics, 1)
+			require.Equal(t, tt.expected, acc.Metrics[0])
+		})
+	}
+}
+
+func TestServiceCheckError(t *testing.T) {
+	now := time.Now()
+	s := newTestStatsd()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	// missing status
+	require.Error(t, s.parseServiceCheckMessage(now, "_sc|agent.up", "", ""))
+
+	// empty name
+	require.Error(t, s.parseServiceCheckMessage(now, "_sc||0", "", ""))
+
+	// invalid status
+	require.Error(t, s.parseServiceCheckMessage(now, "_sc|agent.up|ok", "", ""))
+	require.Error(t, s.parseServiceCheckMessage(now, "_sc|agent.up|4", "", ""))
+
+	// invalid timestamp
+	require.NoError(t, s.parseServiceCheckMessage(now, "_sc|agent.up|0|d:abc", "", ""))
+
+	// unknown metadata
+	require.Error(t, s.parseServiceCheckMessage(now, "_sc|agent.up|0|x:1234", "", ""))
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"os"
 	"regexp"
 	"sort"
 	"strconv"
@@ -84,7 +85,14 @@ type Statsd struct {
 	// https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/?tab=metrics#dogstatsd-protocol-v12
 	DataDogKeepContainerTag bool `toml:"datadog_keep_container_tag"`
 
+	// Determine the container of the sending process for messages received
+	// via Unix domain sockets and use it as container tag.
+	// Requires the DataDogKeepContainerTag flag to be enabled.
+	// https://docs.datadoghq.com/developers/dogstatsd/unix_socket/#origin-detection
+	DataDogOriginDetection bool `toml:"datadog_origin_detection"`
+
 	ReadBufferSize      int              `toml:"read_buffer_size"`
+	SocketMode          string           `toml:"socket_mode"`
 	SanitizeNamesMethod string           `toml:"sanitize_name_method"`
 	Templates           []string         `toml:"templates"` // bucket -> influx templates
 	MaxTCPConnections   int              `toml:"max_tcp_connections"`
@@ -114,15 +122,18 @@ type Statsd struct {
 	// gauges and counters map measurement/tags hash -> field name -> metrics
 	// sets and timings map measurement/tags hash -> metrics
 	// distributions aggregate measurement/tags and are published directly
+	// timestamped gauges and counters are published directly with their time
 	gauges        map[string]cachedgauge
 	counters      map[string]cachedcounter
 	sets          map[string]cachedset
 	timings       map[string]cachedtimings
 	distributions []cacheddistributions
+	timestamped   []cachedtimestamped
 
 	// Protocol listeners
-	UDPlistener *net.UDPConn
-	TCPlistener *net.TCPListener
+	UDPlistener  *net.UDPConn
+	TCPlistener  *net.TCPListener
+	unixListener *net.UnixConn
 
 	// track current connections so we can close them in Stop()
 	conns          map[string]*net.TCPConn
@@ -145,6 +156,9 @@ type internalStats struct {
 	UDPPacketsRecv     selfstat.Stat
 	UDPPacketsDrop     selfstat.Stat
 	UDPBytesRecv       selfstat.Stat
+	UDSPacketsRecv     selfstat.Stat
+	UDSPacketsDrop     selfstat.Stat
+	UDSBytesRecv       selfstat.Stat
 	ParseTimeNS        selfstat.Stat
 	PendingMessages    selfstat.Stat
 	MaxPendingMessages selfstat.Stat
@@ -168,6 +182,8 @@ type input struct {
 	*bytes.Buffer
 	time.Time
 	Addr string
+	// Container of the sending process determined by origin detection
+	Container string
 }
 
 // One statsd metric, form is <bucket>:<value>|<mtype>|@<samplerate>
@@ -183,6 +199,7 @@ type metric struct {
 	additive   bool
 	samplerate float64
 	tags       map[string]string
+	timestamp  time.Time
 }
 
 type cachedset struct {
@@ -219,6 +236,15 @@ type cacheddistributions struct {
 	tags  map[string]string
 }
 
+type cachedtimestamped struct {
+	name      string
+	field     string
+	mtype     string
+	value     interface{}
+	tags      map[string]string
+	timestamp time.Time
+}
+
 func (*Statsd) SampleConfig() string {
 	return sampleConfig
 }
@@ -233,6 +259,7 @@ func (s *Statsd) Start(ac telegraf.Accumulator) error {
 	s.sets = make(map[string]cachedset)
 	s.timings = make(map[string]cachedtimings)
 	s.distributions = make([]cacheddistributions, 0)
+	s.timestamped = make([]cachedtimestamped, 0)
 
 	s.Lock()
 	defer s.Unlock()
@@ -250,6 +277,9 @@ func (s *Statsd) Start(ac telegraf.Accumulator) error {
 	s.Stats.UDPPacketsRecv = selfstat.Register("statsd", "udp_packets_received", tags)
 	s.Stats.UDPPacketsDrop = selfstat.Register("statsd", "udp_packets_dropped", tags)
 	s.Stats.UDPBytesRecv = selfstat.Register("statsd", "udp_bytes_received", tags)
+	s.Stats.UDSPacketsRecv = selfstat.Register("statsd", "uds_packets_received", tags)
+	s.Stats.UDSPacketsDrop = selfstat.Register("statsd", "uds_packets_dropped", tags)
+	s.Stats.UDSBytesRecv = selfstat.Register("statsd", "uds_bytes_received", tags)
 	s.Stats.ParseTimeNS = selfstat.Register("statsd", "parse_time_ns", tags)
 	s.Stats.PendingMessages = selfstat.Register("statsd", "pending_messages", tags)
 	s.Stats.MaxPendingMessages = selfstat.Register("statsd", "max_pending_messages", tags)
@@ -272,7 +302,13 @@ func (s *Statsd) Start(ac telegraf.Accumulator) error {
 		s.MetricSeparator = defaultSeparator
 	}
 
-	if s.isUDP() {
+	if s.DataDogOriginDetection && (!s.DataDogExtensions || !s.DataDogKeepContainerTag || !s.isUnixgram()) {
+		s.Log.Warn("Origin detection requires 'datadog_extensions', 'datadog_keep_container_tag' and the 'unixgram' protocol, ignoring")
+		s.DataDogOriginDetection = false
+	}
+
+	switch {
+	case s.isUDP():
 		address, err := net.ResolveUDPAddr(s.Protocol, s.ServiceAddress)
 		if err != nil {
 			return err
@@ -293,7 +329,23 @@ func (s *Statsd) Start(ac telegraf.Accumulator) error {
 				ac.AddError(err)
 			}
 		}()
-	} else {
+	case s.isUnixgram():
+		conn, err := s.listenUnixgram()
+		if err != nil {
+			return err
+		}
+
+		s.Log.Infof("Unix datagram socket listening on %q", s.ServiceAddress)
+		s.unixListener = conn
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.unixgramListen(conn); err != nil {
+				ac.AddError(err)
+			}
+		}()
+	default:
 		address, err := net.ResolveTCPAddr("tcp", s.ServiceAddress)
 		if err != nil {
 			return err
@@ -345,6 +397,21 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 	}
 	s.distributions = make([]cacheddistributions, 0)
 
+	for _, m := range s.timestamped {
+		fields := map[string]interface{}{
+			m.field: m.value,
+		}
+		if m.mtype == "c" {
+			if s.FloatCounters {
+				fields[m.field] = float64(m.value.(int64))
+			}
+			acc.AddCounter(m.name, fields, m.tags, m.timestamp)
+		} else {
+			acc.AddGauge(m.name, fields, m.tags, m.timestamp)
+		}
+	}
+	s.timestamped = make([]cachedtimestamped, 0)
+
 	for _, m := range s.timings {
 		// Defining a template to parse field names for timers allows us to split
 		// out multiple fields per timer. In this case we prefix each stat with the
@@ -437,11 +504,19 @@ func (s *Statsd) Stop() {
 	s.Lock()
 	s.Log.Infof("Stopping the statsd service")
 	close(s.done)
-	if s.isUDP() {
+	switch {
+	case s.isUDP():
 		if s.UDPlistener != nil {
 			s.UDPlistener.Close()
 		}
-	} else {
+	case s.isUnixgram():
+		if s.unixListener != nil {
+			s.unixListener.Close()
+			if err := os.Remove(s.ServiceAddress); err != nil && !errors.Is(err, os.ErrNotExist) {
+				s.Log.Warnf("Removing socket failed: %v", err)
+			}
+		}
+	default:
 		if s.TCPlistener != nil {
 			s.TCPlistener.Close()
 		}
@@ -564,6 +639,107 @@ func (s *Statsd) udpListen(conn *net.UDPConn) error {
 	}
 }
 
+// listenUnixgram creates the Unix datagram socket replacing stale sockets of
+// previous runs.
+func (s *Statsd) listenUnixgram() (*net.UnixConn, error) {
+	if err := os.Remove(s.ServiceAddress); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("removing socket failed: %w", err)
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: s.ServiceAddress, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("listening (unixgram) failed: %w", err)
+	}
+
+	// Set permissions on socket
+	if s.SocketMode != "" {
+		// Convert from octal in string to int
+		i, err := strconv.ParseUint(s.SocketMode, 8, 32)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("converting socket mode failed: %w", err)
+		}
+		if err := os.Chmod(s.ServiceAddress, os.FileMode(uint32(i))); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("changing socket permissions failed: %w", err)
+		}
+	}
+
+	if s.ReadBufferSize > 0 {
+		if err := conn.SetReadBuffer(s.ReadBufferSize); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if s.DataDogOriginDetection {
+		if err := enableOriginDetection(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("enabling origin detection failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// unixgramListen starts listening for datagrams on the Unix domain socket.
+func (s *Statsd) unixgramListen(conn *net.UnixConn) error {
+	origins := newOriginCache()
+
+	buf := make([]byte, udpMaxPacketSize)
+	oob := make([]byte, oobSize)
+	for {
+		select {
+		case <-s.done:
+			return nil
+		default:
+			n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+			if err != nil {
+				if !strings.Contains(err.Error(), "closed network") {
+					s.Log.Errorf("Error reading: %s", err.Error())
+					continue
+				}
+				return nil
+			}
+			s.Stats.UDSPacketsRecv.Incr(1)
+			s.Stats.UDSBytesRecv.Incr(int64(n))
+
+			var container string
+			if s.DataDogOriginDetection {
+				pid, err := originPID(oob[:oobn])
+				if err == nil {
+					container, err = origins.container(pid)
+				}
+				if err != nil {
+					s.Log.Debugf("Determining origin failed: %v", err)
+				}
+			}
+
+			b, ok := s.bufPool.Get().(*bytes.Buffer)
+			if !ok {
+				return errors.New("bufPool is not a bytes buffer")
+			}
+			b.Reset()
+			b.Write(buf[:n])
+			select {
+			case s.in <- input{
+				Buffer:    b,
+				Time:      time.Now(),
+				Container: container}:
+				s.Stats.PendingMessages.Set(int64(len(s.in)))
+			default:
+				s.Stats.UDSPacketsDrop.Incr(1)
+				s.drops++
+				if s.drops == 1 || s.AllowedPendingMessages == 0 || s.drops%s.AllowedPendingMessages == 0 {
+					s.Log.Errorf("Statsd message queue full. "+
+						"We have dropped %d messages so far. "+
+						"You may want to increase allowed_pending_messages in the config", s.drops)
+				}
+			}
+		}
+	}
+}
+
 // parser monitors the s.in channel, if there is a packet ready, it parses the
 // packet into statsd strings and then calls parseStatsdLine, which parses a
 // single statsd metric into a struct.
@@ -582,15 +758,20 @@ func (s *Statsd) parser() error {
 				switch {
 				case line == "":
 				case s.DataDogExtensions && strings.HasPrefix(line, "_e"):
-					if err := s.parseEventMessage(in.Time, line, in.Addr); err != nil {
+					if err := s.parseEventMessageWithContainer(in.Time, line, in.Addr, in.Container); err != nil {
 						// Log the line causing the parsing error and continue
 						// with the next line to not stop the whole gathering
 						// process.
 						s.Log.Errorf("Parsing line failed: %v", err)
 						s.Log.Debugf("  line was: %s", line)
 					}
+				case s.DataDogExtensions && strings.HasPrefix(line, "_sc"):
+					if err := s.parseServiceCheckMessage(in.Time, line, in.Addr, in.Container); err != nil {
+						s.Log.Errorf("Parsing line failed: %v", err)
+						s.Log.Debugf("  line was: %s", line)
+					}
 				default:
-					if err := s.parseStatsdLine(line); err != nil {
+					if err := s.parseStatsdLineWithContainer(line, in.Container); err != nil {
 						if !errors.Is(err, errParsing) {
 							// Ignore parsing errors but error out on
 							// everything else...
@@ -606,31 +787,62 @@ func (s *Statsd) parser() error {
 }
 
 // parseStatsdLine will parse the given statsd line, validating it as it goes.
-// If the line is valid, it will be cached for the next call to Gather()
+// If the line is valid, it will be cached for the next call to Gather().
 func (s *Statsd) parseStatsdLine(line string) error {
+	return s.parseStatsdLineWithContainer(line, "")
+}
+
+// parseStatsdLineWithContainer parses the line like parseStatsdLine using the
+// container determined by origin detection unless the line contains a
+// container ID field.
+func (s *Statsd) parseStatsdLineWithContainer(line, container string) error {
 	lineTags := make(map[string]string)
+	var timestamp time.Time
+	var multiValue bool
 	if s.DataDogExtensions {
-		recombinedSegments := make([]string, 0)
-		// datadog tags look like this:
+		// datadog extensions look like this:
 		// users.online:1|c|@0.5|#country:china,environment:production
-		// users.online:1|c|#sometagwithnovalue
-		// we will split on the pipe and remove any elements that are datadog
-		// tags, parse them, and rebuild the line sans the datadog tags
+		// users.online:1|c|#sometagwithnovalue|c:<container id>|T1656581400
+		// users.online:1:2:3|d|@0.5|#country:china
+		// we will split on the pipe and remove any elements after the type
+		// that are datadog extensions, parse them, and rebuild the line sans
+		// the extensions. Lines with multiple statsd metrics like
+		// "name:1|c:2|g" do not contain extensions.
 		pipesplit := strings.Split(line, "|")
-		for _, segment := range pipesplit {
-			if len(segment) > 0 && segment[0] == '#' {
-				// we have ourselves a tag; they are comma separated
-				parseDataDogTags(lineTags, segment[1:])
-			} else if len(segment) > 0 && strings.HasPrefix(segment, "c:") {
-				// This is optional container ID field
-				if s.DataDogKeepContainerTag {
-					lineTags["container"] = segment[2:]
+		if len(pipesplit) > 1 && !strings.Contains(pipesplit[1], ":") {
+			recombinedSegments := make([]string, 0, len(pipesplit))
+			recombinedSegments = append(recombinedSegments, pipesplit[:2]...)
+			for _, segment := range pipesplit[2:] {
+				switch {
+				case strings.HasPrefix(segment, "#"):
+					// we have ourselves a tag; they are comma separated
+					parseDataDogTags(lineTags, segment[1:])
+				case strings.HasPrefix(segment, "c:"):
+					// This is optional container ID field
+					container = segment[2:]
+				case strings.HasPrefix(segment, "T"):
+					// This is optional timestamp field in seconds
+					ts, err := strconv.ParseInt(segment[1:], 10, 64)
+					if err != nil {
+						s.Log.Errorf("Parsing timestamp, unable to parse metric: %s", line)
+						return errParsing
+					}
+					timestamp = time.Unix(ts, 0)
+				case ignoredDataDogField(segment):
+					// Not supported, see ignoredDataDogField
+				default:
+					recombinedSegments = append(recombinedSegments, segment)
 				}
-			} else {
-				recombinedSegments = append(recombinedSegments, segment)
 			}
+			line = strings.Join(recombinedSegments, "|")
+
+			// Multiple values of the same metric like "name:1:2:3|d" share
+			// the type and sample rate
+			multiValue = strings.Count(pipesplit[0], ":") > 1
+		}
+		if s.DataDogKeepContainerTag && container != "" {
+			lineTags["container"] = container
 		}
-		line = strings.Join(recombinedSegments, "|")
 	}
 
 	// Validate splitting the line on ":"
@@ -642,12 +854,26 @@ func (s *Statsd) parseStatsdLine(line string) error {
 
 	// Extract bucket name from individual metric bits
 	bucketName, bits := bits[0], bits[1:]
+	if multiValue {
+		// Append the type and sample rate of the last value to all values
+		last := bits[len(bits)-1]
+		idx := strings.Index(last, "|")
+		if idx < 0 {
+			s.Log.Errorf("Splitting '|', unable to parse metric: %s", line)
+			return errParsing
+		}
+		suffix := last[idx:]
+		for i := range bits[:len(bits)-1] {
+			bits[i] += suffix
+		}
+	}
 
 	// Add a metric for each bit available
 	for _, bit := range bits {
 		m := metric{}
 
 		m.bucket = bucketName
+		m.timestamp = timestamp
 
 		// Validate splitting the bit on "|"
 		pipesplit := strings.Split(bit, "|")
@@ -848,6 +1074,25 @@ func (s *Statsd) aggregate(m metric) {
 	s.Lock()
 	defer s.Unlock()
 
+	// Gauges and counters with timestamp are not aggregated but published with
+	// their time, other types ignore the timestamp
+	if !m.timestamp.IsZero() && (m.mtype == "g" || m.mtype == "c") {
+		cached := cachedtimestamped{
+			name:      m.name,
+			field:     m.field,
+			mtype:     m.mtype,
+			tags:      m.tags,
+			timestamp: m.timestamp,
+		}
+		if m.mtype == "c" {
+			cached.value = m.intvalue
+		} else {
+			cached.value = m.floatvalue
+		}
+		s.timestamped = append(s.timestamped, cached)
+		return
+	}
+
 	switch m.mtype {
 	case "d":
 		if s.DataDogExtensions && s.DataDogDistributions {
@@ -1031,6 +1276,12 @@ func (s *Statsd) isUDP() bool {
 	return strings.HasPrefix(s.Protocol, "udp")
 }
 
+// isUnixgram returns true if the protocol is Unix datagram sockets, false
+// otherwise.
+func (s *Statsd) isUnixgram() bool {
+	return s.Protocol == "unixgram"
+}
+
 func (s *Statsd) expireCachedMetrics() {
 	// If Max TTL wasn't configured, skip expiration.
 	if s.MaxTTL == 0 {
@@ -3,6 +3,9 @@ package statsd
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -1440,6 +1443,152 @@ func TestParse_DataDogContainerID(t *testing.T) {
 }
 
 // Test that statsd buckets are parsed to measurement names properly
+func TestParse_DataDogMultipleValues(t *testing.T) {
+	s := newTestStatsd()
+	s.DataDogExtensions = true
+	s.DataDogDistributions = true
+
+	require.NoError(t, s.parseStatsdLine("my_dist:1:2.5:3|d|@0.5|#env:prod|c:abc"))
+	require.NoError(t, s.parseStatsdLine("my_counter:1:2:3|c|#env:prod"))
+	require.NoError(t, s.parseStatsdLine("my_timer:3:5|ms"))
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"my_counter",
+			map[string]string{"env": "prod", "metric_type": "counter"},
+			map[string]interface{}{"value": 6},
+			time.Now(),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"my_dist",
+			map[string]string{"env": "prod", "metric_type": "distribution"},
+			map[string]interface{}{"value": 1.0},
+			time.Now(),
+		),
+		testutil.MustMetric(
+			"my_dist",
+			map[string]string{"env": "prod", "metric_type": "distribution"},
+			map[string]interface{}{"value": 2.5},
+			time.Now(),
+		),
+		testutil.MustMetric(
+			"my_dist",
+			map[string]string{"env": "prod", "metric_type": "distribution"},
+			map[string]interface{}{"value": 3.0},
+			time.Now(),
+		),
+		testutil.MustMetric(
+			"my_timer",
+			map[string]string{"metric_type": "timing"},
+			map[string]interface{}{
+				"count":  2,
+				"lower":  float64(3),
+				"mean":   float64(4),
+				"median": float64(4),
+				"stddev": float64(1),
+				"sum":    float64(8),
+				"upper":  float64(5),
+			},
+			time.Now(),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(),
+		testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+func TestParse_DataDogTimestamp(t *testing.T) {
+	s := newTestStatsd()
+	s.DataDogExtensions = true
+	s.DeleteCounters = true
+	s.DeleteGauges = true
+	s.DeleteSets = true
+
+	// Timestamped gauges and counters are not aggregated
+	require.NoError(t, s.parseStatsdLine("my_gauge:10.1|g|#env:prod|T1656581400"))
+	require.NoError(t, s.parseStatsdLine("my_gauge:11.3|g|#env:prod|T1656581410"))
+	require.NoError(t, s.parseStatsdLine("my_counter:2|c|@0.5|T1656581400|e:it-false,cn-app|card:low"))
+	require.NoError(t, s.parseStatsdLine("my_counter:1|c"))
+	// Other types ignore the timestamp
+	require.NoError(t, s.parseStatsdLine("my_set:1|s|T1656581400"))
+	require.ErrorIs(t, s.parseStatsdLine("my_gauge:1|g|Tnow"), errParsing)
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"my_counter",
+			map[string]string{"metric_type": "counter"},
+			map[string]interface{}{"value": 4},
+			time.Unix(1656581400, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"my_gauge",
+			map[string]string{"env": "prod", "metric_type": "gauge"},
+			map[string]interface{}{"value": 10.1},
+			time.Unix(1656581400, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"my_gauge",
+			map[string]string{"env": "prod", "metric_type": "gauge"},
+			map[string]interface{}{"value": 11.3},
+			time.Unix(1656581410, 0),
+			telegraf.Gauge,
+		),
+	}
+	actual := make([]telegraf.Metric, 0, len(expected))
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Time().Unix() == 1656581400 || m.Time().Unix() == 1656581410 {
+			actual = append(actual, m)
+		}
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.SortMetrics())
+	require.Len(t, acc.GetTelegrafMetrics(), 5)
+
+	// Timestamped metrics are only published once
+	acc.ClearMetrics()
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestParse_DataDogOriginContainer(t *testing.T) {
+	s := newTestStatsd()
+	s.DataDogExtensions = true
+	s.DataDogKeepContainerTag = true
+
+	// The container ID field takes precedence over the origin
+	require.NoError(t, s.parseStatsdLineWithContainer("cpu:42|g|c:abc", "origin"))
+	require.NoError(t, s.parseStatsdLineWithContainer("mem:42|g", "origin"))
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"container": "abc", "metric_type": "gauge"},
+			map[string]interface{}{"value": 42.0},
+			time.Now(),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"container": "origin", "metric_type": "gauge"},
+			map[string]interface{}{"value": 42.0},
+			time.Now(),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(),
+		testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
 func TestParseName(t *testing.T) {
 	s := newTestStatsd()
 
@@ -2155,6 +2304,62 @@ func TestUdp(t *testing.T) {
 	)
 }
 
+func TestUnixgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping on Windows, as unixgram sockets are not supported")
+	}
+
+	sock := filepath.Join(t.TempDir(), "statsd.sock")
+	statsd := Statsd{
+		Log:                    testutil.Logger{},
+		Protocol:               "unixgram",
+		ServiceAddress:         sock,
+		SocketMode:             "722",
+		AllowedPendingMessages: 10000,
+		NumberWorkerThreads:    5,
+		DataDogExtensions:      true,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, statsd.Start(&acc))
+
+	info, err := os.Stat(sock)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o722), info.Mode().Perm())
+
+	conn, err := net.Dial("unixgram", sock)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("cpu.time_idle:42|c|#env:prod\n_sc|agent.up|0\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, statsd.Gather(&acc))
+		return acc.NMetrics() >= 2
+	}, 3*time.Second, 10*time.Millisecond)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"agent.up",
+			map[string]string{},
+			map[string]interface{}{"status": int64(0)},
+			time.Now(),
+		),
+		testutil.MustMetric(
+			"cpu_time_idle",
+			map[string]string{"env": "prod", "metric_type": "counter"},
+			map[string]interface{}{"value": 42},
+			time.Now(),
+			telegraf.Counter,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(),
+		testutil.SortMetrics(), testutil.IgnoreTime())
+
+	// The socket is removed when stopping
+	statsd.Stop()
+	require.NoFileExists(t, sock)
+}
+
 func TestUdpFillQueue(t *testing.T) {
 	logger := testutil.CaptureLogger{}
 	plugin := &Statsd{
@@ -0,0 +1,264 @@
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/leodido/go-syslog/v4"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/relp"
+)
+
+// relpTransaction identifies a message waiting for delivery to be
+// acknowledged to the client
+type relpTransaction struct {
+	session *relp.ServerSession
+	txnr    uint32
+}
+
+// relpServer receives messages via RELP and acknowledges each message after
+// the metric was delivered to the outputs
+type relpServer struct {
+	address        string
+	network        string
+	tlsCfg         *tls.Config
+	maxUndelivered int
+	maxConns       uint64
+	readTimeout    time.Duration
+	keepAlive      *config.Duration
+	newParser      func() syslog.Machine
+	separator      string
+	log            telegraf.Logger
+
+	listener net.Listener
+	acc      telegraf.TrackingAccumulator
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	// Limits the number of messages waiting for delivery
+	sem chan struct{}
+
+	sync.Mutex
+	conns       map[net.Conn]bool
+	undelivered map[telegraf.TrackingID]relpTransaction
+}
+
+func (r *relpServer) start(acc telegraf.Accumulator) error {
+	listener, err := net.Listen(r.network, r.address)
+	if err != nil {
+		return err
+	}
+	if r.tlsCfg != nil {
+		listener = tls.NewListener(listener, r.tlsCfg)
+	}
+	r.listener = listener
+	r.log.Infof("Listening for RELP on %s://%s", listener.Addr().Network(), listener.Addr().String())
+
+	r.acc = acc.WithTracking(r.maxUndelivered)
+	r.sem = make(chan struct{}, r.maxUndelivered)
+	r.conns = make(map[net.Conn]bool)
+	r.undelivered = make(map[telegraf.TrackingID]relpTransaction)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.acknowledge(ctx)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.accept(ctx)
+	}()
+
+	return nil
+}
+
+func (r *relpServer) stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	if r.listener != nil {
+		r.listener.Close()
+	}
+
+	r.Lock()
+	for conn := range r.conns {
+		conn.Close()
+	}
+	r.Unlock()
+
+	r.wg.Wait()
+}
+
+func (r *relpServer) accept(ctx context.Context) {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				r.acc.AddError(fmt.Errorf("accepting connection failed: %w", err))
+			}
+			return
+		}
+
+		// Do not serve connections accepted while stopping
+		r.Lock()
+		if ctx.Err() != nil {
+			r.Unlock()
+			conn.Close()
+			return
+		}
+		if r.maxConns > 0 && uint64(len(r.conns)) >= r.maxConns {
+			r.Unlock()
+			conn.Close()
+			r.acc.AddError(fmt.Errorf("unable to accept connection from %q: too many connections", conn.RemoteAddr()))
+			continue
+		}
+		r.conns[conn] = true
+		r.Unlock()
+		r.setKeepAlive(conn)
+
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			defer func() {
+				r.Lock()
+				delete(r.conns, conn)
+				r.Unlock()
+				conn.Close()
+			}()
+			err := r.handle(ctx, conn)
+			switch {
+			case err == nil, errors.Is(err, net.ErrClosed):
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				// Silently close inactive sessions
+				r.log.Debugf("Closing inactive RELP session with %s", conn.RemoteAddr())
+			default:
+				r.acc.AddError(fmt.Errorf("RELP session with %s failed: %w", conn.RemoteAddr(), err))
+			}
+		}()
+	}
+}
+
+// setKeepAlive applies the configured keep-alive setting to the connection
+func (r *relpServer) setKeepAlive(conn net.Conn) {
+	if r.keepAlive == nil {
+		return
+	}
+	if c, ok := conn.(*tls.Conn); ok {
+		conn = c.NetConn()
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		r.log.Warnf("connection not a TCP connection (%T)", conn)
+		return
+	}
+	if *r.keepAlive == 0 {
+		if err := tcpConn.SetKeepAlive(false); err != nil {
+			r.log.Warnf("Cannot set keep-alive: %v", err)
+		}
+		return
+	}
+	if err := tcpConn.SetKeepAlive(true); err != nil {
+		r.log.Warnf("Cannot set keep-alive: %v", err)
+	}
+	if err := tcpConn.SetKeepAlivePeriod(time.Duration(*r.keepAlive)); err != nil {
+		r.log.Warnf("Cannot set keep-alive period: %v", err)
+	}
+}
+
+// handle serves the session of a client and adds a tracking metric for each
+// message received
+func (r *relpServer) handle(ctx context.Context, conn net.Conn) error {
+	// Remove port from address
+	addr, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		addr = conn.RemoteAddr().String()
+	}
+
+	parser := r.newParser()
+	session := relp.NewServerSession(&timeoutConn{Conn: conn, timeout: r.readTimeout})
+	return session.Serve(func(txnr uint32, data []byte) {
+		msg, err := parser.Parse(data)
+		if err != nil || msg == nil {
+			// Acknowledge invalid messages to not block the client retrying
+			// the same message forever
+			if err == nil {
+				err = fmt.Errorf("unable to parse message: %s", string(data))
+			}
+			r.acc.AddError(err)
+			if err := session.Respond(txnr, relp.StatusOK, "OK"); err != nil {
+				r.log.Debugf("Acknowledging message failed: %v", err)
+			}
+			return
+		}
+		m := metric.New("syslog", tags(msg, addr), fields(msg, r.separator), time.Now())
+
+		// Wait for deliveries if too many messages are in flight
+		select {
+		case r.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		r.Lock()
+		id := r.acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+		r.undelivered[id] = relpTransaction{session: session, txnr: txnr}
+		r.Unlock()
+	})
+}
+
+// acknowledge sends the response for each message once the metric was
+// processed. Messages not delivered are refused so the client sends them
+// again.
+func (r *relpServer) acknowledge(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-r.acc.Delivered():
+			r.Lock()
+			tx, found := r.undelivered[info.ID()]
+			delete(r.undelivered, info.ID())
+			r.Unlock()
+			if !found {
+				continue
+			}
+			<-r.sem
+
+			code, message := relp.StatusOK, "OK"
+			if !info.Delivered() {
+				code, message = relp.StatusError, "message not delivered"
+			}
+			if err := tx.session.Respond(tx.txnr, code, message); err != nil {
+				r.log.Debugf("Acknowledging message failed: %v", err)
+			}
+		}
+	}
+}
+
+// timeoutConn closes the session if no data arrived within the timeout by
+// setting the read deadline before each read
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	if c.timeout > 0 {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, fmt.Errorf("setting read deadline failed: %w", err)
+		}
+	}
+	return c.Conn.Read(b)
+}
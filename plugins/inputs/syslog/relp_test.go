@@ -0,0 +1,224 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/relp"
+	"github.com/influxdata/telegraf/plugins/common/socket"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type sendResult struct {
+	acked []int
+	err   error
+}
+
+func TestRELPInit(t *testing.T) {
+	plugin := &Syslog{
+		Address: "udp://127.0.0.1:0",
+		Framing: "relp",
+		Log:     testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), `protocol "udp" not supported with RELP framing`)
+
+	plugin = &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Framing: "relp",
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, defaultMaxUndeliveredMessages, plugin.MaxUndeliveredMessages)
+}
+
+func TestRELPAcknowledgeOnDelivery(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		name := "tcp"
+		if useTLS {
+			name = "tls"
+		}
+		t.Run(name, func(t *testing.T) {
+			plugin := &Syslog{
+				Address: "tcp://127.0.0.1:0",
+				Framing: "relp",
+				Log:     testutil.Logger{},
+			}
+			if useTLS {
+				plugin.ServerConfig = *pki.TLSServerConfig()
+			}
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Start(&acc))
+			defer plugin.Stop()
+
+			addr := plugin.relp.listener.Addr().String()
+			var conn net.Conn
+			var err error
+			if useTLS {
+				tlscfg, err := pki.TLSClientConfig().TLSConfig()
+				require.NoError(t, err)
+				conn, err = tls.Dial("tcp", addr, tlscfg)
+				require.NoError(t, err)
+			} else {
+				conn, err = net.Dial("tcp", addr)
+				require.NoError(t, err)
+			}
+			client, err := relp.NewClient(conn, 5*time.Second)
+			require.NoError(t, err)
+			defer client.Close()
+
+			messages := [][]byte{
+				[]byte("<13>1 2025-01-01T00:00:00Z host app - - - first"),
+				[]byte("<13>1 2025-01-01T00:00:01Z host app - - - second"),
+				[]byte("invalid"),
+			}
+			result := make(chan sendResult, 1)
+			go func() {
+				acked, err := client.Send(messages, 10)
+				result <- sendResult{acked: acked, err: err}
+			}()
+
+			// Messages are only acknowledged after delivery
+			acc.Wait(2)
+			require.Never(t, func() bool {
+				return len(result) > 0
+			}, 100*time.Millisecond, 10*time.Millisecond)
+
+			metrics := acc.GetTelegrafMetrics()
+			require.Len(t, metrics, 2)
+			require.Equal(t, "first", metrics[0].Fields()["message"])
+			require.Equal(t, "second", metrics[1].Fields()["message"])
+			metrics[0].Accept()
+			metrics[1].Reject()
+
+			// The invalid message is acknowledged to not block the client and
+			// the rejected message is refused to be sent again
+			var r sendResult
+			require.Eventually(t, func() bool {
+				select {
+				case r = <-result:
+					return true
+				default:
+					return false
+				}
+			}, 3*time.Second, 10*time.Millisecond)
+			require.ErrorContains(t, r.err, "message refused: 500 message not delivered")
+			require.ElementsMatch(t, []int{0, 2}, r.acked)
+			require.Len(t, acc.Errors, 1)
+		})
+	}
+}
+
+func TestRELPMaxUndelivered(t *testing.T) {
+	plugin := &Syslog{
+		Address:                "tcp://127.0.0.1:0",
+		Framing:                "relp",
+		MaxUndeliveredMessages: 1,
+		Log:                    testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	conn, err := net.Dial("tcp", plugin.relp.listener.Addr().String())
+	require.NoError(t, err)
+	client, err := relp.NewClient(conn, 5*time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result := make(chan sendResult, 1)
+	go func() {
+		acked, err := client.Send([][]byte{
+			[]byte("<13>1 2025-01-01T00:00:00Z host app - - - first"),
+			[]byte("<13>1 2025-01-01T00:00:01Z host app - - - second"),
+		}, 10)
+		result <- sendResult{acked: acked, err: err}
+	}()
+
+	// The second message is only processed after the first was delivered
+	acc.Wait(1)
+	require.Never(t, func() bool {
+		return acc.NMetrics() > 1
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	acc.GetTelegrafMetrics()[0].Accept()
+
+	acc.Wait(2)
+	acc.GetTelegrafMetrics()[1].Accept()
+	r := <-result
+	require.NoError(t, r.err)
+	require.ElementsMatch(t, []int{0, 1}, r.acked)
+}
+
+func TestRELPMaxConnections(t *testing.T) {
+	plugin := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Framing: "relp",
+		Config:  socket.Config{MaxConnections: 1},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	addr := plugin.relp.listener.Addr().String()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	client, err := relp.NewClient(conn, 5*time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Connections exceeding the limit are closed by the server
+	conn, err = net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	require.Eventually(t, func() bool {
+		acc.Lock()
+		defer acc.Unlock()
+		return len(acc.Errors) > 0
+	}, 3*time.Second, 10*time.Millisecond)
+	acc.Lock()
+	defer acc.Unlock()
+	require.ErrorContains(t, acc.Errors[0], "too many connections")
+}
+
+func TestRELPReadTimeout(t *testing.T) {
+	plugin := &Syslog{
+		Address: "tcp://127.0.0.1:0",
+		Framing: "relp",
+		Config:  socket.Config{ReadTimeout: config.Duration(100 * time.Millisecond)},
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	conn, err := net.Dial("tcp", plugin.relp.listener.Addr().String())
+	require.NoError(t, err)
+	client, err := relp.NewClient(conn, 5*time.Second)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Inactive sessions are closed without reporting an error
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	acc.Lock()
+	defer acc.Unlock()
+	require.Empty(t, acc.Errors)
+}
@@ -27,7 +27,11 @@ import (
 //go:embed sample.conf
 var sampleConfig string
 
-const readTimeoutMsg = "Read timeout set! Connections, inactive for the set duration, will be closed!"
+const (
+	readTimeoutMsg = "Read timeout set! Connections, inactive for the set duration, will be closed!"
+
+	defaultMaxUndeliveredMessages = 1000
+)
 
 type Syslog struct {
 	Address        string                     `toml:"server"`
@@ -36,7 +40,10 @@ type Syslog struct {
 	Trailer        nontransparent.TrailerType `toml:"trailer"`
 	BestEffort     bool                       `toml:"best_effort"`
 	Separator      string                     `toml:"sdparam_separator"`
-	Log            telegraf.Logger            `toml:"-"`
+	// Number of messages received via RELP and not yet delivered to the
+	// outputs before waiting for deliveries
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+	Log                    telegraf.Logger `toml:"-"`
 	socket.Config
 
 	mu sync.Mutex
@@ -44,6 +51,7 @@ type Syslog struct {
 
 	url    *url.URL
 	socket *socket.Socket
+	relp   *relpServer
 }
 
 func (*Syslog) SampleConfig() string {
@@ -55,7 +63,7 @@ func (s *Syslog) Init() error {
 	switch s.Framing {
 	case "":
 		s.Framing = "octet-counting"
-	case "octet-counting", "non-transparent":
+	case "octet-counting", "non-transparent", "relp":
 	default:
 		return fmt.Errorf("invalid 'framing' %q", s.Framing)
 	}
@@ -102,6 +110,35 @@ func (s *Syslog) Init() error {
 		return fmt.Errorf("unknown protocol %q in %q", u.Scheme, s.Address)
 	}
 
+	// RELP uses its own server as acknowledgements are sent to the client
+	if s.Framing == "relp" {
+		switch s.url.Scheme {
+		case "tcp", "tcp4", "tcp6":
+		default:
+			return fmt.Errorf("protocol %q not supported with RELP framing", u.Scheme)
+		}
+		if s.MaxUndeliveredMessages <= 0 {
+			s.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+		}
+		tlsCfg, err := s.Config.ServerConfig.TLSConfig()
+		if err != nil {
+			return err
+		}
+		s.relp = &relpServer{
+			address:        u.Host,
+			network:        u.Scheme,
+			tlsCfg:         tlsCfg,
+			maxUndelivered: s.MaxUndeliveredMessages,
+			maxConns:       s.MaxConnections,
+			readTimeout:    time.Duration(s.ReadTimeout),
+			keepAlive:      s.KeepAlivePeriod,
+			newParser:      s.newMachine,
+			separator:      s.Separator,
+			log:            s.Log,
+		}
+		return nil
+	}
+
 	// Create a socket
 	sock, err := s.Config.NewSocket(u.String(), nil, s.Log)
 	if err != nil {
@@ -116,6 +153,10 @@ func (s *Syslog) Start(acc telegraf.Accumulator) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.relp != nil {
+		return s.relp.start(acc)
+	}
+
 	// Setup the listener
 	if err := s.socket.Setup(); err != nil {
 		return err
@@ -149,6 +190,10 @@ func (s *Syslog) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.relp != nil {
+		s.relp.stop()
+		return
+	}
 	s.socket.Close()
 	s.wg.Wait()
 }
@@ -197,8 +242,9 @@ func (s *Syslog) createStreamDataHandler(acc telegraf.Accumulator) socket.Callba
 	}
 }
 
-func (s *Syslog) createDatagramDataHandler(acc telegraf.Accumulator) socket.CallbackData {
-	// Create the parser depending on syslog standard and other settings
+// newMachine creates a parser for single messages depending on syslog
+// standard and other settings
+func (s *Syslog) newMachine() syslog.Machine {
 	var parser syslog.Machine
 	switch s.SyslogStandard {
 	case "RFC3164":
@@ -209,6 +255,11 @@ func (s *Syslog) createDatagramDataHandler(acc telegraf.Accumulator) socket.Call
 	if s.BestEffort {
 		parser.WithBestEffort()
 	}
+	return parser
+}
+
+func (s *Syslog) createDatagramDataHandler(acc telegraf.Accumulator) socket.CallbackData {
+	parser := s.newMachine()
 
 	// Return the OnData function
 	return func(src net.Addr, data []byte, _ time.Time) {
@@ -190,7 +190,27 @@ func (t *Tail) getSeekInfo(file string) (*tail.SeekInfo, error) {
 }
 
 func (t *Tail) GetState() interface{} {
-	return t.offsets
+	t.tailersMutex.RLock()
+	defer t.tailersMutex.RUnlock()
+
+	// The state is saved periodically while tailing, so return a copy of the
+	// recorded offsets updated with the current offsets of the active tailers
+	state := make(map[string]int64, len(t.offsets)+len(t.tailers))
+	for k, v := range t.offsets {
+		state[k] = v
+	}
+	if t.Pipe {
+		return state
+	}
+	for _, tailer := range t.tailers {
+		offset, err := tailer.Tell()
+		if err != nil {
+			t.Log.Errorf("Recording offset for %q: %s", tailer.Filename, err.Error())
+			continue
+		}
+		state[tailer.Filename] = offset
+	}
+	return state
 }
 
 func (t *Tail) SetState(state interface{}) error {
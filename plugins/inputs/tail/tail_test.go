@@ -3,8 +3,10 @@ package tail
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -828,6 +830,70 @@ func TestStatePersistence(t *testing.T) {
 	require.Equal(t, expectedState, actualState)
 }
 
+func TestStatePersistenceWhileRunning(t *testing.T) {
+	// Prepare the input files
+	content := []byte("metric,tag=value foo=1i 1730478201000000000\nmetric,tag=value foo=2i 1730478211000000000\n")
+	dir := t.TempDir()
+	fileA := filepath.Join(dir, "a.influx")
+	fileB := filepath.Join(dir, "b.influx")
+	require.NoError(t, os.WriteFile(fileA, content, 0600))
+	require.NoError(t, os.WriteFile(fileB, content, 0600))
+
+	// Configure the plugin
+	plugin := &Tail{
+		Files:               []string{filepath.Join(dir, "*.influx")},
+		InitialReadOffset:   "beginning",
+		MaxUndeliveredLines: 1000,
+		offsets:             make(map[string]int64, 0),
+		Log:                 testutil.Logger{},
+	}
+	plugin.SetParserFunc(newInfluxParser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Get the state periodically while tailing as done by the agent when
+	// checkpointing the plugin states. Run with the race detector to catch
+	// unprotected accesses.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, ok := plugin.GetState().(map[string]int64)
+				require.True(t, ok, "state is not a map[string]int64")
+			}
+		}
+	}()
+
+	// The state must reflect the current offsets of the active tailers
+	require.NoError(t, plugin.Gather(&acc))
+	expectedState := map[string]int64{fileA: int64(len(content)), fileB: int64(len(content))}
+	require.Eventually(t, func() bool {
+		actualState, ok := plugin.GetState().(map[string]int64)
+		return ok && reflect.DeepEqual(expectedState, actualState)
+	}, 3*time.Second, 100*time.Millisecond)
+
+	// Removing a file records the offset of the removed tailer
+	require.NoError(t, os.Remove(fileB))
+	require.NoError(t, plugin.Gather(&acc))
+
+	close(done)
+	wg.Wait()
+	plugin.Stop()
+
+	actualState, ok := plugin.GetState().(map[string]int64)
+	require.True(t, ok, "state is not a map[string]int64")
+	require.Equal(t, expectedState, actualState)
+}
+
 func TestGetSeekInfo(t *testing.T) {
 	tests := []struct {
 		name     string
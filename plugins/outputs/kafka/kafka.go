@@ -12,6 +12,7 @@ import (
 	"github.com/gofrs/uuid/v5"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/common/kafka"
 	"github.com/influxdata/telegraf/plugins/common/proxy"
@@ -30,16 +31,20 @@ var ValidTopicSuffixMethods = []string{
 var zeroTime = time.Unix(0, 0)
 
 type Kafka struct {
-	Brokers           []string        `toml:"brokers"`
-	Topic             string          `toml:"topic"`
-	TopicTag          string          `toml:"topic_tag"`
-	ExcludeTopicTag   bool            `toml:"exclude_topic_tag"`
-	TopicSuffix       TopicSuffix     `toml:"topic_suffix"`
-	RoutingTag        string          `toml:"routing_tag"`
-	RoutingKey        string          `toml:"routing_key"`
-	ProducerTimestamp string          `toml:"producer_timestamp"`
-	MetricNameHeader  string          `toml:"metric_name_header"`
-	Log               telegraf.Logger `toml:"-"`
+	Brokers           []string    `toml:"brokers"`
+	Topic             string      `toml:"topic"`
+	TopicTag          string      `toml:"topic_tag"`
+	ExcludeTopicTag   bool        `toml:"exclude_topic_tag"`
+	TopicSuffix       TopicSuffix `toml:"topic_suffix"`
+	RoutingTag        string      `toml:"routing_tag"`
+	RoutingKey        string      `toml:"routing_key"`
+	ProducerTimestamp string      `toml:"producer_timestamp"`
+	MetricNameHeader  string      `toml:"metric_name_header"`
+
+	TransactionalID    string          `toml:"transactional_id"`
+	TransactionTimeout config.Duration `toml:"transaction_timeout"`
+
+	Log telegraf.Logger `toml:"-"`
 	proxy.Socks5ProxyConfig
 	kafka.WriteConfig
 
@@ -123,12 +128,33 @@ func (k *Kafka) Init() error {
 	if err := ValidateTopicSuffixMethod(k.TopicSuffix.Method); err != nil {
 		return err
 	}
-	config := sarama.NewConfig()
+	cfg := sarama.NewConfig()
 
-	if err := k.SetConfig(config, k.Log); err != nil {
+	if err := k.SetConfig(cfg, k.Log); err != nil {
 		return err
 	}
 
+	// Transactions require an idempotent producer
+	if k.TransactionalID != "" {
+		cfg.Producer.Transaction.ID = k.TransactionalID
+		if k.TransactionTimeout > 0 {
+			cfg.Producer.Transaction.Timeout = time.Duration(k.TransactionTimeout)
+		}
+		cfg.Producer.Idempotent = true
+		cfg.Net.MaxOpenRequests = 1
+	}
+	if cfg.Producer.Idempotent {
+		if cfg.Producer.RequiredAcks != sarama.WaitForAll {
+			return errors.New("idempotent writes and transactions require 'required_acks = -1'")
+		}
+		if cfg.Producer.Retry.Max < 1 {
+			return errors.New("idempotent writes and transactions require 'max_retry' to be at least 1")
+		}
+		if !cfg.Version.IsAtLeast(sarama.V0_11_0_0) {
+			return errors.New("idempotent writes and transactions require 'version' to be at least 0.11.0")
+		}
+	}
+
 	// Legacy support ssl config
 	if k.Certificate != "" {
 		k.TLSCert = k.Certificate
@@ -137,15 +163,15 @@ func (k *Kafka) Init() error {
 	}
 
 	if k.Socks5ProxyEnabled {
-		config.Net.Proxy.Enable = true
+		cfg.Net.Proxy.Enable = true
 
 		dialer, err := k.Socks5ProxyConfig.GetDialer()
 		if err != nil {
 			return fmt.Errorf("connecting to proxy server failed: %w", err)
 		}
-		config.Net.Proxy.Dialer = dialer
+		cfg.Net.Proxy.Dialer = dialer
 	}
-	k.saramaConfig = config
+	k.saramaConfig = cfg
 
 	switch k.ProducerTimestamp {
 	case "":
@@ -194,19 +220,34 @@ func (k *Kafka) routingKey(metric telegraf.Metric) (string, error) {
 }
 
 func (k *Kafka) Write(metrics []telegraf.Metric) error {
+	if k.producer == nil {
+		if err := k.Connect(); err != nil {
+			return err
+		}
+	}
+
+	writeErr := &internal.PartialWriteError{
+		MetricsAccept: make([]int, 0, len(metrics)),
+	}
+
 	msgs := make([]*sarama.ProducerMessage, 0, len(metrics))
-	for _, metric := range metrics {
+	for i, metric := range metrics {
 		metric, topic := k.GetTopicName(metric)
 
 		buf, err := k.serializer.Serialize(metric)
 		if err != nil {
-			k.Log.Debugf("Could not serialize metric: %v", err)
+			k.Log.Errorf("Could not serialize metric: %v", err)
+			writeErr.Err = internal.ErrSerialization
+			writeErr.MetricsReject = append(writeErr.MetricsReject, i)
 			continue
 		}
 
+		// Keep the index of the metric to be able to map errors of the
+		// individual messages back to the metrics
 		m := &sarama.ProducerMessage{
-			Topic: topic,
-			Value: sarama.ByteEncoder(buf),
+			Topic:    topic,
+			Value:    sarama.ByteEncoder(buf),
+			Metadata: i,
 		}
 
 		if k.MetricNameHeader != "" {
@@ -234,32 +275,145 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		msgs = append(msgs, m)
 	}
 
-	err := k.producer.SendMessages(msgs)
-	if err != nil {
-		// We could have many errors, return only the first encountered.
-		var errs sarama.ProducerErrors
-		if errors.As(err, &errs) && len(errs) > 0 {
-			// Just return the first error encountered
-			firstErr := errs[0]
-			if errors.Is(firstErr.Err, sarama.ErrMessageSizeTooLarge) {
-				k.Log.Error("Message too large, consider increasing `max_message_bytes`; dropping batch")
-				return nil
-			}
-			if errors.Is(firstErr.Err, sarama.ErrInvalidTimestamp) {
-				k.Log.Error(
-					"The timestamp of the message is out of acceptable range, consider increasing broker `message.timestamp.difference.max.ms`; " +
-						"dropping batch",
-				)
-				return nil
+	if len(msgs) > 0 {
+		var err error
+		if k.producer.IsTransactional() {
+			err = k.sendTransaction(msgs, writeErr)
+		} else {
+			err = k.send(msgs, writeErr)
+		}
+		if err != nil {
+			// Keep all metrics if none was processed
+			if len(writeErr.MetricsAccept) == 0 && len(writeErr.MetricsReject) == 0 {
+				return err
 			}
-			return firstErr
+			writeErr.Err = err
 		}
+	}
+
+	if writeErr.Err != nil {
+		return writeErr
+	}
+	return nil
+}
+
+// send produces the messages and maps errors of individual messages to the
+// corresponding metrics. Metrics of messages failing with a permanent error are
+// rejected, metrics of messages failing with other errors are neither accepted
+// nor rejected, so they are retried with the next write.
+func (k *Kafka) send(msgs []*sarama.ProducerMessage, writeErr *internal.PartialWriteError) error {
+	err := k.producer.SendMessages(msgs)
+	if err == nil {
+		for _, m := range msgs {
+			writeErr.MetricsAccept = append(writeErr.MetricsAccept, m.Metadata.(int))
+		}
+		return nil
+	}
+
+	var errs sarama.ProducerErrors
+	if !errors.As(err, &errs) || len(errs) == 0 {
 		return err
 	}
 
+	failed, firstErr := k.rejectPermanent(errs, writeErr)
+	for _, m := range msgs {
+		if idx := m.Metadata.(int); !failed[idx] {
+			writeErr.MetricsAccept = append(writeErr.MetricsAccept, idx)
+		}
+	}
+
+	if firstErr != nil {
+		k.Log.Debugf("Failed to send %d of %d messages", len(errs), len(msgs))
+		return firstErr
+	}
+	return errors.New("messages rejected by the broker")
+}
+
+// rejectPermanent rejects the metrics of messages failing with a permanent
+// error. It returns the indices of all failed metrics and the first
+// non-permanent error.
+func (k *Kafka) rejectPermanent(errs sarama.ProducerErrors, writeErr *internal.PartialWriteError) (map[int]bool, error) {
+	failed := make(map[int]bool, len(errs))
+	var firstErr error
+	for _, perr := range errs {
+		idx := perr.Msg.Metadata.(int)
+		failed[idx] = true
+
+		switch {
+		case errors.Is(perr.Err, sarama.ErrMessageSizeTooLarge):
+			k.Log.Error("Message too large, consider increasing `max_message_bytes`; dropping metric")
+			writeErr.MetricsReject = append(writeErr.MetricsReject, idx)
+		case errors.Is(perr.Err, sarama.ErrInvalidTimestamp):
+			k.Log.Error(
+				"The timestamp of the message is out of acceptable range, consider increasing broker `message.timestamp.difference.max.ms`; " +
+					"dropping metric",
+			)
+			writeErr.MetricsReject = append(writeErr.MetricsReject, idx)
+		default:
+			if firstErr == nil {
+				firstErr = perr
+			}
+		}
+	}
+	return failed, firstErr
+}
+
+// sendTransaction produces all messages within one transaction, so either all
+// messages are committed or none of the messages are visible to consumers
+// reading committed messages only.
+func (k *Kafka) sendTransaction(msgs []*sarama.ProducerMessage, writeErr *internal.PartialWriteError) error {
+	if err := k.producer.BeginTxn(); err != nil {
+		if k.producer.TxnStatus()&(sarama.ProducerTxnFlagFatalError|sarama.ProducerTxnFlagAbortableError) != 0 {
+			k.resetProducer()
+		}
+		return fmt.Errorf("beginning transaction failed: %w", err)
+	}
+
+	if err := k.producer.SendMessages(msgs); err != nil {
+		k.abortTransaction()
+
+		// None of the messages got committed, so only reject the metrics of
+		// messages failing with a permanent error and retry all others.
+		var errs sarama.ProducerErrors
+		if errors.As(err, &errs) {
+			k.rejectPermanent(errs, writeErr)
+		}
+		return fmt.Errorf("sending messages in transaction failed: %w", err)
+	}
+
+	if err := k.producer.CommitTxn(); err != nil {
+		k.abortTransaction()
+		return fmt.Errorf("committing transaction failed: %w", err)
+	}
+
+	for _, m := range msgs {
+		writeErr.MetricsAccept = append(writeErr.MetricsAccept, m.Metadata.(int))
+	}
 	return nil
 }
 
+func (k *Kafka) abortTransaction() {
+	if k.producer.TxnStatus()&sarama.ProducerTxnFlagFatalError == 0 {
+		err := k.producer.AbortTxn()
+		if err == nil {
+			return
+		}
+		k.Log.Errorf("Aborting transaction failed: %v", err)
+	}
+	k.resetProducer()
+}
+
+// resetProducer closes the producer if its transaction state cannot be
+// recovered, e.g. because it got fenced by another producer using the same
+// transactional ID. A new producer is created with the next write.
+func (k *Kafka) resetProducer() {
+	k.Log.Warn("Transaction in error state, recreating producer")
+	if err := k.producer.Close(); err != nil {
+		k.Log.Errorf("Closing producer failed: %v", err)
+	}
+	k.producer = nil
+}
+
 func init() {
 	outputs.Add("kafka", func() telegraf.Output {
 		return &Kafka{
@@ -9,7 +9,9 @@ import (
 	kafkacontainer "github.com/testcontainers/testcontainers-go/modules/kafka"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/kafka"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -166,7 +168,11 @@ func TestRoutingKey(t *testing.T) {
 }
 
 type MockProducer struct {
-	sent []*sarama.ProducerMessage
+	sent          []*sarama.ProducerMessage
+	fail          func(*sarama.ProducerMessage) error
+	transactional bool
+	status        sarama.ProducerTxnStatusFlag
+	txn           []string
 	sarama.SyncProducer
 }
 
@@ -176,11 +182,49 @@ func (p *MockProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32
 }
 
 func (p *MockProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
-	p.sent = append(p.sent, msgs...)
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if p.fail != nil {
+			if err := p.fail(msg); err != nil {
+				errs = append(errs, &sarama.ProducerError{Msg: msg, Err: err})
+				continue
+			}
+		}
+		p.sent = append(p.sent, msg)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
 
-func (*MockProducer) Close() error {
+func (p *MockProducer) Close() error {
+	if p.transactional {
+		p.txn = append(p.txn, "close")
+	}
+	return nil
+}
+
+func (p *MockProducer) IsTransactional() bool {
+	return p.transactional
+}
+
+func (p *MockProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	return p.status
+}
+
+func (p *MockProducer) BeginTxn() error {
+	p.txn = append(p.txn, "begin")
+	return nil
+}
+
+func (p *MockProducer) CommitTxn() error {
+	p.txn = append(p.txn, "commit")
+	return nil
+}
+
+func (p *MockProducer) AbortTxn() error {
+	p.txn = append(p.txn, "abort")
 	return nil
 }
 
@@ -310,3 +354,274 @@ func TestTopicTag(t *testing.T) {
 		})
 	}
 }
+
+func TestWritePerMessageErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+		accept   []int
+		reject   []int
+	}{
+		{
+			name:     "retryable error",
+			err:      sarama.ErrNotEnoughReplicas,
+			expected: sarama.ErrNotEnoughReplicas,
+			accept:   []int{0, 2},
+		},
+		{
+			name:   "message too large",
+			err:    sarama.ErrMessageSizeTooLarge,
+			accept: []int{0, 2},
+			reject: []int{1},
+		},
+		{
+			name:   "invalid timestamp",
+			err:    sarama.ErrInvalidTimestamp,
+			accept: []int{0, 2},
+			reject: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &MockProducer{
+				fail: func(m *sarama.ProducerMessage) error {
+					if m.Topic == "bad" {
+						return tt.err
+					}
+					return nil
+				},
+			}
+			plugin := &Kafka{
+				Topic:    "good",
+				TopicTag: "topic",
+				Log:      testutil.Logger{},
+				producer: producer,
+			}
+			s := &influx.Serializer{}
+			require.NoError(t, s.Init())
+			plugin.SetSerializer(s)
+
+			input := []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				testutil.MustMetric("cpu", map[string]string{"topic": "bad"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+			}
+
+			err := plugin.Write(input)
+			var writeErr *internal.PartialWriteError
+			require.ErrorAs(t, err, &writeErr)
+			if tt.expected != nil {
+				require.ErrorIs(t, err, tt.expected)
+			}
+			require.Equal(t, tt.accept, writeErr.MetricsAccept)
+			require.Equal(t, tt.reject, writeErr.MetricsReject)
+		})
+	}
+}
+
+func TestWriteSerializationError(t *testing.T) {
+	producer := &MockProducer{}
+	plugin := &Kafka{
+		Topic:    "telegraf",
+		Log:      testutil.Logger{},
+		producer: producer,
+	}
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+	plugin.SetSerializer(s)
+
+	// Metrics without fields cannot be serialized
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{}, time.Unix(0, 0)),
+	}
+
+	err := plugin.Write(input)
+	var writeErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.ErrorIs(t, err, internal.ErrSerialization)
+	require.Equal(t, []int{0}, writeErr.MetricsAccept)
+	require.Equal(t, []int{1}, writeErr.MetricsReject)
+	require.Len(t, producer.sent, 1)
+}
+
+func TestWriteMockBroker(t *testing.T) {
+	// Replace the global sarama logger before the broker starts using it
+	kafka.SetLogger(telegraf.Info)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("good", 0, broker.BrokerID()).
+			SetLeader("bad", 0, broker.BrokerID()),
+		"InitProducerIDRequest": sarama.NewMockInitProducerIDResponse(t),
+		"ProduceRequest":        sarama.NewMockProduceResponse(t).SetError("bad", 0, sarama.ErrNotEnoughReplicas),
+	})
+
+	plugin := &Kafka{
+		Brokers:  []string{broker.Addr()},
+		Topic:    "good",
+		TopicTag: "topic",
+		WriteConfig: kafka.WriteConfig{
+			MaxRetry:         1,
+			RequiredAcks:     -1,
+			IdempotentWrites: true,
+		},
+		Log:          testutil.Logger{},
+		producerFunc: sarama.NewSyncProducer,
+	}
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+	plugin.SetSerializer(s)
+	require.NoError(t, plugin.Init())
+	plugin.saramaConfig.Producer.Retry.Backoff = 0
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"topic": "bad"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+	}
+
+	// Only the metric sent to the failing partition should be kept
+	err := plugin.Write(input)
+	var writeErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.ErrorIs(t, err, sarama.ErrNotEnoughReplicas)
+	require.Equal(t, []int{0}, writeErr.MetricsAccept)
+	require.Empty(t, writeErr.MetricsReject)
+}
+
+func TestWriteTransaction(t *testing.T) {
+	producer := &MockProducer{transactional: true}
+	plugin := &Kafka{
+		Topic:    "good",
+		TopicTag: "topic",
+		Log:      testutil.Logger{},
+		producer: producer,
+	}
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+	plugin.SetSerializer(s)
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"topic": "bad"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+	}
+
+	// All messages are committed in one transaction
+	require.NoError(t, plugin.Write(input))
+	require.Equal(t, []string{"begin", "commit"}, producer.txn)
+	require.Len(t, producer.sent, 2)
+
+	// A failing message aborts the transaction and keeps all metrics
+	producer.txn = nil
+	producer.fail = func(m *sarama.ProducerMessage) error {
+		if m.Topic == "bad" {
+			return sarama.ErrNotEnoughReplicas
+		}
+		return nil
+	}
+	err := plugin.Write(input)
+	require.ErrorContains(t, err, "sending messages in transaction failed")
+	var writeErr *internal.PartialWriteError
+	require.NotErrorAs(t, err, &writeErr)
+	require.Equal(t, []string{"begin", "abort"}, producer.txn)
+
+	// A permanently failing message aborts the transaction and only rejects
+	// the failing metric, all others are retried
+	producer.txn = nil
+	producer.fail = func(m *sarama.ProducerMessage) error {
+		if m.Topic == "bad" {
+			return sarama.ErrMessageSizeTooLarge
+		}
+		return nil
+	}
+	err = plugin.Write(input)
+	require.ErrorContains(t, err, "sending messages in transaction failed")
+	require.ErrorAs(t, err, &writeErr)
+	require.Empty(t, writeErr.MetricsAccept)
+	require.Equal(t, []int{1}, writeErr.MetricsReject)
+	require.Equal(t, []string{"begin", "abort"}, producer.txn)
+
+	// A fatal error recreates the producer with the next write
+	producer.txn = nil
+	producer.status = sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagFatalError
+	plugin.producerFunc = func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+		return &MockProducer{transactional: true}, nil
+	}
+	require.Error(t, plugin.Write(input))
+	require.Equal(t, []string{"begin", "close"}, producer.txn)
+	require.Nil(t, plugin.producer)
+
+	require.NoError(t, plugin.Write(input))
+	recreated, ok := plugin.producer.(*MockProducer)
+	require.True(t, ok)
+	require.Equal(t, []string{"begin", "commit"}, recreated.txn)
+}
+
+func TestInitIdempotent(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Kafka
+		expected string
+	}{
+		{
+			name: "valid idempotent",
+			plugin: &Kafka{
+				WriteConfig: kafka.WriteConfig{MaxRetry: 3, RequiredAcks: -1, IdempotentWrites: true},
+			},
+		},
+		{
+			name: "valid transaction",
+			plugin: &Kafka{
+				TransactionalID: "telegraf-1",
+				WriteConfig:     kafka.WriteConfig{MaxRetry: 3, RequiredAcks: -1},
+			},
+		},
+		{
+			name: "invalid acks",
+			plugin: &Kafka{
+				TransactionalID: "telegraf-1",
+				WriteConfig:     kafka.WriteConfig{MaxRetry: 3, RequiredAcks: 1},
+			},
+			expected: "require 'required_acks = -1'",
+		},
+		{
+			name: "invalid retries",
+			plugin: &Kafka{
+				WriteConfig: kafka.WriteConfig{RequiredAcks: -1, IdempotentWrites: true},
+			},
+			expected: "require 'max_retry' to be at least 1",
+		},
+		{
+			name: "invalid version",
+			plugin: &Kafka{
+				WriteConfig: kafka.WriteConfig{
+					Config:           kafka.Config{Version: "0.10.2.0"},
+					MaxRetry:         3,
+					RequiredAcks:     -1,
+					IdempotentWrites: true,
+				},
+			},
+			expected: "require 'version' to be at least 0.11.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			err := tt.plugin.Init()
+			if tt.expected != "" {
+				require.ErrorContains(t, err, tt.expected)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, tt.plugin.saramaConfig.Producer.Idempotent)
+			require.Equal(t, tt.plugin.TransactionalID, tt.plugin.saramaConfig.Producer.Transaction.ID)
+			require.NoError(t, tt.plugin.saramaConfig.Validate())
+		})
+	}
+}
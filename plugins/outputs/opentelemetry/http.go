@@ -0,0 +1,200 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Maximum size of the response body read from the server
+const maxResponseSize = 64 * 1024
+
+// Upper limit for waiting on the server before sending again
+const maxRetryAfter = 5 * time.Minute
+
+// otlpRequest is implemented by the export requests of all signals
+type otlpRequest interface {
+	MarshalProto() ([]byte, error)
+	MarshalJSON() ([]byte, error)
+}
+
+// otlpResponse is implemented by the export responses of all signals
+type otlpResponse interface {
+	UnmarshalProto(data []byte) error
+	UnmarshalJSON(data []byte) error
+}
+
+// throttleError indicates the server asking to send the data again later
+type throttleError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *throttleError) Error() string {
+	return e.err.Error()
+}
+
+// rejectError indicates the server refusing the data so resending the same
+// data will fail again
+type rejectError struct {
+	err error
+}
+
+func (e *rejectError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectError) Unwrap() error {
+	return e.err
+}
+
+// httpEndpoints computes the OTLP/HTTP endpoints of the signals by appending
+// the signal specific path to the configured base URL
+func httpEndpoints(address string) (map[string]string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service address %q: %w", address, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid scheme %q in service address, expected http or https", u.Scheme)
+	}
+
+	endpoints := make(map[string]string, 3)
+	for _, signal := range []string{"metrics", "traces", "logs"} {
+		e := *u
+		e.Path = path.Join("/", u.Path, "v1", signal)
+		endpoints[signal] = e.String()
+	}
+	return endpoints, nil
+}
+
+// post sends the request to the endpoint of the given signal and decodes the
+// response
+func (o *OpenTelemetry) post(ctx context.Context, signal string, request otlpRequest, response otlpResponse) error {
+	var body []byte
+	var err error
+	contentType := "application/x-protobuf"
+	if o.Protocol == protocolHTTPJSON {
+		contentType = "application/json"
+		body, err = request.MarshalJSON()
+	} else {
+		body, err = request.MarshalProto()
+	}
+	if err != nil {
+		return &rejectError{fmt.Errorf("encoding request failed: %w", err)}
+	}
+	if body, err = o.encoder.Encode(body); err != nil {
+		return fmt.Errorf("compressing request failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoints[signal], bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent)
+	if o.Compression != "" && o.Compression != "none" {
+		req.Header.Set("Content-Encoding", o.Compression)
+	}
+	for k, v := range o.Headers {
+		if strings.EqualFold(k, "host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("reading response failed: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		o.retryCount = 0
+		if len(payload) == 0 {
+			return nil
+		}
+		if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			err = response.UnmarshalJSON(payload)
+		} else {
+			err = response.UnmarshalProto(payload)
+		}
+		if err != nil {
+			// The data was accepted so we should not send it again
+			o.Log.Warnf("Decoding response of %s export failed: %v", signal, err)
+		}
+		return nil
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		o.retryCount++
+		retryAfter := retryDuration(resp.Header.Get("Retry-After"), o.retryCount, time.Now())
+		return &throttleError{
+			err:        fmt.Errorf("exporting %s failed (%s), waiting %s before sending again", signal, resp.Status, retryAfter),
+			retryAfter: retryAfter,
+		}
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout:
+		// Might be resolved by fixing the credentials or network issues
+		return fmt.Errorf("exporting %s failed: %s", signal, resp.Status)
+	}
+
+	// Other client errors are not retryable according to the specification
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return &rejectError{fmt.Errorf("exporting %s failed, server rejected data: %s%s", signal, resp.Status, describe(resp, payload))}
+	}
+	return fmt.Errorf("exporting %s failed: %s%s", signal, resp.Status, describe(resp, payload))
+}
+
+// describe returns the error message of the server if the response is human
+// readable
+func describe(resp *http.Response, payload []byte) string {
+	contentType := resp.Header.Get("Content-Type")
+	if len(payload) == 0 || (!strings.Contains(contentType, "json") && !strings.HasPrefix(contentType, "text/")) {
+		return ""
+	}
+	return ": " + strings.TrimSpace(string(payload))
+}
+
+// retryDuration takes the longer of the Retry-After header, either in seconds
+// or as HTTP-date, and an exponential back-off based on the number of
+// consecutive throttled requests
+func retryDuration(header string, count int, now time.Time) time.Duration {
+	backoff := time.Duration(math.Min(math.Pow(2, float64(count-1)), maxRetryAfter.Seconds())) * time.Second
+
+	var retryAfter time.Duration
+	if header != "" {
+		if seconds, err := strconv.ParseFloat(header, 64); err == nil {
+			retryAfter = time.Duration(seconds * float64(time.Second))
+		} else if t, err := http.ParseTime(header); err == nil {
+			retryAfter = t.Sub(now)
+		}
+	}
+
+	return min(max(backoff, retryAfter), maxRetryAfter)
+}
+
+// newContentEncoder returns the encoder for the request body of OTLP/HTTP
+func newContentEncoder(compression string) (internal.ContentEncoder, error) {
+	if compression == "" || compression == "none" {
+		return internal.NewIdentityEncoder()
+	}
+	return internal.NewContentEncoder(compression)
+}
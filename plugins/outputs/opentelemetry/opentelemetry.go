@@ -5,21 +5,30 @@ import (
 	"context"
 	ntls "crypto/tls"
 	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
 	"sort"
 	"time"
 
 	"github.com/influxdata/influxdb-observability/common"
 	"github.com/influxdata/influxdb-observability/influx2otel"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	_ "google.golang.org/grpc/encoding/gzip" // Blank import to allow gzip encoding
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	otel "github.com/influxdata/telegraf/plugins/common/opentelemetry"
+	"github.com/influxdata/telegraf/plugins/common/proxy"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
@@ -30,21 +39,34 @@ var userAgent = internal.ProductToken()
 var sampleConfig string
 
 type OpenTelemetry struct {
-	ServiceAddress string `toml:"service_address"`
-
+	ServiceAddress string          `toml:"service_address"`
+	Protocol       string          `toml:"protocol"`
+	Passthrough    []string        `toml:"passthrough"`
+	Timeout        config.Duration `toml:"timeout"`
+	Compression    string          `toml:"compression"`
 	tls.ClientConfig
-	Timeout     config.Duration   `toml:"timeout"`
-	Compression string            `toml:"compression"`
-	Headers     map[string]string `toml:"headers"`
-	Attributes  map[string]string `toml:"attributes"`
-	Coralogix   *CoralogixConfig  `toml:"coralogix"`
+	proxy.HTTPProxy
+	Headers    map[string]string `toml:"headers"`
+	Attributes map[string]string `toml:"attributes"`
+	Coralogix  *CoralogixConfig  `toml:"coralogix"`
 
 	Log telegraf.Logger `toml:"-"`
 
 	metricsConverter     *influx2otel.LineProtocolToOtelMetrics
 	grpcClientConn       *grpc.ClientConn
 	metricsServiceClient pmetricotlp.GRPCClient
+	tracesServiceClient  ptraceotlp.GRPCClient
+	logsServiceClient    plogotlp.GRPCClient
 	callOptions          []grpc.CallOption
+
+	httpClient *http.Client
+	endpoints  map[string]string
+	encoder    internal.ContentEncoder
+	retryTime  time.Time
+	retryCount int
+
+	passthroughTraces bool
+	passthroughLogs   bool
 }
 
 type CoralogixConfig struct {
@@ -53,15 +75,41 @@ type CoralogixConfig struct {
 	PrivateKey string `toml:"private_key"`
 }
 
+// batch holds the indices of the metrics sent in one export request
+type batch struct {
+	signal  string
+	indices []int
+}
+
 func (*OpenTelemetry) SampleConfig() string {
 	return sampleConfig
 }
 
-func (o *OpenTelemetry) Connect() error {
-	logger := &otelLogger{o.Log}
+func (o *OpenTelemetry) Init() error {
+	switch o.Protocol {
+	case "":
+		o.Protocol = protocolGRPC
+	case protocolGRPC, protocolHTTPProtobuf, protocolHTTPJSON:
+	default:
+		return fmt.Errorf("invalid protocol %q", o.Protocol)
+	}
+
+	for _, signal := range o.Passthrough {
+		switch signal {
+		case "traces":
+			o.passthroughTraces = true
+		case "logs":
+			o.passthroughLogs = true
+		default:
+			return fmt.Errorf("invalid passthrough signal %q", signal)
+		}
+	}
 
 	if o.ServiceAddress == "" {
 		o.ServiceAddress = defaultServiceAddress
+		if o.isHTTP() {
+			o.ServiceAddress = defaultHTTPServiceAddress
+		}
 	}
 	if o.Timeout <= 0 {
 		o.Timeout = defaultTimeout
@@ -78,10 +126,21 @@ func (o *OpenTelemetry) Connect() error {
 		o.Headers["Authorization"] = "Bearer " + o.Coralogix.PrivateKey
 	}
 
+	return nil
+}
+
+func (o *OpenTelemetry) Connect() error {
+	logger := &otel.Logger{Logger: o.Log}
+
 	metricsConverter, err := influx2otel.NewLineProtocolToOtelMetrics(logger)
 	if err != nil {
 		return err
 	}
+	o.metricsConverter = metricsConverter
+
+	if o.isHTTP() {
+		return o.connectHTTP()
+	}
 
 	var grpcTLSDialOption grpc.DialOption
 	if tlsConfig, err := o.ClientConfig.TLSConfig(); err != nil {
@@ -100,11 +159,10 @@ func (o *OpenTelemetry) Connect() error {
 		return err
 	}
 
-	metricsServiceClient := pmetricotlp.NewGRPCClient(grpcClientConn)
-
-	o.metricsConverter = metricsConverter
 	o.grpcClientConn = grpcClientConn
-	o.metricsServiceClient = metricsServiceClient
+	o.metricsServiceClient = pmetricotlp.NewGRPCClient(grpcClientConn)
+	o.tracesServiceClient = ptraceotlp.NewGRPCClient(grpcClientConn)
+	o.logsServiceClient = plogotlp.NewGRPCClient(grpcClientConn)
 
 	if o.Compression != "" && o.Compression != "none" {
 		o.callOptions = append(o.callOptions, grpc.UseCompressor(o.Compression))
@@ -113,7 +171,45 @@ func (o *OpenTelemetry) Connect() error {
 	return nil
 }
 
+func (o *OpenTelemetry) connectHTTP() error {
+	endpoints, err := httpEndpoints(o.ServiceAddress)
+	if err != nil {
+		return err
+	}
+
+	encoder, err := newContentEncoder(o.Compression)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	proxyFunc, err := o.HTTPProxy.Proxy()
+	if err != nil {
+		return err
+	}
+
+	o.endpoints = endpoints
+	o.encoder = encoder
+	o.httpClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           proxyFunc,
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: time.Duration(o.Timeout),
+	}
+
+	return nil
+}
+
 func (o *OpenTelemetry) Close() error {
+	if o.httpClient != nil {
+		o.httpClient.CloseIdleConnections()
+		o.httpClient = nil
+	}
 	if o.grpcClientConn != nil {
 		err := o.grpcClientConn.Close()
 		o.grpcClientConn = nil
@@ -122,17 +218,31 @@ func (o *OpenTelemetry) Close() error {
 	return nil
 }
 
-// Split metrics up by timestamp and send to Google Cloud Stackdriver
+// Split metrics up by timestamp and send them. Metrics of passed-through
+// traces and logs are sent as one request per signal.
 func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
-	metricBatch := make(map[int64][]telegraf.Metric)
+	if o.retryTime.After(time.Now()) {
+		return errors.New("retry time has not elapsed")
+	}
+
+	spans := batch{signal: "traces"}
+	logs := batch{signal: "logs"}
+	metricBatch := make(map[int64]*batch)
 	timestamps := make([]int64, 0, len(metrics))
-	for _, metric := range metrics {
-		timestamp := metric.Time().UnixNano()
-		if existingSlice, ok := metricBatch[timestamp]; ok {
-			metricBatch[timestamp] = append(existingSlice, metric)
-		} else {
-			metricBatch[timestamp] = []telegraf.Metric{metric}
-			timestamps = append(timestamps, timestamp)
+	for i, metric := range metrics {
+		switch {
+		case o.passthroughTraces && metric.Name() == common.MeasurementSpans:
+			spans.indices = append(spans.indices, i)
+		case o.passthroughLogs && metric.Name() == common.MeasurementLogs:
+			logs.indices = append(logs.indices, i)
+		default:
+			timestamp := metric.Time().UnixNano()
+			if existing, ok := metricBatch[timestamp]; ok {
+				existing.indices = append(existing.indices, i)
+			} else {
+				metricBatch[timestamp] = &batch{signal: "metrics", indices: []int{i}}
+				timestamps = append(timestamps, timestamp)
+			}
 		}
 	}
 
@@ -140,46 +250,139 @@ func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
 	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
 
 	o.Log.Debugf("Received %d metrics and split into %d groups by timestamp", len(metrics), len(metricBatch))
+	batches := make([]*batch, 0, len(timestamps)+2)
 	for _, timestamp := range timestamps {
-		if err := o.sendBatch(metricBatch[timestamp]); err != nil {
+		batches = append(batches, metricBatch[timestamp])
+	}
+	for _, b := range []*batch{&spans, &logs} {
+		if len(b.indices) > 0 {
+			batches = append(batches, b)
+		}
+	}
+
+	writeErr := &internal.PartialWriteError{
+		MetricsAccept: make([]int, 0, len(metrics)),
+	}
+	for _, b := range batches {
+		accept, reject, err := o.sendBatch(metrics, b)
+		writeErr.MetricsAccept = append(writeErr.MetricsAccept, accept...)
+		writeErr.MetricsReject = append(writeErr.MetricsReject, reject...)
+		if err == nil {
+			continue
+		}
+
+		// Do not send any further data if the server is overloaded
+		var terr *throttleError
+		if errors.As(err, &terr) {
+			o.retryTime = time.Now().Add(terr.retryAfter)
+		}
+		if len(writeErr.MetricsAccept) == 0 && len(writeErr.MetricsReject) == 0 {
 			return err
 		}
+		writeErr.Err = err
+		return writeErr
 	}
 
+	if len(writeErr.MetricsReject) > 0 {
+		writeErr.Err = fmt.Errorf("%d metrics rejected", len(writeErr.MetricsReject))
+		return writeErr
+	}
 	return nil
 }
 
-func (o *OpenTelemetry) sendBatch(metrics []telegraf.Metric) error {
+// sendBatch converts and exports the metrics of the batch and returns the
+// indices of the accepted and rejected metrics. An error is returned if the
+// remaining metrics should be sent again later.
+func (o *OpenTelemetry) sendBatch(metrics []telegraf.Metric, b *batch) (accept, reject []int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
+	defer cancel()
+	if !o.isHTTP() && len(o.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+	}
+
+	var converted []int
+	var total, rejected int64
+	var message string
+	switch b.signal {
+	case "traces":
+		builder := newTracesBuilder(o.Attributes)
+		for _, idx := range b.indices {
+			if err := builder.add(metrics[idx]); err != nil {
+				o.Log.Errorf("Converting span %v failed: %v", metrics[idx], err)
+				reject = append(reject, idx)
+				continue
+			}
+			converted = append(converted, idx)
+		}
+		if len(converted) == 0 {
+			return nil, reject, nil
+		}
+		total = int64(builder.traces.SpanCount())
+		rejected, message, err = o.exportTraces(ctx, ptraceotlp.NewExportRequestFromTraces(builder.traces))
+	case "logs":
+		builder := newLogsBuilder(o.Attributes)
+		for _, idx := range b.indices {
+			if err := builder.add(metrics[idx]); err != nil {
+				o.Log.Errorf("Converting log record %v failed: %v", metrics[idx], err)
+				reject = append(reject, idx)
+				continue
+			}
+			converted = append(converted, idx)
+		}
+		if len(converted) == 0 {
+			return nil, reject, nil
+		}
+		total = int64(builder.logs.LogRecordCount())
+		rejected, message, err = o.exportLogs(ctx, plogotlp.NewExportRequestFromLogs(builder.logs))
+	default:
+		md := o.convertMetrics(metrics, b.indices)
+		if md.Metrics().ResourceMetrics().Len() == 0 {
+			return b.indices, nil, nil
+		}
+		converted = b.indices
+		total = int64(md.Metrics().DataPointCount())
+		rejected, message, err = o.exportMetrics(ctx, md)
+	}
+
+	if err != nil {
+		var rerr *rejectError
+		if errors.As(err, &rerr) {
+			o.Log.Errorf("Dropping %d metrics: %v", len(converted), err)
+			return nil, append(reject, converted...), nil
+		}
+		return nil, reject, err
+	}
+
+	// The server only reports the number of rejected items so we can only
+	// reject the metrics if all items of the request were rejected
+	if rejected > 0 {
+		o.Log.Warnf("Server rejected %d of %d %s items: %s", rejected, total, b.signal, message)
+		if rejected >= total {
+			return nil, append(reject, converted...), nil
+		}
+	} else if message != "" {
+		o.Log.Warnf("Server returned warning for %s: %s", b.signal, message)
+	}
+
+	return converted, reject, nil
+}
+
+func (o *OpenTelemetry) convertMetrics(metrics []telegraf.Metric, indices []int) pmetricotlp.ExportRequest {
 	batch := o.metricsConverter.NewBatch()
-	for _, metric := range metrics {
-		var vType common.InfluxMetricValueType
-		switch metric.Type() {
-		case telegraf.Gauge:
-			vType = common.InfluxMetricValueTypeGauge
-		case telegraf.Untyped:
-			vType = common.InfluxMetricValueTypeUntyped
-		case telegraf.Counter:
-			vType = common.InfluxMetricValueTypeSum
-		case telegraf.Histogram:
-			vType = common.InfluxMetricValueTypeHistogram
-		case telegraf.Summary:
-			vType = common.InfluxMetricValueTypeSummary
-		default:
-			o.Log.Warnf("Unrecognized metric type %v", metric.Type())
+	for _, idx := range indices {
+		metric := metrics[idx]
+		vType, err := otel.ValueType(metric.Type())
+		if err != nil {
+			o.Log.Warn(err)
 			continue
 		}
-		err := batch.AddPoint(metric.Name(), metric.Tags(), metric.Fields(), metric.Time(), vType)
-		if err != nil {
+		if err := batch.AddPoint(metric.Name(), metric.Tags(), metric.Fields(), metric.Time(), vType); err != nil {
 			o.Log.Warnf("Failed to add point: %v", err)
 			continue
 		}
 	}
 
 	md := pmetricotlp.NewExportRequestFromMetrics(batch.GetMetrics())
-	if md.Metrics().ResourceMetrics().Len() == 0 {
-		return nil
-	}
-
 	if len(o.Attributes) > 0 {
 		for i := 0; i < md.Metrics().ResourceMetrics().Len(); i++ {
 			for k, v := range o.Attributes {
@@ -187,29 +390,87 @@ func (o *OpenTelemetry) sendBatch(metrics []telegraf.Metric) error {
 			}
 		}
 	}
+	return md
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
+func (o *OpenTelemetry) exportMetrics(ctx context.Context, request pmetricotlp.ExportRequest) (int64, string, error) {
+	var response pmetricotlp.ExportResponse
+	var err error
+	if o.isHTTP() {
+		response = pmetricotlp.NewExportResponse()
+		err = o.post(ctx, "metrics", request, response)
+	} else {
+		response, err = o.metricsServiceClient.Export(ctx, request, o.callOptions...)
+		err = grpcError(err)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return response.PartialSuccess().RejectedDataPoints(), response.PartialSuccess().ErrorMessage(), nil
+}
 
-	if len(o.Headers) > 0 {
-		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+func (o *OpenTelemetry) exportTraces(ctx context.Context, request ptraceotlp.ExportRequest) (int64, string, error) {
+	var response ptraceotlp.ExportResponse
+	var err error
+	if o.isHTTP() {
+		response = ptraceotlp.NewExportResponse()
+		err = o.post(ctx, "traces", request, response)
+	} else {
+		response, err = o.tracesServiceClient.Export(ctx, request, o.callOptions...)
+		err = grpcError(err)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return response.PartialSuccess().RejectedSpans(), response.PartialSuccess().ErrorMessage(), nil
+}
+
+func (o *OpenTelemetry) exportLogs(ctx context.Context, request plogotlp.ExportRequest) (int64, string, error) {
+	var response plogotlp.ExportResponse
+	var err error
+	if o.isHTTP() {
+		response = plogotlp.NewExportResponse()
+		err = o.post(ctx, "logs", request, response)
+	} else {
+		response, err = o.logsServiceClient.Export(ctx, request, o.callOptions...)
+		err = grpcError(err)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return response.PartialSuccess().RejectedLogRecords(), response.PartialSuccess().ErrorMessage(), nil
+}
+
+func (o *OpenTelemetry) isHTTP() bool {
+	return o.Protocol == protocolHTTPProtobuf || o.Protocol == protocolHTTPJSON
+}
+
+// grpcError marks errors caused by invalid data as not retryable
+func grpcError(err error) error {
+	if err != nil && status.Code(err) == codes.InvalidArgument {
+		return &rejectError{err}
 	}
-	defer cancel()
-	_, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...)
 	return err
 }
 
 const (
-	defaultServiceAddress = "localhost:4317"
-	defaultTimeout        = config.Duration(5 * time.Second)
-	defaultCompression    = "gzip"
+	protocolGRPC         = "grpc"
+	protocolHTTPProtobuf = "http/protobuf"
+	protocolHTTPJSON     = "http/json"
+)
+
+const (
+	defaultServiceAddress     = "localhost:4317"
+	defaultHTTPServiceAddress = "http://localhost:4318"
+	defaultTimeout            = config.Duration(5 * time.Second)
+	defaultCompression        = "gzip"
 )
 
 func init() {
 	outputs.Add("opentelemetry", func() telegraf.Output {
 		return &OpenTelemetry{
-			ServiceAddress: defaultServiceAddress,
-			Timeout:        defaultTimeout,
-			Compression:    defaultCompression,
+			Timeout:     defaultTimeout,
+			Compression: defaultCompression,
 		}
 	})
 }
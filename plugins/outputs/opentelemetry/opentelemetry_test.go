@@ -1,8 +1,12 @@
 package opentelemetry
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -10,8 +14,12 @@ import (
 	"github.com/influxdata/influxdb-observability/influx2otel"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
@@ -19,6 +27,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -144,3 +153,367 @@ func (m *mockOtelService) Export(ctx context.Context, request pmetricotlp.Export
 	require.True(m.t, ok)
 	return pmetricotlp.NewExportResponse(), nil
 }
+
+func TestOpenTelemetryHTTP(t *testing.T) {
+	for _, protocol := range []string{"http/protobuf", "http/json"} {
+		t.Run(protocol, func(t *testing.T) {
+			server := newMockHTTPService(t)
+			defer server.Close()
+
+			plugin := &OpenTelemetry{
+				ServiceAddress: server.URL + "/otlp",
+				Protocol:       protocol,
+				Headers:        map[string]string{"test": "header1"},
+				Attributes:     map[string]string{"attr-key": "attr-val"},
+				Log:            testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			input := testutil.MustMetric(
+				"cpu_temp",
+				map[string]string{"foo": "bar", "host.name": "potato"},
+				map[string]interface{}{"gauge": 87.332},
+				time.Unix(0, 1622848686000000000),
+			)
+			require.NoError(t, plugin.Write([]telegraf.Metric{input}))
+
+			require.Len(t, server.requests, 1)
+			req := server.requests[0]
+			require.Equal(t, "/otlp/v1/metrics", req.path)
+			require.Equal(t, "header1", req.header.Get("test"))
+			require.Equal(t, "gzip", req.header.Get("Content-Encoding"))
+			if protocol == "http/json" {
+				require.Equal(t, "application/json", req.header.Get("Content-Type"))
+			} else {
+				require.Equal(t, "application/x-protobuf", req.header.Get("Content-Type"))
+			}
+
+			md := req.metrics()
+			require.Equal(t, 1, md.DataPointCount())
+			rm := md.ResourceMetrics().At(0)
+			v, found := rm.Resource().Attributes().Get("attr-key")
+			require.True(t, found)
+			require.Equal(t, "attr-val", v.Str())
+			m := rm.ScopeMetrics().At(0).Metrics().At(0)
+			require.Equal(t, "cpu_temp", m.Name())
+			require.InDelta(t, 87.332, m.Gauge().DataPoints().At(0).DoubleValue(), 1e-9)
+		})
+	}
+}
+
+func TestOpenTelemetryHTTPRetryAfter(t *testing.T) {
+	server := newMockHTTPService(t)
+	defer server.Close()
+	server.status = http.StatusServiceUnavailable
+	server.header = http.Header{"Retry-After": []string{"30"}}
+
+	plugin := &OpenTelemetry{
+		ServiceAddress: server.URL,
+		Protocol:       "http/protobuf",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+	}
+
+	// The metrics must be kept for the next write
+	err := plugin.Write(input)
+	require.ErrorContains(t, err, "waiting 30s")
+	var writeErr *internal.PartialWriteError
+	require.NotErrorAs(t, err, &writeErr)
+	require.WithinDuration(t, time.Now().Add(30*time.Second), plugin.retryTime, 5*time.Second)
+
+	// No request should be sent before the retry time elapsed
+	require.ErrorContains(t, plugin.Write(input), "retry time has not elapsed")
+	require.Len(t, server.requests, 1)
+
+	// Sending is resumed afterwards
+	server.status = http.StatusOK
+	plugin.retryTime = time.Time{}
+	require.NoError(t, plugin.Write(input))
+	require.Len(t, server.requests, 2)
+}
+
+func TestOpenTelemetryHTTPRejected(t *testing.T) {
+	server := newMockHTTPService(t)
+	defer server.Close()
+	server.status = http.StatusBadRequest
+
+	plugin := &OpenTelemetry{
+		ServiceAddress: server.URL,
+		Protocol:       "http/protobuf",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 23.0}, time.Unix(10, 0)),
+	}
+
+	err := plugin.Write(input)
+	var writeErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.Empty(t, writeErr.MetricsAccept)
+	require.ElementsMatch(t, []int{0, 1}, writeErr.MetricsReject)
+}
+
+func TestOpenTelemetryHTTPPartialSuccess(t *testing.T) {
+	server := newMockHTTPService(t)
+	defer server.Close()
+
+	// Reject all data points of the first request and only some of the second
+	server.response = func(r *mockHTTPRequest) []byte {
+		resp := pmetricotlp.NewExportResponse()
+		if len(server.requests) == 1 {
+			resp.PartialSuccess().SetRejectedDataPoints(int64(r.metrics().DataPointCount()))
+		} else {
+			resp.PartialSuccess().SetRejectedDataPoints(1)
+		}
+		resp.PartialSuccess().SetErrorMessage("invalid data points")
+		buf, err := resp.MarshalProto()
+		require.NoError(t, err)
+		return buf
+	}
+
+	plugin := &OpenTelemetry{
+		ServiceAddress: server.URL,
+		Protocol:       "http/protobuf",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(10, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 23.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(10, 0)),
+	}
+
+	err := plugin.Write(input)
+	var writeErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.Equal(t, []int{1}, writeErr.MetricsReject)
+	require.Equal(t, []int{0, 2}, writeErr.MetricsAccept)
+}
+
+func TestOpenTelemetryPassthrough(t *testing.T) {
+	server := newMockHTTPService(t)
+	defer server.Close()
+
+	plugin := &OpenTelemetry{
+		ServiceAddress: server.URL,
+		Protocol:       "http/json",
+		Passthrough:    []string{"traces", "logs"},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := []telegraf.Metric{
+		testutil.MustMetric(
+			"spans",
+			map[string]string{
+				"trace_id":          "5b8efff798038103d269b633813fc60c",
+				"span_id":           "eee19b7ec3c1b174",
+				"service.name":      "checkout",
+				"otel.library.name": "my-library",
+			},
+			map[string]interface{}{
+				"span.name":          "GET /cart",
+				"span.kind":          "Server",
+				"parent_span_id":     "eee19b7ec3c1b173",
+				"end_time_unix_nano": int64(1622848687000000000),
+				"otel.status_code":   "Error",
+				"attributes":         `{"http.method":"GET","http.status_code":500}`,
+			},
+			time.Unix(0, 1622848686000000000),
+		),
+		testutil.MustMetric(
+			"logs",
+			map[string]string{
+				"trace_id":     "5b8efff798038103d269b633813fc60c",
+				"span_id":      "eee19b7ec3c1b174",
+				"service.name": "checkout",
+			},
+			map[string]interface{}{
+				"body":            "cart not found",
+				"severity_number": int64(17),
+				"severity_text":   "ERROR",
+				"attributes":      `{"cart.id":"42"}`,
+			},
+			time.Unix(0, 1622848686500000000),
+		),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("spans", map[string]string{}, map[string]interface{}{"span.name": "no IDs"}, time.Unix(0, 0)),
+	}
+
+	err := plugin.Write(input)
+	var writeErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.Equal(t, []int{3}, writeErr.MetricsReject)
+	require.Equal(t, []int{2, 0, 1}, writeErr.MetricsAccept)
+
+	require.Len(t, server.requests, 3)
+	require.Equal(t, "/v1/metrics", server.requests[0].path)
+
+	// Check the span
+	require.Equal(t, "/v1/traces", server.requests[1].path)
+	traces := ptraceotlp.NewExportRequest()
+	require.NoError(t, traces.UnmarshalJSON(server.requests[1].body))
+	require.Equal(t, 1, traces.Traces().SpanCount())
+	rs := traces.Traces().ResourceSpans().At(0)
+	require.Equal(t, map[string]interface{}{"service.name": "checkout"}, rs.Resource().Attributes().AsRaw())
+	require.Equal(t, "my-library", rs.ScopeSpans().At(0).Scope().Name())
+	span := rs.ScopeSpans().At(0).Spans().At(0)
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", span.TraceID().String())
+	require.Equal(t, "eee19b7ec3c1b174", span.SpanID().String())
+	require.Equal(t, "eee19b7ec3c1b173", span.ParentSpanID().String())
+	require.Equal(t, "GET /cart", span.Name())
+	require.Equal(t, ptrace.SpanKindServer, span.Kind())
+	require.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	require.Equal(t, time.Second, span.EndTimestamp().AsTime().Sub(span.StartTimestamp().AsTime()))
+	require.Equal(t, map[string]interface{}{"http.method": "GET", "http.status_code": int64(500)}, span.Attributes().AsRaw())
+
+	// Check the log record
+	require.Equal(t, "/v1/logs", server.requests[2].path)
+	logs := plogotlp.NewExportRequest()
+	require.NoError(t, logs.UnmarshalJSON(server.requests[2].body))
+	require.Equal(t, 1, logs.Logs().LogRecordCount())
+	rl := logs.Logs().ResourceLogs().At(0)
+	require.Equal(t, map[string]interface{}{"service.name": "checkout"}, rl.Resource().Attributes().AsRaw())
+	record := rl.ScopeLogs().At(0).LogRecords().At(0)
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", record.TraceID().String())
+	require.Equal(t, "cart not found", record.Body().Str())
+	require.Equal(t, plog.SeverityNumberError, record.SeverityNumber())
+	require.Equal(t, "ERROR", record.SeverityText())
+	require.Equal(t, map[string]interface{}{"cart.id": "42"}, record.Attributes().AsRaw())
+}
+
+func TestOpenTelemetryInvalidSettings(t *testing.T) {
+	plugin := &OpenTelemetry{Protocol: "http", Log: testutil.Logger{}}
+	require.ErrorContains(t, plugin.Init(), "invalid protocol")
+
+	plugin = &OpenTelemetry{Passthrough: []string{"profiles"}, Log: testutil.Logger{}}
+	require.ErrorContains(t, plugin.Init(), "invalid passthrough signal")
+
+	plugin = &OpenTelemetry{ServiceAddress: "localhost:4318", Protocol: "http/protobuf", Log: testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+	require.ErrorContains(t, plugin.Connect(), "invalid scheme")
+}
+
+func TestServiceAddressDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		protocol string
+		expected string
+	}{
+		{name: "grpc", protocol: "grpc", expected: "localhost:4317"},
+		{name: "http", protocol: "http/protobuf", expected: "http://localhost:4318"},
+		{name: "explicit grpc port", address: "localhost:4317", protocol: "http/json", expected: "localhost:4317"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &OpenTelemetry{ServiceAddress: tt.address, Protocol: tt.protocol, Log: testutil.Logger{}}
+			require.NoError(t, plugin.Init())
+			require.Equal(t, tt.expected, plugin.ServiceAddress)
+		})
+	}
+}
+
+func TestRetryDuration(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		header   string
+		count    int
+		expected time.Duration
+	}{
+		{name: "backoff", count: 3, expected: 4 * time.Second},
+		{name: "seconds", header: "30", count: 1, expected: 30 * time.Second},
+		{name: "date", header: now.Add(2 * time.Minute).UTC().Format(http.TimeFormat), count: 1, expected: 2 * time.Minute},
+		{name: "invalid", header: "soon", count: 1, expected: time.Second},
+		{name: "capped", header: "3600", count: 1, expected: maxRetryAfter},
+		{name: "capped backoff", count: 20, expected: maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.InDelta(t, tt.expected.Seconds(), retryDuration(tt.header, tt.count, now).Seconds(), 1)
+		})
+	}
+}
+
+type mockHTTPRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func (r *mockHTTPRequest) metrics() pmetric.Metrics {
+	req := pmetricotlp.NewExportRequest()
+	var err error
+	if r.header.Get("Content-Type") == "application/json" {
+		err = req.UnmarshalJSON(r.body)
+	} else {
+		err = req.UnmarshalProto(r.body)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return req.Metrics()
+}
+
+type mockHTTPService struct {
+	*httptest.Server
+	status   int
+	header   http.Header
+	response func(*mockHTTPRequest) []byte
+	requests []*mockHTTPRequest
+}
+
+func newMockHTTPService(t *testing.T) *mockHTTPService {
+	m := &mockHTTPService{status: http.StatusOK}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			reader = gz
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		req := &mockHTTPRequest{path: r.URL.Path, header: r.Header, body: body}
+		m.requests = append(m.requests, req)
+
+		for k, v := range m.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(m.status)
+		if m.response != nil && m.status == http.StatusOK {
+			if _, err := w.Write(m.response(req)); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	return m
+}
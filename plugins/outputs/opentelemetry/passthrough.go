@@ -0,0 +1,409 @@
+package opentelemetry
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Attribute keys of the OpenTelemetry input plugin not defined in the common
+// package
+const (
+	attributeLibraryName       = "otel.library.name"
+	attributeLibraryVersion    = "otel.library.version"
+	attributeStatusCode        = "otel.status_code"
+	attributeStatusDescription = "otel.status_description"
+)
+
+// attributeSet holds the attributes of a record split by their destination
+type attributeSet struct {
+	resource     pcommon.Map
+	scopeName    string
+	scopeVersion string
+	record       pcommon.Map
+}
+
+// key identifies the resource and instrumentation scope of the record
+func (a *attributeSet) key() (string, string) {
+	keys := make([]string, 0, a.resource.Len())
+	a.resource.Range(func(k string, v pcommon.Value) bool {
+		keys = append(keys, k+"="+v.AsString())
+		return true
+	})
+	sort.Strings(keys)
+	resource := strings.Join(keys, "\x00")
+	return resource, resource + "\x01" + a.scopeName + "\x00" + a.scopeVersion
+}
+
+// splitAttributes distributes the tags and the JSON encoded attributes field
+// of a metric to the resource, the scope and the record. Attributes following
+// the resource semantic conventions are treated as resource attributes.
+func splitAttributes(m telegraf.Metric, skipTags ...string) (*attributeSet, error) {
+	attrs := &attributeSet{
+		resource: pcommon.NewMap(),
+		record:   pcommon.NewMap(),
+	}
+
+	if v, found := m.GetField(common.AttributeAttributes); found {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type %T for field %q", v, common.AttributeAttributes)
+		}
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.UseNumber()
+		var raw map[string]interface{}
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("decoding field %q failed: %w", common.AttributeAttributes, err)
+		}
+		for k, v := range raw {
+			dest := attrs.record
+			if common.ResourceNamespace.MatchString(k) {
+				dest = attrs.resource
+			}
+			if err := dest.PutEmpty(k).FromRaw(fromJSON(v)); err != nil {
+				return nil, fmt.Errorf("converting attribute %q failed: %w", k, err)
+			}
+		}
+	}
+
+	for _, tag := range m.TagList() {
+		switch tag.Key {
+		case attributeLibraryName:
+			attrs.scopeName = tag.Value
+		case attributeLibraryVersion:
+			attrs.scopeVersion = tag.Value
+		default:
+			if slices.Contains(skipTags, tag.Key) {
+				continue
+			}
+			if common.ResourceNamespace.MatchString(tag.Key) {
+				attrs.resource.PutStr(tag.Key, tag.Value)
+			} else {
+				attrs.record.PutStr(tag.Key, tag.Value)
+			}
+		}
+	}
+
+	return attrs, nil
+}
+
+// fromJSON restores integer attribute values lost when decoding JSON numbers
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = fromJSON(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = fromJSON(v[k])
+		}
+	}
+	return v
+}
+
+// tracesBuilder converts metrics produced from spans by the OpenTelemetry
+// input plugin back to OpenTelemetry traces
+type tracesBuilder struct {
+	traces     ptrace.Traces
+	attributes map[string]string
+	resources  map[string]ptrace.ResourceSpans
+	scopes     map[string]ptrace.ScopeSpans
+}
+
+func newTracesBuilder(attributes map[string]string) *tracesBuilder {
+	return &tracesBuilder{
+		traces:     ptrace.NewTraces(),
+		attributes: attributes,
+		resources:  make(map[string]ptrace.ResourceSpans),
+		scopes:     make(map[string]ptrace.ScopeSpans),
+	}
+}
+
+func (b *tracesBuilder) scope(attrs *attributeSet) ptrace.ScopeSpans {
+	resourceKey, scopeKey := attrs.key()
+	if ss, found := b.scopes[scopeKey]; found {
+		return ss
+	}
+
+	rs, found := b.resources[resourceKey]
+	if !found {
+		rs = b.traces.ResourceSpans().AppendEmpty()
+		attrs.resource.CopyTo(rs.Resource().Attributes())
+		for k, v := range b.attributes {
+			rs.Resource().Attributes().PutStr(k, v)
+		}
+		b.resources[resourceKey] = rs
+	}
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName(attrs.scopeName)
+	ss.Scope().SetVersion(attrs.scopeVersion)
+	b.scopes[scopeKey] = ss
+
+	return ss
+}
+
+func (b *tracesBuilder) add(m telegraf.Metric) error {
+	traceID, err := parseTraceID(m)
+	if err != nil {
+		return err
+	}
+	spanID, err := parseSpanID(m, common.AttributeSpanID)
+	if err != nil {
+		return err
+	}
+
+	attrs, err := splitAttributes(m, common.AttributeTraceID, common.AttributeSpanID)
+	if err != nil {
+		return err
+	}
+
+	span := ptrace.NewSpan()
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(m.Time()))
+	for _, field := range m.FieldList() {
+		switch field.Key {
+		case common.AttributeAttributes:
+			// Already handled when splitting the attributes
+		case common.AttributeParentSpanID:
+			id, err := parseSpanID(m, field.Key)
+			if err != nil {
+				return err
+			}
+			span.SetParentSpanID(id)
+		case common.AttributeTraceState:
+			span.TraceState().FromRaw(fmt.Sprint(field.Value))
+		case common.AttributeSpanName:
+			span.SetName(fmt.Sprint(field.Value))
+		case common.AttributeSpanKind:
+			span.SetKind(parseSpanKind(fmt.Sprint(field.Value)))
+		case common.AttributeEndTimeUnixNano:
+			ts, err := internal.ToInt64(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, ts)))
+		case common.AttributeDurationNano:
+			// Derived from the start and end time
+		case common.AttributeDroppedAttributesCount:
+			n, err := internal.ToUint32(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			span.SetDroppedAttributesCount(n)
+		case common.AttributeDroppedEventsCount:
+			n, err := internal.ToUint32(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			span.SetDroppedEventsCount(n)
+		case common.AttributeDroppedLinksCount:
+			n, err := internal.ToUint32(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			span.SetDroppedLinksCount(n)
+		case attributeStatusCode:
+			span.Status().SetCode(parseStatusCode(fmt.Sprint(field.Value)))
+		case attributeStatusDescription:
+			span.Status().SetMessage(fmt.Sprint(field.Value))
+		default:
+			if err := attrs.record.PutEmpty(field.Key).FromRaw(field.Value); err != nil {
+				return fmt.Errorf("converting field %q failed: %w", field.Key, err)
+			}
+		}
+	}
+
+	// Use the duration if the end time is missing
+	if span.EndTimestamp() == 0 {
+		if v, found := m.GetField(common.AttributeDurationNano); found {
+			d, err := internal.ToInt64(v)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", common.AttributeDurationNano, err)
+			}
+			span.SetEndTimestamp(pcommon.NewTimestampFromTime(m.Time().Add(time.Duration(d))))
+		}
+	}
+	attrs.record.CopyTo(span.Attributes())
+
+	span.MoveTo(b.scope(attrs).Spans().AppendEmpty())
+	return nil
+}
+
+// logsBuilder converts metrics produced from log records by the OpenTelemetry
+// input plugin back to OpenTelemetry logs
+type logsBuilder struct {
+	logs       plog.Logs
+	attributes map[string]string
+	resources  map[string]plog.ResourceLogs
+	scopes     map[string]plog.ScopeLogs
+}
+
+func newLogsBuilder(attributes map[string]string) *logsBuilder {
+	return &logsBuilder{
+		logs:       plog.NewLogs(),
+		attributes: attributes,
+		resources:  make(map[string]plog.ResourceLogs),
+		scopes:     make(map[string]plog.ScopeLogs),
+	}
+}
+
+func (b *logsBuilder) scope(attrs *attributeSet) plog.ScopeLogs {
+	resourceKey, scopeKey := attrs.key()
+	if sl, found := b.scopes[scopeKey]; found {
+		return sl
+	}
+
+	rl, found := b.resources[resourceKey]
+	if !found {
+		rl = b.logs.ResourceLogs().AppendEmpty()
+		attrs.resource.CopyTo(rl.Resource().Attributes())
+		for k, v := range b.attributes {
+			rl.Resource().Attributes().PutStr(k, v)
+		}
+		b.resources[resourceKey] = rl
+	}
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(attrs.scopeName)
+	sl.Scope().SetVersion(attrs.scopeVersion)
+	b.scopes[scopeKey] = sl
+
+	return sl
+}
+
+func (b *logsBuilder) add(m telegraf.Metric) error {
+	attrs, err := splitAttributes(m, common.AttributeTraceID, common.AttributeSpanID)
+	if err != nil {
+		return err
+	}
+
+	record := plog.NewLogRecord()
+	record.SetTimestamp(pcommon.NewTimestampFromTime(m.Time()))
+	if _, found := m.GetTag(common.AttributeTraceID); found {
+		traceID, err := parseTraceID(m)
+		if err != nil {
+			return err
+		}
+		record.SetTraceID(traceID)
+	}
+	if _, found := m.GetTag(common.AttributeSpanID); found {
+		spanID, err := parseSpanID(m, common.AttributeSpanID)
+		if err != nil {
+			return err
+		}
+		record.SetSpanID(spanID)
+	}
+	for _, field := range m.FieldList() {
+		switch field.Key {
+		case common.AttributeAttributes:
+			// Already handled when splitting the attributes
+		case common.AttributeBody:
+			record.Body().SetStr(fmt.Sprint(field.Value))
+		case common.AttributeSeverityNumber:
+			n, err := internal.ToInt32(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			record.SetSeverityNumber(plog.SeverityNumber(n))
+		case common.AttributeSeverityText:
+			record.SetSeverityText(fmt.Sprint(field.Value))
+		case common.AttributeObservedTimeUnixNano:
+			ts, err := internal.ToInt64(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			record.SetObservedTimestamp(pcommon.NewTimestampFromTime(time.Unix(0, ts)))
+		case common.AttributeDroppedAttributesCount:
+			n, err := internal.ToUint32(field.Value)
+			if err != nil {
+				return fmt.Errorf("invalid field %q: %w", field.Key, err)
+			}
+			record.SetDroppedAttributesCount(n)
+		default:
+			if err := attrs.record.PutEmpty(field.Key).FromRaw(field.Value); err != nil {
+				return fmt.Errorf("converting field %q failed: %w", field.Key, err)
+			}
+		}
+	}
+	attrs.record.CopyTo(record.Attributes())
+
+	record.MoveTo(b.scope(attrs).LogRecords().AppendEmpty())
+	return nil
+}
+
+func parseTraceID(m telegraf.Metric) (pcommon.TraceID, error) {
+	var id pcommon.TraceID
+	v, found := m.GetTag(common.AttributeTraceID)
+	if !found {
+		return id, errors.New("missing trace ID")
+	}
+	buf, err := hex.DecodeString(v)
+	if err != nil || len(buf) != len(id) {
+		return id, fmt.Errorf("invalid trace ID %q", v)
+	}
+	copy(id[:], buf)
+	return id, nil
+}
+
+func parseSpanID(m telegraf.Metric, key string) (pcommon.SpanID, error) {
+	var id pcommon.SpanID
+	v, found := m.GetTag(key)
+	if !found {
+		fv, ok := m.GetField(key)
+		if !ok {
+			return id, fmt.Errorf("missing %q", key)
+		}
+		v = fmt.Sprint(fv)
+	}
+	buf, err := hex.DecodeString(v)
+	if err != nil || len(buf) != len(id) {
+		return id, fmt.Errorf("invalid %q value %q", key, v)
+	}
+	copy(id[:], buf)
+	return id, nil
+}
+
+func parseSpanKind(s string) ptrace.SpanKind {
+	for _, kind := range []ptrace.SpanKind{
+		ptrace.SpanKindInternal,
+		ptrace.SpanKindServer,
+		ptrace.SpanKindClient,
+		ptrace.SpanKindProducer,
+		ptrace.SpanKindConsumer,
+	} {
+		if strings.EqualFold(s, kind.String()) || strings.EqualFold(s, "SPAN_KIND_"+kind.String()) {
+			return kind
+		}
+	}
+	return ptrace.SpanKindUnspecified
+}
+
+func parseStatusCode(s string) ptrace.StatusCode {
+	switch strings.ToLower(strings.TrimPrefix(strings.ToUpper(s), "STATUS_CODE_")) {
+	case "ok":
+		return ptrace.StatusCodeOk
+	case "error":
+		return ptrace.StatusCodeError
+	}
+	return ptrace.StatusCodeUnset
+}
@@ -0,0 +1,268 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common_pulsar "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Pulsar struct {
+	Topic              string          `toml:"topic"`
+	TopicTag           string          `toml:"topic_tag"`
+	ExcludeTopicTag    bool            `toml:"exclude_topic_tag"`
+	RoutingTag         string          `toml:"routing_tag"`
+	RoutingKey         string          `toml:"routing_key"`
+	MetricNameProperty string          `toml:"metric_name_property"`
+	CompressionType    string          `toml:"compression_type"`
+	SendTimeout        config.Duration `toml:"send_timeout"`
+	Log                telegraf.Logger `toml:"-"`
+	common_pulsar.ClientConfig
+
+	serializer   telegraf.Serializer
+	compression  pulsar.CompressionType
+	client       pulsar.Client
+	producerFunc func(pulsar.ProducerOptions) (pulsar.Producer, error)
+	producers    map[string]pulsar.Producer
+}
+
+// result of sending a single message
+type result struct {
+	index int
+	err   error
+}
+
+func (*Pulsar) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pulsar) SetSerializer(serializer telegraf.Serializer) {
+	p.serializer = serializer
+}
+
+func (p *Pulsar) Init() error {
+	if p.Topic == "" {
+		return errors.New("'topic' required")
+	}
+
+	switch p.CompressionType {
+	case "", "none":
+		p.compression = pulsar.NoCompression
+	case "lz4":
+		p.compression = pulsar.LZ4
+	case "zlib":
+		p.compression = pulsar.ZLib
+	case "zstd":
+		p.compression = pulsar.ZSTD
+	default:
+		return fmt.Errorf("invalid compression type %q", p.CompressionType)
+	}
+
+	p.producers = make(map[string]pulsar.Producer)
+
+	return nil
+}
+
+func (p *Pulsar) Connect() error {
+	if p.producerFunc == nil {
+		client, err := p.ClientConfig.NewClient(p.Log)
+		if err != nil {
+			return fmt.Errorf("creating client failed: %w", err)
+		}
+		p.client = client
+		p.producerFunc = client.CreateProducer
+	}
+
+	// Create the producer of the default topic to check the connection
+	if _, err := p.producer(p.Topic); err != nil {
+		return &internal.StartupError{Err: err, Retry: true}
+	}
+	return nil
+}
+
+func (p *Pulsar) Close() error {
+	p.resetProducers()
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+		p.producerFunc = nil
+	}
+	return nil
+}
+
+func (p *Pulsar) Write(metrics []telegraf.Metric) error {
+	writeErr := &internal.PartialWriteError{
+		MetricsAccept: make([]int, 0, len(metrics)),
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan result, len(metrics))
+	used := make(map[string]pulsar.Producer)
+	var producerErr error
+	for i, metric := range metrics {
+		metric, topic := p.topic(metric)
+
+		buf, err := p.serializer.Serialize(metric)
+		if err != nil {
+			p.Log.Errorf("Could not serialize metric: %v", err)
+			writeErr.Err = internal.ErrSerialization
+			writeErr.MetricsReject = append(writeErr.MetricsReject, i)
+			continue
+		}
+
+		producer, err := p.producer(topic)
+		if err != nil {
+			// Keep the metric to retry with the next write
+			producerErr = err
+			continue
+		}
+		used[topic] = producer
+
+		msg := &pulsar.ProducerMessage{
+			Payload: buf,
+			Key:     p.routingKey(metric),
+		}
+		if p.MetricNameProperty != "" {
+			msg.Properties = map[string]string{p.MetricNameProperty: metric.Name()}
+		}
+		// The event time is unsigned so times before the epoch are omitted
+		if metric.Time().UnixMilli() > 0 {
+			msg.EventTime = metric.Time()
+		}
+
+		wg.Add(1)
+		producer.SendAsync(context.Background(), msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+			defer wg.Done()
+			results <- result{index: i, err: err}
+		})
+	}
+
+	// Send out batched messages and wait for the broker to acknowledge all
+	// messages
+	for topic, producer := range used {
+		if err := producer.FlushWithCtx(context.Background()); err != nil {
+			p.Log.Debugf("Flushing producer of topic %q failed: %v", topic, err)
+		}
+	}
+	wg.Wait()
+	close(results)
+
+	var sendErr error
+	var failed int
+	for r := range results {
+		switch {
+		case r.err == nil:
+			writeErr.MetricsAccept = append(writeErr.MetricsAccept, r.index)
+		case errors.Is(r.err, pulsar.ErrMessageTooLarge), errors.Is(r.err, pulsar.ErrMetaTooLarge):
+			p.Log.Error("Message too large, consider increasing the broker's `maxMessageSize`; dropping metric")
+			writeErr.MetricsReject = append(writeErr.MetricsReject, r.index)
+		default:
+			failed++
+			if sendErr == nil {
+				sendErr = r.err
+			}
+			if errors.Is(r.err, pulsar.ErrProducerClosed) {
+				p.resetProducers()
+			}
+		}
+	}
+	if failed > 0 {
+		p.Log.Debugf("Failed to send %d of %d messages", failed, len(metrics))
+	}
+
+	err := errors.Join(producerErr, sendErr)
+	if err != nil {
+		// Keep all metrics if none was processed
+		if len(writeErr.MetricsAccept) == 0 && len(writeErr.MetricsReject) == 0 {
+			return err
+		}
+		writeErr.Err = err
+	} else if len(writeErr.MetricsReject) > 0 && writeErr.Err == nil {
+		writeErr.Err = errors.New("messages rejected by the broker")
+	}
+
+	if writeErr.Err != nil {
+		return writeErr
+	}
+	return nil
+}
+
+// topic returns the topic of the metric, with the topic tag removed from a
+// copy of the metric if requested
+func (p *Pulsar) topic(metric telegraf.Metric) (telegraf.Metric, string) {
+	if p.TopicTag == "" {
+		return metric, p.Topic
+	}
+
+	topic, ok := metric.GetTag(p.TopicTag)
+	if !ok {
+		return metric, p.Topic
+	}
+
+	// If excluding the topic tag, a copy is required to avoid modifying the
+	// metric buffer.
+	if p.ExcludeTopicTag {
+		metric = metric.Copy()
+		metric.Accept()
+		metric.RemoveTag(p.TopicTag)
+	}
+	return metric, topic
+}
+
+// routingKey returns the message key used by the broker to route messages of
+// key_shared subscriptions and partitioned topics
+func (p *Pulsar) routingKey(metric telegraf.Metric) string {
+	if p.RoutingTag != "" {
+		if key, ok := metric.GetTag(p.RoutingTag); ok {
+			return key
+		}
+	}
+	return p.RoutingKey
+}
+
+// producer returns the cached producer of the topic or creates a new one
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	if producer, found := p.producers[topic]; found {
+		return producer, nil
+	}
+
+	producer, err := p.producerFunc(pulsar.ProducerOptions{
+		Topic:           topic,
+		SendTimeout:     time.Duration(p.SendTimeout),
+		CompressionType: p.compression,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating producer for topic %q failed: %w", topic, err)
+	}
+	p.producers[topic] = producer
+	return producer, nil
+}
+
+// resetProducers closes all producers so they are created again with the next
+// write
+func (p *Pulsar) resetProducers() {
+	for topic, producer := range p.producers {
+		producer.Close()
+		delete(p.producers, topic)
+	}
+}
+
+func init() {
+	outputs.Add("pulsar", func() telegraf.Output {
+		return &Pulsar{}
+	})
+}
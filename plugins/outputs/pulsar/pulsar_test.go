@@ -0,0 +1,237 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	serializers_influx "github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type pendingMessage struct {
+	msg      *pulsar.ProducerMessage
+	callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)
+}
+
+type fakeProducer struct {
+	pulsar.Producer
+	options pulsar.ProducerOptions
+	fail    map[string]error
+	pending []pendingMessage
+	sent    []*pulsar.ProducerMessage
+	closed  bool
+}
+
+// SendAsync defers the callback until flushing like batching does
+func (p *fakeProducer) SendAsync(_ context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	p.pending = append(p.pending, pendingMessage{msg: msg, callback: callback})
+}
+
+func (p *fakeProducer) FlushWithCtx(context.Context) error {
+	for _, pm := range p.takePending() {
+		if err, found := p.fail[string(pm.msg.Payload)]; found {
+			pm.callback(nil, pm.msg, err)
+			continue
+		}
+		p.sent = append(p.sent, pm.msg)
+		pm.callback(nil, pm.msg, nil)
+	}
+	return nil
+}
+
+func (p *fakeProducer) Close() {
+	p.closed = true
+}
+
+func (p *fakeProducer) takePending() []pendingMessage {
+	msgs := p.pending
+	p.pending = nil
+	return msgs
+}
+
+type fakeClient struct {
+	fail      map[string]error
+	producers map[string]*fakeProducer
+}
+
+func (c *fakeClient) create(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+	if c.producers == nil {
+		c.producers = make(map[string]*fakeProducer)
+	}
+	if options.Topic == "unavailable" {
+		return nil, errors.New("topic not found")
+	}
+	p := &fakeProducer{options: options, fail: c.fail}
+	c.producers[options.Topic] = p
+	return p, nil
+}
+
+func newPlugin(t *testing.T, client *fakeClient) *Pulsar {
+	t.Helper()
+
+	serializer := &serializers_influx.Serializer{}
+	require.NoError(t, serializer.Init())
+
+	plugin := &Pulsar{
+		Topic:        "telegraf",
+		Log:          testutil.Logger{},
+		producerFunc: client.create,
+	}
+	plugin.SetSerializer(serializer)
+	return plugin
+}
+
+func TestInit(t *testing.T) {
+	plugin := &Pulsar{}
+	require.ErrorContains(t, plugin.Init(), "'topic' required")
+
+	plugin = &Pulsar{Topic: "telegraf", CompressionType: "gzip"}
+	require.ErrorContains(t, plugin.Init(), `invalid compression type "gzip"`)
+
+	plugin = &Pulsar{Topic: "telegraf", CompressionType: "zstd"}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, pulsar.ZSTD, plugin.compression)
+}
+
+func TestConnectError(t *testing.T) {
+	plugin := newPlugin(t, &fakeClient{})
+	plugin.Topic = "unavailable"
+	require.NoError(t, plugin.Init())
+
+	err := plugin.Connect()
+	var serr *internal.StartupError
+	require.ErrorAs(t, err, &serr)
+	require.True(t, serr.Retry)
+}
+
+func TestWrite(t *testing.T) {
+	client := &fakeClient{}
+	plugin := newPlugin(t, client)
+	plugin.TopicTag = "topic"
+	plugin.ExcludeTopicTag = true
+	plugin.RoutingTag = "host"
+	plugin.RoutingKey = "telegraf"
+	plugin.MetricNameProperty = "metric"
+	plugin.CompressionType = "lz4"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": 42},
+			time.Unix(1715851200, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"topic": "memory"},
+			map[string]interface{}{"value": 23},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	require.Contains(t, client.producers, "telegraf")
+	require.Contains(t, client.producers, "memory")
+	require.Equal(t, pulsar.LZ4, client.producers["telegraf"].options.CompressionType)
+
+	sent := client.producers["telegraf"].sent
+	require.Len(t, sent, 1)
+	require.Equal(t, "cpu,host=a value=42i 1715851200000000000\n", string(sent[0].Payload))
+	require.Equal(t, "a", sent[0].Key)
+	require.Equal(t, map[string]string{"metric": "cpu"}, sent[0].Properties)
+	require.Equal(t, time.Unix(1715851200, 0), sent[0].EventTime)
+
+	// The topic tag is removed and the routing key is used as fallback
+	sent = client.producers["memory"].sent
+	require.Len(t, sent, 1)
+	require.Equal(t, "mem value=23i 0\n", string(sent[0].Payload))
+	require.Equal(t, "telegraf", sent[0].Key)
+	require.Zero(t, sent[0].EventTime)
+
+	require.True(t, client.producers["telegraf"].closed)
+	require.True(t, client.producers["memory"].closed)
+}
+
+func TestWritePerMessageErrors(t *testing.T) {
+	client := &fakeClient{
+		fail: map[string]error{
+			"test value=2i 0\n": pulsar.ErrMessageTooLarge,
+			"test value=3i 0\n": pulsar.ErrSendTimeout,
+		},
+	}
+	plugin := newPlugin(t, client)
+	plugin.TopicTag = "topic"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{"topic": "unavailable"}, map[string]interface{}{"value": 4}, time.Unix(0, 0)),
+	}
+
+	err := plugin.Write(metrics)
+	var werr *internal.PartialWriteError
+	require.ErrorAs(t, err, &werr)
+	require.ErrorIs(t, werr.Err, pulsar.ErrSendTimeout)
+	require.ErrorContains(t, werr.Err, `creating producer for topic "unavailable" failed`)
+	require.Equal(t, []int{0}, werr.MetricsAccept)
+	require.Equal(t, []int{1}, werr.MetricsReject)
+}
+
+func TestWriteAllFailed(t *testing.T) {
+	client := &fakeClient{
+		fail: map[string]error{
+			"test value=1i 0\n": pulsar.ErrSendTimeout,
+		},
+	}
+	plugin := newPlugin(t, client)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	metrics := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+
+	// Keep all metrics if nothing was sent
+	err := plugin.Write(metrics)
+	require.ErrorIs(t, err, pulsar.ErrSendTimeout)
+	var werr *internal.PartialWriteError
+	require.False(t, errors.As(err, &werr))
+}
+
+func TestWriteProducerClosed(t *testing.T) {
+	client := &fakeClient{
+		fail: map[string]error{
+			"test value=1i 0\n": pulsar.ErrProducerClosed,
+		},
+	}
+	plugin := newPlugin(t, client)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	first := client.producers["telegraf"]
+
+	metrics := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+	}
+	require.ErrorIs(t, plugin.Write(metrics), pulsar.ErrProducerClosed)
+	require.True(t, first.closed)
+
+	// The producer is created again with the next write
+	client.fail = nil
+	require.NoError(t, plugin.Write(metrics))
+	require.NotSame(t, first, client.producers["telegraf"])
+	require.Len(t, client.producers["telegraf"].sent, 1)
+}
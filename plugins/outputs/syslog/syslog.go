@@ -17,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/relp"
 	common_tls "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
@@ -35,10 +36,14 @@ type Syslog struct {
 	Separator           string `toml:"sdparam_separator"`
 	Framing             string `toml:"framing"`
 	Trailer             nontransparent.TrailerType
+	SyslogStandard      string          `toml:"syslog_standard"`
+	RELPWindowSize      int             `toml:"relp_window_size"`
+	RELPTimeout         config.Duration `toml:"relp_timeout"`
 	Log                 telegraf.Logger `toml:"-"`
 	net.Conn
 	common_tls.ClientConfig
 	mapper *SyslogMapper
+	relp   *relp.Client
 }
 
 func (*Syslog) SampleConfig() string {
@@ -49,10 +54,23 @@ func (s *Syslog) Init() error {
 	switch s.Framing {
 	case "":
 		s.Framing = "octet-counting"
-	case "octet-counting", "non-transparent":
+	case "octet-counting", "non-transparent", "relp":
 	default:
 		return fmt.Errorf("invalid 'framing' %q", s.Framing)
 	}
+
+	// Check the message format and set default
+	switch s.SyslogStandard {
+	case "":
+		s.SyslogStandard = "RFC5424"
+	case "RFC5424", "RFC3164":
+	default:
+		return fmt.Errorf("invalid 'syslog_standard' %q", s.SyslogStandard)
+	}
+
+	if s.RELPWindowSize < 1 {
+		s.RELPWindowSize = 128
+	}
 	return nil
 }
 
@@ -63,6 +81,13 @@ func (s *Syslog) Connect() error {
 	if len(spl) != 2 {
 		return fmt.Errorf("invalid address: %s", s.Address)
 	}
+	if s.Framing == "relp" {
+		switch spl[0] {
+		case "tcp", "tcp4", "tcp6":
+		default:
+			return fmt.Errorf("protocol %q not supported with RELP framing", spl[0])
+		}
+	}
 
 	tlsCfg, err := s.ClientConfig.TLSConfig()
 	if err != nil {
@@ -83,6 +108,15 @@ func (s *Syslog) Connect() error {
 		s.Log.Warnf("unable to configure keep alive (%s): %s", s.Address, err)
 	}
 
+	if s.Framing == "relp" {
+		client, err := relp.NewClient(c, time.Duration(s.RELPTimeout))
+		if err != nil {
+			c.Close()
+			return &internal.StartupError{Err: fmt.Errorf("opening RELP session failed: %w", err), Retry: true}
+		}
+		s.relp = client
+	}
+
 	s.Conn = c
 	return nil
 }
@@ -108,7 +142,15 @@ func (s *Syslog) Close() error {
 	if s.Conn == nil {
 		return nil
 	}
-	err := s.Conn.Close()
+
+	var err error
+	if s.relp != nil {
+		// Closing the session also closes the connection
+		err = s.relp.Close()
+		s.relp = nil
+	} else {
+		err = s.Conn.Close()
+	}
 	s.Conn = nil
 	return err
 }
@@ -120,6 +162,9 @@ func (s *Syslog) Write(metrics []telegraf.Metric) (err error) {
 			return err
 		}
 	}
+	if s.relp != nil {
+		return s.writeRELP(metrics)
+	}
 	for _, metric := range metrics {
 		msg, err := s.mapper.MapMetricToSyslogMessage(metric)
 		if err != nil {
@@ -145,16 +190,74 @@ func (s *Syslog) Write(metrics []telegraf.Metric) (err error) {
 	return nil
 }
 
+// writeRELP sends the metrics within a RELP session and only accepts the
+// metrics acknowledged by the server. Refused metrics are kept for retrying
+// with the next write.
+func (s *Syslog) writeRELP(metrics []telegraf.Metric) error {
+	writeErr := &internal.PartialWriteError{
+		MetricsAccept: make([]int, 0, len(metrics)),
+	}
+
+	messages := make([][]byte, 0, len(metrics))
+	indices := make([]int, 0, len(metrics))
+	for i, metric := range metrics {
+		msg, err := s.mapper.MapMetricToSyslogMessage(metric)
+		if err != nil {
+			s.Log.Errorf("Failed to create syslog message: %v", err)
+			writeErr.Err = internal.ErrSerialization
+			writeErr.MetricsReject = append(writeErr.MetricsReject, i)
+			continue
+		}
+
+		msgBytes, err := s.formatMessage(msg)
+		if err != nil {
+			s.Log.Errorf("Failed to format syslog message: %v", err)
+			writeErr.Err = internal.ErrSerialization
+			writeErr.MetricsReject = append(writeErr.MetricsReject, i)
+			continue
+		}
+		messages = append(messages, msgBytes)
+		indices = append(indices, i)
+	}
+
+	acked, err := s.relp.Send(messages, s.RELPWindowSize)
+	for _, idx := range acked {
+		writeErr.MetricsAccept = append(writeErr.MetricsAccept, indices[idx])
+	}
+	if err != nil {
+		// The session state is unknown so reconnect with the next write
+		if !errors.Is(err, relp.ErrRefused) {
+			s.Conn.Close()
+			s.Conn = nil
+			s.relp = nil
+			err = fmt.Errorf("closing connection: %w", err)
+		}
+
+		// Keep all metrics if none was processed
+		if len(writeErr.MetricsAccept) == 0 && len(writeErr.MetricsReject) == 0 {
+			return err
+		}
+		writeErr.Err = err
+	}
+
+	if writeErr.Err != nil {
+		return writeErr
+	}
+	return nil
+}
+
 func (s *Syslog) getSyslogMessageBytesWithFraming(msg *rfc5424.SyslogMessage) ([]byte, error) {
-	var msgString string
-	var err error
-	if msgString, err = msg.String(); err != nil {
+	msgBytes, err := s.formatMessage(msg)
+	if err != nil {
 		return nil, err
 	}
-	msgBytes := []byte(msgString)
 
-	if s.Framing == "octet-counting" {
+	switch s.Framing {
+	case "octet-counting":
 		return append([]byte(strconv.Itoa(len(msgBytes))+" "), msgBytes...), nil
+	case "relp":
+		// Messages are framed by the RELP session
+		return msgBytes, nil
 	}
 	// Non-transparent framing
 	trailer, err := s.Trailer.Value()
@@ -164,6 +267,49 @@ func (s *Syslog) getSyslogMessageBytesWithFraming(msg *rfc5424.SyslogMessage) ([
 	return append(msgBytes, byte(trailer)), nil
 }
 
+// formatMessage returns the message formatted according to the configured
+// syslog standard
+func (s *Syslog) formatMessage(msg *rfc5424.SyslogMessage) ([]byte, error) {
+	if s.SyslogStandard == "RFC3164" {
+		return formatRFC3164(msg), nil
+	}
+
+	msgString, err := msg.String()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(msgString), nil
+}
+
+// formatRFC3164 returns the message in the BSD syslog format
+// "<PRI>TIMESTAMP HOSTNAME TAG[PROCID]: MSG" (RFC3164#section-4.1). As the
+// format does not support structured data, SD-PARAMs are dropped. The
+// timestamp does not contain a timezone and is always sent in UTC.
+func formatRFC3164(msg *rfc5424.SyslogMessage) []byte {
+	var buf strings.Builder
+	buf.WriteString("<" + strconv.Itoa(int(*msg.Priority)) + ">")
+	buf.WriteString(msg.Timestamp.UTC().Format(time.Stamp))
+	buf.WriteByte(' ')
+	if msg.Hostname != nil {
+		buf.WriteString(*msg.Hostname)
+	} else {
+		buf.WriteByte('-')
+	}
+	buf.WriteByte(' ')
+	if msg.Appname != nil {
+		buf.WriteString(*msg.Appname)
+	}
+	if msg.ProcID != nil {
+		buf.WriteString("[" + *msg.ProcID + "]")
+	}
+	buf.WriteByte(':')
+	if msg.Message != nil {
+		buf.WriteByte(' ')
+		buf.WriteString(*msg.Message)
+	}
+	return []byte(buf.String())
+}
+
 func (s *Syslog) initializeSyslogMapper() {
 	if s.mapper != nil {
 		return
@@ -184,6 +330,8 @@ func newSyslog() *Syslog {
 		DefaultSeverityCode: uint8(5), // notice
 		DefaultFacilityCode: uint8(1), // user-level
 		DefaultAppname:      "Telegraf",
+		RELPWindowSize:      128,
+		RELPTimeout:         config.Duration(10 * time.Second),
 	}
 }
 
@@ -17,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/relp"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/testutil"
@@ -97,6 +98,61 @@ func TestGetSyslogMessageWithFramingNonTransparentNul(t *testing.T) {
 	require.Equal(t, "<13>1 2010-11-10T23:00:00Z testhost Telegraf - testmetric -\x00", string(messageBytesWithFraming), "Incorrect Octet counting framing")
 }
 
+func TestGetSyslogMessageRFC3164(t *testing.T) {
+	// Init plugin
+	s := newSyslog()
+	s.SyslogStandard = "RFC3164"
+	s.DefaultSdid = "default@32473"
+	require.NoError(t, s.Init())
+	s.initializeSyslogMapper()
+
+	tests := []struct {
+		name     string
+		metric   telegraf.Metric
+		expected string
+	}{
+		{
+			name: "with procid and message",
+			metric: metric.New(
+				"testmetric",
+				map[string]string{"hostname": "testhost"},
+				map[string]interface{}{"procid": "123", "msg": "hello world", "value": 42},
+				time.Date(2010, time.November, 1, 23, 0, 0, 0, time.UTC),
+			),
+			expected: "55 <13>Nov  1 23:00:00 testhost Telegraf[123]: hello world",
+		},
+		{
+			name: "without message",
+			metric: metric.New(
+				"testmetric",
+				map[string]string{"hostname": "testhost", "appname": "app"},
+				map[string]interface{}{"severity_code": 3},
+				time.Date(2010, time.November, 10, 23, 0, 0, 0, time.FixedZone("CET", 3600)),
+			),
+			expected: "33 <11>Nov 10 22:00:00 testhost app:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syslogMessage, err := s.mapper.MapMetricToSyslogMessage(tt.metric)
+			require.NoError(t, err)
+			messageBytesWithFraming, err := s.getSyslogMessageBytesWithFraming(syslogMessage)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(messageBytesWithFraming))
+		})
+	}
+}
+
+func TestInitInvalidSettings(t *testing.T) {
+	s := newSyslog()
+	s.Framing = "foo"
+	require.ErrorContains(t, s.Init(), `invalid 'framing' "foo"`)
+
+	s = newSyslog()
+	s.SyslogStandard = "RFC1234"
+	require.ErrorContains(t, s.Init(), `invalid 'syslog_standard' "RFC1234"`)
+}
+
 func TestSyslogWriteWithTcp(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	require.NoError(t, err)
@@ -251,6 +307,150 @@ func TestSyslogWriteReconnect(t *testing.T) {
 	require.Equal(t, string(messageBytesWithFraming), string(buf[:n]))
 }
 
+func TestSyslogWriteWithRELP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Fake server refusing the second message
+	var wg sync.WaitGroup
+	var received []string
+	var serverErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr = err
+			return
+		}
+		defer conn.Close()
+
+		session := relp.NewServerSession(conn)
+		serverErr = session.Serve(func(txnr uint32, data []byte) {
+			received = append(received, string(data))
+			if len(received) == 2 {
+				//nolint:errcheck // test will fail anyway
+				session.Respond(txnr, relp.StatusError, "refused")
+				return
+			}
+			//nolint:errcheck // test will fail anyway
+			session.Respond(txnr, relp.StatusOK, "OK")
+		})
+	}()
+
+	s := newSyslog()
+	s.Address = "tcp://" + listener.Addr().String()
+	s.Framing = "relp"
+	s.RELPWindowSize = 2
+	s.Log = testutil.Logger{}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"testmetric",
+			map[string]string{"hostname": "testhost"},
+			map[string]interface{}{"msg": "first"},
+			time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
+		),
+		metric.New(
+			"testmetric",
+			map[string]string{"hostname": "testhost"},
+			map[string]interface{}{"msg": "second"},
+			time.Date(2010, time.November, 10, 23, 0, 1, 0, time.UTC),
+		),
+		metric.New(
+			"testmetric",
+			map[string]string{"hostname": "testhost"},
+			map[string]interface{}{"msg": "third"},
+			time.Date(2010, time.November, 10, 23, 0, 2, 0, time.UTC),
+		),
+	}
+	err = s.Write(metrics)
+	require.ErrorContains(t, err, "message refused: 500 refused")
+	var writeErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &writeErr)
+	require.ElementsMatch(t, []int{0, 2}, writeErr.MetricsAccept)
+	require.Empty(t, writeErr.MetricsReject)
+
+	// The session must be usable after refused messages
+	require.NotNil(t, s.Conn)
+	require.NoError(t, s.Close())
+	wg.Wait()
+	require.NoError(t, serverErr)
+
+	expected := []string{
+		"<13>1 2010-11-10T23:00:00Z testhost Telegraf - testmetric - first",
+		"<13>1 2010-11-10T23:00:01Z testhost Telegraf - testmetric - second",
+		"<13>1 2010-11-10T23:00:02Z testhost Telegraf - testmetric - third",
+	}
+	require.Equal(t, expected, received)
+}
+
+func TestSyslogWriteWithRELPReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Fake server dropping the first connection without acknowledging
+	var wg sync.WaitGroup
+	var received []string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 2 {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			session := relp.NewServerSession(conn)
+			//nolint:errcheck // errors are expected when dropping the connection
+			session.Serve(func(txnr uint32, data []byte) {
+				if i == 0 {
+					conn.Close()
+					return
+				}
+				received = append(received, string(data))
+				//nolint:errcheck // test will fail anyway
+				session.Respond(txnr, relp.StatusOK, "OK")
+			})
+			conn.Close()
+		}
+	}()
+
+	s := newSyslog()
+	s.Address = "tcp://" + listener.Addr().String()
+	s.Framing = "relp"
+	s.Log = testutil.Logger{}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "testerr")}
+
+	// Nothing is acknowledged so all metrics are kept and the connection is
+	// closed
+	err = s.Write(metrics)
+	require.ErrorContains(t, err, "closing connection")
+	var writeErr *internal.PartialWriteError
+	require.NotErrorAs(t, err, &writeErr)
+	require.Nil(t, s.Conn)
+
+	// The next write reconnects
+	require.NoError(t, s.Write(metrics))
+	require.NoError(t, s.Close())
+	wg.Wait()
+	require.Len(t, received, 1)
+}
+
+func TestSyslogRELPUnsupportedProtocol(t *testing.T) {
+	s := newSyslog()
+	s.Address = "udp://127.0.0.1:514"
+	s.Framing = "relp"
+	require.NoError(t, s.Init())
+	require.ErrorContains(t, s.Connect(), `protocol "udp" not supported with RELP framing`)
+}
+
 func TestStartupErrorBehaviorDefault(t *testing.T) {
 	// Setup a dummy listener but do not accept connections
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -0,0 +1,5 @@
+//go:build !custom || parsers || parsers.cef
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/parsers/cef" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || parsers || parsers.cloudevents
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/parsers/cloudevents" // register plugin
@@ -1,18 +1,14 @@
 package avro
 
 import (
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
-	"os"
 	"sync"
-	"time"
 
 	"github.com/linkedin/goavro/v2"
+
+	common_avro "github.com/influxdata/telegraf/plugins/common/avro"
 )
 
 type schemaAndCodec struct {
@@ -21,55 +17,22 @@ type schemaAndCodec struct {
 }
 
 type schemaRegistry struct {
-	url      string
-	username string
-	password string
-	cache    map[int]*schemaAndCodec
-	client   *http.Client
-	mu       sync.RWMutex
+	client *common_avro.SchemaRegistry
+	cache  map[int]*schemaAndCodec
+	mu     sync.RWMutex
 }
 
-const schemaByID = "%s/schemas/ids/%d"
+const schemaByID = "/schemas/ids/%d"
 
 func newSchemaRegistry(addr, caCertPath string) (*schemaRegistry, error) {
-	var client *http.Client
-	var tlsCfg *tls.Config
-	if caCertPath != "" {
-		caCert, err := os.ReadFile(caCertPath)
-		if err != nil {
-			return nil, err
-		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tlsCfg = &tls.Config{
-			RootCAs: caCertPool,
-		}
-	}
-	client = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: tlsCfg,
-			MaxIdleConns:    10,
-			IdleConnTimeout: 90 * time.Second,
-		},
-	}
-
-	u, err := url.Parse(addr)
+	client, err := common_avro.NewSchemaRegistry(addr, caCertPath)
 	if err != nil {
-		return nil, fmt.Errorf("parsing registry URL failed: %w", err)
-	}
-
-	var username, password string
-	if u.User != nil {
-		username = u.User.Username()
-		password, _ = u.User.Password()
+		return nil, err
 	}
 
 	registry := &schemaRegistry{
-		url:      u.String(),
-		username: username,
-		password: password,
-		cache:    make(map[int]*schemaAndCodec),
-		client:   client,
+		client: client,
+		cache:  make(map[int]*schemaAndCodec),
 	}
 
 	return registry, nil
@@ -92,23 +55,8 @@ func (sr *schemaRegistry) getSchemaAndCodec(id int) (*schemaAndCodec, error) {
 		return v, nil
 	}
 
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(schemaByID, sr.url, id), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if sr.username != "" {
-		req.SetBasicAuth(sr.username, sr.password)
-	}
-
-	resp, err := sr.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var jsonResponse map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&jsonResponse); err != nil {
+	if err := sr.client.Do(http.MethodGet, fmt.Sprintf(schemaByID, id), nil, &jsonResponse); err != nil {
 		return nil, err
 	}
 
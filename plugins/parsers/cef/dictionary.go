@@ -0,0 +1,46 @@
+package cef
+
+import "strconv"
+
+// Types of the numeric extension keys according to the ArcSight extension
+// dictionary. All other extensions are stored as string fields.
+var integerKeys = map[string]bool{
+	"cn1":                       true,
+	"cn2":                       true,
+	"cn3":                       true,
+	"cnt":                       true,
+	"destinationTranslatedPort": true,
+	"deviceDirection":           true,
+	"dpid":                      true,
+	"dpt":                       true,
+	"dvcpid":                    true,
+	"fsize":                     true,
+	"in":                        true,
+	"oldFileSize":               true,
+	"out":                       true,
+	"sourceTranslatedPort":      true,
+	"spid":                      true,
+	"spt":                       true,
+	"type":                      true,
+}
+
+var floatKeys = map[string]bool{
+	"cfp1":  true,
+	"cfp2":  true,
+	"cfp3":  true,
+	"cfp4":  true,
+	"dlat":  true,
+	"dlong": true,
+	"slat":  true,
+	"slong": true,
+}
+
+func convert(key, value string) (interface{}, error) {
+	switch {
+	case integerKeys[key]:
+		return strconv.ParseInt(value, 10, 64)
+	case floatKeys[key]:
+		return strconv.ParseFloat(value, 64)
+	}
+	return value, nil
+}
@@ -0,0 +1,257 @@
+package cef
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/siem"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Prefix marking the start of the event, anything in front of it such as a
+// syslog header is ignored
+const prefix = "CEF:"
+
+// Names of the tags holding the header fields in order of appearance
+var headerTags = []string{
+	"cef_version",
+	"device_vendor",
+	"device_product",
+	"device_version",
+	"device_event_class_id",
+	"name",
+	"severity",
+}
+
+// Layouts of the receipt time in addition to milliseconds since epoch. The
+// fractional seconds are handled implicitly when parsing.
+var timestampLayouts = []string{
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 15:04:05 MST",
+	"Jan 2 15:04:05",
+}
+
+type Parser struct {
+	TagKeys           []string          `toml:"cef_tag_keys"`
+	Timezone          string            `toml:"cef_timezone"`
+	DefaultMetricName string            `toml:"-"`
+	DefaultTags       map[string]string `toml:"-"`
+	Log               telegraf.Logger   `toml:"-"`
+
+	tagFilter filter.Filter
+	location  *time.Location
+}
+
+func (p *Parser) Init() error {
+	var err error
+	if p.tagFilter, err = filter.Compile(p.TagKeys); err != nil {
+		return fmt.Errorf("invalid 'cef_tag_keys': %w", err)
+	}
+
+	if p.Timezone == "" {
+		p.Timezone = "UTC"
+	}
+	if p.location, err = time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("invalid 'cef_timezone' %q: %w", p.Timezone, err)
+	}
+
+	return nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(buf)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		m, err := p.ParseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, scanner.Err()
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	start := strings.Index(line, prefix)
+	if start < 0 {
+		return nil, errors.New("no CEF header found")
+	}
+	line = strings.TrimRight(line[start+len(prefix):], "\r\n")
+
+	// The header consists of seven pipe-separated fields followed by the
+	// extension
+	header, extension, err := siem.SplitHeader(line, len(headerTags))
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(headerTags))
+	for i, value := range header {
+		if value != "" {
+			tags[headerTags[i]] = value
+		}
+	}
+
+	pairs, err := splitExtension(extension)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{}, len(pairs))
+	ts := time.Now()
+	for _, kv := range pairs {
+		if kv.value == "" {
+			continue
+		}
+		if kv.key == "rt" {
+			if ts, err = p.parseTimestamp(kv.value); err != nil {
+				return nil, fmt.Errorf("parsing receipt time %q failed: %w", kv.value, err)
+			}
+			continue
+		}
+		if p.tagFilter != nil && p.tagFilter.Match(kv.key) {
+			tags[kv.key] = kv.value
+			continue
+		}
+		v, err := convert(kv.key, kv.value)
+		if err != nil {
+			return nil, fmt.Errorf("converting extension %q failed: %w", kv.key, err)
+		}
+		fields[kv.key] = v
+	}
+
+	for k, v := range p.DefaultTags {
+		if _, found := tags[k]; !found {
+			tags[k] = v
+		}
+	}
+
+	return metric.New(p.DefaultMetricName, tags, fields, ts), nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parseTimestamp(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+
+	for _, layout := range timestampLayouts {
+		ts, err := time.ParseInLocation(layout, value, p.location)
+		if err != nil {
+			continue
+		}
+		// Timestamps without year refer to the current year
+		return siem.CurrentYear(ts), nil
+	}
+	return time.Time{}, errors.New("unknown format")
+}
+
+type keyValue struct {
+	key   string
+	value string
+}
+
+// splitExtension splits the space-separated key=value pairs of the extension.
+// Values may contain spaces, so a pair ends where the next key starts. Equal
+// signs and backslashes in values are escaped with a backslash.
+func splitExtension(extension string) ([]keyValue, error) {
+	// Find the start and end of all keys, i.e. words directly followed by an
+	// unescaped equal sign and preceded by a space
+	type span struct{ start, end int }
+	var keys []span
+	for i := 0; i < len(extension); i++ {
+		switch extension[i] {
+		case '\\':
+			i++
+		case '=':
+			start := i
+			for start > 0 && isKeyChar(extension[start-1]) {
+				start--
+			}
+			if start == i || (start > 0 && extension[start-1] != ' ') {
+				continue
+			}
+			keys = append(keys, span{start, i})
+		}
+	}
+
+	if len(keys) == 0 {
+		if strings.TrimSpace(extension) != "" {
+			return nil, errors.New("invalid extension without keys")
+		}
+		return nil, nil
+	}
+	if strings.TrimSpace(extension[:keys[0].start]) != "" {
+		return nil, fmt.Errorf("invalid extension %q", extension[:keys[0].start])
+	}
+
+	pairs := make([]keyValue, 0, len(keys))
+	for i, k := range keys {
+		end := len(extension)
+		if i+1 < len(keys) {
+			end = keys[i+1].start
+		}
+		value := strings.TrimRight(extension[k.end+1:end], " ")
+		pairs = append(pairs, keyValue{key: extension[k.start:k.end], value: unescape(value)})
+	}
+	return pairs, nil
+}
+
+func isKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '.' || c == '[' || c == ']'
+}
+
+func unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			buf.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case '=', '\\':
+			buf.WriteByte(value[i])
+		default:
+			buf.WriteByte('\\')
+			buf.WriteByte(value[i])
+		}
+	}
+	return buf.String()
+}
+
+func init() {
+	parsers.Add("cef",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{DefaultMetricName: defaultMetricName}
+		},
+	)
+}
@@ -0,0 +1,253 @@
+package cef
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/file"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/plugins/processors/parser"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestCases(t *testing.T) {
+	// Get all test-case directories
+	folders, err := os.ReadDir("testcases")
+	require.NoError(t, err)
+	// Make sure testdata contains data
+	require.NotEmpty(t, folders)
+
+	// Set up for file inputs
+	inputs.Add("file", func() telegraf.Input {
+		return &file.File{}
+	})
+
+	for _, f := range folders {
+		fname := f.Name()
+		testdataPath := filepath.Join("testcases", fname)
+		configFilename := filepath.Join(testdataPath, "telegraf.conf")
+		expectedFilename := filepath.Join(testdataPath, "expected.out")
+		expectedErrorFilename := filepath.Join(testdataPath, "expected.err")
+
+		t.Run(fname, func(t *testing.T) {
+			// Get parser to parse expected output
+			testdataParser := &influx.Parser{}
+			require.NoError(t, testdataParser.Init())
+
+			expected, err := testutil.ParseMetricsFromFile(expectedFilename, testdataParser)
+			require.NoError(t, err)
+
+			// Read the expected errors if any
+			var expectedErrors []string
+			if _, err := os.Stat(expectedErrorFilename); err == nil {
+				expectedErrors, err = testutil.ParseLinesFromFile(expectedErrorFilename)
+				require.NoError(t, err)
+				require.NotEmpty(t, expectedErrors)
+			}
+
+			// Configure the plugin
+			cfg := config.NewConfig()
+			require.NoError(t, cfg.LoadConfig(configFilename))
+			require.Len(t, cfg.Inputs, 1)
+
+			// Gather the metrics and check for potential errors
+			var acc testutil.Accumulator
+			var actualErrors []string
+			for _, input := range cfg.Inputs {
+				require.NoError(t, input.Init())
+				if err := input.Gather(&acc); err != nil {
+					actualErrors = append(actualErrors, err.Error())
+				}
+			}
+			require.ElementsMatch(t, expectedErrors, actualErrors)
+
+			// Process expected metrics and compare with resulting metrics
+			actual := acc.GetTelegrafMetrics()
+			testutil.RequireMetricsEqual(t, expected, actual)
+		})
+	}
+}
+
+func TestInitInvalid(t *testing.T) {
+	plugin := &Parser{TagKeys: []string{"["}}
+	require.ErrorContains(t, plugin.Init(), "invalid 'cef_tag_keys'")
+
+	plugin = &Parser{Timezone: "Mars/Olympus_Mons"}
+	require.ErrorContains(t, plugin.Init(), `invalid 'cef_timezone' "Mars/Olympus_Mons"`)
+}
+
+func TestParseLineEscaping(t *testing.T) {
+	plugin := &Parser{DefaultMetricName: "cef"}
+	require.NoError(t, plugin.Init())
+
+	line := `CEF:0|vendor\\inc|product|1.0|100|event|1|msg=first line\nsecond line\r path=C:\temp rt=1682613051000`
+	actual, err := plugin.ParseLine(line)
+	require.NoError(t, err)
+
+	expected := metric.New(
+		"cef",
+		map[string]string{
+			"cef_version":           "0",
+			"device_vendor":         `vendor\inc`,
+			"device_product":        "product",
+			"device_version":        "1.0",
+			"device_event_class_id": "100",
+			"name":                  "event",
+			"severity":              "1",
+		},
+		map[string]interface{}{
+			"msg":  "first line\nsecond line\r",
+			"path": `C:\temp`,
+		},
+		time.UnixMilli(1682613051000),
+	)
+	testutil.RequireMetricEqual(t, expected, actual)
+}
+
+func TestParseLineWithoutExtension(t *testing.T) {
+	plugin := &Parser{DefaultMetricName: "cef"}
+	require.NoError(t, plugin.Init())
+
+	actual, err := plugin.ParseLine("CEF:0|vendor|product|1.0|100|event|1|")
+	require.NoError(t, err)
+	require.Empty(t, actual.FieldList())
+	require.Equal(t, "event", actual.Tags()["name"])
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "no header",
+			line:     "<134>Oct 11 22:14:15 fw01 session ended",
+			expected: "no CEF header found",
+		},
+		{
+			name:     "text before first key",
+			line:     "CEF:0|vendor|product|1.0|100|event|1|foo src=10.0.0.1",
+			expected: `invalid extension "foo "`,
+		},
+		{
+			name:     "no keys",
+			line:     "CEF:0|vendor|product|1.0|100|event|1|foo",
+			expected: "invalid extension without keys",
+		},
+		{
+			name:     "invalid receipt time",
+			line:     "CEF:0|vendor|product|1.0|100|event|1|rt=yesterday",
+			expected: `parsing receipt time "yesterday" failed`,
+		},
+	}
+
+	plugin := &Parser{}
+	require.NoError(t, plugin.Init())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := plugin.ParseLine(tt.line)
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseTimestampWithoutYear(t *testing.T) {
+	plugin := &Parser{}
+	require.NoError(t, plugin.Init())
+
+	actual, err := plugin.ParseLine("CEF:0|vendor|product|1.0|100|event|1|rt=Oct 11 22:14:15 cnt=1")
+	require.NoError(t, err)
+	expected := time.Date(time.Now().UTC().Year(), time.October, 11, 22, 14, 15, 0, time.UTC)
+	require.Equal(t, expected, actual.Time().UTC())
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	plugin := &Parser{DefaultMetricName: "cef"}
+	require.NoError(t, plugin.Init())
+	plugin.SetDefaultTags(map[string]string{"host": "collector", "severity": "unknown"})
+
+	actual, err := plugin.Parse([]byte("CEF:0|vendor|product|1.0|100|event|5|cnt=1 rt=1682613051000\n\n"))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cef",
+			map[string]string{
+				"cef_version":           "0",
+				"device_vendor":         "vendor",
+				"device_product":        "product",
+				"device_version":        "1.0",
+				"device_event_class_id": "100",
+				"name":                  "event",
+				"severity":              "5",
+				"host":                  "collector",
+			},
+			map[string]interface{}{"cnt": int64(1)},
+			time.UnixMilli(1682613051000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParserProcessorSyslogMessage(t *testing.T) {
+	plugin := &Parser{
+		DefaultMetricName: "parser",
+		TagKeys:           []string{"src"},
+	}
+	require.NoError(t, plugin.Init())
+
+	processor := &parser.Parser{
+		ParseFields: []string{"message"},
+		Merge:       "override-with-timestamp",
+		Log:         testutil.Logger{},
+	}
+	require.NoError(t, processor.Init())
+	processor.SetParser(plugin)
+
+	// Metric as produced by the syslog input plugin
+	input := metric.New(
+		"syslog",
+		map[string]string{"hostname": "fw01", "appname": "firewall", "severity": "info"},
+		map[string]interface{}{
+			"message":       "CEF:0|vendor|product|1.0|100|blocked|7|src=10.0.0.1 dpt=22 rt=1682613051000",
+			"severity_code": 6,
+		},
+		time.Unix(1700000000, 0),
+	)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"syslog",
+			map[string]string{
+				"hostname":              "fw01",
+				"appname":               "firewall",
+				"cef_version":           "0",
+				"device_vendor":         "vendor",
+				"device_product":        "product",
+				"device_version":        "1.0",
+				"device_event_class_id": "100",
+				"name":                  "blocked",
+				"severity":              "7",
+				"src":                   "10.0.0.1",
+			},
+			map[string]interface{}{
+				"message":       "CEF:0|vendor|product|1.0|100|blocked|7|src=10.0.0.1 dpt=22 rt=1682613051000",
+				"severity_code": 6,
+				"dpt":           int64(22),
+			},
+			time.UnixMilli(1682613051000),
+		),
+	}
+
+	actual := processor.Apply(input)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
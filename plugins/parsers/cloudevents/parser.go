@@ -0,0 +1,351 @@
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/event"
+	"github.com/cloudevents/sdk-go/v2/types"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Header prefix of event attributes in HTTP binary content mode
+const headerPrefix = "Ce-"
+
+type Parser struct {
+	DataFormat        string            `toml:"cloudevents_data_format"`
+	DataConfig        dataConfig        `toml:"cloudevents_data"`
+	TypeTag           string            `toml:"cloudevents_type_tag"`
+	SourceTag         string            `toml:"cloudevents_source_tag"`
+	SubjectTag        string            `toml:"cloudevents_subject_tag"`
+	IDTag             string            `toml:"cloudevents_id_tag"`
+	Extensions        []string          `toml:"cloudevents_extensions"`
+	UseEventTime      bool              `toml:"cloudevents_use_event_time"`
+	DefaultMetricName string            `toml:"-"`
+	DefaultTags       map[string]string `toml:"-"`
+	Log               telegraf.Logger   `toml:"-"`
+
+	data       telegraf.Parser
+	extensions filter.Filter
+}
+
+// dataConfig captures the configuration table of the data parser to apply it
+// once the data format is known
+type dataConfig struct {
+	unmarshal func(interface{}) error
+}
+
+func (c *dataConfig) UnmarshalTOML(fn func(interface{}) error) error {
+	c.unmarshal = fn
+	return nil
+}
+
+func (p *Parser) Init() error {
+	if p.DataFormat == "" {
+		p.DataFormat = "telegraf"
+	}
+
+	var err error
+	if p.extensions, err = filter.Compile(p.Extensions); err != nil {
+		return fmt.Errorf("invalid 'cloudevents_extensions': %w", err)
+	}
+
+	// The payload created by the CloudEvents serializer is handled directly
+	if p.DataFormat == "telegraf" {
+		if p.DataConfig.unmarshal != nil {
+			return errors.New("'cloudevents_data' cannot be used with the 'telegraf' data format")
+		}
+		return nil
+	}
+
+	if p.DataFormat == "cloudevents" {
+		return errors.New("'cloudevents' cannot be used as data format")
+	}
+	creator, found := parsers.Parsers[p.DataFormat]
+	if !found {
+		return fmt.Errorf("undefined data format %q", p.DataFormat)
+	}
+	parser := creator(p.DefaultMetricName)
+	if p.DataConfig.unmarshal != nil {
+		if err := p.DataConfig.unmarshal(parser); err != nil {
+			return fmt.Errorf("applying 'cloudevents_data' failed: %w", err)
+		}
+	}
+	models.SetLoggerOnPlugin(parser, p.Log)
+	if init, ok := parser.(telegraf.Initializer); ok {
+		if err := init.Init(); err != nil {
+			return fmt.Errorf("initializing data parser failed: %w", err)
+		}
+	}
+	p.data = parser
+
+	return nil
+}
+
+// Parse parses events in structured content mode, i.e. a single event or a
+// batch of events in JSON format
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 {
+		return nil, nil
+	}
+
+	var events []*event.Event
+	if buf[0] == '[' {
+		if err := json.Unmarshal(buf, &events); err != nil {
+			return nil, fmt.Errorf("decoding event batch failed: %w", err)
+		}
+	} else {
+		evt := event.New()
+		if err := evt.UnmarshalJSON(buf); err != nil {
+			return nil, fmt.Errorf("decoding event failed: %w", err)
+		}
+		events = append(events, &evt)
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(events))
+	for _, evt := range events {
+		m, err := p.convert(evt)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+// ParseWithHeader parses events in HTTP binary content mode, i.e. with the
+// event attributes transported as headers and the payload as event data.
+// Events in structured content mode are passed to Parse.
+func (p *Parser) ParseWithHeader(buf []byte, header http.Header) ([]telegraf.Metric, error) {
+	specversion := header.Get(headerPrefix + "Specversion")
+	if specversion == "" {
+		return p.Parse(buf)
+	}
+
+	evt := event.New(specversion)
+	for key, values := range header {
+		if !strings.HasPrefix(key, headerPrefix) || len(values) == 0 {
+			continue
+		}
+		value := values[0]
+		switch name := strings.ToLower(strings.TrimPrefix(key, headerPrefix)); name {
+		case "specversion":
+		case "id":
+			evt.SetID(value)
+		case "source":
+			evt.SetSource(value)
+		case "type":
+			evt.SetType(value)
+		case "subject":
+			evt.SetSubject(value)
+		case "dataschema", "schemaurl":
+			evt.SetDataSchema(value)
+		case "time":
+			ts, err := types.ParseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid event time %q: %w", value, err)
+			}
+			evt.SetTime(ts)
+		default:
+			evt.SetExtension(name, value)
+		}
+	}
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		evt.SetDataContentType(contentType)
+	}
+	evt.DataEncoded = buf
+
+	return p.convert(&evt)
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, errors.New("no metrics in line")
+	}
+
+	if len(metrics) > 1 {
+		return nil, errors.New("more than one metric in line")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// convert parses the data of the event and adds the event attributes to the
+// resulting metrics
+func (p *Parser) convert(evt *event.Event) ([]telegraf.Metric, error) {
+	if err := evt.Validate(); err != nil {
+		// The SDK reports all violations line by line
+		return nil, fmt.Errorf("invalid event: %s", strings.ReplaceAll(strings.TrimSpace(err.Error()), "\n", "; "))
+	}
+
+	var metrics []telegraf.Metric
+	var err error
+	if p.data == nil {
+		metrics, err = p.parseTelegraf(evt)
+	} else {
+		metrics, err = p.data.Parse(evt.Data())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing data of event %q failed: %w", evt.ID(), err)
+	}
+
+	tags := make(map[string]string)
+	if p.TypeTag != "" {
+		tags[p.TypeTag] = evt.Type()
+	}
+	if p.SourceTag != "" {
+		tags[p.SourceTag] = evt.Source()
+	}
+	if p.SubjectTag != "" && evt.Subject() != "" {
+		tags[p.SubjectTag] = evt.Subject()
+	}
+	if p.IDTag != "" {
+		tags[p.IDTag] = evt.ID()
+	}
+	for name, value := range evt.Extensions() {
+		if p.extensions != nil && !p.extensions.Match(name) {
+			continue
+		}
+		v, err := types.Format(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of extension %q: %w", name, err)
+		}
+		tags[name] = v
+	}
+
+	for _, m := range metrics {
+		for k, v := range p.DefaultTags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+		for k, v := range tags {
+			m.AddTag(k, v)
+		}
+		if p.UseEventTime && !evt.Time().IsZero() {
+			m.SetTime(evt.Time())
+		}
+	}
+
+	return metrics, nil
+}
+
+// parseTelegraf parses a single metric or a list of metrics in the format
+// created by the CloudEvents serializer
+func (p *Parser) parseTelegraf(evt *event.Event) ([]telegraf.Metric, error) {
+	if contentType := evt.DataContentType(); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != event.ApplicationJSON && !strings.HasSuffix(mediaType, "+json")) {
+			return nil, fmt.Errorf("unsupported data content type %q", contentType)
+		}
+	}
+
+	data := bytes.TrimSpace(evt.Data())
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var entries []metricData
+	if data[0] == '[' {
+		if err := unmarshalNumbers(data, &entries); err != nil {
+			return nil, err
+		}
+	} else {
+		var entry metricData
+		if err := unmarshalNumbers(data, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(entries))
+	for _, entry := range entries {
+		m, err := entry.metric(p.DefaultMetricName)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func unmarshalNumbers(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// metricData is the representation of a metric used by the CloudEvents
+// serializer
+type metricData struct {
+	Name      string                 `json:"name"`
+	Tags      map[string]string      `json:"tags"`
+	Fields    map[string]interface{} `json:"fields"`
+	Timestamp *int64                 `json:"timestamp"`
+}
+
+func (d *metricData) metric(defaultName string) (telegraf.Metric, error) {
+	name := d.Name
+	if name == "" {
+		name = defaultName
+	}
+
+	fields := make(map[string]interface{}, len(d.Fields))
+	for k, v := range d.Fields {
+		switch v := v.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				fields[k] = i
+			} else if f, err := v.Float64(); err == nil {
+				fields[k] = f
+			} else {
+				return nil, fmt.Errorf("invalid number %q for field %q", v, k)
+			}
+		case string, bool:
+			fields[k] = v
+		default:
+			return nil, fmt.Errorf("unsupported type %T for field %q", v, k)
+		}
+	}
+
+	ts := time.Now()
+	if d.Timestamp != nil {
+		ts = time.Unix(0, *d.Timestamp)
+	}
+
+	return metric.New(name, d.Tags, fields, ts), nil
+}
+
+func init() {
+	parsers.Add("cloudevents",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{
+				DefaultMetricName: defaultMetricName,
+				TypeTag:           "type",
+				SourceTag:         "source",
+				SubjectTag:        "subject",
+			}
+		},
+	)
+}
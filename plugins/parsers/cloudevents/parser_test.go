@@ -0,0 +1,309 @@
+package cloudevents
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/file"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	_ "github.com/influxdata/telegraf/plugins/parsers/json"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/cloudevents"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var _ telegraf.HeaderParser = &Parser{}
+
+func TestCases(t *testing.T) {
+	// Get all test-case directories
+	folders, err := os.ReadDir("testcases")
+	require.NoError(t, err)
+	// Make sure testdata contains data
+	require.NotEmpty(t, folders)
+
+	// Set up for file inputs
+	inputs.Add("file", func() telegraf.Input {
+		return &file.File{}
+	})
+
+	for _, f := range folders {
+		fname := f.Name()
+		testdataPath := filepath.Join("testcases", fname)
+		configFilename := filepath.Join(testdataPath, "telegraf.conf")
+		expectedFilename := filepath.Join(testdataPath, "expected.out")
+		expectedErrorFilename := filepath.Join(testdataPath, "expected.err")
+
+		t.Run(fname, func(t *testing.T) {
+			// Get parser to parse expected output
+			testdataParser := &influx.Parser{}
+			require.NoError(t, testdataParser.Init())
+
+			expected, err := testutil.ParseMetricsFromFile(expectedFilename, testdataParser)
+			require.NoError(t, err)
+
+			// Read the expected errors if any
+			var expectedErrors []string
+			if _, err := os.Stat(expectedErrorFilename); err == nil {
+				expectedErrors, err = testutil.ParseLinesFromFile(expectedErrorFilename)
+				require.NoError(t, err)
+				require.NotEmpty(t, expectedErrors)
+			}
+
+			// Configure the plugin
+			cfg := config.NewConfig()
+			require.NoError(t, cfg.LoadConfig(configFilename))
+			require.Len(t, cfg.Inputs, 1)
+
+			// Gather the metrics and check for potential errors
+			var acc testutil.Accumulator
+			var actualErrors []string
+			for _, input := range cfg.Inputs {
+				require.NoError(t, input.Init())
+				if err := input.Gather(&acc); err != nil {
+					actualErrors = append(actualErrors, err.Error())
+				}
+			}
+			require.ElementsMatch(t, expectedErrors, actualErrors)
+
+			// Process expected metrics and compare with resulting metrics
+			actual := acc.GetTelegrafMetrics()
+			testutil.RequireMetricsEqual(t, expected, actual, testutil.SortMetrics())
+		})
+	}
+}
+
+func TestInitInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Parser
+		expected string
+	}{
+		{
+			name:     "unknown data format",
+			plugin:   &Parser{DataFormat: "foo"},
+			expected: `undefined data format "foo"`,
+		},
+		{
+			name:     "recursive data format",
+			plugin:   &Parser{DataFormat: "cloudevents"},
+			expected: "'cloudevents' cannot be used as data format",
+		},
+		{
+			name:     "invalid extension filter",
+			plugin:   &Parser{Extensions: []string{"["}},
+			expected: "invalid 'cloudevents_extensions'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestParseBinaryMode(t *testing.T) {
+	plugin := &Parser{
+		TypeTag:    "type",
+		SourceTag:  "source",
+		SubjectTag: "subject",
+		IDTag:      "id",
+		DataFormat: "influx",
+	}
+	require.NoError(t, plugin.Init())
+
+	header := http.Header{}
+	header.Set("Ce-Specversion", "1.0")
+	header.Set("Ce-Id", "4711")
+	header.Set("Ce-Source", "/sensors/42")
+	header.Set("Ce-Type", "com.example.sensor.reading")
+	header.Set("Ce-Subject", "kitchen")
+	header.Set("Ce-Time", "2024-01-02T03:04:05Z")
+	header.Set("Ce-Tenant", "acme")
+	header.Set("Content-Type", "text/plain")
+
+	actual, err := plugin.ParseWithHeader([]byte("climate temperature=21.5 1682613051000000000\n"), header)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"climate",
+			map[string]string{
+				"id":      "4711",
+				"source":  "/sensors/42",
+				"type":    "com.example.sensor.reading",
+				"subject": "kitchen",
+				"tenant":  "acme",
+			},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(0, 1682613051000000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseBinaryModeInvalid(t *testing.T) {
+	plugin := &Parser{}
+	require.NoError(t, plugin.Init())
+
+	// The source attribute is missing
+	header := http.Header{}
+	header.Set("Ce-Specversion", "1.0")
+	header.Set("Ce-Id", "4711")
+	header.Set("Ce-Type", "com.example.sensor.reading")
+	header.Set("Content-Type", "application/json")
+
+	_, err := plugin.ParseWithHeader([]byte(`{"name": "cpu", "fields": {"value": 1}}`), header)
+	require.ErrorContains(t, err, "invalid event")
+
+	// Invalid event time
+	header.Set("Ce-Source", "/sensors/42")
+	header.Set("Ce-Time", "yesterday")
+	_, err = plugin.ParseWithHeader([]byte(`{"name": "cpu", "fields": {"value": 1}}`), header)
+	require.ErrorContains(t, err, `invalid event time "yesterday"`)
+}
+
+func TestParseWithHeaderStructuredMode(t *testing.T) {
+	plugin := &Parser{TypeTag: "type"}
+	require.NoError(t, plugin.Init())
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/cloudevents+json")
+
+	input := `{
+		"specversion": "1.0",
+		"id": "4711",
+		"source": "/sensors/42",
+		"type": "com.example.sensor.reading",
+		"data": {"name": "cpu", "fields": {"value": 1}, "timestamp": 1682613051000000000}
+	}`
+	actual, err := plugin.ParseWithHeader([]byte(input), header)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"type": "com.example.sensor.reading"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 1682613051000000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseBase64Data(t *testing.T) {
+	plugin := &Parser{DataFormat: "influx"}
+	require.NoError(t, plugin.Init())
+
+	input := `{
+		"specversion": "1.0",
+		"id": "4711",
+		"source": "/sensors/42",
+		"type": "com.example.sensor.reading",
+		"datacontenttype": "text/plain",
+		"data_base64": "Y2xpbWF0ZSB0ZW1wZXJhdHVyZT0yMS41IDE2ODI2MTMwNTEwMDAwMDAwMDA="
+	}`
+	actual, err := plugin.Parse([]byte(input))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"climate",
+			map[string]string{},
+			map[string]interface{}{"temperature": 21.5},
+			time.Unix(0, 1682613051000000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	plugin := &Parser{TypeTag: "type"}
+	require.NoError(t, plugin.Init())
+	plugin.SetDefaultTags(map[string]string{"host": "default", "type": "default"})
+
+	input := `{
+		"specversion": "1.0",
+		"id": "4711",
+		"source": "/sensors/42",
+		"type": "com.example.sensor.reading",
+		"data": [
+			{"name": "cpu", "fields": {"value": 1}, "timestamp": 1682613051000000000},
+			{"name": "cpu", "tags": {"host": "server01"}, "fields": {"value": 2}, "timestamp": 1682613051000000000}
+		]
+	}`
+	actual, err := plugin.Parse([]byte(input))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "default", "type": "com.example.sensor.reading"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 1682613051000000000),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "type": "com.example.sensor.reading"},
+			map[string]interface{}{"value": int64(2)},
+			time.Unix(0, 1682613051000000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseUnsupportedContentType(t *testing.T) {
+	plugin := &Parser{}
+	require.NoError(t, plugin.Init())
+
+	input := `{
+		"specversion": "1.0",
+		"id": "4711",
+		"source": "/sensors/42",
+		"type": "com.example.sensor.reading",
+		"datacontenttype": "text/plain",
+		"data": "climate temperature=21.5"
+	}`
+	_, err := plugin.Parse([]byte(input))
+	require.ErrorContains(t, err, `unsupported data content type "text/plain"`)
+}
+
+func TestRoundTripSerializer(t *testing.T) {
+	input := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 98.5, "usage_user": int64(1), "state": "ok"},
+			time.Unix(1700000000, 123456789),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"used": int64(42), "swap": false},
+			time.Unix(1700000000, 987654321),
+		),
+	}
+
+	for _, format := range []string{"events", "metrics"} {
+		t.Run(format, func(t *testing.T) {
+			s := &serializer.Serializer{BatchFormat: format}
+			require.NoError(t, s.Init())
+			buf, err := s.SerializeBatch(input)
+			require.NoError(t, err)
+
+			plugin := &Parser{}
+			require.NoError(t, plugin.Init())
+			actual, err := plugin.Parse(buf)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, input, actual)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package leef
+
+import "strconv"
+
+// Types of the predefined numeric and boolean attributes according to the
+// LEEF specification. All other attributes are stored as string fields.
+var integerKeys = map[string]bool{
+	"dstBytes":       true,
+	"dstPackets":     true,
+	"dstPort":        true,
+	"dstPostNATPort": true,
+	"dstPreNATPort":  true,
+	"sev":            true,
+	"srcBytes":       true,
+	"srcPackets":     true,
+	"srcPort":        true,
+	"srcPostNATPort": true,
+	"srcPreNATPort":  true,
+	"totalPackets":   true,
+}
+
+var booleanKeys = map[string]bool{
+	"isLoginEvent":  true,
+	"isLogoutEvent": true,
+}
+
+func convert(key, value string) (interface{}, error) {
+	switch {
+	case integerKeys[key]:
+		return strconv.ParseInt(value, 10, 64)
+	case booleanKeys[key]:
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,221 @@
+package leef
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/siem"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Prefix marking the start of the event, anything in front of it such as a
+// syslog header is ignored
+const prefix = "LEEF:"
+
+// Names of the tags holding the header fields in order of appearance
+var headerTags = []string{
+	"leef_version",
+	"vendor",
+	"product",
+	"product_version",
+	"event_id",
+}
+
+type Parser struct {
+	Delimiter         string            `toml:"leef_delimiter"`
+	TagKeys           []string          `toml:"leef_tag_keys"`
+	Timezone          string            `toml:"leef_timezone"`
+	DefaultMetricName string            `toml:"-"`
+	DefaultTags       map[string]string `toml:"-"`
+	Log               telegraf.Logger   `toml:"-"`
+
+	delimiter string
+	tagFilter filter.Filter
+	location  *time.Location
+}
+
+func (p *Parser) Init() error {
+	// Events without delimiter definition use a tab by default
+	p.delimiter = "\t"
+	if p.Delimiter != "" {
+		d, ok := parseDelimiter(p.Delimiter)
+		if !ok {
+			return fmt.Errorf("invalid 'leef_delimiter' %q", p.Delimiter)
+		}
+		p.delimiter = d
+	}
+
+	var err error
+	if p.tagFilter, err = filter.Compile(p.TagKeys); err != nil {
+		return fmt.Errorf("invalid 'leef_tag_keys': %w", err)
+	}
+
+	if p.Timezone == "" {
+		p.Timezone = "UTC"
+	}
+	if p.location, err = time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("invalid 'leef_timezone' %q: %w", p.Timezone, err)
+	}
+
+	return nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	var metrics []telegraf.Metric
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(buf)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m, err := p.ParseLine(line)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, scanner.Err()
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	start := strings.Index(line, prefix)
+	if start < 0 {
+		return nil, errors.New("no LEEF header found")
+	}
+	line = strings.TrimRight(line[start+len(prefix):], "\r\n")
+
+	header, attributes, err := siem.SplitHeader(line, len(headerTags))
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(headerTags))
+	for i, value := range header {
+		if value != "" {
+			tags[headerTags[i]] = value
+		}
+	}
+
+	// Version 2 events define the attribute delimiter in an additional header
+	// field which may be omitted
+	delimiter := p.delimiter
+	if strings.HasPrefix(header[0], "2") {
+		if field, remainder, found := strings.Cut(attributes, "|"); found {
+			if d, ok := parseDelimiter(field); ok {
+				if d != "" {
+					delimiter = d
+				}
+				attributes = remainder
+			}
+		}
+	}
+
+	pairs := make(map[string]string)
+	for _, attribute := range strings.Split(attributes, delimiter) {
+		key, value, found := strings.Cut(attribute, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || value == "" {
+			continue
+		}
+		pairs[key] = value
+	}
+
+	ts := time.Now()
+	if value, found := pairs["devTime"]; found {
+		if ts, err = p.parseTimestamp(value, pairs["devTimeFormat"]); err != nil {
+			return nil, fmt.Errorf("parsing device time %q failed: %w", value, err)
+		}
+		delete(pairs, "devTime")
+		delete(pairs, "devTimeFormat")
+	}
+
+	fields := make(map[string]interface{}, len(pairs))
+	for key, value := range pairs {
+		if p.tagFilter != nil && p.tagFilter.Match(key) {
+			tags[key] = value
+			continue
+		}
+		v, err := convert(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("converting attribute %q failed: %w", key, err)
+		}
+		fields[key] = v
+	}
+
+	for k, v := range p.DefaultTags {
+		if _, found := tags[k]; !found {
+			tags[k] = v
+		}
+	}
+
+	return metric.New(p.DefaultMetricName, tags, fields, ts), nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parseTimestamp(value, format string) (time.Time, error) {
+	if format == "" {
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.UnixMilli(ms), nil
+		}
+	}
+
+	formats := defaultTimestampFormats
+	if format != "" {
+		formats = []string{format}
+	}
+	for _, f := range formats {
+		ts, err := time.ParseInLocation(javaToGoLayout(f), value, p.location)
+		if err != nil {
+			continue
+		}
+		// Timestamps without year refer to the current year
+		return siem.CurrentYear(ts), nil
+	}
+	return time.Time{}, errors.New("unknown format")
+}
+
+// parseDelimiter decodes a delimiter given as single character or as
+// hexadecimal code prefixed by "0x" or "x". An empty delimiter is valid and
+// denotes the default delimiter.
+func parseDelimiter(s string) (string, bool) {
+	if len(s) <= 1 {
+		return s, s != "|"
+	}
+
+	var hex string
+	switch {
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		hex = s[2:]
+	case strings.HasPrefix(s, "x"), strings.HasPrefix(s, "X"):
+		hex = s[1:]
+	default:
+		return "", false
+	}
+	code, err := strconv.ParseUint(hex, 16, 8)
+	if err != nil || code == 0 || code == '|' {
+		return "", false
+	}
+	return string(rune(code)), true
+}
+
+func init() {
+	parsers.Add("leef",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{DefaultMetricName: defaultMetricName}
+		},
+	)
+}
@@ -0,0 +1,194 @@
+package leef
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/file"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestCases(t *testing.T) {
+	// Get all test-case directories
+	folders, err := os.ReadDir("testcases")
+	require.NoError(t, err)
+	// Make sure testdata contains data
+	require.NotEmpty(t, folders)
+
+	// Set up for file inputs
+	inputs.Add("file", func() telegraf.Input {
+		return &file.File{}
+	})
+
+	for _, f := range folders {
+		fname := f.Name()
+		testdataPath := filepath.Join("testcases", fname)
+		configFilename := filepath.Join(testdataPath, "telegraf.conf")
+		expectedFilename := filepath.Join(testdataPath, "expected.out")
+		expectedErrorFilename := filepath.Join(testdataPath, "expected.err")
+
+		t.Run(fname, func(t *testing.T) {
+			// Get parser to parse expected output
+			testdataParser := &influx.Parser{}
+			require.NoError(t, testdataParser.Init())
+
+			expected, err := testutil.ParseMetricsFromFile(expectedFilename, testdataParser)
+			require.NoError(t, err)
+
+			// Read the expected errors if any
+			var expectedErrors []string
+			if _, err := os.Stat(expectedErrorFilename); err == nil {
+				expectedErrors, err = testutil.ParseLinesFromFile(expectedErrorFilename)
+				require.NoError(t, err)
+				require.NotEmpty(t, expectedErrors)
+			}
+
+			// Configure the plugin
+			cfg := config.NewConfig()
+			require.NoError(t, cfg.LoadConfig(configFilename))
+			require.Len(t, cfg.Inputs, 1)
+
+			// Gather the metrics and check for potential errors
+			var acc testutil.Accumulator
+			var actualErrors []string
+			for _, input := range cfg.Inputs {
+				require.NoError(t, input.Init())
+				if err := input.Gather(&acc); err != nil {
+					actualErrors = append(actualErrors, err.Error())
+				}
+			}
+			require.ElementsMatch(t, expectedErrors, actualErrors)
+
+			// Process expected metrics and compare with resulting metrics
+			actual := acc.GetTelegrafMetrics()
+			testutil.RequireMetricsEqual(t, expected, actual)
+		})
+	}
+}
+
+func TestInitInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Parser
+		expected string
+	}{
+		{
+			name:     "delimiter with multiple characters",
+			plugin:   &Parser{Delimiter: "ab"},
+			expected: `invalid 'leef_delimiter' "ab"`,
+		},
+		{
+			name:     "pipe delimiter",
+			plugin:   &Parser{Delimiter: "0x7C"},
+			expected: `invalid 'leef_delimiter' "0x7C"`,
+		},
+		{
+			name:     "invalid tag keys",
+			plugin:   &Parser{TagKeys: []string{"["}},
+			expected: "invalid 'leef_tag_keys'",
+		},
+		{
+			name:     "invalid timezone",
+			plugin:   &Parser{Timezone: "Mars/Olympus_Mons"},
+			expected: `invalid 'leef_timezone' "Mars/Olympus_Mons"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestParseLineInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{
+			name:     "no header",
+			line:     "<13>Oct 11 22:14:15 fw01 session ended",
+			expected: "no LEEF header found",
+		},
+		{
+			name:     "invalid device time",
+			line:     "LEEF:1.0|Vendor|Product|1.0|scan|devTime=yesterday",
+			expected: `parsing device time "yesterday" failed`,
+		},
+		{
+			name:     "device time not matching format",
+			line:     "LEEF:1.0|Vendor|Product|1.0|scan|devTime=Oct 11 2024 22:14:15\tdevTimeFormat=yyyy-MM-dd",
+			expected: `parsing device time "Oct 11 2024 22:14:15" failed`,
+		},
+		{
+			name:     "invalid type",
+			line:     "LEEF:1.0|Vendor|Product|1.0|scan|srcPort=http",
+			expected: `converting attribute "srcPort" failed`,
+		},
+	}
+
+	plugin := &Parser{}
+	require.NoError(t, plugin.Init())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := plugin.ParseLine(tt.line)
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	plugin := &Parser{DefaultMetricName: "leef"}
+	require.NoError(t, plugin.Init())
+	plugin.SetDefaultTags(map[string]string{"host": "collector", "vendor": "unknown"})
+
+	actual, err := plugin.Parse([]byte("LEEF:1.0|Vendor|Product|1.0|scan|srcPort=4711\tdevTime=1682613051000\n\n"))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"leef",
+			map[string]string{
+				"leef_version":    "1.0",
+				"vendor":          "Vendor",
+				"product":         "Product",
+				"product_version": "1.0",
+				"event_id":        "scan",
+				"host":            "collector",
+			},
+			map[string]interface{}{"srcPort": int64(4711)},
+			time.UnixMilli(1682613051000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestJavaToGoLayout(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{format: "MMM dd yyyy HH:mm:ss", expected: "Jan 02 2006 15:04:05"},
+		{format: "yyyy-MM-dd'T'HH:mm:ss.SSSZ", expected: "2006-01-02T15:04:05.000-0700"},
+		{format: "dd/MM/yy hh:mm:ss a zzz", expected: "02/01/06 03:04:05 PM MST"},
+		{format: "EEEE, d MMMM yyyy H:m:s.SSSSSS XXX", expected: "Monday, 2 January 2006 15:4:5.000000 Z07:00"},
+		{format: "yyyy.MM.dd 'at' HH 'o''clock'", expected: "2006.01.02 at 15 o'clock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			require.Equal(t, tt.expected, javaToGoLayout(tt.format))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package leef
+
+import "strings"
+
+// Formats of the device time used if the event does not specify the format
+var defaultTimestampFormats = []string{
+	"MMM dd yyyy HH:mm:ss zzz",
+	"MMM dd yyyy HH:mm:ss",
+}
+
+// Go layout elements for the Java SimpleDateFormat letters by number of
+// repetitions, the last element is used for all higher counts
+var layoutElements = map[byte][]string{
+	'y': {"2006", "06", "2006"},
+	'M': {"1", "01", "Jan", "January"},
+	'd': {"2", "02"},
+	'H': {"15", "15"},
+	'h': {"3", "03"},
+	'm': {"4", "04"},
+	's': {"5", "05"},
+	'E': {"Mon", "Mon", "Mon", "Monday"},
+	'a': {"PM"},
+	'z': {"MST"},
+	'Z': {"-0700"},
+	'X': {"Z07", "Z0700", "Z07:00"},
+}
+
+// javaToGoLayout converts the Java SimpleDateFormat pattern used to specify
+// the device time format to a Go time layout. Fractional seconds are only
+// supported following a dot or comma.
+func javaToGoLayout(format string) string {
+	var layout strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+
+		// Quoted literal text where two single quotes denote a single quote
+		if c == '\'' {
+			i++
+			if i < len(format) && format[i] == '\'' {
+				layout.WriteByte('\'')
+				continue
+			}
+			for ; i < len(format); i++ {
+				if format[i] == '\'' {
+					if i+1 < len(format) && format[i+1] == '\'' {
+						i++
+					} else {
+						break
+					}
+				}
+				layout.WriteByte(format[i])
+			}
+			continue
+		}
+
+		elements, found := layoutElements[c]
+		if !found && c != 'S' {
+			layout.WriteByte(c)
+			continue
+		}
+		n := 1
+		for i+n < len(format) && format[i+n] == c {
+			n++
+		}
+		i += n - 1
+
+		// Each letter of fractional seconds denotes one digit
+		if c == 'S' {
+			layout.WriteString(strings.Repeat("0", min(n, 9)))
+			continue
+		}
+		layout.WriteString(elements[min(n, len(elements))-1])
+	}
+	return layout.String()
+}
@@ -0,0 +1,147 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"github.com/influxdata/influxdb-observability/otel2influx"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	otel "github.com/influxdata/telegraf/plugins/common/opentelemetry"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type Parser struct {
+	Format        string            `toml:"otlp_format"`
+	MetricsSchema string            `toml:"otlp_metrics_schema"`
+	DefaultTags   map[string]string `toml:"-"`
+	Log           telegraf.Logger   `toml:"-"`
+
+	converter *otel2influx.OtelMetricsToLineProtocol
+	collector *collector
+}
+
+func (p *Parser) Init() error {
+	switch p.Format {
+	case "":
+		p.Format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return fmt.Errorf("invalid 'otlp_format' %q", p.Format)
+	}
+
+	if p.MetricsSchema == "" {
+		p.MetricsSchema = "prometheus-v1"
+	}
+	schema, found := otel.MetricsSchemata[p.MetricsSchema]
+	if !found {
+		return fmt.Errorf("invalid 'otlp_metrics_schema' %q", p.MetricsSchema)
+	}
+
+	p.collector = &collector{}
+	cfg := otel2influx.DefaultOtelMetricsToLineProtocolConfig()
+	cfg.Logger = &otel.Logger{Logger: p.Log}
+	cfg.Writer = p.collector
+	cfg.Schema = schema
+	converter, err := otel2influx.NewOtelMetricsToLineProtocol(cfg)
+	if err != nil {
+		return fmt.Errorf("creating converter failed: %w", err)
+	}
+	p.converter = converter
+
+	return nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	request := pmetricotlp.NewExportRequest()
+	switch p.Format {
+	case "protobuf":
+		if err := request.UnmarshalProto(buf); err != nil {
+			return nil, fmt.Errorf("decoding protobuf failed: %w", err)
+		}
+	case "json":
+		if err := request.UnmarshalJSON(buf); err != nil {
+			return nil, fmt.Errorf("decoding JSON failed: %w", err)
+		}
+	}
+
+	p.collector.metrics = make([]telegraf.Metric, 0, request.Metrics().DataPointCount())
+	if err := p.converter.WriteMetrics(context.Background(), request.Metrics()); err != nil {
+		return nil, fmt.Errorf("converting metrics failed: %w", err)
+	}
+	metrics := p.collector.metrics
+	p.collector.metrics = nil
+
+	for _, m := range metrics {
+		for k, v := range p.DefaultTags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, errors.New("no metrics in line")
+	}
+
+	if len(metrics) > 1 {
+		return nil, errors.New("more than one metric in line")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// collector receives the converted points
+type collector struct {
+	metrics []telegraf.Metric
+}
+
+func (c *collector) NewBatch() otel2influx.InfluxWriterBatch {
+	return c
+}
+
+func (c *collector) EnqueuePoint(
+	_ context.Context,
+	measurement string,
+	tags map[string]string,
+	fields map[string]interface{},
+	ts time.Time,
+	vType common.InfluxMetricValueType,
+) error {
+	mtype, err := otel.MetricType(vType)
+	if err != nil {
+		return err
+	}
+	c.metrics = append(c.metrics, metric.New(measurement, tags, fields, ts, mtype))
+	return nil
+}
+
+func (*collector) WriteBatch(context.Context) error {
+	return nil
+}
+
+func init() {
+	parsers.Add("otlp",
+		func(string) telegraf.Parser {
+			return &Parser{}
+		},
+	)
+}
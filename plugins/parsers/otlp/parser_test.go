@@ -0,0 +1,197 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var ts = time.Unix(1700000000, 123456789)
+
+// exportRequest creates a request as sent by OpenTelemetry collectors
+// containing a gauge, a sum and a histogram
+func exportRequest() pmetricotlp.ExportRequest {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	rm.Resource().Attributes().PutStr("host.name", "server01")
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("instrumentation")
+	sm.Scope().SetVersion("1.0.0")
+
+	gauge := sm.Metrics().AppendEmpty()
+	gauge.SetName("cpu_temperature")
+	dp := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	dp.SetDoubleValue(42.5)
+	dp.Attributes().PutStr("cpu", "cpu0")
+
+	sum := sm.Metrics().AppendEmpty()
+	sum.SetName("http_requests_total")
+	sum.SetEmptySum().SetIsMonotonic(true)
+	sum.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	dp = sum.Sum().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	dp.SetIntValue(1027)
+	dp.Attributes().PutStr("method", "GET")
+
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("request_duration_seconds")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	hdp := histogram.Histogram().DataPoints().AppendEmpty()
+	hdp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	hdp.SetCount(10)
+	hdp.SetSum(3.5)
+	hdp.ExplicitBounds().FromRaw([]float64{0.1, 1})
+	hdp.BucketCounts().FromRaw([]uint64{4, 5, 1})
+
+	return pmetricotlp.NewExportRequestFromMetrics(md)
+}
+
+func TestParse(t *testing.T) {
+	request := exportRequest()
+	protobufData, err := request.MarshalProto()
+	require.NoError(t, err)
+	jsonData, err := request.MarshalJSON()
+	require.NoError(t, err)
+
+	tags := map[string]string{
+		"host.name":            "server01",
+		"service.name":         "checkout",
+		"otel.library.name":    "instrumentation",
+		"otel.library.version": "1.0.0",
+	}
+	with := func(key, value string) map[string]string {
+		result := make(map[string]string, len(tags)+1)
+		for k, v := range tags {
+			result[k] = v
+		}
+		result[key] = value
+		return result
+	}
+
+	expectedV1 := []telegraf.Metric{
+		metric.New("cpu_temperature", with("cpu", "cpu0"), map[string]interface{}{"gauge": 42.5}, ts, telegraf.Gauge),
+		metric.New("http_requests_total", with("method", "GET"), map[string]interface{}{"counter": int64(1027)}, ts, telegraf.Counter),
+		metric.New(
+			"request_duration_seconds",
+			tags,
+			map[string]interface{}{"count": float64(10), "sum": 3.5, "0.1": float64(4), "1": float64(9), "+Inf": float64(10)},
+			ts,
+			telegraf.Histogram,
+		),
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		schema   string
+		data     []byte
+		expected []telegraf.Metric
+	}{
+		{
+			name:     "protobuf",
+			format:   "protobuf",
+			data:     protobufData,
+			expected: expectedV1,
+		},
+		{
+			name:     "json",
+			format:   "json",
+			data:     jsonData,
+			expected: expectedV1,
+		},
+		{
+			name:   "prometheus-v2",
+			format: "protobuf",
+			schema: "prometheus-v2",
+			data:   protobufData,
+			expected: []telegraf.Metric{
+				metric.New("prometheus", with("cpu", "cpu0"), map[string]interface{}{"cpu_temperature": 42.5}, ts, telegraf.Gauge),
+				metric.New("prometheus", with("method", "GET"), map[string]interface{}{"http_requests_total": int64(1027)}, ts, telegraf.Counter),
+				metric.New(
+					"prometheus",
+					tags,
+					map[string]interface{}{"request_duration_seconds_count": float64(10), "request_duration_seconds_sum": 3.5},
+					ts,
+					telegraf.Histogram,
+				),
+				metric.New("prometheus", with("le", "0.1"), map[string]interface{}{"request_duration_seconds_bucket": float64(4)}, ts, telegraf.Histogram),
+				metric.New("prometheus", with("le", "1"), map[string]interface{}{"request_duration_seconds_bucket": float64(9)}, ts, telegraf.Histogram),
+				metric.New("prometheus", with("le", "+Inf"), map[string]interface{}{"request_duration_seconds_bucket": float64(10)}, ts, telegraf.Histogram),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{
+				Format:        tt.format,
+				MetricsSchema: tt.schema,
+				Log:           testutil.Logger{},
+			}
+			require.NoError(t, parser.Init())
+
+			actual, err := parser.Parse(tt.data)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.SortMetrics())
+		})
+	}
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("host.name", "server01")
+	m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("temperature")
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	dp.SetDoubleValue(21.5)
+	buf, err := pmetricotlp.NewExportRequestFromMetrics(md).MarshalJSON()
+	require.NoError(t, err)
+
+	parser := &Parser{Format: "json", Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+	parser.SetDefaultTags(map[string]string{"host.name": "default", "dc": "eu"})
+
+	actual, err := parser.ParseLine(string(buf))
+	require.NoError(t, err)
+
+	expected := metric.New(
+		"temperature",
+		map[string]string{"host.name": "server01", "dc": "eu"},
+		map[string]interface{}{"gauge": 21.5},
+		ts,
+		telegraf.Gauge,
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{actual})
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser := &Parser{Format: "json", Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+	_, err := parser.Parse([]byte(`{"resourceMetrics": 42}`))
+	require.ErrorContains(t, err, "decoding JSON failed")
+
+	parser = &Parser{Log: testutil.Logger{}}
+	require.NoError(t, parser.Init())
+	_, err = parser.Parse([]byte{0xff, 0xff, 0xff})
+	require.ErrorContains(t, err, "decoding protobuf failed")
+}
+
+func TestInitInvalid(t *testing.T) {
+	parser := &Parser{Format: "xml"}
+	require.ErrorContains(t, parser.Init(), `invalid 'otlp_format' "xml"`)
+
+	parser = &Parser{MetricsSchema: "prometheus-v3"}
+	require.ErrorContains(t, parser.Init(), `invalid 'otlp_metrics_schema' "prometheus-v3"`)
+}
@@ -4,6 +4,7 @@ package dedup
 import (
 	_ "embed"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -22,6 +23,7 @@ type Dedup struct {
 
 	flushTime time.Time
 	cache     map[uint64]telegraf.Metric
+	sync.Mutex
 }
 
 func (*Dedup) SampleConfig() string {
@@ -29,6 +31,9 @@ func (*Dedup) SampleConfig() string {
 }
 
 func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	d.Lock()
+	defer d.Unlock()
+
 	idx := 0
 	for _, metric := range metrics {
 		id := metric.HashID()
@@ -100,11 +105,16 @@ func (d *Dedup) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
 }
 
 func (d *Dedup) GetState() interface{} {
-	s := &serializers_influx.Serializer{}
+	// The state is saved periodically while metrics are processed and
+	// cached metrics are modified when merging fields
+	d.Lock()
+	defer d.Unlock()
+
 	v := make([]telegraf.Metric, 0, len(d.cache))
 	for _, value := range d.cache {
 		v = append(v, value)
 	}
+	s := &serializers_influx.Serializer{}
 	state, err := s.SerializeBatch(v)
 	if err != nil {
 		d.Log.Errorf("dedup processor failed to serialize metric batch: %v", err)
@@ -530,3 +530,45 @@ func TestStatePersistence(t *testing.T) {
 	}
 	require.Len(t, actualState, expectedLen)
 }
+
+func TestStatePersistenceConcurrent(t *testing.T) {
+	plugin := &Dedup{
+		DedupInterval: config.Duration(10 * time.Hour),
+		flushTime:     time.Now(),
+		cache:         make(map[uint64]telegraf.Metric),
+	}
+
+	// Get the state periodically while processing metrics as done by the
+	// agent when checkpointing the plugin states. Run with the race detector
+	// to catch unprotected accesses.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, ok := plugin.GetState().([]byte)
+				require.True(t, ok, "state is not a bytes array")
+			}
+		}
+	}()
+
+	now := time.Now()
+	for i := range 1000 {
+		m := metric.New(
+			"metric",
+			map[string]string{"tag": fmt.Sprintf("value%d", i%10)},
+			map[string]interface{}{fmt.Sprintf("field%d", i%3): i},
+			now,
+		)
+		plugin.Apply(m)
+	}
+	close(done)
+	wg.Wait()
+
+	require.Len(t, plugin.cache, 10)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -3711,6 +3712,65 @@ def apply(metric):
 	require.EqualValues(t, expectedState, actualState, "mismatch in state")
 }
 
+func TestStatePersistenceConcurrent(t *testing.T) {
+	source := `
+def apply(metric):
+  hosts = state.setdefault("hosts", {})
+  hosts[metric.tags["host"]] = hosts.get(metric.tags["host"], 0) + 1
+  return metric
+`
+	// Configure the plugin
+	plugin := &Starlark{
+		Common: common.Common{
+			StarlarkLoadFunc: testLoadFunc,
+			Source:           source,
+			Log:              testutil.Logger{},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Get the state periodically while processing metrics as done by the
+	// agent when checkpointing the plugin states. Run with the race detector
+	// to catch unprotected accesses.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, ok := plugin.GetState().([]byte)
+				require.True(t, ok, "state is not a bytes array")
+			}
+		}
+	}()
+
+	for i := range 1000 {
+		m := metric.New(
+			"test",
+			map[string]string{"host": fmt.Sprintf("host%d", i%10)},
+			map[string]interface{}{"value": i},
+			time.Unix(1713188113, 0),
+		)
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	close(done)
+	wg.Wait()
+
+	var actualState map[string]interface{}
+	stateData, ok := plugin.GetState().([]byte)
+	require.True(t, ok, "state is not a bytes array")
+	require.NoError(t, gob.NewDecoder(bytes.NewBuffer(stateData)).Decode(&actualState))
+	require.Len(t, actualState["hosts"], 10)
+}
+
 func TestUsePredefinedStateName(t *testing.T) {
 	source := `
 def apply(metric):
@@ -3728,6 +3788,293 @@ def apply(metric):
 	require.ErrorContains(t, plugin.Init(), "'state' constant uses reserved name")
 }
 
+func TestLoadLibrary(t *testing.T) {
+	// Setup the libraries
+	libdir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(libdir, "units"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(libdir, "units", "convert.star"), []byte(`
+def kb_to_bytes(value):
+  return value * 1024
+`), 0640))
+	require.NoError(t, os.WriteFile(filepath.Join(libdir, "helpers.star"), []byte(`
+load("units/convert.star", "kb_to_bytes")
+load("math.star", "math")
+
+def convert_fields(metric, fields):
+  for name in fields:
+    metric.fields[name] = kb_to_bytes(metric.fields[name])
+  metric.tags["converted"] = str(math.floor(len(fields)))
+`), 0640))
+
+	source := `
+load("helpers.star", "convert_fields")
+
+def apply(metric):
+  convert_fields(metric, ["used", "free"])
+  return metric
+`
+	plugin := newStarlarkFromSource(source)
+	plugin.LibraryPaths = []string{t.TempDir(), libdir}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	require.NoError(t, plugin.Add(metric.New(
+		"mem",
+		map[string]string{},
+		map[string]interface{}{"used": 2, "free": 3},
+		time.Unix(0, 0),
+	), &acc))
+	plugin.Stop()
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"mem",
+			map[string]string{"converted": "2"},
+			map[string]interface{}{"used": 2048, "free": 3072},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestLoadLibraryError(t *testing.T) {
+	libdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(libdir, "a.star"), []byte(`load("b.star", "b")`+"\na = 1\n"), 0640))
+	require.NoError(t, os.WriteFile(filepath.Join(libdir, "b.star"), []byte(`load("a.star", "a")`+"\nb = 1\n"), 0640))
+	outside := filepath.Join(filepath.Dir(libdir), "outside.star")
+	require.NoError(t, os.WriteFile(outside, []byte("x = 1\n"), 0640))
+	defer os.Remove(outside)
+
+	tests := []struct {
+		name     string
+		module   string
+		expected string
+	}{
+		{
+			name:     "not found",
+			module:   "missing.star",
+			expected: "module missing.star is not available",
+		},
+		{
+			name:     "outside of library path",
+			module:   "../outside.star",
+			expected: "module ../outside.star is not available",
+		},
+		{
+			name:     "absolute path",
+			module:   outside,
+			expected: "is not available",
+		},
+		{
+			name:     "cycle",
+			module:   "a.star",
+			expected: `cycle in loading module "a.star"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := "load(\"" + tt.module + "\", \"x\")\ndef apply(metric):\n  return metric\n"
+			plugin := newStarlarkFromSource(source)
+			plugin.LibraryPaths = []string{libdir}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+
+	// Library paths must be existing directories
+	plugin := newStarlarkFromSource("def apply(metric):\n  return metric\n")
+	plugin.LibraryPaths = []string{filepath.Join(libdir, "a.star")}
+	require.ErrorContains(t, plugin.Init(), "is not a directory")
+}
+
+func TestWindow(t *testing.T) {
+	source := `
+def apply(metric):
+  if "window" not in state:
+    state["window"] = Window("10s")
+  window = state["window"]
+  window.add(metric)
+
+  # Emit the sum of all completed windows
+  results = []
+  for start, metrics in window.expired(metric.time):
+    m = Metric("window_sum", tags={"period": str(window.period)})
+    total = 0
+    for x in metrics:
+      total += x.fields["value"]
+    m.fields["sum"] = total
+    m.fields["count"] = len(metrics)
+    m.time = start
+    results.append(m)
+  return results
+`
+	input := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(100, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(105, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(110, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 4}, time.Unix(119, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 5}, time.Unix(135, 0)),
+	}
+	expected := []telegraf.Metric{
+		metric.New(
+			"window_sum",
+			map[string]string{"period": "10s"},
+			map[string]interface{}{"sum": 3, "count": 2},
+			time.Unix(100, 0),
+		),
+		metric.New(
+			"window_sum",
+			map[string]string{"period": "10s"},
+			map[string]interface{}{"sum": 7, "count": 2},
+			time.Unix(110, 0),
+		),
+	}
+
+	plugin := newStarlarkFromSource(source)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	plugin.Stop()
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWindowInvalidPeriod(t *testing.T) {
+	plugin := newStarlarkFromSource(`
+w = Window("-1s")
+
+def apply(metric):
+  return metric
+`)
+	require.ErrorContains(t, plugin.Init(), "period must be positive")
+}
+
+func TestStatePersistenceWindow(t *testing.T) {
+	source := `
+def apply(metric):
+  if "window" not in state:
+    state["window"] = Window("1m")
+    state["seen"] = {}
+    state["history"] = []
+  state["window"].add(metric)
+  state["seen"][metric.tags["host"]] = (metric.fields["value"], True)
+  state["history"].append(metric.fields["value"])
+  state["last"] = deepcopy(metric)
+  return None
+`
+	input := []telegraf.Metric{
+		metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(60, 0)),
+		metric.New("test", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(90, 0)),
+	}
+
+	// Process metrics and get the state
+	plugin := newStarlarkFromSource(source)
+	require.NoError(t, plugin.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	plugin.Stop()
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	var pi telegraf.StatefulPlugin = plugin
+	state := pi.GetState()
+
+	// Restore the state in a new instance and flush the window
+	source = `
+def apply(metric):
+  results = []
+  for start, metrics in state["window"].flush():
+    for m in metrics:
+      m.tags["start"] = str(start)
+      results.append(m)
+  last = state["last"]
+  last.fields["history"] = str(state["history"])
+  last.fields["seen"] = str(state["seen"]["b"])
+  results.append(last)
+  return results
+`
+	restored := newStarlarkFromSource(source)
+	require.NoError(t, restored.Init())
+	require.NoError(t, restored.SetState(state))
+	require.NoError(t, restored.Start(&acc))
+	require.NoError(t, restored.Add(testutil.TestMetric(1), &acc))
+	restored.Stop()
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"host": "a", "start": "60000000000"},
+			map[string]interface{}{"value": 1},
+			time.Unix(60, 0),
+		),
+		metric.New(
+			"test",
+			map[string]string{"host": "b", "start": "60000000000"},
+			map[string]interface{}{"value": 2.5},
+			time.Unix(90, 0),
+		),
+		metric.New(
+			"test",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"value": 2.5, "history": "[1, 2.5]", "seen": "(2.5, True)"},
+			time.Unix(90, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestSharedState(t *testing.T) {
+	producer := newStarlarkFromSource(`
+load("shared.star", "shared")
+
+def apply(metric):
+  shared.set("threshold", {"value": metric.fields["value"], "host": metric.tags["host"]})
+  return None
+`)
+	consumer := newStarlarkFromSource(`
+load("shared.star", "shared")
+
+def apply(metric):
+  threshold = shared.get("threshold", {"value": 0})
+  metric.fields["above"] = metric.fields["value"] > threshold["value"]
+  # Modifications of the copy must not change the shared value
+  threshold["value"] = -1
+  return metric
+`)
+	require.NoError(t, producer.Init())
+	require.NoError(t, consumer.Init())
+	defer consumer.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, producer.Start(&acc))
+	require.NoError(t, consumer.Start(&acc))
+	defer producer.Stop()
+
+	require.NoError(t, producer.Add(
+		metric.New("limits", map[string]string{"host": "a"}, map[string]interface{}{"value": 10}, time.Unix(0, 0)),
+		&acc,
+	))
+	require.NoError(t, consumer.Add(
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 5}, time.Unix(0, 0)),
+		&acc,
+	))
+	require.NoError(t, consumer.Add(
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 15}, time.Unix(0, 0)),
+		&acc,
+	))
+
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 5, "above": false}, time.Unix(0, 0)),
+		metric.New("test", map[string]string{}, map[string]interface{}{"value": 15, "above": true}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
 // parses metric lines out of line protocol following a header, with a trailing blank line
 func parseMetricsFrom(t *testing.T, lines []string, header string) (metrics []telegraf.Metric) {
 	parser := &influx.Parser{}
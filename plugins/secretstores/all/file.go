@@ -0,0 +1,5 @@
+//go:build !custom || secretstores || secretstores.file
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/secretstores/file" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || secretstores || secretstores.sops
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/secretstores/sops" // register plugin
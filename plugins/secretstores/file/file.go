@@ -0,0 +1,146 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package file
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type File struct {
+	ID          string `toml:"id"`
+	Directory   string `toml:"directory"`
+	TrimNewline bool   `toml:"trim_newline"`
+
+	cache map[string]*entry
+	sync.Mutex
+}
+
+// entry caches the value of a secret file until the file changes
+type entry struct {
+	value   []byte
+	modTime time.Time
+	size    int64
+}
+
+func (*File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Init() error {
+	if f.ID == "" {
+		return errors.New("id missing")
+	}
+	if f.Directory == "" {
+		return errors.New("directory missing")
+	}
+
+	dir, err := filepath.Abs(f.Directory)
+	if err != nil {
+		return fmt.Errorf("resolving directory %q failed: %w", f.Directory, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("accessing directory %q failed: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+	f.Directory = dir
+	f.cache = make(map[string]*entry)
+
+	return nil
+}
+
+func (f *File) Get(key string) ([]byte, error) {
+	if key == "" || strings.HasPrefix(key, ".") || filepath.Base(key) != key {
+		return nil, fmt.Errorf("invalid key %q", key)
+	}
+	fn := filepath.Join(f.Directory, key)
+
+	// Follow symbolic links as Kubernetes rotates secrets by replacing the
+	// linked data directory
+	info, err := os.Stat(fn)
+	if err != nil {
+		return nil, fmt.Errorf("accessing secret %q failed: %w", key, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("secret %q is not a regular file", key)
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if e, found := f.cache[key]; found && e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+		return bytes.Clone(e.value), nil
+	}
+
+	value, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret %q failed: %w", key, err)
+	}
+	if f.TrimNewline {
+		value = bytes.TrimRight(value, "\r\n")
+	}
+	f.cache[key] = &entry{value: value, modTime: info.ModTime(), size: info.Size()}
+
+	return bytes.Clone(value), nil
+}
+
+func (f *File) List() ([]string, error) {
+	entries, err := os.ReadDir(f.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory failed: %w", err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		// Skip hidden entries such as the data directories of Kubernetes
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(f.Directory, e.Name()))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		keys = append(keys, e.Name())
+	}
+	return keys, nil
+}
+
+func (*File) Set(_, _ string) error {
+	return errors.New("setting secrets not supported")
+}
+
+func (f *File) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	// Make sure the secret exists on startup
+	if _, err := f.Get(key); err != nil {
+		return nil, err
+	}
+
+	// Resolve the secret on each access to pick up rotated secrets, the
+	// file is only read again if it changed
+	resolver := func() ([]byte, bool, error) {
+		s, err := f.Get(key)
+		return s, true, err
+	}
+	return resolver, nil
+}
+
+func init() {
+	secretstores.Add("file", func(id string) telegraf.SecretStore {
+		return &File{ID: id, TrimNewline: true}
+	})
+}
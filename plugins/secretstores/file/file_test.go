@@ -0,0 +1,150 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &File{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *File
+		expected string
+	}{
+		{
+			name:     "missing id",
+			plugin:   &File{},
+			expected: "id missing",
+		},
+		{
+			name:     "missing directory",
+			plugin:   &File{ID: "test"},
+			expected: "directory missing",
+		},
+		{
+			name:     "non-existent directory",
+			plugin:   &File{ID: "test", Directory: "non/existent/path"},
+			expected: "accessing directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestListGet(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "username"), []byte("admin\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("pa$$word"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("ignore me"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "subdir"), 0700))
+
+	plugin := &File{ID: "test", Directory: dir, TrimNewline: true}
+	require.NoError(t, plugin.Init())
+
+	keys, err := plugin.List()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"username", "password"}, keys)
+
+	value, err := plugin.Get("username")
+	require.NoError(t, err)
+	require.Equal(t, "admin", string(value))
+
+	value, err = plugin.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "pa$$word", string(value))
+
+	_, err = plugin.Get("missing")
+	require.ErrorContains(t, err, "accessing secret")
+
+	_, err = plugin.Get("../password")
+	require.ErrorContains(t, err, "invalid key")
+
+	_, err = plugin.Get(".hidden")
+	require.ErrorContains(t, err, "invalid key")
+
+	require.ErrorContains(t, plugin.Set("foo", "bar"), "not supported")
+}
+
+func TestResolverRotation(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(fn, []byte("first"), 0600))
+
+	plugin := &File{ID: "test", Directory: dir}
+	require.NoError(t, plugin.Init())
+
+	_, err := plugin.GetResolver("missing")
+	require.Error(t, err)
+
+	resolver, err := plugin.GetResolver("token")
+	require.NoError(t, err)
+
+	value, dynamic, err := resolver()
+	require.NoError(t, err)
+	require.True(t, dynamic)
+	require.Equal(t, "first", string(value))
+
+	// Rotate the secret and make sure the modification time differs even on
+	// file-systems with coarse timestamps
+	require.NoError(t, os.WriteFile(fn, []byte("second"), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+
+	value, _, err = resolver()
+	require.NoError(t, err)
+	require.Equal(t, "second", string(value))
+}
+
+func TestKubernetesLayout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping on Windows, as creating symbolic links requires privileges")
+	}
+
+	// Kubernetes links the secret files to a data directory which is swapped
+	// atomically on updates
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..2024_01_01"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "..2024_01_01", "token"), []byte("first"), 0600))
+	require.NoError(t, os.Symlink("..2024_01_01", filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "token"), filepath.Join(dir, "token")))
+
+	plugin := &File{ID: "test", Directory: dir}
+	require.NoError(t, plugin.Init())
+
+	keys, err := plugin.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"token"}, keys)
+
+	resolver, err := plugin.GetResolver("token")
+	require.NoError(t, err)
+	value, _, err := resolver()
+	require.NoError(t, err)
+	require.Equal(t, "first", string(value))
+
+	// Swap the data directory
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..2024_01_02"), 0700))
+	fn := filepath.Join(dir, "..2024_01_02", "token")
+	require.NoError(t, os.WriteFile(fn, []byte("second"), 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+	require.NoError(t, os.Symlink("..2024_01_02", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+
+	value, _, err = resolver()
+	require.NoError(t, err)
+	require.Equal(t, "second", string(value))
+}
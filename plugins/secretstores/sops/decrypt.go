@@ -0,0 +1,254 @@
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// Format of values encrypted by SOPS
+var encryptedPattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]`)
+
+// Known sequence used by SOPS to initialize the MAC when only encrypted values
+// are authenticated, see https://github.com/getsops/sops/blob/main/sops.go
+var macOnlyEncryptedInitialization = []byte{
+	0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0x0b,
+	0x0b, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69,
+}
+
+type metadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	LastModified     string `yaml:"lastmodified"`
+	MAC              string `yaml:"mac"`
+	MACOnlyEncrypted bool   `yaml:"mac_only_encrypted"`
+}
+
+// decrypter walks a SOPS document, decrypting the values and computing the
+// MAC over the plain-text values in document order
+type decrypter struct {
+	key              []byte
+	macOnlyEncrypted bool
+	hash             io.Writer
+	secrets          map[string][]byte
+}
+
+// decrypt decrypts a SOPS encrypted YAML or JSON document using the given age
+// identities and returns the secrets. Keys of nested values are joined by
+// underscores, list items are referenced by their index.
+func decrypt(buf []byte, identities []age.Identity) (map[string][]byte, error) {
+	// JSON is a subset of YAML so we can parse both formats while keeping the
+	// order of the values required for checking the MAC
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("parsing document failed: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("document is not a map")
+	}
+	root := doc.Content[0]
+
+	// Extract the metadata
+	var meta *metadata
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "sops" {
+			continue
+		}
+		meta = &metadata{}
+		if err := root.Content[i+1].Decode(meta); err != nil {
+			return nil, fmt.Errorf("decoding metadata failed: %w", err)
+		}
+	}
+	if meta == nil {
+		return nil, errors.New("no SOPS metadata found")
+	}
+	if meta.MAC == "" {
+		return nil, errors.New("no MAC found in metadata")
+	}
+
+	key, err := dataKey(meta, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decrypt the values
+	hash := sha512.New()
+	if meta.MACOnlyEncrypted {
+		hash.Write(macOnlyEncryptedInitialization)
+	}
+	d := &decrypter{
+		key:              key,
+		macOnlyEncrypted: meta.MACOnlyEncrypted,
+		hash:             hash,
+		secrets:          make(map[string][]byte),
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		name := root.Content[i].Value
+		if name == "sops" {
+			continue
+		}
+		if err := d.walk(root.Content[i+1], []string{name}, name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the integrity of the document
+	mac, _, err := decryptValue(meta.MAC, key, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting MAC failed: %w", err)
+	}
+	if !strings.EqualFold(string(mac), fmt.Sprintf("%X", hash.Sum(nil))) {
+		return nil, errors.New("MAC mismatch, the file might have been tampered with")
+	}
+
+	return d.secrets, nil
+}
+
+// dataKey decrypts the key used to encrypt the values with the first matching
+// age identity
+func dataKey(meta *metadata, identities []age.Identity) ([]byte, error) {
+	if len(meta.Age) == 0 {
+		return nil, errors.New("no age recipients found in metadata, only age is supported")
+	}
+
+	for _, recipient := range meta.Age {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(recipient.Enc)), identities...)
+		if err != nil {
+			var noMatch *age.NoIdentityMatchError
+			if errors.As(err, &noMatch) {
+				continue
+			}
+			return nil, fmt.Errorf("decrypting data key for recipient %q failed: %w", recipient.Recipient, err)
+		}
+		key, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading data key for recipient %q failed: %w", recipient.Recipient, err)
+		}
+		return key, nil
+	}
+	return nil, errors.New("no matching age identity found for any recipient")
+}
+
+func (d *decrypter) walk(node *yaml.Node, path []string, name string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k := node.Content[i].Value
+			if err := d.walk(node.Content[i+1], append(path, k), name+"_"+k); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		// Items of lists share the path of the list
+		for i, item := range node.Content {
+			if err := d.walk(item, path, name+"_"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return d.walk(node.Alias, path, name)
+	case yaml.ScalarNode:
+		return d.scalar(node, path, name)
+	}
+	return nil
+}
+
+func (d *decrypter) scalar(node *yaml.Node, path []string, name string) error {
+	if node.Tag != "!!str" || !encryptedPattern.MatchString(node.Value) {
+		// Unencrypted value
+		if !d.macOnlyEncrypted {
+			d.hash.Write(macBytes(node))
+		}
+		d.secrets[name] = []byte(node.Value)
+		return nil
+	}
+
+	additionalData := strings.Join(path, ":") + ":"
+	value, datatype, err := decryptValue(node.Value, d.key, additionalData)
+	if err != nil {
+		return fmt.Errorf("decrypting %q failed: %w", name, err)
+	}
+	if datatype == "comment" {
+		return nil
+	}
+	d.hash.Write(value)
+	d.secrets[name] = value
+
+	return nil
+}
+
+// decryptValue decrypts a single value and returns the plain-text and the
+// type of the value
+func decryptValue(value string, key []byte, additionalData string) ([]byte, string, error) {
+	parts := encryptedPattern.FindStringSubmatch(value)
+	if parts == nil {
+		return nil, "", errors.New("invalid format of encrypted value")
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding data failed: %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding IV failed: %w", err)
+	}
+	tag, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding tag failed: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", err
+	}
+	return plaintext, parts[4], nil
+}
+
+// macBytes returns the representation of an unencrypted value used by SOPS
+// when computing the MAC
+func macBytes(node *yaml.Node) []byte {
+	switch node.Tag {
+	case "!!int":
+		var v int
+		if err := node.Decode(&v); err == nil {
+			return []byte(strconv.Itoa(v))
+		}
+	case "!!float":
+		var v float64
+		if err := node.Decode(&v); err == nil {
+			return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	case "!!bool":
+		var v bool
+		if err := node.Decode(&v); err == nil {
+			if v {
+				return []byte("True")
+			}
+			return []byte("False")
+		}
+	case "!!null":
+		return nil
+	}
+	return []byte(node.Value)
+}
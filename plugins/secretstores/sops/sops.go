@@ -0,0 +1,182 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package sops
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type SOPS struct {
+	ID         string          `toml:"id"`
+	Path       string          `toml:"path"`
+	AgeKeyFile string          `toml:"age_key_file"`
+	Log        telegraf.Logger `toml:"-"`
+
+	identities []age.Identity
+	secrets    map[string][]byte
+	modTime    time.Time
+	size       int64
+	sync.Mutex
+}
+
+func (*SOPS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SOPS) Init() error {
+	if s.ID == "" {
+		return errors.New("id missing")
+	}
+	if s.Path == "" {
+		return errors.New("path missing")
+	}
+
+	identities, err := s.loadIdentities()
+	if err != nil {
+		return err
+	}
+	s.identities = identities
+
+	// Decrypt the file on startup to detect errors early
+	return s.refresh()
+}
+
+func (s *SOPS) Get(key string) ([]byte, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	v, found := s.secrets[key]
+	if !found {
+		return nil, errors.New("not found")
+	}
+	return bytes.Clone(v), nil
+}
+
+func (*SOPS) Set(_, _ string) error {
+	return errors.New("setting secrets not supported")
+}
+
+func (s *SOPS) List() ([]string, error) {
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	keys := make([]string, 0, len(s.secrets))
+	for k := range s.secrets {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (s *SOPS) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	if _, err := s.Get(key); err != nil {
+		return nil, fmt.Errorf("getting secret %q failed: %w", key, err)
+	}
+
+	// Resolve the secret on each access to pick up changes of the file, the
+	// file is only decrypted again if it changed
+	resolver := func() ([]byte, bool, error) {
+		v, err := s.Get(key)
+		return v, true, err
+	}
+	return resolver, nil
+}
+
+// refresh decrypts the file if it changed since the last call. In case the
+// changed file cannot be decrypted the previous secrets are kept.
+func (s *SOPS) refresh() error {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return fmt.Errorf("accessing file failed: %w", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.secrets != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+
+	buf, err := os.ReadFile(s.Path)
+	if err == nil {
+		var secrets map[string][]byte
+		secrets, err = decrypt(buf, s.identities)
+		if err == nil {
+			s.secrets = secrets
+			s.modTime = info.ModTime()
+			s.size = info.Size()
+			return nil
+		}
+	}
+
+	if s.secrets == nil {
+		return fmt.Errorf("decrypting %q failed: %w", s.Path, err)
+	}
+	s.Log.Warnf("Decrypting changed file %q failed, keeping previous secrets: %v", s.Path, err)
+
+	// Do not try again until the file changes
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return nil
+}
+
+// loadIdentities reads the age identities from the configured file or, as
+// done by SOPS, from the environment
+func (s *SOPS) loadIdentities() ([]age.Identity, error) {
+	var r io.Reader
+	switch {
+	case s.AgeKeyFile != "":
+		f, err := os.Open(s.AgeKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening age key file failed: %w", err)
+		}
+		defer f.Close()
+		r = f
+	case os.Getenv("SOPS_AGE_KEY_FILE") != "":
+		f, err := os.Open(os.Getenv("SOPS_AGE_KEY_FILE"))
+		if err != nil {
+			return nil, fmt.Errorf("opening age key file from environment failed: %w", err)
+		}
+		defer f.Close()
+		r = f
+	case os.Getenv("SOPS_AGE_KEY") != "":
+		r = strings.NewReader(os.Getenv("SOPS_AGE_KEY"))
+	default:
+		return nil, errors.New("no age identities, set 'age_key_file' or the SOPS_AGE_KEY_FILE environment variable")
+	}
+
+	identities, err := age.ParseIdentities(r)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age identities failed: %w", err)
+	}
+	return identities, nil
+}
+
+func init() {
+	secretstores.Add("sops", func(id string) telegraf.SecretStore {
+		return &SOPS{ID: id}
+	})
+}
@@ -0,0 +1,232 @@
+package sops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &SOPS{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("SOPS_AGE_KEY", "")
+
+	tests := []struct {
+		name     string
+		plugin   *SOPS
+		expected string
+	}{
+		{
+			name:     "missing id",
+			plugin:   &SOPS{},
+			expected: "id missing",
+		},
+		{
+			name:     "missing path",
+			plugin:   &SOPS{ID: "test"},
+			expected: "path missing",
+		},
+		{
+			name:     "missing identities",
+			plugin:   &SOPS{ID: "test", Path: "testdata/secrets.enc.yaml"},
+			expected: "no age identities",
+		},
+		{
+			name:     "non-existent age key file",
+			plugin:   &SOPS{ID: "test", Path: "testdata/secrets.enc.yaml", AgeKeyFile: "testdata/non_existent.key"},
+			expected: "opening age key file failed",
+		},
+		{
+			name:     "non-existent file",
+			plugin:   &SOPS{ID: "test", Path: "testdata/non_existent.yaml", AgeKeyFile: "testdata/age.key"},
+			expected: "accessing file failed",
+		},
+		{
+			name:     "unencrypted file",
+			plugin:   &SOPS{ID: "test", Path: "testdata/age.key", AgeKeyFile: "testdata/age.key"},
+			expected: "document is not a map",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestListGet(t *testing.T) {
+	expected := map[string]string{
+		"username":                   "admin",
+		"password":                   "pa$$word",
+		"port":                       "5432",
+		"database_host":              "db.example.com",
+		"database_token_unencrypted": "public",
+		"servers_0":                  "first",
+		"servers_1":                  "second",
+	}
+
+	for _, fn := range []string{"secrets.enc.yaml", "secrets_mac_only.enc.yaml"} {
+		t.Run(fn, func(t *testing.T) {
+			plugin := &SOPS{
+				ID:         "test",
+				Path:       filepath.Join("testdata", fn),
+				AgeKeyFile: filepath.Join("testdata", "age.key"),
+				Log:        testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			keys, err := plugin.List()
+			require.NoError(t, err)
+			require.Len(t, keys, len(expected))
+
+			for k, v := range expected {
+				actual, err := plugin.Get(k)
+				require.NoError(t, err, k)
+				require.Equal(t, v, string(actual), k)
+			}
+
+			_, err = plugin.Get("missing")
+			require.ErrorContains(t, err, "not found")
+			require.ErrorContains(t, plugin.Set("foo", "bar"), "not supported")
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	key, err := os.ReadFile(filepath.Join("testdata", "age.key"))
+	require.NoError(t, err)
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	t.Setenv("SOPS_AGE_KEY", string(key))
+
+	plugin := &SOPS{
+		ID:   "test",
+		Path: filepath.Join("testdata", "secrets.enc.json"),
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	keys, err := plugin.List()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"username", "password", "database_host"}, keys)
+
+	resolver, err := plugin.GetResolver("password")
+	require.NoError(t, err)
+	value, dynamic, err := resolver()
+	require.NoError(t, err)
+	require.True(t, dynamic)
+	require.Equal(t, "pa$$word", string(value))
+}
+
+func TestWrongIdentity(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	fn := filepath.Join(t.TempDir(), "age.key")
+	require.NoError(t, os.WriteFile(fn, []byte(identity.String()), 0600))
+
+	plugin := &SOPS{
+		ID:         "test",
+		Path:       filepath.Join("testdata", "secrets.enc.yaml"),
+		AgeKeyFile: fn,
+		Log:        testutil.Logger{},
+	}
+	require.ErrorContains(t, plugin.Init(), "no matching age identity")
+}
+
+func TestTampered(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "secrets.enc.yaml"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		modify   func(string) string
+		expected string
+	}{
+		{
+			name: "modified unencrypted value",
+			modify: func(s string) string {
+				return strings.Replace(s, "token_unencrypted: public", "token_unencrypted: private", 1)
+			},
+			expected: "MAC mismatch",
+		},
+		{
+			name: "swapped values",
+			modify: func(s string) string {
+				lines := strings.Split(s, "\n")
+				username := strings.TrimPrefix(lines[0], "username: ")
+				password := strings.TrimPrefix(lines[1], "password: ")
+				lines[0] = "username: " + password
+				lines[1] = "password: " + username
+				return strings.Join(lines, "\n")
+			},
+			expected: `decrypting "username" failed`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+			require.NoError(t, os.WriteFile(fn, []byte(tt.modify(string(buf))), 0600))
+
+			plugin := &SOPS{
+				ID:         "test",
+				Path:       fn,
+				AgeKeyFile: filepath.Join("testdata", "age.key"),
+				Log:        testutil.Logger{},
+			}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	yamlFile, err := os.ReadFile(filepath.Join("testdata", "secrets.enc.yaml"))
+	require.NoError(t, err)
+	jsonFile, err := os.ReadFile(filepath.Join("testdata", "secrets.enc.json"))
+	require.NoError(t, err)
+
+	fn := filepath.Join(t.TempDir(), "secrets.enc")
+	require.NoError(t, os.WriteFile(fn, jsonFile, 0600))
+
+	plugin := &SOPS{
+		ID:         "test",
+		Path:       fn,
+		AgeKeyFile: filepath.Join("testdata", "age.key"),
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	_, err = plugin.Get("port")
+	require.ErrorContains(t, err, "not found")
+
+	// Replace the file and make sure the modification time differs even on
+	// file-systems with coarse timestamps
+	require.NoError(t, os.WriteFile(fn, yamlFile, 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+
+	value, err := plugin.Get("port")
+	require.NoError(t, err)
+	require.Equal(t, "5432", string(value))
+
+	// Keep the previous secrets if the file is broken
+	require.NoError(t, os.WriteFile(fn, []byte("broken"), 0600))
+	future = future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(fn, future, future))
+
+	value, err = plugin.Get("port")
+	require.NoError(t, err)
+	require.Equal(t, "5432", string(value))
+}
@@ -0,0 +1,96 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// response is the common envelope of Vault API responses
+type response struct {
+	LeaseID       string          `json:"lease_id"`
+	Renewable     bool            `json:"renewable"`
+	LeaseDuration int64           `json:"lease_duration"`
+	Data          json.RawMessage `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		Renewable     bool   `json:"renewable"`
+		LeaseDuration int64  `json:"lease_duration"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+// lease tracks the validity of a token or secret
+type lease struct {
+	id        string
+	renewable bool
+	duration  time.Duration
+	renewAt   time.Time
+}
+
+func newLease(id string, renewable bool, seconds int64, now time.Time) lease {
+	duration := time.Duration(seconds) * time.Second
+	l := lease{id: id, renewable: renewable, duration: duration}
+	if duration > 0 {
+		// Renew after two thirds of the lease duration to leave time for
+		// retries
+		l.renewAt = now.Add(duration * 2 / 3)
+	}
+	return l
+}
+
+// request sends a request to the Vault API at the given path relative to the
+// API prefix and decodes the response
+func (v *Vault) request(ctx context.Context, method, path, token string, body interface{}) (*response, error) {
+	var r io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request failed: %w", err)
+		}
+		r = bytes.NewReader(buf)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(v.Timeout))
+	defer cancel()
+
+	u := strings.TrimSuffix(v.Address, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, fmt.Errorf("creating request failed: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decoding response with status %d failed: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("request to %q failed with status %d: %s", path, resp.StatusCode, strings.Join(result.Errors, "; "))
+		}
+		return nil, fmt.Errorf("request to %q failed with status %d", path, resp.StatusCode)
+	}
+
+	return &result, nil
+}
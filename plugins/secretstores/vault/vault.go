@@ -0,0 +1,552 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package vault
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_tls "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const defaultKubernetesJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Delay before retrying failed renewals
+var retryInterval = 10 * time.Second
+
+type Vault struct {
+	ID              string          `toml:"id"`
+	Address         string          `toml:"address"`
+	Namespace       string          `toml:"namespace"`
+	AuthMethod      string          `toml:"auth_method"`
+	AuthMount       string          `toml:"auth_mount"`
+	Token           config.Secret   `toml:"token"`
+	RoleID          config.Secret   `toml:"role_id"`
+	SecretID        config.Secret   `toml:"secret_id"`
+	Role            string          `toml:"role"`
+	JWT             config.Secret   `toml:"jwt"`
+	JWTFile         string          `toml:"jwt_file"`
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+	Timeout         config.Duration `toml:"timeout"`
+	Secrets         []secretConfig  `toml:"secret"`
+	Log             telegraf.Logger `toml:"-"`
+	common_tls.ClientConfig
+
+	client *http.Client
+
+	// The token and the leases are only accessed when starting and by the
+	// renewal goroutine, the mutex guards the secret values read by Get
+	token      string
+	tokenLease lease
+	secrets    []*secret
+	sync.Mutex
+
+	startLock sync.Mutex
+	started   bool
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+type secretConfig struct {
+	Key     string `toml:"key"`
+	Engine  string `toml:"engine"`
+	Mount   string `toml:"mount"`
+	Path    string `toml:"path"`
+	Version int    `toml:"version"`
+}
+
+// secret holds the fields of a secret read from Vault
+type secret struct {
+	secretConfig
+	values    map[string][]byte
+	lease     lease
+	initial   time.Duration
+	refreshAt time.Time
+}
+
+func (*Vault) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Vault) Init() error {
+	if v.ID == "" {
+		return errors.New("id missing")
+	}
+	if v.Address == "" {
+		return errors.New("address missing")
+	}
+	if v.Timeout <= 0 {
+		v.Timeout = config.Duration(5 * time.Second)
+	}
+
+	// Check the authentication settings
+	switch v.AuthMethod {
+	case "", "token":
+		v.AuthMethod = "token"
+		if v.Token.Empty() {
+			return errors.New("'token' required for authentication method \"token\"")
+		}
+	case "approle":
+		if v.RoleID.Empty() || v.SecretID.Empty() {
+			return errors.New("'role_id' and 'secret_id' required for authentication method \"approle\"")
+		}
+	case "jwt", "kubernetes":
+		if v.Role == "" {
+			return fmt.Errorf("'role' required for authentication method %q", v.AuthMethod)
+		}
+		if v.AuthMethod == "kubernetes" && v.JWT.Empty() && v.JWTFile == "" {
+			v.JWTFile = defaultKubernetesJWTFile
+		}
+		if v.JWT.Empty() == (v.JWTFile == "") {
+			return fmt.Errorf("exactly one of 'jwt' and 'jwt_file' required for authentication method %q", v.AuthMethod)
+		}
+	default:
+		return fmt.Errorf("unknown authentication method %q", v.AuthMethod)
+	}
+	if v.AuthMount == "" {
+		v.AuthMount = v.AuthMethod
+	}
+
+	// Check the secrets
+	keys := make(map[string]bool, len(v.Secrets))
+	for _, cfg := range v.Secrets {
+		if cfg.Key == "" {
+			return errors.New("'key' not specified")
+		}
+		if keys[cfg.Key] {
+			return fmt.Errorf("secret with key %q already defined", cfg.Key)
+		}
+		keys[cfg.Key] = true
+
+		if cfg.Path == "" {
+			return fmt.Errorf("'path' not specified for key %q", cfg.Key)
+		}
+		switch cfg.Engine {
+		case "":
+			cfg.Engine = "kv-v2"
+		case "kv-v1", "kv-v2", "database":
+		default:
+			return fmt.Errorf("unknown engine %q for key %q", cfg.Engine, cfg.Key)
+		}
+		if cfg.Version != 0 && cfg.Engine != "kv-v2" {
+			return fmt.Errorf("'version' not supported by engine %q for key %q", cfg.Engine, cfg.Key)
+		}
+		if cfg.Mount == "" {
+			cfg.Mount = "secret"
+			if cfg.Engine == "database" {
+				cfg.Mount = "database"
+			}
+		}
+		v.secrets = append(v.secrets, &secret{secretConfig: cfg})
+	}
+
+	// Setup the client
+	tlsCfg, err := v.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	v.client = &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsCfg,
+		},
+	}
+
+	return nil
+}
+
+func (v *Vault) Get(key string) ([]byte, error) {
+	if err := v.start(); err != nil {
+		return nil, err
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	for _, s := range v.secrets {
+		field, found := strings.CutPrefix(key, s.Key+"_")
+		if !found {
+			continue
+		}
+		if value, found := s.values[field]; found {
+			return bytes.Clone(value), nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (*Vault) Set(_, _ string) error {
+	return errors.New("setting secrets not supported")
+}
+
+func (v *Vault) List() ([]string, error) {
+	if err := v.start(); err != nil {
+		return nil, err
+	}
+
+	v.Lock()
+	defer v.Unlock()
+
+	var keys []string
+	for _, s := range v.secrets {
+		for field := range s.values {
+			keys = append(keys, s.Key+"_"+field)
+		}
+	}
+	return keys, nil
+}
+
+func (v *Vault) GetResolver(key string) (telegraf.ResolveFunc, error) {
+	if _, err := v.Get(key); err != nil {
+		return nil, fmt.Errorf("getting secret %q failed: %w", key, err)
+	}
+
+	// Secrets might change on renewal, so resolve them on each access
+	resolver := func() ([]byte, bool, error) {
+		s, err := v.Get(key)
+		return s, true, err
+	}
+	return resolver, nil
+}
+
+// Stop terminates the background renewal of the token and leases. The
+// secrets are read again on the next access.
+func (v *Vault) Stop() {
+	v.startLock.Lock()
+	defer v.startLock.Unlock()
+
+	if !v.started {
+		return
+	}
+	v.cancel()
+	v.wg.Wait()
+	v.cancel = nil
+	v.started = false
+}
+
+// start authenticates and reads the secrets on first access and starts the
+// background renewal
+func (v *Vault) start() error {
+	v.startLock.Lock()
+	defer v.startLock.Unlock()
+
+	if v.started {
+		return nil
+	}
+
+	ctx := context.Background()
+	if err := v.login(ctx); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, s := range v.secrets {
+		if err := v.read(ctx, s, now); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancel = cancel
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+		v.maintain(ctx)
+	}()
+	v.started = true
+
+	return nil
+}
+
+func (v *Vault) maintain(ctx context.Context) {
+	timer := time.NewTimer(v.nextRenewal())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		v.renew(ctx)
+		timer.Reset(v.nextRenewal())
+	}
+}
+
+// nextRenewal returns the time until the token or any secret is due for
+// renewal
+func (v *Vault) nextRenewal() time.Duration {
+	// Check periodically even if nothing needs to be renewed
+	next := time.Now().Add(time.Hour)
+	due := func(t time.Time) {
+		if !t.IsZero() && t.Before(next) {
+			next = t
+		}
+	}
+	due(v.tokenLease.renewAt)
+	for _, s := range v.secrets {
+		due(s.lease.renewAt)
+		due(s.refreshAt)
+	}
+	return max(time.Until(next), 0)
+}
+
+// renew renews the token and secrets due for renewal. The requests are sent
+// without holding the lock so accessing the secrets is not blocked.
+func (v *Vault) renew(ctx context.Context) {
+	now := time.Now()
+	if due(v.tokenLease.renewAt, now) {
+		relogin, err := v.renewToken(ctx, now)
+		if err != nil {
+			v.Log.Errorf("Renewing token failed: %v", err)
+			v.tokenLease.renewAt = now.Add(retryInterval)
+			return
+		}
+		if relogin {
+			// Leases are revoked together with the token they were created
+			// with, so all secrets are read again using the new token
+			for _, s := range v.secrets {
+				s.refreshAt = now
+				s.lease.renewAt = time.Time{}
+			}
+		}
+	}
+
+	for _, s := range v.secrets {
+		if due(s.lease.renewAt, now) && s.lease.renewable {
+			err := v.renewLease(ctx, s, now)
+			if err == nil {
+				continue
+			}
+			v.Log.Warnf("Renewing lease of %q failed, reading secret again: %v", s.Key, err)
+		}
+
+		if due(s.lease.renewAt, now) || due(s.refreshAt, now) {
+			if err := v.read(ctx, s, now); err != nil {
+				v.Log.Errorf("Reading secret %q failed: %v", s.Key, err)
+				s.refreshAt = now.Add(retryInterval)
+				s.lease.renewAt = time.Time{}
+			}
+		}
+	}
+}
+
+func due(t, now time.Time) bool {
+	return !t.IsZero() && !now.Before(t)
+}
+
+// renewToken renews the token or authenticates again if the token cannot be
+// renewed anymore. It returns true if a new token was obtained.
+func (v *Vault) renewToken(ctx context.Context, now time.Time) (bool, error) {
+	if v.tokenLease.renewable {
+		resp, err := v.request(ctx, http.MethodPost, "auth/token/renew-self", v.token, struct{}{})
+		if err == nil && resp.Auth != nil {
+			l := newLease("", resp.Auth.Renewable, resp.Auth.LeaseDuration, now)
+			// Authenticate again if the maximum TTL of the token is close
+			if v.AuthMethod == "token" || l.duration >= v.tokenLease.duration/3 {
+				v.Log.Debugf("Renewed token for %s", l.duration)
+				v.tokenLease.renewable = l.renewable
+				v.tokenLease.renewAt = l.renewAt
+				return false, nil
+			}
+		} else if err != nil {
+			v.Log.Warnf("Renewing token failed: %v", err)
+		}
+	}
+
+	if v.AuthMethod == "token" {
+		return false, errors.New("token cannot be renewed")
+	}
+	if err := v.login(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// renewLease extends the lease of the secret. An error is returned if the
+// lease cannot be renewed or if its maximum TTL is close so the secret needs
+// to be read again.
+func (v *Vault) renewLease(ctx context.Context, s *secret, now time.Time) error {
+	body := map[string]string{"lease_id": s.lease.id}
+	resp, err := v.request(ctx, http.MethodPut, "sys/leases/renew", v.token, body)
+	if err != nil {
+		return err
+	}
+
+	l := newLease(resp.LeaseID, resp.Renewable, resp.LeaseDuration, now)
+	if l.duration < s.initial/3 {
+		return fmt.Errorf("maximum lease time reached, remaining %s", l.duration)
+	}
+	if l.id == "" {
+		l.id = s.lease.id
+	}
+	s.lease = l
+	v.Log.Debugf("Renewed lease of %q for %s", s.Key, l.duration)
+
+	return nil
+}
+
+// login authenticates using the configured method
+func (v *Vault) login(ctx context.Context) error {
+	if v.AuthMethod == "token" {
+		token, err := v.Token.Get()
+		if err != nil {
+			return fmt.Errorf("getting token failed: %w", err)
+		}
+		defer token.Destroy()
+		v.token = strings.TrimSpace(token.String())
+
+		// Lookup the token to determine if it needs to be renewed
+		resp, err := v.request(ctx, http.MethodGet, "auth/token/lookup-self", v.token, nil)
+		if err != nil {
+			return fmt.Errorf("looking up token failed: %w", err)
+		}
+		var info struct {
+			TTL       int64 `json:"ttl"`
+			Renewable bool  `json:"renewable"`
+		}
+		if err := json.Unmarshal(resp.Data, &info); err != nil {
+			return fmt.Errorf("decoding token information failed: %w", err)
+		}
+		v.tokenLease = newLease("", info.Renewable, info.TTL, time.Now())
+		return nil
+	}
+
+	body := make(map[string]string)
+	switch v.AuthMethod {
+	case "approle":
+		roleID, err := v.RoleID.Get()
+		if err != nil {
+			return fmt.Errorf("getting role ID failed: %w", err)
+		}
+		defer roleID.Destroy()
+		secretID, err := v.SecretID.Get()
+		if err != nil {
+			return fmt.Errorf("getting secret ID failed: %w", err)
+		}
+		defer secretID.Destroy()
+		body["role_id"] = roleID.String()
+		body["secret_id"] = secretID.String()
+	case "jwt", "kubernetes":
+		body["role"] = v.Role
+		if v.JWTFile != "" {
+			// Read the file on each login to pick up rotated tokens
+			buf, err := os.ReadFile(v.JWTFile)
+			if err != nil {
+				return fmt.Errorf("reading JWT file failed: %w", err)
+			}
+			body["jwt"] = strings.TrimSpace(string(buf))
+		} else {
+			jwt, err := v.JWT.Get()
+			if err != nil {
+				return fmt.Errorf("getting JWT failed: %w", err)
+			}
+			defer jwt.Destroy()
+			body["jwt"] = strings.TrimSpace(jwt.String())
+		}
+	}
+
+	resp, err := v.request(ctx, http.MethodPost, "auth/"+v.AuthMount+"/login", "", body)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return errors.New("login failed: no token received")
+	}
+	v.token = resp.Auth.ClientToken
+	v.tokenLease = newLease("", resp.Auth.Renewable, resp.Auth.LeaseDuration, time.Now())
+	v.Log.Debugf("Logged in using %q, token valid for %s", v.AuthMethod, v.tokenLease.duration)
+
+	return nil
+}
+
+// read reads the secret from the configured engine
+func (v *Vault) read(ctx context.Context, s *secret, now time.Time) error {
+	var path string
+	switch s.Engine {
+	case "kv-v1":
+		path = s.Mount + "/" + s.Path
+	case "kv-v2":
+		path = s.Mount + "/data/" + s.Path
+		if s.Version > 0 {
+			path += "?" + url.Values{"version": []string{strconv.Itoa(s.Version)}}.Encode()
+		}
+	case "database":
+		path = s.Mount + "/creds/" + s.Path
+	}
+
+	resp, err := v.request(ctx, http.MethodGet, path, v.token, nil)
+	if err != nil {
+		return fmt.Errorf("reading secret %q failed: %w", s.Key, err)
+	}
+
+	data := resp.Data
+	if s.Engine == "kv-v2" {
+		var versioned struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &versioned); err != nil {
+			return fmt.Errorf("decoding secret %q failed: %w", s.Key, err)
+		}
+		data = versioned.Data
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("decoding secret %q failed: %w", s.Key, err)
+	}
+	if fields == nil {
+		return fmt.Errorf("secret %q has no data, maybe it was deleted", s.Key)
+	}
+
+	values := make(map[string][]byte, len(fields))
+	for k, raw := range fields {
+		switch value := raw.(type) {
+		case string:
+			values[k] = []byte(value)
+		default:
+			buf, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("encoding field %q of secret %q failed: %w", k, s.Key, err)
+			}
+			values[k] = buf
+		}
+	}
+	v.Lock()
+	s.values = values
+	v.Unlock()
+
+	// Only dynamic secrets come with a lease, KV secrets are read again
+	// periodically if requested
+	s.lease = lease{}
+	s.refreshAt = time.Time{}
+	if resp.LeaseID != "" {
+		s.lease = newLease(resp.LeaseID, resp.Renewable, resp.LeaseDuration, now)
+		s.initial = s.lease.duration
+	} else if v.RefreshInterval > 0 {
+		s.refreshAt = now.Add(time.Duration(v.RefreshInterval))
+	}
+
+	return nil
+}
+
+func init() {
+	secretstores.Add("vault", func(id string) telegraf.SecretStore {
+		return &Vault{ID: id}
+	})
+}
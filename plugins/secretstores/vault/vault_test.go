@@ -0,0 +1,506 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &Vault{}
+	require.NotEmpty(t, plugin.SampleConfig())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Vault
+		expected string
+	}{
+		{
+			name:     "missing id",
+			plugin:   &Vault{},
+			expected: "id missing",
+		},
+		{
+			name:     "missing address",
+			plugin:   &Vault{ID: "test"},
+			expected: "address missing",
+		},
+		{
+			name:     "missing token",
+			plugin:   &Vault{ID: "test", Address: "http://localhost"},
+			expected: "'token' required",
+		},
+		{
+			name: "missing secret id",
+			plugin: &Vault{
+				ID:         "test",
+				Address:    "http://localhost",
+				AuthMethod: "approle",
+				RoleID:     config.NewSecret([]byte("role")),
+			},
+			expected: "'role_id' and 'secret_id' required",
+		},
+		{
+			name:     "missing jwt",
+			plugin:   &Vault{ID: "test", Address: "http://localhost", AuthMethod: "jwt", Role: "telegraf"},
+			expected: "exactly one of 'jwt' and 'jwt_file' required",
+		},
+		{
+			name:     "unknown method",
+			plugin:   &Vault{ID: "test", Address: "http://localhost", AuthMethod: "ldap"},
+			expected: `unknown authentication method "ldap"`,
+		},
+		{
+			name: "unknown engine",
+			plugin: &Vault{
+				ID:      "test",
+				Address: "http://localhost",
+				Token:   config.NewSecret([]byte("token")),
+				Secrets: []secretConfig{{Key: "db", Engine: "aws", Path: "foo"}},
+			},
+			expected: `unknown engine "aws" for key "db"`,
+		},
+		{
+			name: "duplicate key",
+			plugin: &Vault{
+				ID:      "test",
+				Address: "http://localhost",
+				Token:   config.NewSecret([]byte("token")),
+				Secrets: []secretConfig{{Key: "db", Path: "foo"}, {Key: "db", Path: "bar"}},
+			},
+			expected: `secret with key "db" already defined`,
+		},
+		{
+			name: "version for kv-v1",
+			plugin: &Vault{
+				ID:      "test",
+				Address: "http://localhost",
+				Token:   config.NewSecret([]byte("token")),
+				Secrets: []secretConfig{{Key: "db", Engine: "kv-v1", Path: "foo", Version: 2}},
+			},
+			expected: `'version' not supported by engine "kv-v1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestTokenKV(t *testing.T) {
+	server := newFakeVault(t)
+	server.namespace = "team"
+	server.tokens["root"] = true
+	defer server.Close()
+
+	plugin := &Vault{
+		ID:        "test",
+		Address:   server.URL,
+		Namespace: "team",
+		Token:     config.NewSecret([]byte("root")),
+		Secrets: []secretConfig{
+			{Key: "legacy", Engine: "kv-v1", Mount: "kv", Path: "app"},
+			{Key: "latest", Path: "app"},
+			{Key: "pinned", Path: "app", Version: 1},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Stop()
+
+	keys, err := plugin.List()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"legacy_password", "legacy_port", "latest_password", "pinned_password"}, keys)
+
+	expected := map[string]string{
+		"legacy_password": "v1-secret",
+		"legacy_port":     "5432",
+		"latest_password": "second",
+		"pinned_password": "first",
+	}
+	for k, v := range expected {
+		resolver, err := plugin.GetResolver(k)
+		require.NoError(t, err, k)
+		value, dynamic, err := resolver()
+		require.NoError(t, err, k)
+		require.True(t, dynamic)
+		require.Equal(t, v, string(value), k)
+	}
+
+	_, err = plugin.Get("latest_username")
+	require.ErrorContains(t, err, "not found")
+	require.ErrorContains(t, plugin.Set("foo", "bar"), "not supported")
+}
+
+func TestTokenInvalid(t *testing.T) {
+	server := newFakeVault(t)
+	defer server.Close()
+
+	plugin := &Vault{
+		ID:      "test",
+		Address: server.URL,
+		Token:   config.NewSecret([]byte("invalid")),
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	_, err := plugin.List()
+	require.ErrorContains(t, err, "permission denied")
+}
+
+func TestAppRoleLeaseRenewal(t *testing.T) {
+	server := newFakeVault(t)
+	server.leaseDuration = 1
+	server.renewDuration = 1
+	defer server.Close()
+
+	plugin := &Vault{
+		ID:         "test",
+		Address:    server.URL,
+		AuthMethod: "approle",
+		RoleID:     config.NewSecret([]byte("telegraf")),
+		SecretID:   config.NewSecret([]byte("s3cr3t")),
+		Secrets:    []secretConfig{{Key: "db", Engine: "database", Path: "readonly"}},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Stop()
+
+	username, err := plugin.Get("db_username")
+	require.NoError(t, err)
+	require.Equal(t, "user-1", string(username))
+
+	// The lease is renewed in the background keeping the credentials
+	require.Eventually(t, func() bool {
+		return server.renewals() >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	username, err = plugin.Get("db_username")
+	require.NoError(t, err)
+	require.Equal(t, "user-1", string(username))
+}
+
+func TestLeaseMaximumTTL(t *testing.T) {
+	server := newFakeVault(t)
+	server.leaseDuration = 1
+	server.renewDuration = 0
+	defer server.Close()
+
+	plugin := &Vault{
+		ID:         "test",
+		Address:    server.URL,
+		AuthMethod: "approle",
+		RoleID:     config.NewSecret([]byte("telegraf")),
+		SecretID:   config.NewSecret([]byte("s3cr3t")),
+		Secrets:    []secretConfig{{Key: "db", Engine: "database", Path: "readonly"}},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Stop()
+
+	resolver, err := plugin.GetResolver("db_username")
+	require.NoError(t, err)
+
+	// The lease cannot be extended anymore so new credentials are read
+	require.Eventually(t, func() bool {
+		username, _, err := resolver()
+		return err == nil && string(username) != "user-1"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestJWTRelogin(t *testing.T) {
+	server := newFakeVault(t)
+	server.tokenDuration = 1
+	server.tokenRenewable = false
+	defer server.Close()
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(jwtFile, []byte("header.payload.signature\n"), 0600))
+
+	plugin := &Vault{
+		ID:         "test",
+		Address:    server.URL,
+		AuthMethod: "jwt",
+		Role:       "telegraf",
+		JWTFile:    jwtFile,
+		Secrets:    []secretConfig{{Key: "db", Engine: "database", Path: "readonly"}},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Stop()
+
+	resolver, err := plugin.GetResolver("db_password")
+	require.NoError(t, err)
+	password, _, err := resolver()
+	require.NoError(t, err)
+	require.Equal(t, "password-1", string(password))
+
+	// The token cannot be renewed so the plugin logs in again and reads the
+	// credentials using the new token
+	require.Eventually(t, func() bool {
+		return server.logins() >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Eventually(t, func() bool {
+		password, _, err := resolver()
+		return err == nil && string(password) != "password-1"
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestStop(t *testing.T) {
+	server := newFakeVault(t)
+	server.leaseDuration = 1
+	server.renewDuration = 1
+	defer server.Close()
+
+	plugin := &Vault{
+		ID:         "test",
+		Address:    server.URL,
+		AuthMethod: "approle",
+		RoleID:     config.NewSecret([]byte("telegraf")),
+		SecretID:   config.NewSecret([]byte("s3cr3t")),
+		Secrets:    []secretConfig{{Key: "db", Engine: "database", Path: "readonly"}},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	_, err := plugin.Get("db_username")
+	require.NoError(t, err)
+	plugin.Stop()
+
+	// No renewals should happen after stopping
+	renewals := server.renewals()
+	time.Sleep(1500 * time.Millisecond)
+	require.Equal(t, renewals, server.renewals())
+}
+
+func TestGetDuringRenewal(t *testing.T) {
+	server := newFakeVault(t)
+	server.leaseDuration = 1
+	server.renewPending = make(chan struct{}, 1)
+	server.renewRelease = make(chan struct{})
+	defer server.Close()
+
+	plugin := &Vault{
+		ID:         "test",
+		Address:    server.URL,
+		AuthMethod: "approle",
+		RoleID:     config.NewSecret([]byte("telegraf")),
+		SecretID:   config.NewSecret([]byte("s3cr3t")),
+		Secrets:    []secretConfig{{Key: "db", Engine: "database", Path: "readonly"}},
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	defer plugin.Stop()
+	defer close(server.renewRelease)
+
+	_, err := plugin.Get("db_username")
+	require.NoError(t, err)
+
+	// Accessing the secrets must not wait for the pending renewal
+	select {
+	case <-server.renewPending:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no renewal requested")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		username, err := plugin.Get("db_username")
+		require.NoError(t, err)
+		require.Equal(t, "user-1", string(username))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "getting secret blocked by renewal")
+	}
+}
+
+// fakeVault is a minimal stand-in for the Vault HTTP API
+type fakeVault struct {
+	*httptest.Server
+	t *testing.T
+
+	namespace      string
+	tokenDuration  int64
+	tokenRenewable bool
+	leaseDuration  int64
+	renewDuration  int64
+
+	// Hold back lease renewals until released, signalling pending ones
+	renewPending chan struct{}
+	renewRelease chan struct{}
+
+	tokens       map[string]bool
+	loginCount   int
+	leaseCount   int
+	renewalCount int
+	sync.Mutex
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	f := &fakeVault{
+		t:              t,
+		tokenDuration:  3600,
+		tokenRenewable: true,
+		leaseDuration:  3600,
+		renewDuration:  3600,
+		tokens:         make(map[string]bool),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *fakeVault) logins() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.loginCount
+}
+
+func (f *fakeVault) renewals() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.renewalCount
+}
+
+func (f *fakeVault) handle(w http.ResponseWriter, r *http.Request) {
+	if f.renewRelease != nil && r.URL.Path == "/v1/sys/leases/renew" {
+		select {
+		case f.renewPending <- struct{}{}:
+		default:
+		}
+		<-f.renewRelease
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("X-Vault-Namespace") != f.namespace {
+		f.reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"wrong namespace"}})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+
+	// Login endpoints
+	if strings.HasPrefix(path, "auth/") && strings.HasSuffix(path, "/login") {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{err.Error()}})
+			return
+		}
+		var valid bool
+		switch path {
+		case "auth/approle/login":
+			valid = body["role_id"] == "telegraf" && body["secret_id"] == "s3cr3t"
+		case "auth/jwt/login", "auth/kubernetes/login":
+			valid = body["role"] == "telegraf" && body["jwt"] == "header.payload.signature"
+		}
+		if !valid {
+			f.reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid credentials"}})
+			return
+		}
+		f.loginCount++
+		token := fmt.Sprintf("token-%d", f.loginCount)
+		f.tokens[token] = true
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   token,
+				"lease_duration": f.tokenDuration,
+				"renewable":      f.tokenRenewable,
+			},
+		})
+		return
+	}
+
+	// All other endpoints require a valid token
+	if !f.tokens[r.Header.Get("X-Vault-Token")] {
+		f.reply(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	switch {
+	case path == "auth/token/lookup-self" && r.Method == http.MethodGet:
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"ttl": 0, "renewable": false},
+		})
+	case path == "auth/token/renew-self" && r.Method == http.MethodPost:
+		if !f.tokenRenewable {
+			f.reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"token not renewable"}})
+			return
+		}
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   r.Header.Get("X-Vault-Token"),
+				"lease_duration": f.tokenDuration,
+				"renewable":      true,
+			},
+		})
+	case path == "kv/app" && r.Method == http.MethodGet:
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"lease_duration": 2764800,
+			"data":           map[string]interface{}{"password": "v1-secret", "port": 5432},
+		})
+	case path == "secret/data/app" && r.Method == http.MethodGet:
+		password := "second"
+		if r.URL.Query().Get("version") == "1" {
+			password = "first"
+		}
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]interface{}{"password": password},
+				"metadata": map[string]interface{}{"version": 2},
+			},
+		})
+	case path == "database/creds/readonly" && r.Method == http.MethodGet:
+		f.leaseCount++
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"lease_id":       fmt.Sprintf("database/creds/readonly/%d", f.leaseCount),
+			"lease_duration": f.leaseDuration,
+			"renewable":      true,
+			"data": map[string]interface{}{
+				"username": fmt.Sprintf("user-%d", f.leaseCount),
+				"password": fmt.Sprintf("password-%d", f.leaseCount),
+			},
+		})
+	case path == "sys/leases/renew" && r.Method == http.MethodPut:
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			f.reply(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{err.Error()}})
+			return
+		}
+		f.renewalCount++
+		f.reply(w, http.StatusOK, map[string]interface{}{
+			"lease_id":       body["lease_id"],
+			"lease_duration": f.renewDuration,
+			"renewable":      true,
+		})
+	default:
+		f.reply(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+	}
+}
+
+func (f *fakeVault) reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		f.t.Error(err)
+	}
+}
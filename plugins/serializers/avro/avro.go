@@ -0,0 +1,206 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// If SchemaRegistry is set, the output is in Confluent Wire Format
+// (https://docs.confluent.io/platform/current/schema-registry/fundamentals/serdes-develop/index.html#wire-format)
+// and the schema is registered or looked up in the registry.
+
+// Otherwise the output is Avro binary format without an attached schema or
+// schema fingerprint.
+
+type Serializer struct {
+	SchemaRegistry   string          `toml:"avro_schema_registry"`
+	CaCertPath       string          `toml:"avro_schema_registry_cert"`
+	AutoRegister     bool            `toml:"avro_schema_registry_auto_register"`
+	Subject          string          `toml:"avro_subject"`
+	SchemaMode       string          `toml:"avro_schema_mode"`
+	Schema           string          `toml:"avro_schema"`
+	SchemaVersion    string          `toml:"avro_schema_version"`
+	Namespace        string          `toml:"avro_namespace"`
+	MeasurementField string          `toml:"avro_measurement_field"`
+	Timestamp        string          `toml:"avro_timestamp"`
+	TimestampFormat  string          `toml:"avro_timestamp_format"`
+	Log              telegraf.Logger `toml:"-"`
+
+	registry *schemaRegistry
+	pinned   *recordSchema
+	derived  map[string]*recordSchema
+	mu       sync.Mutex
+}
+
+func (s *Serializer) Init() error {
+	switch s.SchemaMode {
+	case "":
+		s.SchemaMode = "derived"
+	case "derived", "pinned":
+	default:
+		return fmt.Errorf("invalid 'avro_schema_mode' %q", s.SchemaMode)
+	}
+
+	switch s.TimestampFormat {
+	case "":
+		s.TimestampFormat = "unix_ns"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid 'avro_timestamp_format' %q", s.TimestampFormat)
+	}
+
+	if s.Timestamp == "" {
+		s.Timestamp = "timestamp"
+	}
+	if s.Namespace == "" {
+		s.Namespace = "telegraf"
+	}
+	if s.SchemaVersion == "" {
+		s.SchemaVersion = "latest"
+	}
+
+	if s.SchemaRegistry != "" {
+		registry, err := newSchemaRegistry(s.SchemaRegistry, s.CaCertPath)
+		if err != nil {
+			return fmt.Errorf("error connecting to the schema registry %q: %w", s.SchemaRegistry, err)
+		}
+		s.registry = registry
+	}
+
+	switch s.SchemaMode {
+	case "derived":
+		if s.Schema != "" {
+			return errors.New("'avro_schema' can only be used with 'pinned' schema mode")
+		}
+		if s.registry == nil {
+			return errors.New("'derived' schema mode requires a schema registry")
+		}
+		s.derived = make(map[string]*recordSchema)
+	case "pinned":
+		if s.Schema == "" {
+			// The schema is taken from the given subject version in the registry
+			if s.registry == nil {
+				return errors.New("either 'avro_schema' or 'avro_schema_registry' must be specified")
+			}
+			if s.Subject == "" {
+				return errors.New("'avro_subject' is required to get the schema from the registry")
+			}
+			return nil
+		}
+		schema, err := parseSchema(s.Schema, s.MeasurementField, s.Timestamp)
+		if err != nil {
+			return err
+		}
+		s.pinned = schema
+	}
+
+	return nil
+}
+
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	schema, err := s.schema(m)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := schema.record(m, s.TimestampFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	if s.registry != nil {
+		// Confluent wire format header consisting of the magic byte and the
+		// schema ID
+		buf = make([]byte, 5, 64)
+		binary.BigEndian.PutUint32(buf[1:], uint32(schema.id))
+	}
+	return schema.codec.BinaryFromNative(buf, record)
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	serialized := make([]byte, 0)
+	for _, m := range metrics {
+		buf, err := s.Serialize(m)
+		if err != nil {
+			return nil, err
+		}
+		serialized = append(serialized, buf...)
+	}
+	return serialized, nil
+}
+
+// schema returns the record schema for the metric and makes sure the schema
+// is known to the registry if any
+func (s *Serializer) schema(m telegraf.Metric) (*recordSchema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var schema *recordSchema
+	switch s.SchemaMode {
+	case "pinned":
+		if s.pinned == nil {
+			id, definition, err := s.registry.version(s.Subject, s.SchemaVersion)
+			if err != nil {
+				return nil, err
+			}
+			pinned, err := parseSchema(definition, s.MeasurementField, s.Timestamp)
+			if err != nil {
+				return nil, fmt.Errorf("schema version %q of subject %q: %w", s.SchemaVersion, s.Subject, err)
+			}
+			pinned.id = id
+			pinned.registered = true
+			s.pinned = pinned
+		}
+		schema = s.pinned
+	case "derived":
+		key := signature(m)
+		var found bool
+		if schema, found = s.derived[key]; !found {
+			derived, err := deriveSchema(m, s.Namespace, s.MeasurementField, s.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+			s.derived[key] = derived
+			schema = derived
+		}
+	}
+
+	if s.registry == nil || schema.registered {
+		return schema, nil
+	}
+
+	// Use the fully qualified record name as subject if not specified
+	subject := s.Subject
+	if subject == "" {
+		subject = schema.fullname
+	}
+
+	var id int
+	var err error
+	if s.AutoRegister {
+		id, err = s.registry.register(subject, schema.schema)
+	} else {
+		id, err = s.registry.lookup(subject, schema.schema)
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema.id = id
+	schema.registered = true
+
+	return schema, nil
+}
+
+func init() {
+	serializers.Add("avro",
+		func() telegraf.Serializer {
+			return &Serializer{AutoRegister: true}
+		},
+	)
+}
@@ -0,0 +1,587 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const cpuSchema = `
+{
+  "type": "record",
+  "name": "cpu",
+  "namespace": "telegraf",
+  "fields": [
+    {"name": "timestamp", "type": "long"},
+    {"name": "host", "type": "string"},
+    {"name": "usage_idle", "type": "double"},
+    {"name": "usage_user", "type": ["null", "double"], "default": null},
+    {"name": "state", "type": {"type": "enum", "name": "state", "symbols": ["on", "off"]}, "default": "on"}
+  ]
+}
+`
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+type errorResponse struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// mockRegistry is a minimal Confluent Schema Registry
+type mockRegistry struct {
+	schemas  []string
+	subjects map[string][]int
+	requests []string
+	fail     bool
+	sync.Mutex
+}
+
+func newMockRegistry() *mockRegistry {
+	return &mockRegistry{subjects: make(map[string][]int)}
+}
+
+func (r *mockRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+	if r.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error_code":50001,"message":"Error in the backend data store"}`))
+		return
+	}
+
+	var body schemaRequest
+	if req.Method == http.MethodPost {
+		if req.Header.Get("Content-Type") != contentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "schemas" && parts[1] == "ids":
+		var id int
+		if _, err := fmt.Sscan(parts[2], &id); err != nil || id < 1 || id > len(r.schemas) {
+			r.notFound(w, 40403, "Schema not found")
+			return
+		}
+		r.respond(w, schemaResponse{Schema: r.schemas[id-1]})
+	case len(parts) == 3 && parts[0] == "subjects" && parts[2] == "versions" && req.Method == http.MethodPost:
+		id := r.find(body.Schema)
+		if id == 0 {
+			r.schemas = append(r.schemas, body.Schema)
+			id = len(r.schemas)
+		}
+		versions := r.subjects[parts[1]]
+		if !containsID(versions, id) {
+			r.subjects[parts[1]] = append(versions, id)
+		}
+		r.respond(w, schemaResponse{ID: id})
+	case len(parts) == 2 && parts[0] == "subjects" && req.Method == http.MethodPost:
+		id := r.find(body.Schema)
+		versions, found := r.subjects[parts[1]]
+		if !found {
+			r.notFound(w, 40401, "Subject not found")
+			return
+		}
+		for i, v := range versions {
+			if v == id {
+				r.respond(w, schemaResponse{ID: id, Schema: body.Schema, Version: i + 1})
+				return
+			}
+		}
+		r.notFound(w, 40403, "Schema not found")
+	case len(parts) == 4 && parts[0] == "subjects" && parts[2] == "versions" && req.Method == http.MethodGet:
+		versions, found := r.subjects[parts[1]]
+		if !found || len(versions) == 0 {
+			r.notFound(w, 40401, "Subject not found")
+			return
+		}
+		idx := len(versions) - 1
+		if parts[3] != "latest" {
+			if _, err := fmt.Sscan(parts[3], &idx); err != nil || idx < 1 || idx > len(versions) {
+				r.notFound(w, 40402, "Version not found")
+				return
+			}
+			idx--
+		}
+		id := versions[idx]
+		r.respond(w, schemaResponse{ID: id, Schema: r.schemas[id-1], Version: idx + 1})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *mockRegistry) find(schema string) int {
+	for i, s := range r.schemas {
+		if s == schema {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (*mockRegistry) respond(w http.ResponseWriter, resp schemaResponse) {
+	w.Header().Set("Content-Type", contentType)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (*mockRegistry) notFound(w http.ResponseWriter, code int, msg string) {
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(errorResponse{Code: code, Message: msg})
+}
+
+func containsID(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInitInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Serializer
+		expected string
+	}{
+		{
+			name:     "invalid schema mode",
+			plugin:   &Serializer{SchemaMode: "foo"},
+			expected: `invalid 'avro_schema_mode' "foo"`,
+		},
+		{
+			name:     "invalid timestamp format",
+			plugin:   &Serializer{SchemaMode: "pinned", Schema: cpuSchema, TimestampFormat: "rfc3339"},
+			expected: `invalid 'avro_timestamp_format' "rfc3339"`,
+		},
+		{
+			name:     "derived without registry",
+			plugin:   &Serializer{},
+			expected: "'derived' schema mode requires a schema registry",
+		},
+		{
+			name:     "derived with schema",
+			plugin:   &Serializer{SchemaRegistry: "http://localhost:8081", Schema: cpuSchema},
+			expected: "'avro_schema' can only be used with 'pinned' schema mode",
+		},
+		{
+			name:     "pinned without schema",
+			plugin:   &Serializer{SchemaMode: "pinned"},
+			expected: "either 'avro_schema' or 'avro_schema_registry' must be specified",
+		},
+		{
+			name:     "pinned without subject",
+			plugin:   &Serializer{SchemaMode: "pinned", SchemaRegistry: "http://localhost:8081"},
+			expected: "'avro_subject' is required to get the schema from the registry",
+		},
+		{
+			name:     "pinned with non-record schema",
+			plugin:   &Serializer{SchemaMode: "pinned", Schema: `{"type": "string"}`},
+			expected: `schema type must be 'record' but is "string"`,
+		},
+		{
+			name:     "pinned with invalid schema",
+			plugin:   &Serializer{SchemaMode: "pinned", Schema: `{"type": "record", "name": "cpu", "fields": [{"name": "x"}]}`},
+			expected: "invalid schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestSerializePinned(t *testing.T) {
+	plugin := &Serializer{
+		SchemaMode: "pinned",
+		Schema:     cpuSchema,
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage_idle": 98.5},
+		time.Unix(1700000000, 123),
+	)
+	buf, err := plugin.Serialize(m)
+	require.NoError(t, err)
+
+	// Without a registry the output is bare Avro binary
+	codec, err := goavro.NewCodec(cpuSchema)
+	require.NoError(t, err)
+	native, remaining, err := codec.NativeFromBinary(buf)
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+
+	expected := map[string]interface{}{
+		"timestamp":  int64(1700000000000000123),
+		"host":       "server01",
+		"usage_idle": 98.5,
+		"usage_user": nil,
+		"state":      "on",
+	}
+	require.Equal(t, expected, native)
+}
+
+func TestSerializePinnedMissingField(t *testing.T) {
+	plugin := &Serializer{
+		SchemaMode: "pinned",
+		Schema:     cpuSchema,
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"usage_idle": 98.5},
+		time.Unix(1700000000, 0),
+	)
+	_, err := plugin.Serialize(m)
+	require.ErrorContains(t, err, `no value for record field "host"`)
+}
+
+func TestSerializePinnedTimestampFormats(t *testing.T) {
+	schema := `
+{
+  "type": "record",
+  "name": "ts",
+  "fields": [
+    {"name": "time", "type": "long"},
+    {"name": "value", "type": "long"}
+  ]
+}
+`
+	ts := time.Unix(1700000000, 123456789).UTC()
+	tests := []struct {
+		format   string
+		expected int64
+	}{
+		{format: "unix", expected: 1700000000},
+		{format: "unix_ms", expected: 1700000000123},
+		{format: "unix_us", expected: 1700000000123456},
+		{format: "unix_ns", expected: 1700000000123456789},
+	}
+
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			plugin := &Serializer{
+				SchemaMode:      "pinned",
+				Schema:          schema,
+				Timestamp:       "time",
+				TimestampFormat: tt.format,
+			}
+			require.NoError(t, plugin.Init())
+
+			m := metric.New(
+				"test",
+				map[string]string{},
+				map[string]interface{}{"value": 42},
+				ts,
+			)
+			buf, err := plugin.Serialize(m)
+			require.NoError(t, err)
+
+			native, _, err := codec.NativeFromBinary(buf)
+			require.NoError(t, err)
+			record := native.(map[string]interface{})
+			require.Equal(t, tt.expected, record["time"])
+			require.Equal(t, int64(42), record["value"])
+		})
+	}
+}
+
+func TestSerializePinnedLogicalTimestamp(t *testing.T) {
+	schema := `
+{
+  "type": "record",
+  "name": "ts",
+  "fields": [
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "value", "type": ["null", "string", "long"]}
+  ]
+}
+`
+	plugin := &Serializer{
+		SchemaMode: "pinned",
+		Schema:     schema,
+		Timestamp:  "time",
+	}
+	require.NoError(t, plugin.Init())
+
+	ts := time.Unix(1700000000, 123456789).UTC()
+	m := metric.New("test", map[string]string{}, map[string]interface{}{"value": 42}, ts)
+	buf, err := plugin.Serialize(m)
+	require.NoError(t, err)
+
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+	native, _, err := codec.NativeFromBinary(buf)
+	require.NoError(t, err)
+	record := native.(map[string]interface{})
+	require.Equal(t, ts.Truncate(time.Millisecond), record["time"].(time.Time).UTC())
+
+	// The first matching branch of the union is used
+	require.Equal(t, map[string]interface{}{"string": "42"}, record["value"])
+}
+
+func TestSerializeDerived(t *testing.T) {
+	registry := newMockRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	plugin := &Serializer{
+		SchemaRegistry:   server.URL,
+		MeasurementField: "measurement",
+		AutoRegister:     true,
+	}
+	require.NoError(t, plugin.Init())
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "cpu-total": "true"},
+			map[string]interface{}{"usage_idle": 98.5, "count": uint64(3), "ok": true},
+			time.Unix(1700000000, 0),
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02", "cpu-total": "false"},
+			map[string]interface{}{"count": uint64(4), "usage_idle": 12.5, "ok": false},
+			time.Unix(1700000010, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"used": int64(1024)},
+			time.Unix(1700000020, 0),
+		),
+	}
+
+	var ids []uint32
+	for _, m := range metrics {
+		buf, err := plugin.Serialize(m)
+		require.NoError(t, err)
+		require.Equal(t, byte(0), buf[0])
+		ids = append(ids, binary.BigEndian.Uint32(buf[1:5]))
+	}
+	require.Equal(t, []uint32{1, 1, 2}, ids)
+
+	// Each schema is registered only once under the record name as subject
+	require.Equal(t, []string{
+		"POST /subjects/telegraf.cpu/versions",
+		"POST /subjects/telegraf.mem/versions",
+	}, registry.requests)
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(registry.schemas[0]), &schema))
+	require.Equal(t, "cpu", schema["name"])
+	require.Equal(t, "telegraf", schema["namespace"])
+	var names []string
+	for _, f := range schema["fields"].([]interface{}) {
+		names = append(names, f.(map[string]interface{})["name"].(string))
+	}
+	require.Equal(t, []string{"timestamp", "measurement", "cpu_total", "host", "count", "ok", "usage_idle"}, names)
+}
+
+func TestSerializeDerivedRoundTrip(t *testing.T) {
+	registry := newMockRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	plugin := &Serializer{
+		SchemaRegistry:   server.URL,
+		MeasurementField: "measurement",
+		AutoRegister:     true,
+	}
+	require.NoError(t, plugin.Init())
+
+	parser := &avro.Parser{
+		SchemaRegistry:   server.URL,
+		MeasurementField: "measurement",
+		Tags:             []string{"host"},
+		Fields:           []string{"count", "mode", "usage_idle"},
+		Timestamp:        "timestamp",
+		TimestampFormat:  "unix_ns",
+		UnionMode:        "nullable",
+	}
+	require.NoError(t, parser.Init())
+
+	input := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage_idle": 98.5, "count": int64(3), "mode": "user"},
+		time.Unix(1700000000, 123),
+	)
+
+	buf, err := plugin.Serialize(input)
+	require.NoError(t, err)
+
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{input}, actual)
+}
+
+func TestSerializeLookupWithoutAutoRegister(t *testing.T) {
+	registry := newMockRegistry()
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	plugin := &Serializer{
+		SchemaRegistry: server.URL,
+		Subject:        "metrics-value",
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage_idle": 98.5},
+		time.Unix(1700000000, 0),
+	)
+
+	// The schema is not registered yet so serialization must fail
+	_, err := plugin.Serialize(m)
+	require.ErrorContains(t, err, `looking up schema for subject "metrics-value" failed`)
+	require.ErrorContains(t, err, "Subject not found (error code 40401)")
+
+	// Register the schema externally and retry
+	schema, err := deriveSchema(m, "telegraf", "", "timestamp")
+	require.NoError(t, err)
+	registry.schemas = append(registry.schemas, "{}", schema.schema)
+	registry.subjects["metrics-value"] = []int{2}
+
+	buf, err := plugin.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 2}, buf[:5])
+}
+
+func TestSerializePinnedFromRegistry(t *testing.T) {
+	registry := newMockRegistry()
+	registry.schemas = []string{cpuSchema, strings.Replace(cpuSchema, `"name": "state",`, `"name": "status",`, 1)}
+	registry.subjects["cpu-value"] = []int{1, 2}
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage_idle": 98.5, "usage_user": 1.5},
+		time.Unix(1700000000, 0),
+	)
+
+	tests := []struct {
+		version    string
+		expectedID uint32
+	}{
+		{version: "", expectedID: 2},
+		{version: "1", expectedID: 1},
+	}
+	for _, tt := range tests {
+		t.Run("version "+tt.version, func(t *testing.T) {
+			plugin := &Serializer{
+				SchemaRegistry: server.URL,
+				SchemaMode:     "pinned",
+				Subject:        "cpu-value",
+				SchemaVersion:  tt.version,
+				AutoRegister:   true,
+			}
+			require.NoError(t, plugin.Init())
+
+			buf, err := plugin.SerializeBatch([]telegraf.Metric{m, m})
+			require.NoError(t, err)
+			require.Equal(t, byte(0), buf[0])
+			require.Equal(t, tt.expectedID, binary.BigEndian.Uint32(buf[1:5]))
+
+			codec, err := goavro.NewCodec(registry.schemas[tt.expectedID-1])
+			require.NoError(t, err)
+			native, remaining, err := codec.NativeFromBinary(buf[5:])
+			require.NoError(t, err)
+			require.Equal(t, "server01", native.(map[string]interface{})["host"])
+			require.Equal(t, map[string]interface{}{"double": 1.5}, native.(map[string]interface{})["usage_user"])
+
+			// The second metric uses the same wire format
+			require.Equal(t, buf[:5], remaining[:5])
+		})
+	}
+}
+
+func TestSerializeRegistryUnavailable(t *testing.T) {
+	registry := newMockRegistry()
+	registry.fail = true
+	server := httptest.NewServer(registry)
+	defer server.Close()
+
+	plugin := &Serializer{
+		SchemaRegistry: server.URL,
+		AutoRegister:   true,
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42},
+		time.Unix(1700000000, 0),
+	)
+	_, err := plugin.Serialize(m)
+	require.ErrorContains(t, err, "Error in the backend data store (error code 50001)")
+
+	// Failures must not be cached
+	registry.Lock()
+	registry.fail = false
+	registry.Unlock()
+	buf, err := plugin.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 1}, buf[:5])
+}
+
+func TestRegistryBasicAuth(t *testing.T) {
+	registry := newMockRegistry()
+	var username, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		registry.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	plugin := &Serializer{
+		SchemaRegistry: strings.Replace(server.URL, "http://", "http://user:secret@", 1),
+		AutoRegister:   true,
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+	_, err := plugin.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "user", username)
+	require.Equal(t, "secret", password)
+}
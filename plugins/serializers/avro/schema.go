@@ -0,0 +1,414 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Source of the value of a record field
+type source int
+
+const (
+	sourceAuto source = iota
+	sourceTag
+	sourceField
+	sourceMeasurement
+	sourceTimestamp
+)
+
+var primitiveTypes = []string{"null", "boolean", "int", "long", "float", "double", "bytes", "string"}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// fieldType is a non-union type of a record field
+type fieldType struct {
+	name    string
+	logical string
+}
+
+// unionName returns the name used by goavro to select the union branch
+func (t fieldType) unionName() string {
+	if t.logical != "" {
+		return t.name + "." + t.logical
+	}
+	return t.name
+}
+
+type recordField struct {
+	name       string
+	types      []fieldType
+	union      bool
+	nullable   bool
+	hasDefault bool
+
+	// Where to take the value of the field from
+	source source
+	key    string
+}
+
+// recordSchema is an Avro record schema used to encode metrics
+type recordSchema struct {
+	fullname string
+	schema   string
+	fields   []recordField
+	codec    *goavro.Codec
+
+	// ID of the schema in the schema registry if registered
+	id         int
+	registered bool
+}
+
+// parseSchema parses a record schema given by the user. The values of the
+// record fields are taken from the tags or fields of the same name if the
+// field is not the configured measurement or timestamp field.
+func parseSchema(schema, measurementField, timestampField string) (*recordSchema, error) {
+	var definition struct {
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		Fields    []struct {
+			Name    string           `json:"name"`
+			Type    interface{}      `json:"type"`
+			Default *json.RawMessage `json:"default"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schema), &definition); err != nil {
+		return nil, fmt.Errorf("decoding schema failed: %w", err)
+	}
+	if definition.Type != "record" {
+		return nil, fmt.Errorf("schema type must be 'record' but is %q", definition.Type)
+	}
+
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	fullname := definition.Name
+	if definition.Namespace != "" && !strings.Contains(fullname, ".") {
+		fullname = definition.Namespace + "." + fullname
+	}
+
+	fields := make([]recordField, 0, len(definition.Fields))
+	for _, f := range definition.Fields {
+		field := recordField{
+			name:       f.Name,
+			key:        f.Name,
+			hasDefault: f.Default != nil,
+		}
+		switch f.Name {
+		case measurementField:
+			field.source = sourceMeasurement
+		case timestampField:
+			field.source = sourceTimestamp
+		}
+
+		if branches, ok := f.Type.([]interface{}); ok {
+			field.union = true
+			for _, branch := range branches {
+				t, err := parseFieldType(branch)
+				if err != nil {
+					return nil, fmt.Errorf("field %q: %w", f.Name, err)
+				}
+				if t.name == "null" {
+					field.nullable = true
+				}
+				field.types = append(field.types, t)
+			}
+		} else {
+			t, err := parseFieldType(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", f.Name, err)
+			}
+			field.types = []fieldType{t}
+		}
+		fields = append(fields, field)
+	}
+
+	return &recordSchema{
+		fullname: fullname,
+		schema:   schema,
+		fields:   fields,
+		codec:    codec,
+	}, nil
+}
+
+func parseFieldType(definition interface{}) (fieldType, error) {
+	switch d := definition.(type) {
+	case string:
+		if slices.Contains(primitiveTypes, d) {
+			return fieldType{name: d}, nil
+		}
+		return fieldType{}, fmt.Errorf("unsupported type %q", d)
+	case map[string]interface{}:
+		name, _ := d["type"].(string)
+		logical, _ := d["logicalType"].(string)
+		switch {
+		case name == "enum":
+			return fieldType{name: "enum"}, nil
+		case slices.Contains(primitiveTypes, name):
+			return fieldType{name: name, logical: logical}, nil
+		}
+		return fieldType{}, fmt.Errorf("unsupported type %q", name)
+	}
+	return fieldType{}, fmt.Errorf("unsupported type definition %v", definition)
+}
+
+// deriveSchema creates a record schema from the measurement name, tags and
+// fields of the metric. All tags and fields are optional to allow the schema
+// to evolve in a compatible way.
+func deriveSchema(m telegraf.Metric, namespace, measurementField, timestampField string) (*recordSchema, error) {
+	fields := make([]recordField, 0, len(m.TagList())+len(m.FieldList())+2)
+	fields = append(fields, recordField{
+		name:   timestampField,
+		types:  []fieldType{{name: "long"}},
+		source: sourceTimestamp,
+	})
+	if measurementField != "" {
+		fields = append(fields, recordField{
+			name:   measurementField,
+			types:  []fieldType{{name: "string"}},
+			source: sourceMeasurement,
+		})
+	}
+	for _, tag := range m.TagList() {
+		fields = append(fields, recordField{
+			name:       sanitize(tag.Key),
+			types:      []fieldType{{name: "null"}, {name: "string"}},
+			union:      true,
+			nullable:   true,
+			hasDefault: true,
+			source:     sourceTag,
+			key:        tag.Key,
+		})
+	}
+	for _, field := range sortedFields(m) {
+		var typename string
+		switch field.Value.(type) {
+		case string:
+			typename = "string"
+		case bool:
+			typename = "boolean"
+		case int64, uint64:
+			typename = "long"
+		case float64:
+			typename = "double"
+		default:
+			return nil, fmt.Errorf("unsupported type %T of field %q", field.Value, field.Key)
+		}
+		fields = append(fields, recordField{
+			name:       sanitize(field.Key),
+			types:      []fieldType{{name: "null"}, {name: typename}},
+			union:      true,
+			nullable:   true,
+			hasDefault: true,
+			source:     sourceField,
+			key:        field.Key,
+		})
+	}
+
+	// Create the schema definition
+	definitions := make([]map[string]interface{}, 0, len(fields))
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if names[f.name] {
+			return nil, fmt.Errorf("duplicate record field %q", f.name)
+		}
+		names[f.name] = true
+
+		definition := map[string]interface{}{"name": f.name}
+		if f.union {
+			types := make([]string, 0, len(f.types))
+			for _, t := range f.types {
+				types = append(types, t.name)
+			}
+			definition["type"] = types
+			definition["default"] = nil
+		} else {
+			definition["type"] = f.types[0].name
+		}
+		definitions = append(definitions, definition)
+	}
+	name := sanitize(m.Name())
+	schema, err := json.Marshal(map[string]interface{}{
+		"type":      "record",
+		"name":      name,
+		"namespace": namespace,
+		"fields":    definitions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid derived schema: %w", err)
+	}
+
+	return &recordSchema{
+		fullname: namespace + "." + name,
+		schema:   string(schema),
+		fields:   fields,
+		codec:    codec,
+	}, nil
+}
+
+// signature returns a key identifying the derived schema of the metric
+func signature(m telegraf.Metric) string {
+	var sb strings.Builder
+	sb.WriteString(m.Name())
+	for _, tag := range m.TagList() {
+		sb.WriteString("\x00t")
+		sb.WriteString(tag.Key)
+	}
+	for _, field := range sortedFields(m) {
+		sb.WriteString("\x00f")
+		sb.WriteString(field.Key)
+		fmt.Fprintf(&sb, "\x00%T", field.Value)
+	}
+	return sb.String()
+}
+
+// sortedFields returns the fields of the metric sorted by key to get the same
+// schema independent of the order of the fields
+func sortedFields(m telegraf.Metric) []*telegraf.Field {
+	fields := slices.Clone(m.FieldList())
+	slices.SortFunc(fields, func(a, b *telegraf.Field) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return fields
+}
+
+// sanitize converts the name to a valid Avro name
+func sanitize(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// record converts the metric to the native representation of the record
+func (rs *recordSchema) record(m telegraf.Metric, timestampFormat string) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(rs.fields))
+	for _, f := range rs.fields {
+		value, found := f.value(m)
+		if !found {
+			switch {
+			case f.hasDefault:
+				// The default value is used by the codec
+			case f.nullable:
+				record[f.name] = nil
+			default:
+				return nil, fmt.Errorf("no value for record field %q", f.name)
+			}
+			continue
+		}
+
+		v, err := f.convert(value, timestampFormat)
+		if err != nil {
+			return nil, fmt.Errorf("record field %q: %w", f.name, err)
+		}
+		record[f.name] = v
+	}
+	return record, nil
+}
+
+func (f *recordField) value(m telegraf.Metric) (interface{}, bool) {
+	switch f.source {
+	case sourceMeasurement:
+		return m.Name(), true
+	case sourceTimestamp:
+		return m.Time(), true
+	case sourceTag:
+		return m.GetTag(f.key)
+	case sourceField:
+		return m.GetField(f.key)
+	}
+
+	if v, found := m.GetTag(f.key); found {
+		return v, true
+	}
+	return m.GetField(f.key)
+}
+
+// convert converts the value to the native representation of the field
+// type. For unions the first non-null type the value can be converted to is
+// used.
+func (f *recordField) convert(value interface{}, timestampFormat string) (interface{}, error) {
+	if !f.union {
+		return convertValue(value, f.types[0], timestampFormat)
+	}
+
+	for _, t := range f.types {
+		if t.name == "null" {
+			continue
+		}
+		if v, err := convertValue(value, t, timestampFormat); err == nil {
+			return goavro.Union(t.unionName(), v), nil
+		}
+	}
+	return nil, fmt.Errorf("value %v of type %T does not match any type of the union", value, value)
+}
+
+func convertValue(value interface{}, t fieldType, timestampFormat string) (interface{}, error) {
+	if ts, ok := value.(time.Time); ok {
+		return convertTimestamp(ts, t, timestampFormat)
+	}
+
+	switch t.name {
+	case "boolean":
+		return internal.ToBool(value)
+	case "int":
+		return internal.ToInt32(value)
+	case "long":
+		return internal.ToInt64(value)
+	case "float":
+		return internal.ToFloat32(value)
+	case "double":
+		return internal.ToFloat64(value)
+	case "string", "enum":
+		return internal.ToString(value)
+	case "bytes":
+		v, err := internal.ToString(value)
+		return []byte(v), err
+	}
+	return nil, fmt.Errorf("cannot convert value to type %q", t.name)
+}
+
+func convertTimestamp(ts time.Time, t fieldType, timestampFormat string) (interface{}, error) {
+	switch t.logical {
+	case "timestamp-millis", "timestamp-micros":
+		return ts, nil
+	}
+
+	switch t.name {
+	case "long":
+		switch timestampFormat {
+		case "unix":
+			return ts.Unix(), nil
+		case "unix_ms":
+			return ts.UnixMilli(), nil
+		case "unix_us":
+			return ts.UnixMicro(), nil
+		case "unix_ns":
+			return ts.UnixNano(), nil
+		}
+		return nil, fmt.Errorf("invalid timestamp format %q", timestampFormat)
+	case "double":
+		return float64(ts.UnixNano()) / float64(time.Second), nil
+	case "string":
+		return ts.Format(time.RFC3339Nano), nil
+	}
+	return nil, errors.New("timestamp requires a long, double or string type")
+}
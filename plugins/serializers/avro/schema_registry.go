@@ -0,0 +1,96 @@
+package avro
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	common_avro "github.com/influxdata/telegraf/plugins/common/avro"
+)
+
+type schemaRegistry struct {
+	client *common_avro.SchemaRegistry
+
+	// Schema IDs cached by subject and schema
+	cache map[string]int
+	mu    sync.Mutex
+}
+
+type schemaRequest struct {
+	Schema string `json:"schema"`
+}
+
+type schemaResponse struct {
+	ID      int    `json:"id"`
+	Schema  string `json:"schema"`
+	Version int    `json:"version"`
+}
+
+func newSchemaRegistry(addr, caCertPath string) (*schemaRegistry, error) {
+	client, err := common_avro.NewSchemaRegistry(addr, caCertPath)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaRegistry{
+		client: client,
+		cache:  make(map[string]int),
+	}, nil
+}
+
+// register registers the schema under the given subject if not registered yet
+// and returns the ID of the schema
+func (sr *schemaRegistry) register(subject, schema string) (int, error) {
+	return sr.cached(subject, schema, func() (int, error) {
+		var resp schemaResponse
+		endpoint := "/subjects/" + url.PathEscape(subject) + "/versions"
+		if err := sr.client.Do(http.MethodPost, endpoint, &schemaRequest{Schema: schema}, &resp); err != nil {
+			return 0, fmt.Errorf("registering schema for subject %q failed: %w", subject, err)
+		}
+		return resp.ID, nil
+	})
+}
+
+// lookup returns the ID of the schema already registered under the given
+// subject
+func (sr *schemaRegistry) lookup(subject, schema string) (int, error) {
+	return sr.cached(subject, schema, func() (int, error) {
+		var resp schemaResponse
+		endpoint := "/subjects/" + url.PathEscape(subject)
+		if err := sr.client.Do(http.MethodPost, endpoint, &schemaRequest{Schema: schema}, &resp); err != nil {
+			return 0, fmt.Errorf("looking up schema for subject %q failed: %w", subject, err)
+		}
+		return resp.ID, nil
+	})
+}
+
+// version returns the ID and the schema of the given version of the subject
+func (sr *schemaRegistry) version(subject, version string) (int, string, error) {
+	var resp schemaResponse
+	endpoint := "/subjects/" + url.PathEscape(subject) + "/versions/" + url.PathEscape(version)
+	if err := sr.client.Do(http.MethodGet, endpoint, nil, &resp); err != nil {
+		return 0, "", fmt.Errorf("getting version %q of subject %q failed: %w", version, subject, err)
+	}
+	return resp.ID, resp.Schema, nil
+}
+
+func (sr *schemaRegistry) cached(subject, schema string, query func() (int, error)) (int, error) {
+	key := subject + "\x00" + schema
+
+	sr.mu.Lock()
+	id, found := sr.cache[key]
+	sr.mu.Unlock()
+	if found {
+		return id, nil
+	}
+
+	id, err := query()
+	if err != nil {
+		return 0, err
+	}
+
+	sr.mu.Lock()
+	sr.cache[key] = id
+	sr.mu.Unlock()
+	return id, nil
+}
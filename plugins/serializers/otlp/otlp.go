@@ -0,0 +1,94 @@
+package otlp
+
+import (
+	"fmt"
+
+	"github.com/influxdata/influxdb-observability/influx2otel"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	otel "github.com/influxdata/telegraf/plugins/common/opentelemetry"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type Serializer struct {
+	Format     string            `toml:"otlp_format"`
+	Attributes map[string]string `toml:"otlp_resource_attributes"`
+	Log        telegraf.Logger   `toml:"-"`
+
+	converter *influx2otel.LineProtocolToOtelMetrics
+}
+
+func (s *Serializer) Init() error {
+	switch s.Format {
+	case "":
+		s.Format = "protobuf"
+	case "protobuf", "json":
+	default:
+		return fmt.Errorf("invalid 'otlp_format' %q", s.Format)
+	}
+
+	converter, err := influx2otel.NewLineProtocolToOtelMetrics(&otel.Logger{Logger: s.Log})
+	if err != nil {
+		return fmt.Errorf("creating converter failed: %w", err)
+	}
+	s.converter = converter
+
+	return nil
+}
+
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	vType, err := otel.ValueType(m.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	batch := s.converter.NewBatch()
+	if err := batch.AddPoint(m.Name(), m.Tags(), m.Fields(), m.Time(), vType); err != nil {
+		return nil, fmt.Errorf("converting metric failed: %w", err)
+	}
+	return s.encode(pmetricotlp.NewExportRequestFromMetrics(batch.GetMetrics()))
+}
+
+// SerializeBatch serializes all metrics into a single export request. Metrics
+// that cannot be converted are skipped.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	batch := s.converter.NewBatch()
+	for _, m := range metrics {
+		vType, err := otel.ValueType(m.Type())
+		if err != nil {
+			s.Log.Warnf("Skipping metric %q: %v", m.Name(), err)
+			continue
+		}
+		if err := batch.AddPoint(m.Name(), m.Tags(), m.Fields(), m.Time(), vType); err != nil {
+			s.Log.Warnf("Skipping metric %q: converting failed: %v", m.Name(), err)
+			continue
+		}
+	}
+	return s.encode(pmetricotlp.NewExportRequestFromMetrics(batch.GetMetrics()))
+}
+
+func (s *Serializer) encode(request pmetricotlp.ExportRequest) ([]byte, error) {
+	if len(s.Attributes) > 0 {
+		resourceMetrics := request.Metrics().ResourceMetrics()
+		for i := range resourceMetrics.Len() {
+			attributes := resourceMetrics.At(i).Resource().Attributes()
+			for k, v := range s.Attributes {
+				attributes.PutStr(k, v)
+			}
+		}
+	}
+
+	if s.Format == "json" {
+		return request.MarshalJSON()
+	}
+	return request.MarshalProto()
+}
+
+func init() {
+	serializers.Add("otlp",
+		func() telegraf.Serializer {
+			return &Serializer{}
+		},
+	)
+}
@@ -0,0 +1,144 @@
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/otlp"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var ts = time.Unix(1700000000, 123456789)
+
+var testMetrics = []telegraf.Metric{
+	metric.New(
+		"cpu_temperature",
+		map[string]string{"host.name": "server01", "cpu": "cpu0"},
+		map[string]interface{}{"gauge": 42.5},
+		ts,
+		telegraf.Gauge,
+	),
+	metric.New(
+		"http_requests_total",
+		map[string]string{"host.name": "server01", "method": "GET"},
+		map[string]interface{}{"counter": int64(1027)},
+		ts,
+		telegraf.Counter,
+	),
+	metric.New(
+		"request_duration_seconds",
+		map[string]string{"host.name": "server01"},
+		map[string]interface{}{"count": float64(10), "sum": 3.5, "0.1": float64(4), "1": float64(9), "+Inf": float64(10)},
+		ts,
+		telegraf.Histogram,
+	),
+}
+
+func TestInitInvalid(t *testing.T) {
+	serializer := &Serializer{Format: "xml"}
+	require.ErrorContains(t, serializer.Init(), `invalid 'otlp_format' "xml"`)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	for _, format := range []string{"protobuf", "json"} {
+		t.Run(format, func(t *testing.T) {
+			serializer := &Serializer{
+				Format:     format,
+				Attributes: map[string]string{"deployment.environment": "production"},
+				Log:        testutil.Logger{},
+			}
+			require.NoError(t, serializer.Init())
+
+			buf, err := serializer.SerializeBatch(testMetrics)
+			require.NoError(t, err)
+
+			request := pmetricotlp.NewExportRequest()
+			if format == "json" {
+				require.NoError(t, request.UnmarshalJSON(buf))
+			} else {
+				require.NoError(t, request.UnmarshalProto(buf))
+			}
+
+			// All metrics share the same resource derived from the semantic
+			// convention tags
+			md := request.Metrics()
+			require.Equal(t, 1, md.ResourceMetrics().Len())
+			resource := md.ResourceMetrics().At(0).Resource()
+			require.Equal(t, map[string]interface{}{
+				"host.name":              "server01",
+				"deployment.environment": "production",
+			}, resource.Attributes().AsRaw())
+
+			metrics := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+			types := make(map[string]pmetric.MetricType, metrics.Len())
+			for i := range metrics.Len() {
+				types[metrics.At(i).Name()] = metrics.At(i).Type()
+			}
+			require.Equal(t, map[string]pmetric.MetricType{
+				"cpu_temperature":          pmetric.MetricTypeGauge,
+				"http_requests_total":      pmetric.MetricTypeSum,
+				"request_duration_seconds": pmetric.MetricTypeHistogram,
+			}, types)
+		})
+	}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	for _, format := range []string{"protobuf", "json"} {
+		t.Run(format, func(t *testing.T) {
+			serializer := &Serializer{Format: format, Log: testutil.Logger{}}
+			require.NoError(t, serializer.Init())
+
+			parser := &otlp.Parser{Format: format, Log: testutil.Logger{}}
+			require.NoError(t, parser.Init())
+
+			// Single metrics
+			for _, m := range testMetrics {
+				buf, err := serializer.Serialize(m)
+				require.NoError(t, err)
+
+				actual, err := parser.Parse(buf)
+				require.NoError(t, err)
+				testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, actual)
+			}
+
+			// Batches
+			buf, err := serializer.SerializeBatch(testMetrics)
+			require.NoError(t, err)
+
+			actual, err := parser.Parse(buf)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, testMetrics, actual, testutil.SortMetrics())
+		})
+	}
+}
+
+func TestSerializeInvalidMetric(t *testing.T) {
+	serializer := &Serializer{Log: testutil.Logger{}}
+	require.NoError(t, serializer.Init())
+
+	// Histograms require a 'sum' field in the prometheus schema
+	invalid := metric.New(
+		"request_duration_seconds",
+		map[string]string{},
+		map[string]interface{}{"count": float64(10), "+Inf": float64(10)},
+		ts,
+		telegraf.Histogram,
+	)
+	_, err := serializer.Serialize(invalid)
+	require.ErrorContains(t, err, "converting metric failed")
+
+	// Invalid metrics are skipped in batches
+	buf, err := serializer.SerializeBatch([]telegraf.Metric{invalid, testMetrics[0]})
+	require.NoError(t, err)
+
+	request := pmetricotlp.NewExportRequest()
+	require.NoError(t, request.UnmarshalProto(buf))
+	require.Equal(t, 1, request.Metrics().DataPointCount())
+}
@@ -0,0 +1,190 @@
+package protobuf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+const timestampMessage = "google.protobuf.Timestamp"
+
+// fieldPath is the chain of field descriptors leading from the top-level
+// message to the target field through nested messages
+type fieldPath []protoreflect.FieldDescriptor
+
+// resolvePath resolves the dot-separated field names to a singular,
+// non-message field or a timestamp message
+func resolvePath(desc protoreflect.MessageDescriptor, name string) (fieldPath, error) {
+	path, err := resolve(desc, name)
+	if err != nil {
+		return nil, err
+	}
+	fd := path.leaf()
+	if fd.Cardinality() == protoreflect.Repeated {
+		return nil, fmt.Errorf("field %q is a repeated field", name)
+	}
+	if fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() != timestampMessage {
+		return nil, fmt.Errorf("field %q of message type %q is not supported", name, fd.Message().FullName())
+	}
+	return path, nil
+}
+
+// resolveMapPath resolves the dot-separated field names to a map field with
+// string keys and scalar values
+func resolveMapPath(desc protoreflect.MessageDescriptor, name string) (fieldPath, error) {
+	path, err := resolve(desc, name)
+	if err != nil {
+		return nil, err
+	}
+	fd := path.leaf()
+	if !fd.IsMap() || fd.MapKey().Kind() != protoreflect.StringKind {
+		return nil, fmt.Errorf("field %q is not a map with string keys", name)
+	}
+	if kind := fd.MapValue().Kind(); kind == protoreflect.MessageKind || kind == protoreflect.GroupKind {
+		return nil, fmt.Errorf("field %q must have scalar map values", name)
+	}
+	return path, nil
+}
+
+func resolve(desc protoreflect.MessageDescriptor, name string) (fieldPath, error) {
+	parts := strings.Split(name, ".")
+	path := make(fieldPath, 0, len(parts))
+	for i, part := range parts {
+		fd := desc.Fields().ByName(protoreflect.Name(part))
+		if fd == nil {
+			return nil, fmt.Errorf("message %q has no field %q", desc.FullName(), part)
+		}
+		path = append(path, fd)
+		if i == len(parts)-1 {
+			break
+		}
+		if fd.Kind() != protoreflect.MessageKind || fd.Cardinality() == protoreflect.Repeated {
+			return nil, fmt.Errorf("field %q is not a singular message field", strings.Join(parts[:i+1], "."))
+		}
+		desc = fd.Message()
+	}
+	return path, nil
+}
+
+func (p fieldPath) leaf() protoreflect.FieldDescriptor {
+	return p[len(p)-1]
+}
+
+// parent returns the message containing the leaf field, creating all
+// intermediate messages
+func (p fieldPath) parent(msg protoreflect.Message) protoreflect.Message {
+	for _, fd := range p[:len(p)-1] {
+		msg = msg.Mutable(fd).Message()
+	}
+	return msg
+}
+
+func (p fieldPath) set(msg protoreflect.Message, value interface{}, timestampFormat string) error {
+	parent := p.parent(msg)
+	fd := p.leaf()
+
+	var v protoreflect.Value
+	var err error
+	if ts, ok := value.(time.Time); ok {
+		v, err = convertTimestamp(parent, fd, ts, timestampFormat)
+	} else {
+		v, err = convertValue(fd, value)
+	}
+	if err != nil {
+		return err
+	}
+	parent.Set(fd, v)
+	return nil
+}
+
+func (p fieldPath) setMapEntry(msg protoreflect.Message, key string, value interface{}) error {
+	fd := p.leaf()
+	v, err := convertValue(fd.MapValue(), value)
+	if err != nil {
+		return err
+	}
+	p.parent(msg).Mutable(fd).Map().Set(protoreflect.ValueOfString(key).MapKey(), v)
+	return nil
+}
+
+func convertValue(fd protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		v, err := internal.ToBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := internal.ToInt32(value)
+		return protoreflect.ValueOfInt32(v), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := internal.ToInt64(value)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := internal.ToUint32(value)
+		return protoreflect.ValueOfUint32(v), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := internal.ToUint64(value)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := internal.ToFloat32(value)
+		return protoreflect.ValueOfFloat32(v), err
+	case protoreflect.DoubleKind:
+		v, err := internal.ToFloat64(value)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.StringKind:
+		v, err := internal.ToString(value)
+		return protoreflect.ValueOfString(v), err
+	case protoreflect.BytesKind:
+		v, err := internal.ToString(value)
+		return protoreflect.ValueOfBytes([]byte(v)), err
+	case protoreflect.EnumKind:
+		// Enums can be set by name or by number
+		if name, ok := value.(string); ok {
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(name)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), nil
+			}
+		}
+		v, err := internal.ToInt32(value)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid value %v for enum %q", value, fd.Enum().FullName())
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("cannot convert %T to %s", value, fd.Kind())
+}
+
+func convertTimestamp(parent protoreflect.Message, fd protoreflect.FieldDescriptor, ts time.Time, format string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		if fd.Message().FullName() != timestampMessage {
+			break
+		}
+		msg := parent.NewField(fd).Message()
+		fields := msg.Descriptor().Fields()
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(ts.Unix()))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(int32(ts.Nanosecond())))
+		return protoreflect.ValueOfMessage(msg), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var v int64
+		switch format {
+		case "unix":
+			v = ts.Unix()
+		case "unix_ms":
+			v = ts.UnixMilli()
+		case "unix_us":
+			v = ts.UnixMicro()
+		case "unix_ns":
+			v = ts.UnixNano()
+		}
+		return convertValue(fd, v)
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		return convertValue(fd, float64(ts.UnixNano())/float64(time.Second))
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(ts.Format(time.RFC3339Nano)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("cannot store timestamp in field of type %s", fd.Kind())
+}
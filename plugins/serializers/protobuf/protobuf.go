@@ -0,0 +1,301 @@
+package protobuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type Serializer struct {
+	Files            []string          `toml:"protobuf_files"`
+	ImportPaths      []string          `toml:"protobuf_import_paths"`
+	MessageType      string            `toml:"protobuf_message_type"`
+	BatchMessageType string            `toml:"protobuf_batch_message_type"`
+	Framing          string            `toml:"protobuf_framing"`
+	MeasurementField string            `toml:"protobuf_measurement_field"`
+	TimestampField   string            `toml:"protobuf_timestamp_field"`
+	TimestampFormat  string            `toml:"protobuf_timestamp_format"`
+	TagMapping       map[string]string `toml:"protobuf_tag_mapping"`
+	FieldMapping     map[string]string `toml:"protobuf_field_mapping"`
+	TagsField        string            `toml:"protobuf_tags_field"`
+	FieldsField      string            `toml:"protobuf_fields_field"`
+	Log              telegraf.Logger   `toml:"-"`
+
+	msgDesc     protoreflect.MessageDescriptor
+	batchDesc   protoreflect.MessageDescriptor
+	batchField  protoreflect.FieldDescriptor
+	measurement fieldPath
+	timestamp   fieldPath
+	tags        map[string]fieldPath
+	fields      map[string]fieldPath
+	tagsMap     fieldPath
+	fieldsMap   fieldPath
+	marshaller  proto.MarshalOptions
+}
+
+func (s *Serializer) Init() error {
+	switch s.Framing {
+	case "":
+		s.Framing = "none"
+	case "none", "length_delimited":
+	default:
+		return fmt.Errorf("invalid 'protobuf_framing' %q", s.Framing)
+	}
+
+	switch s.TimestampFormat {
+	case "":
+		s.TimestampFormat = "unix_ns"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid 'protobuf_timestamp_format' %q", s.TimestampFormat)
+	}
+
+	if len(s.Files) == 0 {
+		return errors.New("'protobuf_files' must be specified")
+	}
+	if s.MessageType == "" {
+		return errors.New("'protobuf_message_type' must be specified")
+	}
+
+	// Load the message definitions
+	resolver := &protocompile.SourceResolver{ImportPaths: s.ImportPaths}
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(resolver),
+	}
+	files, err := compiler.Compile(context.Background(), s.Files...)
+	if err != nil {
+		return fmt.Errorf("parsing protocol-buffer definition failed: %w", err)
+	}
+	var registry protoregistry.Files
+	for _, f := range files {
+		if err := registry.RegisterFile(f); err != nil {
+			return fmt.Errorf("adding file %q to registry failed: %w", f.Path(), err)
+		}
+	}
+
+	s.msgDesc, err = findMessage(&registry, s.MessageType)
+	if err != nil {
+		return err
+	}
+
+	if s.BatchMessageType != "" {
+		s.batchDesc, err = findMessage(&registry, s.BatchMessageType)
+		if err != nil {
+			return err
+		}
+
+		// Use the first repeated field of the message type to hold the batch
+		fields := s.batchDesc.Fields()
+		for i := range fields.Len() {
+			fd := fields.Get(i)
+			if fd.IsList() && fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == s.msgDesc.FullName() {
+				s.batchField = fd
+				break
+			}
+		}
+		if s.batchField == nil {
+			return fmt.Errorf("batch message %q has no repeated field of type %q", s.BatchMessageType, s.MessageType)
+		}
+	}
+
+	// Resolve the field mapping
+	if s.MeasurementField != "" {
+		if s.measurement, err = resolvePath(s.msgDesc, s.MeasurementField); err != nil {
+			return fmt.Errorf("measurement field: %w", err)
+		}
+	}
+	if s.TimestampField != "" {
+		if s.timestamp, err = resolvePath(s.msgDesc, s.TimestampField); err != nil {
+			return fmt.Errorf("timestamp field: %w", err)
+		}
+	}
+	s.tags = make(map[string]fieldPath, len(s.TagMapping))
+	for key, name := range s.TagMapping {
+		if s.tags[key], err = resolvePath(s.msgDesc, name); err != nil {
+			return fmt.Errorf("mapping of tag %q: %w", key, err)
+		}
+	}
+	s.fields = make(map[string]fieldPath, len(s.FieldMapping))
+	for key, name := range s.FieldMapping {
+		if s.fields[key], err = resolvePath(s.msgDesc, name); err != nil {
+			return fmt.Errorf("mapping of field %q: %w", key, err)
+		}
+	}
+	if s.TagsField != "" {
+		if s.tagsMap, err = resolveMapPath(s.msgDesc, s.TagsField); err != nil {
+			return fmt.Errorf("tags field: %w", err)
+		}
+		if s.tagsMap.leaf().MapValue().Kind() != protoreflect.StringKind {
+			return fmt.Errorf("tags field %q must be of type map<string, string>", s.TagsField)
+		}
+	}
+	if s.FieldsField != "" {
+		if s.fieldsMap, err = resolveMapPath(s.msgDesc, s.FieldsField); err != nil {
+			return fmt.Errorf("fields field: %w", err)
+		}
+	}
+
+	s.marshaller = proto.MarshalOptions{Deterministic: true}
+
+	return nil
+}
+
+func (s *Serializer) Serialize(m telegraf.Metric) ([]byte, error) {
+	msg, err := s.message(m)
+	if err != nil {
+		return nil, err
+	}
+	return s.marshal(msg)
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	// Without a batch message the individual messages are concatenated. The
+	// messages are always length-delimited as they could not be separated
+	// otherwise.
+	if s.batchDesc == nil {
+		serialized := make([]byte, 0)
+		for _, m := range metrics {
+			msg, err := s.message(m)
+			if err != nil {
+				return nil, err
+			}
+			buf, err := s.marshaller.Marshal(msg.Interface())
+			if err != nil {
+				return nil, err
+			}
+			serialized = protowire.AppendBytes(serialized, buf)
+		}
+		return serialized, nil
+	}
+
+	batch := dynamicpb.NewMessage(s.batchDesc)
+	list := batch.Mutable(s.batchField).List()
+	for _, m := range metrics {
+		msg, err := s.message(m)
+		if err != nil {
+			return nil, err
+		}
+		list.Append(protoreflect.ValueOfMessage(msg))
+	}
+	return s.marshal(batch)
+}
+
+func (s *Serializer) marshal(msg protoreflect.Message) ([]byte, error) {
+	buf, err := s.marshaller.Marshal(msg.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if s.Framing == "length_delimited" {
+		return protowire.AppendBytes(make([]byte, 0, protowire.SizeBytes(len(buf))), buf), nil
+	}
+	return buf, nil
+}
+
+// message fills a new message with the values of the metric. Tags and fields
+// are assigned, in order of precedence, to the explicitly mapped message
+// field, the top-level scalar field of the same name or the tags and fields
+// map field. Tags and fields without a target field are dropped.
+func (s *Serializer) message(m telegraf.Metric) (protoreflect.Message, error) {
+	msg := dynamicpb.NewMessage(s.msgDesc)
+
+	if s.measurement != nil {
+		if err := s.measurement.set(msg, m.Name(), s.TimestampFormat); err != nil {
+			return nil, fmt.Errorf("setting measurement failed: %w", err)
+		}
+	}
+	if s.timestamp != nil {
+		if err := s.timestamp.set(msg, m.Time(), s.TimestampFormat); err != nil {
+			return nil, fmt.Errorf("setting timestamp failed: %w", err)
+		}
+	}
+
+	for _, tag := range m.TagList() {
+		path, found := s.tags[tag.Key]
+		if !found {
+			path = s.byName(tag.Key)
+		}
+		if path != nil {
+			if err := path.set(msg, tag.Value, s.TimestampFormat); err != nil {
+				return nil, fmt.Errorf("setting tag %q failed: %w", tag.Key, err)
+			}
+			continue
+		}
+		if s.tagsMap != nil {
+			if err := s.tagsMap.setMapEntry(msg, tag.Key, tag.Value); err != nil {
+				return nil, fmt.Errorf("setting tag %q failed: %w", tag.Key, err)
+			}
+		}
+	}
+
+	for _, field := range m.FieldList() {
+		path, found := s.fields[field.Key]
+		if !found {
+			path = s.byName(field.Key)
+		}
+		if path != nil {
+			if err := path.set(msg, field.Value, s.TimestampFormat); err != nil {
+				return nil, fmt.Errorf("setting field %q failed: %w", field.Key, err)
+			}
+			continue
+		}
+		if s.fieldsMap != nil {
+			if err := s.fieldsMap.setMapEntry(msg, field.Key, field.Value); err != nil {
+				return nil, fmt.Errorf("setting field %q failed: %w", field.Key, err)
+			}
+		}
+	}
+
+	return msg, nil
+}
+
+// byName returns the top-level scalar message field with the given name if
+// the field is not used for the measurement or timestamp
+func (s *Serializer) byName(name string) fieldPath {
+	fd := s.msgDesc.Fields().ByName(protoreflect.Name(name))
+	if fd == nil || fd.Cardinality() == protoreflect.Repeated || fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+		return nil
+	}
+	if (s.measurement != nil && s.measurement.leaf() == fd) || (s.timestamp != nil && s.timestamp.leaf() == fd) {
+		return nil
+	}
+	return fieldPath{fd}
+}
+
+func findMessage(registry *protoregistry.Files, name string) (protoreflect.MessageDescriptor, error) {
+	descriptor, err := registry.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		var known []string
+		registry.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			messages := fd.Messages()
+			for i := range messages.Len() {
+				known = append(known, string(messages.Get(i).FullName()))
+			}
+			return true
+		})
+		return nil, fmt.Errorf("message type %q not found, known types are %s", name, strings.Join(known, ", "))
+	}
+	desc, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message descriptor (%T)", name, descriptor)
+	}
+	return desc, nil
+}
+
+func init() {
+	serializers.Add("protobuf",
+		func() telegraf.Serializer {
+			return &Serializer{}
+		},
+	)
+}
@@ -0,0 +1,346 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/xpath"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func decode(t *testing.T, desc protoreflect.MessageDescriptor, buf []byte) string {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(buf, msg))
+	out, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestInitInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Serializer
+		expected string
+	}{
+		{
+			name:     "no files",
+			plugin:   &Serializer{MessageType: "telegraf.test.Metric"},
+			expected: "'protobuf_files' must be specified",
+		},
+		{
+			name:     "no message type",
+			plugin:   &Serializer{Files: []string{"testdata/metrics.proto"}},
+			expected: "'protobuf_message_type' must be specified",
+		},
+		{
+			name:     "invalid framing",
+			plugin:   &Serializer{Framing: "foo"},
+			expected: `invalid 'protobuf_framing' "foo"`,
+		},
+		{
+			name: "unknown message type",
+			plugin: &Serializer{
+				Files:       []string{"testdata/metrics.proto"},
+				MessageType: "telegraf.test.Unknown",
+			},
+			expected: `message type "telegraf.test.Unknown" not found, known types are telegraf.test.Details, telegraf.test.Metric, telegraf.test.Batch`,
+		},
+		{
+			name: "invalid batch message type",
+			plugin: &Serializer{
+				Files:            []string{"testdata/metrics.proto"},
+				MessageType:      "telegraf.test.Metric",
+				BatchMessageType: "telegraf.test.Details",
+			},
+			expected: `batch message "telegraf.test.Details" has no repeated field of type "telegraf.test.Metric"`,
+		},
+		{
+			name: "unknown field",
+			plugin: &Serializer{
+				Files:        []string{"testdata/metrics.proto"},
+				MessageType:  "telegraf.test.Metric",
+				FieldMapping: map[string]string{"usage": "details.foo"},
+			},
+			expected: `mapping of field "usage": message "telegraf.test.Details" has no field "foo"`,
+		},
+		{
+			name: "repeated field",
+			plugin: &Serializer{
+				Files:       []string{"testdata/metrics.proto"},
+				MessageType: "telegraf.test.Metric",
+				TagMapping:  map[string]string{"label": "labels"},
+			},
+			expected: `mapping of tag "label": field "labels" is a repeated field`,
+		},
+		{
+			name: "message field",
+			plugin: &Serializer{
+				Files:            []string{"testdata/metrics.proto"},
+				MessageType:      "telegraf.test.Metric",
+				MeasurementField: "details",
+			},
+			expected: `measurement field: field "details" of message type "telegraf.test.Details" is not supported`,
+		},
+		{
+			name: "tags field not a map",
+			plugin: &Serializer{
+				Files:       []string{"testdata/metrics.proto"},
+				MessageType: "telegraf.test.Metric",
+				TagsField:   "host",
+			},
+			expected: `tags field: field "host" is not a map with string keys`,
+		},
+		{
+			name: "tags field with non-string values",
+			plugin: &Serializer{
+				Files:       []string{"testdata/metrics.proto"},
+				MessageType: "telegraf.test.Metric",
+				TagsField:   "values",
+			},
+			expected: `tags field "values" must be of type map<string, string>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	plugin := &Serializer{
+		Files:            []string{"testdata/metrics.proto"},
+		MessageType:      "telegraf.test.Metric",
+		MeasurementField: "name",
+		TimestampField:   "time",
+		TagMapping:       map[string]string{"region": "details.region"},
+		FieldMapping:     map[string]string{"usage_idle": "details.usage"},
+		TagsField:        "tags",
+		FieldsField:      "values",
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{
+			"host":   "server01",
+			"region": "eu-west",
+			"status": "FAILED",
+			"cpu":    "cpu0",
+		},
+		map[string]interface{}{
+			"usage_idle":   98.5,
+			"usage_system": int64(1),
+			"count":        uint64(42),
+		},
+		time.Unix(1700000000, 123456789),
+	)
+
+	buf, err := plugin.Serialize(m)
+	require.NoError(t, err)
+
+	expected := `
+	{
+		"name": "cpu",
+		"time": "2023-11-14T22:13:20.123456789Z",
+		"host": "server01",
+		"status": "FAILED",
+		"details": {"region": "eu-west", "usage": 98.5},
+		"tags": {"cpu": "cpu0"},
+		"values": {"usage_system": 1},
+		"count": "42"
+	}`
+	require.JSONEq(t, expected, decode(t, plugin.msgDesc, buf))
+}
+
+func TestSerializeDropUnmapped(t *testing.T) {
+	plugin := &Serializer{
+		Files:       []string{"testdata/metrics.proto"},
+		MessageType: "telegraf.test.Metric",
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01", "cpu": "cpu0"},
+		map[string]interface{}{"count": 3, "status": 1, "usage_idle": 98.5},
+		time.Unix(1700000000, 0),
+	)
+
+	buf, err := plugin.Serialize(m)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"host": "server01", "count": "3", "status": "OK"}`, decode(t, plugin.msgDesc, buf))
+}
+
+func TestSerializeConversionError(t *testing.T) {
+	plugin := &Serializer{
+		Files:       []string{"testdata/metrics.proto"},
+		MessageType: "telegraf.test.Metric",
+		FieldsField: "values",
+	}
+	require.NoError(t, plugin.Init())
+
+	m := metric.New(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"mode": "idle"},
+		time.Unix(1700000000, 0),
+	)
+
+	_, err := plugin.Serialize(m)
+	require.ErrorContains(t, err, `setting field "mode" failed`)
+}
+
+func TestSerializeTimestampFormats(t *testing.T) {
+	tests := []struct {
+		field    string
+		format   string
+		expected string
+	}{
+		{field: "count", format: "unix", expected: `{"count": "1700000000"}`},
+		{field: "count", format: "unix_ms", expected: `{"count": "1700000000123"}`},
+		{field: "count", format: "unix_us", expected: `{"count": "1700000000123456"}`},
+		{field: "count", format: "unix_ns", expected: `{"count": "1700000000123456789"}`},
+		{field: "host", format: "unix", expected: `{"host": "2023-11-14T22:13:20.123456789Z"}`},
+		{field: "details.usage", format: "unix", expected: `{"details": {"usage": 1700000000.1234567}}`},
+	}
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(1700000000, 123456789))
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.format, func(t *testing.T) {
+			plugin := &Serializer{
+				Files:           []string{"testdata/metrics.proto"},
+				MessageType:     "telegraf.test.Metric",
+				TimestampField:  tt.field,
+				TimestampFormat: tt.format,
+			}
+			require.NoError(t, plugin.Init())
+
+			buf, err := plugin.Serialize(m)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.expected, decode(t, plugin.msgDesc, buf))
+		})
+	}
+}
+
+func TestSerializeLengthDelimited(t *testing.T) {
+	// Batches without batch message are always length-delimited
+	for _, framing := range []string{"none", "length_delimited"} {
+		t.Run(framing, func(t *testing.T) {
+			plugin := &Serializer{
+				Files:            []string{"testdata/metrics.proto"},
+				MessageType:      "telegraf.test.Metric",
+				MeasurementField: "name",
+				Framing:          framing,
+			}
+			require.NoError(t, plugin.Init())
+
+			metrics := []telegraf.Metric{
+				metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"count": 1}, time.Unix(0, 0)),
+				metric.New("mem", map[string]string{"host": "b"}, map[string]interface{}{"count": 2}, time.Unix(0, 0)),
+			}
+
+			buf, err := plugin.SerializeBatch(metrics)
+			require.NoError(t, err)
+
+			expected := []string{
+				`{"name": "cpu", "host": "a", "count": "1"}`,
+				`{"name": "mem", "host": "b", "count": "2"}`,
+			}
+			for _, e := range expected {
+				size, n := protowire.ConsumeVarint(buf)
+				require.Positive(t, n)
+				buf = buf[n:]
+				require.JSONEq(t, e, decode(t, plugin.msgDesc, buf[:size]))
+				buf = buf[size:]
+			}
+			require.Empty(t, buf)
+		})
+	}
+}
+
+func TestSerializeBatchMessage(t *testing.T) {
+	plugin := &Serializer{
+		Files:            []string{"testdata/metrics.proto"},
+		MessageType:      "telegraf.test.Metric",
+		BatchMessageType: "telegraf.test.Batch",
+		MeasurementField: "name",
+	}
+	require.NoError(t, plugin.Init())
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"count": 1}, time.Unix(0, 0)),
+		metric.New("mem", map[string]string{"host": "b"}, map[string]interface{}{"count": 2}, time.Unix(0, 0)),
+	}
+
+	buf, err := plugin.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	expected := `
+	{
+		"metrics": [
+			{"name": "cpu", "host": "a", "count": "1"},
+			{"name": "mem", "host": "b", "count": "2"}
+		]
+	}`
+	require.JSONEq(t, expected, decode(t, plugin.batchDesc, buf))
+
+	// Single metrics are not wrapped
+	buf, err = plugin.Serialize(metrics[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "cpu", "host": "a", "count": "1"}`, decode(t, plugin.msgDesc, buf))
+}
+
+func TestRoundTripXPathParser(t *testing.T) {
+	plugin := &Serializer{
+		Files:            []string{"testdata/metrics.proto"},
+		MessageType:      "telegraf.test.Metric",
+		MeasurementField: "name",
+		TimestampField:   "count",
+		FieldMapping:     map[string]string{"usage_idle": "details.usage"},
+	}
+	require.NoError(t, plugin.Init())
+
+	parser := &xpath.Parser{
+		Format:               "xpath_protobuf",
+		DefaultMetricName:    "proto",
+		ProtobufMessageFiles: []string{"testdata/metrics.proto"},
+		ProtobufMessageType:  "telegraf.test.Metric",
+		Configs: []xpath.Config{
+			{
+				MetricQuery:  "name",
+				Timestamp:    "count",
+				TimestampFmt: "unix_ns",
+				Tags:         map[string]string{"host": "host"},
+				Fields:       map[string]string{"usage_idle": "number(details/usage)"},
+			},
+		},
+		Log: testutil.Logger{Name: "parsers.xpath_protobuf"},
+	}
+	require.NoError(t, parser.Init())
+
+	input := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"usage_idle": 98.5},
+		time.Unix(1700000000, 123456789),
+	)
+	buf, err := plugin.Serialize(input)
+	require.NoError(t, err)
+
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{input}, actual)
+}